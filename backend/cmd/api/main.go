@@ -7,6 +7,7 @@ import (
 	"github.com/cyclingstream/backend/internal/logger"
 	"github.com/cyclingstream/backend/internal/server"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -31,13 +32,28 @@ func main() {
 	logger.Info("Database connection established")
 
 	// Initialize chat hub and rate limiter
-	hub := chat.NewHub()
-	rateLimiter := chat.NewRateLimiter()
+	var broadcaster chat.Broadcaster = chat.NewMemoryBroadcaster()
+	if cfg.Chat.Broadcaster == "postgres" {
+		instanceID := cfg.Chat.InstanceID
+		if instanceID == "" {
+			instanceID = uuid.NewString()
+		}
+		broadcaster = chat.NewPostgresBroadcaster(db, instanceID)
+	}
+
+	hub, err := chat.NewHubWithBroadcaster(broadcaster)
+	if err != nil {
+		logger.Fatalf("Failed to start chat broadcaster: %v", err)
+	}
+	defer hub.Close()
+
+	rateLimiter := chat.NewRateLimiter(chatRateLimitConfig(cfg.Chat.RateLimit))
+	defer rateLimiter.Stop()
 
 	// Start hub in background
 	go hub.Run()
 
-	logger.Info("Chat hub started")
+	logger.WithField("broadcaster", cfg.Chat.Broadcaster).Info("Chat hub started")
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,3 +88,18 @@ func main() {
 	}
 }
 
+// chatRateLimitConfig maps the env configuration onto the chat rate limiter
+func chatRateLimitConfig(c config.ChatRateLimitConfig) chat.RateLimitConfig {
+	tier := func(t config.ChatRateLimitTier) chat.RateLimitTier {
+		return chat.RateLimitTier{Burst: t.Burst, Refill: t.Refill}
+	}
+	return chat.RateLimitConfig{
+		Tiers: map[string]chat.RateLimitTier{
+			chat.RateTierAnonymous:  tier(c.Anonymous),
+			chat.RateTierUser:       tier(c.User),
+			chat.RateTierSubscriber: tier(c.Subscriber),
+			chat.RateTierAdmin:      tier(c.Admin),
+		},
+		DuplicateWindow: c.DuplicateWindow,
+	}
+}
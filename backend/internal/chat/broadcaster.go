@@ -0,0 +1,98 @@
+package chat
+
+import "sync"
+
+// BroadcastTarget is the local side of a Broadcaster. The Hub implements it so
+// a broadcaster can hand over messages published anywhere in the cluster and
+// read the local room sizes it needs to share with other instances.
+type BroadcastTarget interface {
+	// DeliverToRoom writes a frame to the clients connected to this instance.
+	DeliverToRoom(raceID string, frame *Frame)
+	// DeliverToUser writes a frame to one user's clients in a room on this instance.
+	DeliverToUser(raceID, userID string, frame *Frame)
+	// LocalRoomCounts returns the number of local clients per room.
+	LocalRoomCounts() map[string]int
+	// DeliverReactions hands over reaction counts shared by another instance.
+	DeliverReactions(raceID string, counts map[string]int)
+}
+
+// Broadcaster fans room messages out to every hub participating in the chat
+// cluster. A single API replica uses the in-memory implementation; multiple
+// replicas share messages through a backplane such as Postgres LISTEN/NOTIFY.
+type Broadcaster interface {
+	// Start attaches the local hub. It must be called once before Publish.
+	Start(target BroadcastTarget) error
+	// Publish delivers a frame to a room on every instance, including this
+	// one. Other instances receive it as JSON.
+	Publish(raceID string, frame *Frame) error
+	// PublishToUser delivers a frame to one user's clients in a room on
+	// every instance, including this one.
+	PublishToUser(raceID, userID string, frame *Frame) error
+	// PublishReactions shares reaction counts made on this instance with the
+	// other instances, so each can send its clients the room's total. They
+	// are not delivered to this instance.
+	PublishReactions(raceID string, counts map[string]int) error
+	// RemoteClientCount returns the number of clients in a room connected to
+	// other instances.
+	RemoteClientCount(raceID string) int
+	// Close releases any backplane resources.
+	Close() error
+}
+
+// MemoryBroadcaster delivers messages only to the local hub.
+type MemoryBroadcaster struct {
+	mu     sync.RWMutex
+	target BroadcastTarget
+}
+
+// NewMemoryBroadcaster creates a broadcaster for single-instance deployments.
+func NewMemoryBroadcaster() *MemoryBroadcaster {
+	return &MemoryBroadcaster{}
+}
+
+// Start attaches the local hub.
+func (b *MemoryBroadcaster) Start(target BroadcastTarget) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.target = target
+	return nil
+}
+
+// Publish delivers the frame to the local hub synchronously.
+func (b *MemoryBroadcaster) Publish(raceID string, frame *Frame) error {
+	b.mu.RLock()
+	target := b.target
+	b.mu.RUnlock()
+
+	if target != nil {
+		target.DeliverToRoom(raceID, frame)
+	}
+	return nil
+}
+
+// PublishToUser delivers the frame to the user's local clients synchronously.
+func (b *MemoryBroadcaster) PublishToUser(raceID, userID string, frame *Frame) error {
+	b.mu.RLock()
+	target := b.target
+	b.mu.RUnlock()
+
+	if target != nil {
+		target.DeliverToUser(raceID, userID, frame)
+	}
+	return nil
+}
+
+// PublishReactions is a no-op because there are no other instances.
+func (b *MemoryBroadcaster) PublishReactions(raceID string, counts map[string]int) error {
+	return nil
+}
+
+// RemoteClientCount always returns zero because there are no other instances.
+func (b *MemoryBroadcaster) RemoteClientCount(raceID string) int {
+	return 0
+}
+
+// Close is a no-op for the in-memory broadcaster.
+func (b *MemoryBroadcaster) Close() error {
+	return nil
+}
@@ -0,0 +1,382 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/database"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/lib/pq"
+)
+
+const (
+	// pgMessageChannel carries room messages between instances
+	pgMessageChannel = "chat_broadcast"
+	// pgPresenceChannel carries per-instance room client counts
+	pgPresenceChannel = "chat_presence"
+	// pgReactionChannel carries per-instance room reaction counts
+	pgReactionChannel = "chat_reactions"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more
+	pgMaxPayloadBytes = 7900
+
+	// How often each instance shares its room counts
+	presenceInterval = 5 * time.Second
+	// Remote counts older than this are ignored (instance presumed gone)
+	presenceTTL = 3 * presenceInterval
+
+	listenerMinReconnect = 1 * time.Second
+	listenerMaxReconnect = 30 * time.Second
+	listenerPingInterval = 90 * time.Second
+)
+
+// pgMessageEnvelope is the NOTIFY payload for room messages. UserID is set
+// for messages meant for a single user's clients.
+type pgMessageEnvelope struct {
+	Origin  string `json:"o"`
+	RaceID  string `json:"r"`
+	UserID  string `json:"u,omitempty"`
+	Message []byte `json:"m"`
+}
+
+// pgPresenceEnvelope is the NOTIFY payload for presence heartbeats.
+type pgPresenceEnvelope struct {
+	Origin string         `json:"o"`
+	Counts map[string]int `json:"c"`
+}
+
+// pgReactionEnvelope is the NOTIFY payload for a room's reaction counts.
+type pgReactionEnvelope struct {
+	Origin string         `json:"o"`
+	RaceID string         `json:"r"`
+	Counts map[string]int `json:"c"`
+}
+
+type remotePresence struct {
+	counts map[string]int
+	seenAt time.Time
+}
+
+// PostgresBroadcaster shares room messages between API replicas using
+// Postgres LISTEN/NOTIFY. Messages are delivered to the local hub immediately
+// and relayed to other instances, which ignore notifications they originated.
+type PostgresBroadcaster struct {
+	db         *database.DB
+	instanceID string
+	listener   *pq.Listener
+
+	mu       sync.RWMutex
+	target   BroadcastTarget
+	presence map[string]*remotePresence // instanceID -> last heartbeat
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPostgresBroadcaster creates a broadcaster backed by the given database.
+// instanceID must be unique per running API process.
+func NewPostgresBroadcaster(db *database.DB, instanceID string) *PostgresBroadcaster {
+	return &PostgresBroadcaster{
+		db:         db,
+		instanceID: instanceID,
+		presence:   make(map[string]*remotePresence),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start opens the LISTEN connection and begins relaying notifications.
+func (b *PostgresBroadcaster) Start(target BroadcastTarget) error {
+	if b.db == nil {
+		return errors.New("postgres broadcaster requires a database")
+	}
+
+	b.mu.Lock()
+	b.target = target
+	b.mu.Unlock()
+
+	b.listener = b.db.NewListener(listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.WithFields(map[string]interface{}{
+				"event": ev,
+				"error": err.Error(),
+			}).Warn("Chat broadcaster listener event")
+		}
+	})
+
+	for _, channel := range []string{pgMessageChannel, pgPresenceChannel, pgReactionChannel} {
+		if err := b.listener.Listen(channel); err != nil {
+			_ = b.listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	b.wg.Add(2)
+	go b.listen()
+	go b.heartbeat()
+
+	logger.WithField("instance_id", b.instanceID).Info("Postgres chat broadcaster started")
+	return nil
+}
+
+// Publish delivers the frame locally and notifies the other instances.
+func (b *PostgresBroadcaster) Publish(raceID string, frame *Frame) error {
+	b.mu.RLock()
+	target := b.target
+	b.mu.RUnlock()
+
+	if target != nil {
+		target.DeliverToRoom(raceID, frame)
+	}
+
+	message, err := frame.JSON()
+	if err != nil {
+		return err
+	}
+	return b.notify(pgMessageEnvelope{
+		Origin:  b.instanceID,
+		RaceID:  raceID,
+		Message: message,
+	})
+}
+
+// PublishToUser delivers the frame to the user's local clients and notifies
+// the other instances.
+func (b *PostgresBroadcaster) PublishToUser(raceID, userID string, frame *Frame) error {
+	b.mu.RLock()
+	target := b.target
+	b.mu.RUnlock()
+
+	if target != nil {
+		target.DeliverToUser(raceID, userID, frame)
+	}
+
+	message, err := frame.JSON()
+	if err != nil {
+		return err
+	}
+	return b.notify(pgMessageEnvelope{
+		Origin:  b.instanceID,
+		RaceID:  raceID,
+		UserID:  userID,
+		Message: message,
+	})
+}
+
+// PublishReactions notifies the other instances of a room's reaction counts.
+func (b *PostgresBroadcaster) PublishReactions(raceID string, counts map[string]int) error {
+	return b.notifyChannel(pgReactionChannel, pgReactionEnvelope{
+		Origin: b.instanceID,
+		RaceID: raceID,
+		Counts: counts,
+	})
+}
+
+func (b *PostgresBroadcaster) notify(env pgMessageEnvelope) error {
+	return b.notifyChannel(pgMessageChannel, env)
+}
+
+func (b *PostgresBroadcaster) notifyChannel(channel string, env interface{}) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat broadcast: %w", err)
+	}
+
+	if len(payload) > pgMaxPayloadBytes {
+		return fmt.Errorf("chat broadcast payload too large for NOTIFY (%d bytes)", len(payload))
+	}
+
+	if _, err := b.db.Exec(`SELECT pg_notify($1, $2)`, channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify chat broadcast: %w", err)
+	}
+
+	return nil
+}
+
+// RemoteClientCount sums the most recent counts reported by other instances.
+func (b *PostgresBroadcaster) RemoteClientCount(raceID string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := time.Now()
+	total := 0
+	for _, p := range b.presence {
+		if now.Sub(p.seenAt) > presenceTTL {
+			continue
+		}
+		total += p.counts[raceID]
+	}
+	return total
+}
+
+// Close stops the relay goroutines, withdraws this instance's presence and
+// closes the LISTEN connection.
+func (b *PostgresBroadcaster) Close() error {
+	var err error
+	b.stopOnce.Do(func() {
+		close(b.stop)
+		b.wg.Wait()
+
+		// Tell the other instances we no longer hold any clients
+		b.publishPresence(map[string]int{})
+
+		if b.listener != nil {
+			err = b.listener.Close()
+		}
+	})
+	return err
+}
+
+func (b *PostgresBroadcaster) listen() {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case n := <-b.listener.Notify:
+			// A nil notification is sent after the listener reconnects
+			if n == nil {
+				continue
+			}
+			b.handleNotification(n.Channel, []byte(n.Extra))
+
+		case <-time.After(listenerPingInterval):
+			go func() {
+				if err := b.listener.Ping(); err != nil {
+					logger.WithError(err).Warn("Chat broadcaster listener ping failed")
+				}
+			}()
+
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *PostgresBroadcaster) heartbeat() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	b.sharePresence()
+	for {
+		select {
+		case <-ticker.C:
+			b.sharePresence()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *PostgresBroadcaster) sharePresence() {
+	b.mu.RLock()
+	target := b.target
+	b.mu.RUnlock()
+
+	counts := map[string]int{}
+	if target != nil {
+		counts = target.LocalRoomCounts()
+	}
+	b.publishPresence(counts)
+	b.prunePresence()
+}
+
+func (b *PostgresBroadcaster) publishPresence(counts map[string]int) {
+	payload, err := json.Marshal(pgPresenceEnvelope{
+		Origin: b.instanceID,
+		Counts: counts,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to marshal chat presence")
+		return
+	}
+
+	if len(payload) > pgMaxPayloadBytes {
+		logger.WithField("bytes", len(payload)).Warn("Chat presence payload too large for NOTIFY")
+		return
+	}
+
+	if _, err := b.db.Exec(`SELECT pg_notify($1, $2)`, pgPresenceChannel, string(payload)); err != nil {
+		logger.WithError(err).Warn("Failed to notify chat presence")
+	}
+}
+
+func (b *PostgresBroadcaster) prunePresence() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for id, p := range b.presence {
+		if now.Sub(p.seenAt) > presenceTTL {
+			delete(b.presence, id)
+		}
+	}
+}
+
+// handleNotification applies a NOTIFY payload received from any instance.
+func (b *PostgresBroadcaster) handleNotification(channel string, payload []byte) {
+	switch channel {
+	case pgMessageChannel:
+		var env pgMessageEnvelope
+		if err := json.Unmarshal(payload, &env); err != nil {
+			logger.WithError(err).Warn("Discarding malformed chat broadcast")
+			return
+		}
+		// Our own messages were already delivered locally in Publish
+		if env.Origin == b.instanceID || env.RaceID == "" {
+			return
+		}
+
+		b.mu.RLock()
+		target := b.target
+		b.mu.RUnlock()
+		if target == nil {
+			return
+		}
+		frame := NewJSONFrame(env.Message)
+		if env.UserID != "" {
+			target.DeliverToUser(env.RaceID, env.UserID, frame)
+		} else {
+			target.DeliverToRoom(env.RaceID, frame)
+		}
+
+	case pgReactionChannel:
+		var env pgReactionEnvelope
+		if err := json.Unmarshal(payload, &env); err != nil {
+			logger.WithError(err).Warn("Discarding malformed chat reactions")
+			return
+		}
+		if env.Origin == b.instanceID || env.RaceID == "" || len(env.Counts) == 0 {
+			return
+		}
+
+		b.mu.RLock()
+		target := b.target
+		b.mu.RUnlock()
+		if target != nil {
+			target.DeliverReactions(env.RaceID, env.Counts)
+		}
+
+	case pgPresenceChannel:
+		var env pgPresenceEnvelope
+		if err := json.Unmarshal(payload, &env); err != nil {
+			logger.WithError(err).Warn("Discarding malformed chat presence")
+			return
+		}
+		if env.Origin == b.instanceID || env.Origin == "" {
+			return
+		}
+
+		b.mu.Lock()
+		if len(env.Counts) == 0 {
+			delete(b.presence, env.Origin)
+		} else {
+			b.presence[env.Origin] = &remotePresence{counts: env.Counts, seenAt: time.Now()}
+		}
+		b.mu.Unlock()
+	}
+}
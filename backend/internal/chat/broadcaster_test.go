@@ -0,0 +1,191 @@
+package chat
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroadcaster records published messages and reports fixed remote counts
+type fakeBroadcaster struct {
+	mu        sync.Mutex
+	target    BroadcastTarget
+	published []string
+	remote    map[string]int
+}
+
+func (f *fakeBroadcaster) Start(target BroadcastTarget) error {
+	f.target = target
+	return nil
+}
+
+func (f *fakeBroadcaster) Publish(raceID string, frame *Frame) error {
+	f.mu.Lock()
+	f.published = append(f.published, raceID)
+	f.mu.Unlock()
+	f.target.DeliverToRoom(raceID, frame)
+	return nil
+}
+
+func (f *fakeBroadcaster) PublishToUser(raceID, userID string, frame *Frame) error {
+	f.mu.Lock()
+	f.published = append(f.published, raceID+"/"+userID)
+	f.mu.Unlock()
+	f.target.DeliverToUser(raceID, userID, frame)
+	return nil
+}
+
+func (f *fakeBroadcaster) PublishReactions(raceID string, counts map[string]int) error {
+	f.mu.Lock()
+	f.published = append(f.published, raceID+"/reactions")
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeBroadcaster) RemoteClientCount(raceID string) int {
+	return f.remote[raceID]
+}
+
+func (f *fakeBroadcaster) Close() error {
+	return nil
+}
+
+func TestHub_BroadcastGoesThroughBroadcaster(t *testing.T) {
+	fake := &fakeBroadcaster{remote: map[string]int{"race-a": 4}}
+	hub, err := NewHubWithBroadcaster(fake)
+	require.NoError(t, err)
+
+	client := createTestClient(hub, nil, "User")
+	hub.RegisterClient(client)
+	hub.JoinRoom(client, "race-a")
+
+	hello, encoded := newTestMessage(t, "hello")
+	hub.BroadcastToRoom("race-a", hello)
+
+	assert.Equal(t, []string{"race-a"}, fake.published)
+	msg, ok := receiveFrame(client, 100*time.Millisecond)
+	require.True(t, ok, "Client did not receive message")
+	assert.Equal(t, encoded, msg)
+
+	t.Run("Room count includes remote clients", func(t *testing.T) {
+		assert.Equal(t, 1, hub.GetLocalRoomClientCount("race-a"))
+		assert.Equal(t, 5, hub.GetRoomClientCount("race-a"))
+		assert.Equal(t, map[string]int{"race-a": 1}, hub.LocalRoomCounts())
+	})
+}
+
+// fakeTarget captures deliveries from a broadcaster as JSON
+type fakeTarget struct {
+	mu        sync.Mutex
+	delivered map[string][][]byte
+	reactions map[string]map[string]int
+}
+
+func (f *fakeTarget) DeliverToRoom(raceID string, frame *Frame) {
+	message, _ := frame.JSON()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.delivered == nil {
+		f.delivered = make(map[string][][]byte)
+	}
+	f.delivered[raceID] = append(f.delivered[raceID], message)
+}
+
+func (f *fakeTarget) DeliverToUser(raceID, userID string, frame *Frame) {
+	f.DeliverToRoom(raceID+"/"+userID, frame)
+}
+
+func (f *fakeTarget) LocalRoomCounts() map[string]int {
+	return map[string]int{}
+}
+
+func (f *fakeTarget) DeliverReactions(raceID string, counts map[string]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reactions == nil {
+		f.reactions = make(map[string]map[string]int)
+	}
+	f.reactions[raceID] = counts
+}
+
+func TestMemoryBroadcaster(t *testing.T) {
+	target := &fakeTarget{}
+	b := NewMemoryBroadcaster()
+	require.NoError(t, b.Start(target))
+
+	msg, encoded := newTestMessage(t, "msg")
+	require.NoError(t, b.Publish("race-1", NewFrame(msg)))
+	assert.Equal(t, [][]byte{encoded}, target.delivered["race-1"])
+	assert.Equal(t, 0, b.RemoteClientCount("race-1"))
+}
+
+func TestPostgresBroadcaster_HandleNotification(t *testing.T) {
+	target := &fakeTarget{}
+	b := NewPostgresBroadcaster(nil, "instance-a")
+	b.target = target
+
+	notify := func(channel string, v interface{}) {
+		payload, err := json.Marshal(v)
+		require.NoError(t, err)
+		b.handleNotification(channel, payload)
+	}
+
+	t.Run("Delivers messages from other instances", func(t *testing.T) {
+		notify(pgMessageChannel, pgMessageEnvelope{Origin: "instance-b", RaceID: "race-1", Message: []byte(`{"type":"message"}`)})
+		assert.Equal(t, [][]byte{[]byte(`{"type":"message"}`)}, target.delivered["race-1"])
+	})
+
+	t.Run("Delivers user-targeted messages to that user", func(t *testing.T) {
+		notify(pgMessageChannel, pgMessageEnvelope{Origin: "instance-b", RaceID: "race-3", UserID: "user-1", Message: []byte("dm")})
+		assert.Equal(t, [][]byte{[]byte("dm")}, target.delivered["race-3/user-1"])
+		assert.Empty(t, target.delivered["race-3"])
+	})
+
+	t.Run("Ignores its own messages", func(t *testing.T) {
+		notify(pgMessageChannel, pgMessageEnvelope{Origin: "instance-a", RaceID: "race-2", Message: []byte("x")})
+		assert.Empty(t, target.delivered["race-2"])
+	})
+
+	t.Run("Hands reaction counts from other instances to the target", func(t *testing.T) {
+		notify(pgReactionChannel, pgReactionEnvelope{Origin: "instance-b", RaceID: "race-4", Counts: map[string]int{"fire": 2}})
+		notify(pgReactionChannel, pgReactionEnvelope{Origin: "instance-a", RaceID: "race-5", Counts: map[string]int{"fire": 1}})
+		assert.Equal(t, map[string]int{"fire": 2}, target.reactions["race-4"])
+		assert.Empty(t, target.reactions["race-5"], "Own counts are already in the local summary")
+		assert.Empty(t, target.delivered["race-4"], "Counts aren't sent to clients as they are")
+	})
+
+	t.Run("Ignores malformed payloads", func(t *testing.T) {
+		b.handleNotification(pgMessageChannel, []byte("not-json"))
+		b.handleNotification(pgReactionChannel, []byte("not-json"))
+		b.handleNotification(pgPresenceChannel, []byte("not-json"))
+	})
+
+	t.Run("Sums remote presence across instances", func(t *testing.T) {
+		notify(pgPresenceChannel, pgPresenceEnvelope{Origin: "instance-b", Counts: map[string]int{"race-1": 3}})
+		notify(pgPresenceChannel, pgPresenceEnvelope{Origin: "instance-c", Counts: map[string]int{"race-1": 2, "race-9": 1}})
+		notify(pgPresenceChannel, pgPresenceEnvelope{Origin: "instance-a", Counts: map[string]int{"race-1": 100}})
+
+		assert.Equal(t, 5, b.RemoteClientCount("race-1"))
+		assert.Equal(t, 1, b.RemoteClientCount("race-9"))
+	})
+
+	t.Run("Empty presence withdraws an instance", func(t *testing.T) {
+		notify(pgPresenceChannel, pgPresenceEnvelope{Origin: "instance-c", Counts: map[string]int{}})
+		assert.Equal(t, 3, b.RemoteClientCount("race-1"))
+	})
+
+	t.Run("Stale presence is ignored", func(t *testing.T) {
+		b.mu.Lock()
+		b.presence["instance-b"].seenAt = time.Now().Add(-2 * presenceTTL)
+		b.mu.Unlock()
+
+		assert.Equal(t, 0, b.RemoteClientCount("race-1"))
+		b.prunePresence()
+		assert.Empty(t, b.presence)
+	})
+}
@@ -1,7 +1,6 @@
 package chat
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/cyclingstream/backend/internal/logger"
@@ -20,6 +19,9 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 2048
+
+	// Close code sent to clients disconnected for falling behind
+	closeCodeSlowConsumer = websocket.CloseTryAgainLater
 )
 
 // MessageHandler is a function that handles incoming messages from a client
@@ -29,25 +31,36 @@ type MessageHandler func(*Client, *WSMessage)
 type Client struct {
 	hub            *Hub
 	conn           *websocket.Conn
-	send           chan []byte
+	send           *sendQueue
 	userID         *string
 	username       string
 	isAdmin        bool
 	raceID         string
+	role           string
+	badges         []string
+	subscribed     bool
+	protocol       Protocol
 	messageHandler MessageHandler
 	onClose        func(*Client)
 }
 
-// NewClient creates a new Client
+// NewClient creates a new Client. Its protocol follows the subprotocol
+// negotiated on the connection.
 func NewClient(hub *Hub, conn *websocket.Conn, userID *string, username string, isAdmin bool, raceID string, messageHandler MessageHandler, onClose func(*Client)) *Client {
+	protocol := ProtocolJSON
+	if conn != nil && conn.Conn != nil {
+		protocol = ProtocolForSubprotocol(conn.Subprotocol())
+	}
+
 	return &Client{
 		hub:            hub,
 		conn:           conn,
-		send:           make(chan []byte, 256),
+		send:           newSendQueue(sendQueueSize),
 		userID:         userID,
 		username:       username,
 		isAdmin:        isAdmin,
 		raceID:         raceID,
+		protocol:       protocol,
 		messageHandler: messageHandler,
 		onClose:        onClose,
 	}
@@ -80,28 +93,22 @@ func (c *Client) readPump() {
 		}
 
 		// Parse the message
-		var msg WSMessage
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
+		msg, err := c.protocol.Decode(messageBytes)
+		if err != nil {
 			logger.WithError(err).Error("Failed to unmarshal WebSocket message")
-			errorMsg := NewErrorWSMessage("Invalid message format")
-			if errorBytes, err := json.Marshal(errorMsg); err == nil {
-				c.send <- errorBytes
-			}
+			c.Send(NewErrorWSMessage("Invalid message format"))
 			continue
 		}
 
 		// Handle ping messages
 		if msg.Type == string(MessageTypePing) {
-			pongMsg := NewPongWSMessage()
-			if pongBytes, err := json.Marshal(pongMsg); err == nil {
-				c.send <- pongBytes
-			}
+			c.Send(NewPongWSMessage())
 			continue
 		}
 
 		// Forward other messages to the message handler
 		if c.messageHandler != nil {
-			c.messageHandler(c, &msg)
+			c.messageHandler(c, msg)
 		}
 	}
 }
@@ -116,28 +123,20 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.send.ready:
+			frames, closeCode, closed := c.send.drain()
 			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if closed {
+				// The hub closed the queue, or the client fell too far behind
+				closeMessage := []byte{}
+				if closeCode != 0 {
+					closeMessage = websocket.FormatCloseMessage(closeCode, "client too slow")
+				}
+				_ = c.conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			_, _ = w.Write(message)
-
-			// Add queued messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				_, _ = w.Write([]byte{'\n'})
-				_, _ = w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			if err := c.writeFrames(frames); err != nil {
 				return
 			}
 
@@ -150,6 +149,36 @@ func (c *Client) writePump() {
 	}
 }
 
+// writeFrames writes queued frames to the connection. JSON frames are joined
+// into one websocket message with newlines.
+func (c *Client) writeFrames(frames [][]byte) error {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// Binary frames can't be joined with newlines, so each one is its own
+	// websocket message
+	if c.protocol.wsMessageType() == websocket.BinaryMessage {
+		for _, frame := range frames {
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	_, _ = w.Write(frames[0])
+	for _, frame := range frames[1:] {
+		_, _ = w.Write([]byte{'\n'})
+		_, _ = w.Write(frame)
+	}
+	return w.Close()
+}
+
 // Start starts the client's read and write pumps
 func (c *Client) Start() {
 	// Register client with hub
@@ -160,21 +189,85 @@ func (c *Client) Start() {
 	c.readPump()
 }
 
-// SendMessage sends a message to the client
-// Returns true if the message was successfully queued, false if the channel is full
+// Send encodes a message in the client's protocol and queues it.
+// Returns false if it could not be encoded or was dropped.
+func (c *Client) Send(msg *WSMessage) bool {
+	outcome, ok := c.deliver(NewFrame(msg))
+	c.hub.recordDelivery(c.raceID, outcome)
+	return ok && outcome.queued
+}
+
+// deliver queues a frame in the client's protocol. ok is false if it could
+// not be encoded.
+func (c *Client) deliver(frame *Frame) (outcome enqueueOutcome, ok bool) {
+	queued, err := frame.queued(c.protocol)
+	if err != nil {
+		logger.WithError(err).WithField("protocol", c.protocol.String()).Warn("Failed to encode chat frame")
+		return enqueueOutcome{}, false
+	}
+	return c.enqueue(queued), true
+}
+
+// SendMessage queues a message already encoded in the client's protocol
+// Returns true if the message was queued, false if it was dropped because
+// the client is not reading fast enough
 func (c *Client) SendMessage(message []byte) bool {
-	select {
-	case c.send <- message:
-		return true
-	default:
-		// Channel is full, message will be dropped
-		// Log this as a warning since it indicates the client is not reading fast enough
+	outcome := c.enqueue(queuedFrame{data: message})
+	c.hub.recordDelivery(c.raceID, outcome)
+	return outcome.queued
+}
+
+// enqueue adds a frame to the send queue, which drops frames while the client
+// is behind and disconnects it if it stays behind
+func (c *Client) enqueue(frame queuedFrame) enqueueOutcome {
+	outcome := c.send.push(frame, time.Now())
+	if outcome.disconnect {
 		logger.WithFields(map[string]interface{}{
 			"username": c.username,
 			"user_id":  c.userID,
-		}).Warn("Client send channel full, message dropped")
-		return false
+			"race_id":  c.raceID,
+		}).Warn("Disconnecting slow chat client")
+	} else if outcome.dropped > 0 {
+		logger.WithFields(map[string]interface{}{
+			"username": c.username,
+			"user_id":  c.userID,
+		}).Debug("Client send queue full, message dropped")
 	}
+	return outcome
+}
+
+// Protocol returns the encoding the client negotiated
+func (c *Client) Protocol() Protocol {
+	return c.protocol
+}
+
+// SetRoleAndBadges sets the role and badges the client is listed with in
+// room presence. Call it before the client joins rooms.
+func (c *Client) SetRoleAndBadges(role string, badges []string) {
+	c.role = role
+	c.badges = badges
+}
+
+// Role returns the role set with SetRoleAndBadges
+func (c *Client) Role() string {
+	return c.role
+}
+
+// Badges returns the badges set with SetRoleAndBadges
+func (c *Client) Badges() []string {
+	return c.badges
+}
+
+// SetSubscribed records whether the user had an active subscription when
+// they connected, so messages don't look it up again
+func (c *Client) SetSubscribed(subscribed bool) {
+	c.subscribed = subscribed
+}
+
+// IsSubscribed reports whether the user had an active subscription when
+// they connected
+func (c *Client) IsSubscribed() bool {
+	return c.subscribed
 }
 
 // IsAdmin exposes whether the current client is an administrator.
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cyclingstream/backend/internal/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -39,12 +40,9 @@ func TestClient_NewClient(t *testing.T) {
 		client.SendMessage(message)
 
 		// Verify message was queued
-		select {
-		case msg := <-client.send:
-			assert.Equal(t, message, msg)
-		case <-time.After(100 * time.Millisecond):
-			t.Fatal("Client should be able to receive messages")
-		}
+		msg, ok := receiveFrame(client, 100*time.Millisecond)
+		require.True(t, ok, "Client should be able to receive messages")
+		assert.Equal(t, message, msg)
 	})
 
 	t.Run("Anonymous client can receive messages", func(t *testing.T) {
@@ -60,12 +58,9 @@ func TestClient_NewClient(t *testing.T) {
 		message := []byte("broadcast")
 		client.SendMessage(message)
 
-		select {
-		case msg := <-client.send:
-			assert.Equal(t, message, msg)
-		case <-time.After(100 * time.Millisecond):
-			t.Fatal("Anonymous client should be able to receive messages")
-		}
+		msg, ok := receiveFrame(client, 100*time.Millisecond)
+		require.True(t, ok, "Anonymous client should be able to receive messages")
+		assert.Equal(t, message, msg)
 	})
 }
 
@@ -94,38 +89,31 @@ func TestClient_SendMessage(t *testing.T) {
 
 		// Verify all messages are received in order
 		for i, expectedMsg := range messages {
-			select {
-			case msg := <-client.send:
-				assert.Equal(t, expectedMsg, msg, "Message %d should match", i+1)
-			case <-time.After(100 * time.Millisecond):
-				t.Fatalf("Message %d was not received", i+1)
-			}
+			msg, ok := receiveFrame(client, 100*time.Millisecond)
+			require.Truef(t, ok, "Message %d was not received", i+1)
+			assert.Equal(t, expectedMsg, msg, "Message %d should match", i+1)
 		}
 	})
 
-	t.Run("Client drops messages when send channel is full", func(t *testing.T) {
-		// Create a client with a very small channel to test dropping behavior
-		smallChannel := make(chan []byte, 1)
-		client.send = smallChannel
+	t.Run("Client drops messages when send queue is full", func(t *testing.T) {
+		// Use a very small queue to test dropping behavior
+		client.send = newSendQueue(1)
 
-		// Fill the channel
-		smallChannel <- []byte("first")
+		// Fill the queue
+		assert.True(t, client.SendMessage([]byte("first")))
 
 		// Try to send another message (should be dropped, returns false)
 		success := client.SendMessage([]byte("second"))
-		assert.False(t, success, "Message should be dropped when channel is full")
+		assert.False(t, success, "Message should be dropped when queue is full")
 
-		// Verify only first message is in channel
-		msg := <-smallChannel
+		// Verify only first message is queued
+		msg, ok := receiveFrame(client, 0)
+		require.True(t, ok)
 		assert.Equal(t, []byte("first"), msg)
 
 		// Verify second message was dropped
-		select {
-		case <-smallChannel:
-			t.Fatal("Second message should have been dropped")
-		default:
-			// Expected - channel is empty
-		}
+		_, ok = receiveFrame(client, 0)
+		assert.False(t, ok, "Second message should have been dropped")
 	})
 }
 
@@ -141,7 +129,7 @@ func TestClient_MessageHandler(t *testing.T) {
 
 		client := &Client{
 			hub:            hub,
-			send:           make(chan []byte, 256),
+			send:           newSendQueue(sendQueueSize),
 			messageHandler: handler,
 		}
 
@@ -170,7 +158,7 @@ func TestClient_OnClose(t *testing.T) {
 
 		client := &Client{
 			hub:     hub,
-			send:    make(chan []byte, 256),
+			send:    newSendQueue(sendQueueSize),
 			onClose: onClose,
 		}
 
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -10,7 +11,7 @@ import (
 
 // TestRateLimiter_ConcurrentAccess tests rate limiter under concurrent access
 func TestRateLimiter_ConcurrentAccess(t *testing.T) {
-	rl := NewRateLimiter()
+	rl := newTestRateLimiter(0)
 	defer rl.Stop()
 
 	t.Run("Concurrent rate limit checks are thread-safe", func(t *testing.T) {
@@ -28,8 +29,7 @@ func TestRateLimiter_ConcurrentAccess(t *testing.T) {
 			go func() {
 				defer wg.Done()
 				for j := 0; j < messagesPerGoroutine; j++ {
-					allowed := rl.CheckRateLimit(identifier)
-					if allowed {
+					if sendTestMessage(rl, identifier, RateTierUser).Allowed {
 						mu.Lock()
 						allowedCount++
 						mu.Unlock()
@@ -42,28 +42,27 @@ func TestRateLimiter_ConcurrentAccess(t *testing.T) {
 
 		wg.Wait()
 
-		// Should not exceed the rate limit
-		assert.LessOrEqual(t, allowedCount, ChatRateLimitMaxMessages,
+		// Exactly the burst should get through
+		assert.Equal(t, testBurst, allowedCount,
 			"Concurrent access should respect rate limit, got %d allowed messages", allowedCount)
 	})
 
 	t.Run("Multiple identifiers have separate rate limits concurrently", func(t *testing.T) {
 		numIdentifiers := 10
-		messagesPerIdentifier := ChatRateLimitMaxMessages
 
 		var wg sync.WaitGroup
 		successCount := 0
 		var mu sync.Mutex
 
-		// Each identifier should be able to send max messages
+		// Each identifier should be able to send its burst
 		for i := 0; i < numIdentifiers; i++ {
 			wg.Add(1)
 			go func(id int) {
 				defer wg.Done()
-				identifier := "user-" + string(rune(id))
+				identifier := fmt.Sprintf("user-%d", id)
 				localSuccess := 0
-				for j := 0; j < messagesPerIdentifier; j++ {
-					if rl.CheckRateLimit(identifier) {
+				for j := 0; j < 2*testBurst; j++ {
+					if sendTestMessage(rl, identifier, RateTierUser).Allowed {
 						localSuccess++
 					}
 				}
@@ -75,36 +74,35 @@ func TestRateLimiter_ConcurrentAccess(t *testing.T) {
 
 		wg.Wait()
 
-		// Each identifier should have been able to send max messages
-		expectedSuccess := numIdentifiers * ChatRateLimitMaxMessages
+		expectedSuccess := numIdentifiers * testBurst
 		assert.Equal(t, expectedSuccess, successCount,
 			"Each identifier should have separate rate limit, expected %d, got %d", expectedSuccess, successCount)
 	})
 
-	t.Run("GetRemainingMessages is thread-safe under concurrent access", func(t *testing.T) {
+	t.Run("Remaining is thread-safe under concurrent access", func(t *testing.T) {
 		identifier := "remaining-user"
 		numGoroutines := 10
 
-		// Fill up the limit first
-		for i := 0; i < ChatRateLimitMaxMessages; i++ {
-			rl.CheckRateLimit(identifier)
+		// Empty the bucket first
+		for i := 0; i < testBurst; i++ {
+			sendTestMessage(rl, identifier, RateTierUser)
 		}
 
 		var wg sync.WaitGroup
 		remainingValues := make([]int, numGoroutines)
 
-		// Concurrently get remaining messages
+		// Concurrently get remaining tokens
 		for i := 0; i < numGoroutines; i++ {
 			wg.Add(1)
 			go func(idx int) {
 				defer wg.Done()
-				remainingValues[idx] = rl.GetRemainingMessages(identifier)
+				remainingValues[idx] = rl.Remaining(identifier, RateTierUser)
 			}(i)
 		}
 
 		wg.Wait()
 
-		// All should return 0 (limit is full)
+		// All should return 0 (bucket is empty)
 		for i, remaining := range remainingValues {
 			assert.Equal(t, 0, remaining, "Goroutine %d should return 0 remaining", i)
 		}
@@ -145,7 +143,7 @@ func TestHub_ConcurrentBroadcast(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			for i := 0; i < 5; i++ {
-				hub.BroadcastToRoom(raceID, []byte("broadcast message"))
+				hub.BroadcastToRoom(raceID, &WSMessage{Type: "broadcast message"})
 				time.Sleep(10 * time.Millisecond)
 			}
 		}()
@@ -224,7 +222,7 @@ func TestHub_ConcurrentBroadcast(t *testing.T) {
 			wg.Add(1)
 			go func(roomID string) {
 				defer wg.Done()
-				hub.BroadcastToRoom(roomID, []byte("room-specific message"))
+				hub.BroadcastToRoom(roomID, &WSMessage{Type: "room-specific message"})
 			}(rooms[i])
 		}
 
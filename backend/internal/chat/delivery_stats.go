@@ -0,0 +1,60 @@
+package chat
+
+// RoomDeliveryStats describes how well a room's clients on this instance keep
+// up with the frames sent to them. Counters run from when the room was
+// created on this instance.
+type RoomDeliveryStats struct {
+	Clients int `json:"clients"`
+	// Frames waiting to be written, across the room's clients and for the
+	// most backed up one
+	Queued    int `json:"queued"`
+	MaxQueued int `json:"max_queued"`
+
+	Enqueued        int64 `json:"enqueued"`
+	Coalesced       int64 `json:"coalesced"`
+	Dropped         int64 `json:"dropped"`
+	SlowDisconnects int64 `json:"slow_disconnects"`
+}
+
+// DeliveryStats is a snapshot of the hub's send queue counters
+type DeliveryStats struct {
+	QueueSize int                          `json:"queue_size"`
+	Totals    RoomDeliveryStats            `json:"totals"`
+	Rooms     map[string]RoomDeliveryStats `json:"rooms"`
+}
+
+type deliveryCounters struct {
+	enqueued        int64
+	coalesced       int64
+	dropped         int64
+	slowDisconnects int64
+}
+
+func (c *deliveryCounters) add(outcome enqueueOutcome) {
+	if outcome.queued {
+		c.enqueued++
+	}
+	if outcome.coalesced {
+		c.coalesced++
+	}
+	c.dropped += int64(outcome.dropped)
+	if outcome.disconnect {
+		c.slowDisconnects++
+	}
+}
+
+func (c *deliveryCounters) merge(other deliveryCounters) {
+	c.enqueued += other.enqueued
+	c.coalesced += other.coalesced
+	c.dropped += other.dropped
+	c.slowDisconnects += other.slowDisconnects
+}
+
+func (c deliveryCounters) stats() RoomDeliveryStats {
+	return RoomDeliveryStats{
+		Enqueued:        c.enqueued,
+		Coalesced:       c.coalesced,
+		Dropped:         c.dropped,
+		SlowDisconnects: c.slowDisconnects,
+	}
+}
@@ -1,28 +1,263 @@
 package chat
 
 import (
-	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
 )
 
-var specialEmotePattern = regexp.MustCompile(`(?i):(bike|fire|zap|bolt|clap|crown|rocket|heart|star|podium):`)
+const (
+	// Longest emote code, not counting the surrounding colons
+	maxEmoteCodeLength = 32
 
-// IsSpecialEmoteMessage returns true when the message consists entirely of known emotes.
-func IsSpecialEmoteMessage(message string) bool {
-	trimmed := strings.TrimSpace(message)
-	if trimmed == "" {
-		return false
+	// How often the emote set is reloaded to pick up changes made through other instances
+	emoteRefreshInterval = 30 * time.Second
+)
+
+// Built-in emotes, used until the registry has loaded from the store
+var defaultEmoteCodes = []string{"bike", "fire", "zap", "bolt", "clap", "crown", "rocket", "heart", "star", "podium"}
+
+// EmoteStore loads the emote set. It is implemented by repository.ChatEmoteRepository.
+type EmoteStore interface {
+	ListAll() ([]*models.ChatEmote, error)
+}
+
+// EmoteRegistry caches the emote set in memory. Call Refresh after changing
+// emotes; Start also refreshes it periodically.
+type EmoteRegistry struct {
+	mu     sync.RWMutex
+	store  EmoteStore
+	global map[string]*models.ChatEmote            // code -> emote
+	races  map[string]map[string]*models.ChatEmote // raceID -> code -> emote
+
+	stopRefresh chan struct{}
+	refreshDone chan struct{}
+}
+
+// NewEmoteRegistry creates a registry holding the built-in emotes. store may
+// be nil, in which case only the built-in emotes are available.
+func NewEmoteRegistry(store EmoteStore) *EmoteRegistry {
+	global := make(map[string]*models.ChatEmote, len(defaultEmoteCodes))
+	for _, code := range defaultEmoteCodes {
+		global[code] = &models.ChatEmote{Code: code}
 	}
 
-	parts := strings.Fields(trimmed)
-	if len(parts) == 0 {
+	return &EmoteRegistry{
+		store:  store,
+		global: global,
+		races:  make(map[string]map[string]*models.ChatEmote),
+	}
+}
+
+// Refresh reloads the emote set from the store
+func (r *EmoteRegistry) Refresh() error {
+	if r.store == nil {
+		return nil
+	}
+
+	emotes, err := r.store.ListAll()
+	if err != nil {
+		return err
+	}
+
+	global := make(map[string]*models.ChatEmote)
+	races := make(map[string]map[string]*models.ChatEmote)
+	for _, emote := range emotes {
+		if emote.RaceID == nil {
+			global[emote.Code] = emote
+			continue
+		}
+		if races[*emote.RaceID] == nil {
+			races[*emote.RaceID] = make(map[string]*models.ChatEmote)
+		}
+		races[*emote.RaceID][emote.Code] = emote
+	}
+
+	r.mu.Lock()
+	r.global = global
+	r.races = races
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Start refreshes the emote set in the background until Stop is called
+func (r *EmoteRegistry) Start() {
+	r.stopRefresh = make(chan struct{})
+	r.refreshDone = make(chan struct{})
+
+	go func() {
+		defer close(r.refreshDone)
+		ticker := time.NewTicker(emoteRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Refresh(); err != nil {
+					logger.WithError(err).Warn("Failed to refresh chat emotes")
+				}
+			case <-r.stopRefresh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background refresh
+func (r *EmoteRegistry) Stop() {
+	if r.stopRefresh == nil {
+		return
+	}
+	close(r.stopRefresh)
+	<-r.refreshDone
+	r.stopRefresh = nil
+}
+
+// Lookup returns the emote a code refers to in a race, or nil. Race emotes
+// take precedence over global emotes with the same code.
+func (r *EmoteRegistry) Lookup(raceID, code string) *models.ChatEmote {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookupLocked(raceID, strings.ToLower(code))
+}
+
+func (r *EmoteRegistry) lookupLocked(raceID, code string) *models.ChatEmote {
+	if emote, ok := r.races[raceID][code]; ok {
+		return emote
+	}
+	return r.global[code]
+}
+
+// Available returns the emotes usable in a race, sorted by code
+func (r *EmoteRegistry) Available(raceID string) []*models.ChatEmote {
+	r.mu.RLock()
+	byCode := make(map[string]*models.ChatEmote, len(r.global)+len(r.races[raceID]))
+	for code, emote := range r.global {
+		byCode[code] = emote
+	}
+	for code, emote := range r.races[raceID] {
+		byCode[code] = emote
+	}
+	r.mu.RUnlock()
+
+	emotes := make([]*models.ChatEmote, 0, len(byCode))
+	for _, emote := range byCode {
+		emotes = append(emotes, emote)
+	}
+	sort.Slice(emotes, func(i, j int) bool { return emotes[i].Code < emotes[j].Code })
+	return emotes
+}
+
+// ParseEmotes finds the :code: emotes in a message. allowed decides whether
+// the sender may use a gated emote; nil allows every emote. Codes that are
+// unknown or not allowed stay plain text.
+func (r *EmoteRegistry) ParseEmotes(raceID, message string, allowed func(emote *models.ChatEmote) bool) []models.ChatEmoteSpan {
+	var spans []models.ChatEmoteSpan
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for start := strings.IndexByte(message, ':'); start >= 0; {
+		end := strings.IndexByte(message[start+1:], ':')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		code := strings.ToLower(message[start+1 : end])
+		var emote *models.ChatEmote
+		if isEmoteCode(code) {
+			emote = r.lookupLocked(raceID, code)
+		}
+		if emote == nil || (allowed != nil && !allowed(emote)) {
+			// The closing colon may open the next emote (":gg:fire:")
+			start = end
+			continue
+		}
+
+		offset := utf16Len(message[:start])
+		spans = append(spans, models.ChatEmoteSpan{
+			Code:     emote.Code,
+			Start:    offset,
+			End:      offset + utf16Len(message[start:end+1]),
+			ImageURL: emote.ImageURL,
+		})
+
+		next := strings.IndexByte(message[end+1:], ':')
+		if next < 0 {
+			break
+		}
+		start = end + 1 + next
+	}
+
+	return spans
+}
+
+// CanUseEmote reports whether a user with the given level and ticket may use an emote
+func CanUseEmote(emote *models.ChatEmote, level int, hasTicket bool) bool {
+	return level >= emote.MinLevel && (!emote.RequiresTicket || hasTicket)
+}
+
+// IsEmoteOnly returns true when a message consists entirely of the given
+// emote spans and whitespace
+func IsEmoteOnly(message string, spans []models.ChatEmoteSpan) bool {
+	if len(spans) == 0 {
 		return false
 	}
 
-	for _, part := range parts {
-		if !specialEmotePattern.MatchString(part) {
+	units := utf16.Encode([]rune(message))
+	covered := make([]bool, len(units))
+	for _, span := range spans {
+		if span.Start < 0 || span.End > len(units) {
+			return false
+		}
+		for i := span.Start; i < span.End; i++ {
+			covered[i] = true
+		}
+	}
+
+	for i, unit := range units {
+		if !covered[i] && !unicode.IsSpace(rune(unit)) {
 			return false
 		}
 	}
 	return true
 }
+
+// NormalizeEmoteCode lowercases a code and strips surrounding colons. It
+// returns false if the result is not a valid code.
+func NormalizeEmoteCode(code string) (string, bool) {
+	code = strings.ToLower(strings.Trim(strings.TrimSpace(code), ":"))
+	return code, isEmoteCode(code)
+}
+
+func isEmoteCode(code string) bool {
+	if len(code) < 2 || len(code) > maxEmoteCodeLength {
+		return false
+	}
+	for _, c := range code {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEmoteStore struct {
+	emotes []*models.ChatEmote
+}
+
+func (f *fakeEmoteStore) ListAll() ([]*models.ChatEmote, error) {
+	return f.emotes, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestEmoteRegistry_ParseEmotes(t *testing.T) {
+	store := &fakeEmoteStore{emotes: []*models.ChatEmote{
+		{Code: "fire"},
+		{Code: "jersey", ImageURL: strPtr("https://cdn.example.com/global-jersey.png")},
+		{Code: "jersey", ImageURL: strPtr("https://cdn.example.com/tdf-jersey.png"), RaceID: strPtr("race-tdf")},
+		{Code: "legend", ImageURL: strPtr("/emotes/legend.png"), MinLevel: 10},
+	}}
+	registry := NewEmoteRegistry(store)
+	require.NoError(t, registry.Refresh())
+
+	t.Run("Finds known emotes with offsets", func(t *testing.T) {
+		spans := registry.ParseEmotes("race-1", "go :fire: :nope: :JERSEY:", nil)
+		require.Len(t, spans, 2)
+		assert.Equal(t, models.ChatEmoteSpan{Code: "fire", Start: 3, End: 9}, spans[0])
+		assert.Equal(t, "jersey", spans[1].Code)
+		assert.Equal(t, 17, spans[1].Start)
+		assert.Equal(t, 25, spans[1].End)
+		assert.Equal(t, "https://cdn.example.com/global-jersey.png", *spans[1].ImageURL)
+	})
+
+	t.Run("Race emotes override global emotes", func(t *testing.T) {
+		spans := registry.ParseEmotes("race-tdf", ":jersey:", nil)
+		require.Len(t, spans, 1)
+		assert.Equal(t, "https://cdn.example.com/tdf-jersey.png", *spans[0].ImageURL)
+	})
+
+	t.Run("Unknown codes don't swallow the next emote", func(t *testing.T) {
+		spans := registry.ParseEmotes("race-1", ":gg:fire:", nil)
+		require.Len(t, spans, 1)
+		assert.Equal(t, 3, spans[0].Start)
+	})
+
+	t.Run("Offsets count UTF-16 code units", func(t *testing.T) {
+		spans := registry.ParseEmotes("race-1", "🚴 :fire:", nil)
+		require.Len(t, spans, 1)
+		assert.Equal(t, 3, spans[0].Start)
+		assert.Equal(t, 9, spans[0].End)
+	})
+
+	t.Run("Gated emotes stay text when not allowed", func(t *testing.T) {
+		allowed := func(emote *models.ChatEmote) bool { return CanUseEmote(emote, 5, false) }
+		spans := registry.ParseEmotes("race-1", ":legend: :fire:", allowed)
+		require.Len(t, spans, 1)
+		assert.Equal(t, "fire", spans[0].Code)
+	})
+
+	t.Run("Built-in emotes are removed once the store drops them", func(t *testing.T) {
+		assert.Nil(t, registry.Lookup("race-1", "rocket"))
+		assert.NotNil(t, NewEmoteRegistry(nil).Lookup("race-1", "rocket"))
+	})
+}
+
+func TestEmoteRegistry_Available(t *testing.T) {
+	registry := NewEmoteRegistry(&fakeEmoteStore{emotes: []*models.ChatEmote{
+		{Code: "fire"},
+		{Code: "bike"},
+		{Code: "jersey", RaceID: strPtr("race-tdf")},
+	}})
+	require.NoError(t, registry.Refresh())
+
+	codes := func(emotes []*models.ChatEmote) []string {
+		var out []string
+		for _, e := range emotes {
+			out = append(out, e.Code)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"bike", "fire"}, codes(registry.Available("race-1")))
+	assert.Equal(t, []string{"bike", "fire", "jersey"}, codes(registry.Available("race-tdf")))
+}
+
+func TestCanUseEmote(t *testing.T) {
+	emote := &models.ChatEmote{Code: "vip", MinLevel: 15, RequiresTicket: true}
+	assert.False(t, CanUseEmote(emote, 20, false))
+	assert.False(t, CanUseEmote(emote, 10, true))
+	assert.True(t, CanUseEmote(emote, 15, true))
+}
+
+func TestIsEmoteOnly(t *testing.T) {
+	registry := NewEmoteRegistry(nil)
+
+	tests := []struct {
+		message  string
+		expected bool
+	}{
+		{":fire:", true},
+		{"  :fire: :bike:  ", true},
+		{":fire::bike:", true},
+		{":fire: go", false},
+		{"no emotes", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			spans := registry.ParseEmotes("race-1", tt.message, nil)
+			assert.Equal(t, tt.expected, IsEmoteOnly(tt.message, spans))
+		})
+	}
+}
+
+func TestNormalizeEmoteCode(t *testing.T) {
+	code, ok := NormalizeEmoteCode(" :Yellow_Jersey: ")
+	assert.True(t, ok)
+	assert.Equal(t, "yellow_jersey", code)
+
+	_, ok = NormalizeEmoteCode("x")
+	assert.False(t, ok)
+	_, ok = NormalizeEmoteCode("no spaces")
+	assert.False(t, ok)
+}
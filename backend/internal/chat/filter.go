@@ -0,0 +1,234 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+const (
+	// How long a room's filter is trusted before its config is reloaded, so
+	// changes made on another instance take effect
+	filterRefreshInterval = 30 * time.Second
+
+	maxFilterBannedWords = 500
+	maxFilterLinkHosts   = 100
+)
+
+// Filter rule names, as reported in matches
+const (
+	FilterRuleBannedWords = "banned_words"
+	FilterRuleLinks       = "links"
+	FilterRuleCaps        = "caps"
+	FilterRuleEmojiSpam   = "emoji_spam"
+	FilterRuleRepeats     = "repeats"
+)
+
+var filterActionSeverity = map[string]int{
+	models.ChatFilterAllow:  0,
+	models.ChatFilterMask:   1,
+	models.ChatFilterFlag:   2,
+	models.ChatFilterReject: 3,
+}
+
+// FilterVerdict is a rule's decision about a message. Message is the
+// rewritten text when Action is mask.
+type FilterVerdict struct {
+	Action  string
+	Message string
+	Detail  string
+}
+
+// FilterRule is a step in the content filter pipeline
+type FilterRule interface {
+	Name() string
+	Apply(message string) FilterVerdict
+}
+
+// FilterResult is the outcome of running a message through a filter. Action
+// is the most severe action of the rules that matched; Message has every
+// mask applied.
+type FilterResult struct {
+	Action  string
+	Message string
+	Matches []models.ChatFilterMatch
+}
+
+// Filter runs a message through its rules in order. Masks rewrite the message
+// seen by later rules; a reject stops the pipeline.
+type Filter struct {
+	rules []FilterRule
+}
+
+// NewFilter creates a filter from rules
+func NewFilter(rules ...FilterRule) *Filter {
+	return &Filter{rules: rules}
+}
+
+// NewFilterFromConfig builds the standard rule pipeline from a race's config.
+// Rules whose action is allow are left out.
+func NewFilterFromConfig(cfg models.ChatFilterConfig) *Filter {
+	f := NewFilter()
+	if cfg.Repeats.Action != models.ChatFilterAllow {
+		f.Add(&RepeatRule{Action: cfg.Repeats.Action, MaxRun: cfg.Repeats.MaxRun})
+	}
+	if cfg.BannedWords.Action != models.ChatFilterAllow && len(cfg.BannedWords.Words) > 0 {
+		f.Add(NewBannedWordsRule(cfg.BannedWords.Action, cfg.BannedWords.Words))
+	}
+	if cfg.Links.Action != models.ChatFilterAllow || len(cfg.Links.Deny) > 0 {
+		f.Add(NewLinkRule(cfg.Links.Action, cfg.Links.Allow, cfg.Links.Deny))
+	}
+	if cfg.Caps.Action != models.ChatFilterAllow {
+		f.Add(&CapsRule{Action: cfg.Caps.Action, MinLetters: cfg.Caps.MinLetters, MaxRatio: cfg.Caps.MaxRatio})
+	}
+	if cfg.EmojiSpam.Action != models.ChatFilterAllow {
+		f.Add(&EmojiSpamRule{Action: cfg.EmojiSpam.Action, MaxEmojis: cfg.EmojiSpam.MaxEmojis})
+	}
+	return f
+}
+
+// Add appends a rule to the pipeline
+func (f *Filter) Add(rule FilterRule) {
+	f.rules = append(f.rules, rule)
+}
+
+// Run passes a message through every rule
+func (f *Filter) Run(message string) FilterResult {
+	result := FilterResult{Action: models.ChatFilterAllow, Message: message}
+	if f == nil {
+		return result
+	}
+
+	for _, rule := range f.rules {
+		verdict := rule.Apply(result.Message)
+		if verdict.Action == "" || verdict.Action == models.ChatFilterAllow {
+			continue
+		}
+
+		result.Matches = append(result.Matches, models.ChatFilterMatch{
+			Rule:   rule.Name(),
+			Action: verdict.Action,
+			Detail: verdict.Detail,
+		})
+		if filterActionSeverity[verdict.Action] > filterActionSeverity[result.Action] {
+			result.Action = verdict.Action
+		}
+
+		switch verdict.Action {
+		case models.ChatFilterMask:
+			result.Message = verdict.Message
+		case models.ChatFilterReject:
+			return result
+		}
+	}
+
+	// Masking can leave nothing worth sending
+	if strings.TrimSpace(result.Message) == "" {
+		result.Action = models.ChatFilterReject
+	}
+	return result
+}
+
+// DefaultFilterConfig returns the filter used by races without their own config
+func DefaultFilterConfig() models.ChatFilterConfig {
+	return models.ChatFilterConfig{
+		BannedWords: models.ChatFilterBannedWords{Action: models.ChatFilterMask, Words: []string{}},
+		Links:       models.ChatFilterLinks{Action: models.ChatFilterFlag, Allow: []string{}, Deny: []string{}},
+		Caps:        models.ChatFilterCaps{Action: models.ChatFilterMask, MinLetters: 12, MaxRatio: 0.8},
+		EmojiSpam:   models.ChatFilterEmojiSpam{Action: models.ChatFilterMask, MaxEmojis: 10},
+		Repeats:     models.ChatFilterRepeats{Action: models.ChatFilterMask, MaxRun: 4},
+	}
+}
+
+// ValidateFilterConfig checks a filter config set by an admin and normalizes
+// its word and host lists
+func ValidateFilterConfig(cfg *models.ChatFilterConfig) error {
+	actions := [][2]string{
+		{FilterRuleBannedWords, cfg.BannedWords.Action},
+		{FilterRuleLinks, cfg.Links.Action},
+		{FilterRuleCaps, cfg.Caps.Action},
+		{FilterRuleEmojiSpam, cfg.EmojiSpam.Action},
+		{FilterRuleRepeats, cfg.Repeats.Action},
+	}
+	for _, a := range actions {
+		if _, ok := filterActionSeverity[a[1]]; !ok {
+			return &ValidationError{Message: fmt.Sprintf("%s action must be one of: allow, mask, flag, reject", a[0])}
+		}
+	}
+
+	if len(cfg.BannedWords.Words) > maxFilterBannedWords {
+		return &ValidationError{Message: fmt.Sprintf("At most %d banned words are allowed", maxFilterBannedWords)}
+	}
+	if len(cfg.Links.Allow) > maxFilterLinkHosts || len(cfg.Links.Deny) > maxFilterLinkHosts {
+		return &ValidationError{Message: fmt.Sprintf("At most %d link hosts are allowed per list", maxFilterLinkHosts)}
+	}
+	if cfg.Caps.Action != models.ChatFilterAllow && (cfg.Caps.MinLetters < 1 || cfg.Caps.MaxRatio <= 0 || cfg.Caps.MaxRatio >= 1) {
+		return &ValidationError{Message: "caps needs min_letters of at least 1 and max_ratio between 0 and 1"}
+	}
+	if cfg.EmojiSpam.Action != models.ChatFilterAllow && cfg.EmojiSpam.MaxEmojis < 1 {
+		return &ValidationError{Message: "emoji_spam needs max_emojis of at least 1"}
+	}
+	if cfg.Repeats.Action != models.ChatFilterAllow && cfg.Repeats.MaxRun < 2 {
+		return &ValidationError{Message: "repeats needs max_run of at least 2"}
+	}
+
+	cfg.BannedWords.Words = normalizeFilterList(cfg.BannedWords.Words)
+	cfg.Links.Allow = normalizeFilterList(cfg.Links.Allow)
+	cfg.Links.Deny = normalizeFilterList(cfg.Links.Deny)
+	return nil
+}
+
+// normalizeFilterList lowercases, trims and dedupes a word or host list
+func normalizeFilterList(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
+}
+
+type cachedFilter struct {
+	filter   *Filter
+	loadedAt time.Time
+}
+
+// FilterCache holds each room's compiled filter
+type FilterCache struct {
+	mu      sync.Mutex
+	filters map[string]cachedFilter // raceID -> filter
+}
+
+// NewFilterCache creates an empty filter cache
+func NewFilterCache() *FilterCache {
+	return &FilterCache{filters: make(map[string]cachedFilter)}
+}
+
+// Set stores a room's filter
+func (c *FilterCache) Set(raceID string, filter *Filter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filters[raceID] = cachedFilter{filter: filter, loadedAt: time.Now()}
+}
+
+// Get returns a room's filter and whether it is fresh enough to use without
+// reloading it
+func (c *FilterCache) Get(raceID string) (*Filter, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.filters[raceID]
+	if !ok {
+		return nil, false
+	}
+	return cached.filter, time.Since(cached.loadedAt) <= filterRefreshInterval
+}
@@ -0,0 +1,357 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// leetspeakReplacer maps common character substitutions back to letters
+var leetspeakReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g",
+	"@", "a", "$", "s", "!", "i", "|", "l", "+", "t",
+)
+
+// normalizeWord lowercases a word, undoes leetspeak and drops everything
+// that isn't a letter, so "Sh!t" and "s.h.1.t" both become "shit"
+func normalizeWord(word string) string {
+	word = leetspeakReplacer.Replace(strings.ToLower(word))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, word)
+}
+
+// collapseRepeats squeezes runs of the same character to one
+func collapseRepeats(s string) string {
+	var b strings.Builder
+	var last rune = -1
+	for _, r := range s {
+		if r != last {
+			b.WriteRune(r)
+			last = r
+		}
+	}
+	return b.String()
+}
+
+// wordSpans returns the byte ranges of the whitespace-separated words in a
+// message, with surrounding punctuation trimmed
+func wordSpans(message string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range message + " " {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+
+		word := message[start:i]
+		trimmed := strings.TrimLeftFunc(word, isWordEdgePunct)
+		begin := start + len(word) - len(trimmed)
+		trimmed = strings.TrimRightFunc(trimmed, isWordEdgePunct)
+		if trimmed != "" {
+			spans = append(spans, [2]int{begin, begin + len(trimmed)})
+		}
+		start = -1
+	}
+	return spans
+}
+
+// isWordEdgePunct reports punctuation trimmed from word edges. Characters
+// used in leetspeak stay.
+func isWordEdgePunct(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return false
+	}
+	return !strings.ContainsRune("@$|+", r)
+}
+
+// BannedWordsRule matches words from a list. Words are compared after
+// undoing leetspeak, and stretched words ("baaad") match when they collapse
+// to the same letters as a banned word. Mask replaces the word with asterisks.
+type BannedWordsRule struct {
+	Action string
+
+	words     map[string]bool
+	collapsed map[string]int // collapsed word -> shortest banned word length
+}
+
+// NewBannedWordsRule creates a banned words rule
+func NewBannedWordsRule(action string, words []string) *BannedWordsRule {
+	rule := &BannedWordsRule{
+		Action:    action,
+		words:     make(map[string]bool, len(words)),
+		collapsed: make(map[string]int, len(words)),
+	}
+	for _, word := range words {
+		normalized := normalizeWord(word)
+		if normalized == "" {
+			continue
+		}
+		rule.words[normalized] = true
+		key := collapseRepeats(normalized)
+		if n, ok := rule.collapsed[key]; !ok || len(normalized) < n {
+			rule.collapsed[key] = len(normalized)
+		}
+	}
+	return rule
+}
+
+func (r *BannedWordsRule) Name() string { return FilterRuleBannedWords }
+
+func (r *BannedWordsRule) matches(word string) bool {
+	normalized := normalizeWord(word)
+	if normalized == "" {
+		return false
+	}
+	if r.words[normalized] {
+		return true
+	}
+	// A stretched word must be at least as long as the banned word, so "as"
+	// doesn't match "ass"
+	n, ok := r.collapsed[collapseRepeats(normalized)]
+	return ok && len(normalized) >= n
+}
+
+func (r *BannedWordsRule) Apply(message string) FilterVerdict {
+	var b strings.Builder
+	last, count := 0, 0
+	for _, span := range wordSpans(message) {
+		word := message[span[0]:span[1]]
+		if !r.matches(word) {
+			continue
+		}
+		count++
+		b.WriteString(message[last:span[0]])
+		b.WriteString(strings.Repeat("*", len([]rune(word))))
+		last = span[1]
+	}
+	if count == 0 {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+	b.WriteString(message[last:])
+
+	return FilterVerdict{
+		Action:  r.Action,
+		Message: b.String(),
+		Detail:  fmt.Sprintf("%d banned word(s)", count),
+	}
+}
+
+// Links with a scheme or www. prefix always count; bare domains only count
+// with a common TLD so "Mr.Smith" isn't taken for a link
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s]+|\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+(?:com|net|org|io|gg|tv|co|me|ly|be|fr|de|nl|it|es|uk|eu|info|xyz|app|dev|link|live|ru|cn|shop|site|online)\b(?:/[^\s]*)?`)
+
+// linkReplacement replaces masked links
+const linkReplacement = "[link removed]"
+
+// LinkRule matches links. Links to denied hosts are rejected, allowed hosts
+// pass and any other link gets Action. Hosts match themselves and their
+// subdomains. Mask replaces the link.
+type LinkRule struct {
+	Action string
+	Allow  []string
+	Deny   []string
+}
+
+// NewLinkRule creates a link rule
+func NewLinkRule(action string, allow, deny []string) *LinkRule {
+	return &LinkRule{Action: action, Allow: normalizeFilterList(allow), Deny: normalizeFilterList(deny)}
+}
+
+func (r *LinkRule) Name() string { return FilterRuleLinks }
+
+// linkHost extracts the lowercased host from a matched link
+func linkHost(link string) string {
+	host := strings.ToLower(link)
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimPrefix(strings.TrimRight(host, "."), "www.")
+}
+
+func hostMatches(host string, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.TrimPrefix(h, "www.")
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LinkRule) Apply(message string) FilterVerdict {
+	matches := linkPattern.FindAllStringIndex(message, -1)
+	if len(matches) == 0 {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+
+	var b strings.Builder
+	last, count := 0, 0
+	for _, m := range matches {
+		host := linkHost(message[m[0]:m[1]])
+		if hostMatches(host, r.Deny) {
+			return FilterVerdict{Action: models.ChatFilterReject, Detail: "denied host " + host}
+		}
+		if hostMatches(host, r.Allow) {
+			continue
+		}
+		count++
+		b.WriteString(message[last:m[0]])
+		b.WriteString(linkReplacement)
+		last = m[1]
+	}
+	if count == 0 || r.Action == models.ChatFilterAllow {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+	b.WriteString(message[last:])
+
+	return FilterVerdict{
+		Action:  r.Action,
+		Message: b.String(),
+		Detail:  fmt.Sprintf("%d link(s)", count),
+	}
+}
+
+// CapsRule matches shouting: at least MinLetters letters with more than
+// MaxRatio of them in capitals. Mask lowercases the message.
+type CapsRule struct {
+	Action     string
+	MinLetters int
+	MaxRatio   float64
+}
+
+func (r *CapsRule) Name() string { return FilterRuleCaps }
+
+func (r *CapsRule) Apply(message string) FilterVerdict {
+	letters, upper := 0, 0
+	for _, ch := range message {
+		if !unicode.IsLetter(ch) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(ch) {
+			upper++
+		}
+	}
+	if letters < r.MinLetters || letters == 0 {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+
+	ratio := float64(upper) / float64(letters)
+	if ratio <= r.MaxRatio {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+
+	return FilterVerdict{
+		Action:  r.Action,
+		Message: strings.ToLower(message),
+		Detail:  fmt.Sprintf("%.0f%% capitals", ratio*100),
+	}
+}
+
+// isEmoji reports whether a rune is an emoji or pictograph
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, emoticons, transport, flags
+		r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	}
+	return false
+}
+
+// EmojiSpamRule matches messages with more than MaxEmojis emoji. Mask drops
+// the emoji past the limit.
+type EmojiSpamRule struct {
+	Action    string
+	MaxEmojis int
+}
+
+func (r *EmojiSpamRule) Name() string { return FilterRuleEmojiSpam }
+
+func (r *EmojiSpamRule) Apply(message string) FilterVerdict {
+	count := 0
+	for _, ch := range message {
+		if isEmoji(ch) {
+			count++
+		}
+	}
+	if count <= r.MaxEmojis {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+
+	seen := 0
+	masked := strings.Map(func(ch rune) rune {
+		if !isEmoji(ch) {
+			return ch
+		}
+		seen++
+		if seen > r.MaxEmojis {
+			return -1
+		}
+		return ch
+	}, message)
+
+	return FilterVerdict{
+		Action:  r.Action,
+		Message: strings.TrimSpace(masked),
+		Detail:  fmt.Sprintf("%d emoji", count),
+	}
+}
+
+// RepeatRule matches runs of the same character longer than MaxRun, such as
+// "noooooooo" or "!!!!!!!!". Mask collapses each run to MaxRun characters.
+type RepeatRule struct {
+	Action string
+	MaxRun int
+}
+
+func (r *RepeatRule) Name() string { return FilterRuleRepeats }
+
+func (r *RepeatRule) Apply(message string) FilterVerdict {
+	var b strings.Builder
+	var last rune = -1
+	run, longest := 0, 0
+	for _, ch := range message {
+		if ch == last {
+			run++
+		} else {
+			last, run = ch, 1
+		}
+		if run > longest {
+			longest = run
+		}
+		if run <= r.MaxRun {
+			b.WriteRune(ch)
+		}
+	}
+	if longest <= r.MaxRun {
+		return FilterVerdict{Action: models.ChatFilterAllow}
+	}
+
+	return FilterVerdict{
+		Action:  r.Action,
+		Message: b.String(),
+		Detail:  fmt.Sprintf("run of %d", longest),
+	}
+}
@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBannedWordsRule(t *testing.T) {
+	rule := NewBannedWordsRule(models.ChatFilterMask, []string{"doper", "ass"})
+
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+		matched  bool
+	}{
+		{"Clean message", "What a climb", "", false},
+		{"Exact word", "what a doper", "what a *****", true},
+		{"Case and punctuation", "DOPER!", "*****!", true},
+		{"Leetspeak", "d0p3r spotted", "***** spotted", true},
+		{"Symbols inside the word", "d.o.p.e.r", "*********", true},
+		{"Stretched word", "dooooper", "********", true},
+		{"Shorter word that collapses the same", "as fast as", "", false},
+		{"Word inside another word", "passion", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := rule.Apply(tt.message)
+			if !tt.matched {
+				assert.Equal(t, models.ChatFilterAllow, verdict.Action)
+				return
+			}
+			assert.Equal(t, models.ChatFilterMask, verdict.Action)
+			assert.Equal(t, tt.expected, verdict.Message)
+		})
+	}
+}
+
+func TestLinkRule(t *testing.T) {
+	rule := NewLinkRule(models.ChatFilterMask, []string{"procyclingstats.com"}, []string{"scam.io"})
+
+	t.Run("Allowed host and subdomains pass", func(t *testing.T) {
+		assert.Equal(t, models.ChatFilterAllow, rule.Apply("see https://www.procyclingstats.com/race/tdf").Action)
+		assert.Equal(t, models.ChatFilterAllow, rule.Apply("stats.procyclingstats.com").Action)
+	})
+
+	t.Run("Other links are masked", func(t *testing.T) {
+		verdict := rule.Apply("watch at http://example.net/live now")
+		assert.Equal(t, models.ChatFilterMask, verdict.Action)
+		assert.Equal(t, "watch at [link removed] now", verdict.Message)
+	})
+
+	t.Run("Denied hosts are rejected", func(t *testing.T) {
+		verdict := rule.Apply("free stuff at login.scam.io")
+		assert.Equal(t, models.ChatFilterReject, verdict.Action)
+	})
+
+	t.Run("Dotted names without a known TLD are not links", func(t *testing.T) {
+		assert.Equal(t, models.ChatFilterAllow, rule.Apply("Go Mr.Pogacar").Action)
+	})
+}
+
+func TestCapsRule(t *testing.T) {
+	rule := &CapsRule{Action: models.ChatFilterMask, MinLetters: 10, MaxRatio: 0.7}
+
+	assert.Equal(t, models.ChatFilterAllow, rule.Apply("GO GO").Action, "Short messages pass")
+	assert.Equal(t, models.ChatFilterAllow, rule.Apply("What a move by UAE Team Emirates").Action)
+
+	verdict := rule.Apply("WHAT AN ATTACK!!")
+	assert.Equal(t, models.ChatFilterMask, verdict.Action)
+	assert.Equal(t, "what an attack!!", verdict.Message)
+}
+
+func TestEmojiSpamRule(t *testing.T) {
+	rule := &EmojiSpamRule{Action: models.ChatFilterMask, MaxEmojis: 3}
+
+	assert.Equal(t, models.ChatFilterAllow, rule.Apply("go 🚴🚴🚴").Action)
+
+	verdict := rule.Apply("go 🚴🚴🚴🚴🚴")
+	assert.Equal(t, models.ChatFilterMask, verdict.Action)
+	assert.Equal(t, "go 🚴🚴🚴", verdict.Message)
+}
+
+func TestRepeatRule(t *testing.T) {
+	rule := &RepeatRule{Action: models.ChatFilterMask, MaxRun: 3}
+
+	assert.Equal(t, models.ChatFilterAllow, rule.Apply("allez allez!!!").Action)
+
+	verdict := rule.Apply("noooooooo!!!!!!")
+	assert.Equal(t, models.ChatFilterMask, verdict.Action)
+	assert.Equal(t, "nooo!!!", verdict.Message)
+	assert.Equal(t, "run of 8", verdict.Detail)
+}
+
+func TestFilter_Run(t *testing.T) {
+	cfg := DefaultFilterConfig()
+	cfg.BannedWords.Words = []string{"doper"}
+	cfg.Links.Deny = []string{"scam.io"}
+	filter := NewFilterFromConfig(cfg)
+
+	t.Run("Clean message is allowed unchanged", func(t *testing.T) {
+		result := filter.Run("Great ride today")
+		assert.Equal(t, models.ChatFilterAllow, result.Action)
+		assert.Equal(t, "Great ride today", result.Message)
+		assert.Empty(t, result.Matches)
+	})
+
+	t.Run("Masks from several rules are combined", func(t *testing.T) {
+		result := filter.Run("doooooooper")
+		assert.Equal(t, models.ChatFilterMask, result.Action)
+		assert.Equal(t, "********", result.Message, "Repeats are collapsed before the banned word is masked")
+		require.Len(t, result.Matches, 2)
+		assert.Equal(t, FilterRuleRepeats, result.Matches[0].Rule)
+		assert.Equal(t, FilterRuleBannedWords, result.Matches[1].Rule)
+	})
+
+	t.Run("Flag is more severe than mask", func(t *testing.T) {
+		result := filter.Run("doper, see example.com")
+		assert.Equal(t, models.ChatFilterFlag, result.Action)
+		assert.Equal(t, "*****, see example.com", result.Message, "Flagged links are left in place")
+	})
+
+	t.Run("Reject stops the pipeline", func(t *testing.T) {
+		result := filter.Run("WIN BIG AT SCAM.IO NOW")
+		assert.Equal(t, models.ChatFilterReject, result.Action)
+		require.Len(t, result.Matches, 1)
+		assert.Equal(t, FilterRuleLinks, result.Matches[0].Rule)
+	})
+
+	t.Run("Nil filter allows everything", func(t *testing.T) {
+		var f *Filter
+		assert.Equal(t, models.ChatFilterAllow, f.Run("anything").Action)
+	})
+}
+
+func TestValidateFilterConfig(t *testing.T) {
+	t.Run("Default config is valid", func(t *testing.T) {
+		cfg := DefaultFilterConfig()
+		assert.NoError(t, ValidateFilterConfig(&cfg))
+	})
+
+	t.Run("Unknown action is rejected", func(t *testing.T) {
+		cfg := DefaultFilterConfig()
+		cfg.Caps.Action = "shout"
+		assert.Error(t, ValidateFilterConfig(&cfg))
+	})
+
+	t.Run("Disabled rules skip their thresholds", func(t *testing.T) {
+		cfg := DefaultFilterConfig()
+		cfg.Repeats = models.ChatFilterRepeats{Action: models.ChatFilterAllow}
+		assert.NoError(t, ValidateFilterConfig(&cfg))
+	})
+
+	t.Run("Lists are normalized", func(t *testing.T) {
+		cfg := DefaultFilterConfig()
+		cfg.BannedWords.Words = []string{" Doper ", "doper", ""}
+		cfg.Links.Allow = []string{"Example.COM"}
+		require.NoError(t, ValidateFilterConfig(&cfg))
+		assert.Equal(t, []string{"doper"}, cfg.BannedWords.Words)
+		assert.Equal(t, []string{"example.com"}, cfg.Links.Allow)
+	})
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// Frame is a message on its way to many clients. It is serialized at most
+// once per protocol, however many clients receive it.
+type Frame struct {
+	msg *WSMessage
+	// JSON of a frame received from another instance; msg is decoded from it
+	// only when a MessagePack client needs it
+	json []byte
+
+	once    [protocolCount]sync.Once
+	encoded [protocolCount][]byte
+	errs    [protocolCount]error
+
+	classOnce sync.Once
+	class     frameClass
+	key       string
+}
+
+// NewFrame creates a frame for a message
+func NewFrame(msg *WSMessage) *Frame {
+	return &Frame{msg: msg}
+}
+
+// NewJSONFrame creates a frame from a message already encoded as JSON
+func NewJSONFrame(data []byte) *Frame {
+	return &Frame{json: data}
+}
+
+// Encode returns the frame serialized for a protocol
+func (f *Frame) Encode(p Protocol) ([]byte, error) {
+	f.once[p].Do(func() {
+		f.encoded[p], f.errs[p] = f.encode(p)
+	})
+	return f.encoded[p], f.errs[p]
+}
+
+// JSON returns the frame as JSON, the encoding shared between instances
+func (f *Frame) JSON() ([]byte, error) {
+	return f.Encode(ProtocolJSON)
+}
+
+func (f *Frame) encode(p Protocol) ([]byte, error) {
+	if f.json != nil {
+		if p == ProtocolJSON {
+			return f.json, nil
+		}
+		msg, err := decodeServerFrame(f.json)
+		if err != nil {
+			return nil, err
+		}
+		return p.Encode(msg)
+	}
+	return p.Encode(f.msg)
+}
+
+// queued returns the frame encoded for a protocol, classified for the
+// client's send queue
+func (f *Frame) queued(p Protocol) (queuedFrame, error) {
+	data, err := f.Encode(p)
+	if err != nil {
+		return queuedFrame{}, err
+	}
+	f.classOnce.Do(f.classify)
+	return queuedFrame{data: data, class: f.class, key: f.key}, nil
+}
+
+func (f *Frame) classify() {
+	msgType, dataID := f.typeAndDataID()
+	switch MessageType(msgType) {
+	case MessageTypeJoined, MessageTypeLeft, MessageTypeReactionSummary, MessageTypeTyping:
+		f.class = frameDroppable
+	case MessageTypePollUpdate:
+		if dataID != "" {
+			f.class = frameCoalesced
+			f.key = "poll:" + dataID
+		}
+	case MessageTypePinUpdated:
+		// Only the latest pin matters
+		f.class = frameCoalesced
+		f.key = "pin"
+	case MessageTypeStreamStatus:
+		// Only the current status of each feed matters
+		f.class = frameCoalesced
+		f.key = "stream_status:" + dataID
+	}
+}
+
+// typeAndDataID returns the frame's type and, for frames coalesced per poll
+// or per stream, the ID of the poll or stream
+func (f *Frame) typeAndDataID() (string, string) {
+	if f.msg != nil {
+		switch data := f.msg.Data.(type) {
+		case *models.ChatPoll:
+			if data != nil {
+				return f.msg.Type, data.ID
+			}
+		case StreamStatusData:
+			return f.msg.Type, data.StreamID
+		}
+		return f.msg.Type, ""
+	}
+
+	var envelope struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(f.json, &envelope); err != nil {
+		return "", ""
+	}
+	var ids struct {
+		ID       string `json:"id"`
+		StreamID string `json:"stream_id"`
+	}
+	switch MessageType(envelope.Type) {
+	case MessageTypePollUpdate:
+		_ = json.Unmarshal(envelope.Data, &ids)
+		return envelope.Type, ids.ID
+	case MessageTypeStreamStatus:
+		_ = json.Unmarshal(envelope.Data, &ids)
+		return envelope.Type, ids.StreamID
+	}
+	return envelope.Type, ""
+}
@@ -2,6 +2,8 @@ package chat
 
 import (
 	"sync"
+
+	"github.com/cyclingstream/backend/internal/logger"
 )
 
 // RoomAction represents an action to join or leave a room
@@ -15,7 +17,8 @@ type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
-	// Race-specific rooms (race_id -> clients in that room)
+	// Rooms (room ID -> clients in that room). Race rooms are keyed by race
+	// ID, other rooms by RoomID.
 	rooms map[string]map[*Client]bool
 
 	// Inbound messages from clients
@@ -33,21 +36,53 @@ type Hub struct {
 	// Leave room requests
 	leaveRoom chan *RoomAction
 
+	// Fans room messages out to every instance in the cluster
+	broadcaster Broadcaster
+
+	// Limits how often each user's typing indicator is relayed
+	typing *typingThrottle
+
+	// Receives reaction counts shared by other instances
+	reactionSink func(raceID string, counts map[string]int)
+
 	// Mutex for thread-safe access
 	mu sync.RWMutex
+
+	// Send queue counters per local room and for the hub. Guarded by
+	// statsMu, which is taken after mu.
+	roomStats  map[string]*deliveryCounters
+	totalStats deliveryCounters
+	statsMu    sync.Mutex
 }
 
-// NewHub creates a new Hub
+// NewHub creates a new Hub that only broadcasts to clients of this instance
 func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		rooms:      make(map[string]map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		joinRoom:   make(chan *RoomAction),
-		leaveRoom:  make(chan *RoomAction),
+	// The in-memory broadcaster never fails to start
+	hub, _ := NewHubWithBroadcaster(NewMemoryBroadcaster())
+	return hub
+}
+
+// NewHubWithBroadcaster creates a new Hub that publishes room messages through
+// the given broadcaster and starts it.
+func NewHubWithBroadcaster(broadcaster Broadcaster) (*Hub, error) {
+	h := &Hub{
+		clients:     make(map[*Client]bool),
+		rooms:       make(map[string]map[*Client]bool),
+		broadcast:   make(chan []byte, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		joinRoom:    make(chan *RoomAction),
+		leaveRoom:   make(chan *RoomAction),
+		broadcaster: broadcaster,
+		typing:      newTypingThrottle(),
+		roomStats:   make(map[string]*deliveryCounters),
 	}
+
+	if err := broadcaster.Start(h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
 }
 
 // Run starts the hub's main loop
@@ -68,16 +103,11 @@ func (h *Hub) Run() {
 
 		case message := <-h.broadcast:
 			// Broadcast to all clients (not used for room-specific messages)
-			h.mu.Lock()
+			h.mu.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+				client.enqueue(queuedFrame{data: message})
 			}
-			h.mu.Unlock()
+			h.mu.RUnlock()
 		}
 	}
 }
@@ -96,7 +126,7 @@ func (h *Hub) UnregisterClient(client *Client) {
 
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
-		close(client.send)
+		client.send.close()
 
 		// Remove client from all rooms
 		for raceID, roomClients := range h.rooms {
@@ -104,7 +134,7 @@ func (h *Hub) UnregisterClient(client *Client) {
 				delete(roomClients, client)
 				// Clean up empty rooms
 				if len(roomClients) == 0 {
-					delete(h.rooms, raceID)
+					h.deleteRoomLocked(raceID)
 				}
 			}
 		}
@@ -131,32 +161,160 @@ func (h *Hub) LeaveRoom(client *Client, raceID string) {
 		delete(roomClients, client)
 		// Clean up empty rooms
 		if len(roomClients) == 0 {
-			delete(h.rooms, raceID)
+			h.deleteRoomLocked(raceID)
+		}
+	}
+}
+
+// deleteRoomLocked removes an empty room and its counters. Callers must hold
+// the write lock.
+func (h *Hub) deleteRoomLocked(roomID string) {
+	delete(h.rooms, roomID)
+
+	h.statsMu.Lock()
+	delete(h.roomStats, roomID)
+	h.statsMu.Unlock()
+}
+
+// BroadcastToRoom sends a message to all clients in a specific room across
+// every instance sharing the hub's broadcaster. The message is serialized
+// once per protocol in use, not once per client.
+func (h *Hub) BroadcastToRoom(raceID string, msg *WSMessage) {
+	if err := h.broadcaster.Publish(raceID, NewFrame(msg)); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"race_id": raceID,
+			"error":   err.Error(),
+		}).Warn("Failed to publish chat message to other instances")
+	}
+}
+
+// DeliverToRoom sends a frame to the clients in a room connected to this
+// instance. Broadcasters call it for messages published anywhere in the cluster.
+// Clients that fall behind are handled by their send queues; they leave the
+// room when their connection closes.
+func (h *Hub) DeliverToRoom(raceID string, frame *Frame) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	roomClients, ok := h.rooms[raceID]
+	if !ok {
+		return
+	}
+
+	var counters deliveryCounters
+	for client := range roomClients {
+		if outcome, ok := client.deliver(frame); ok {
+			counters.add(outcome)
 		}
 	}
+	h.recordCountersLocked(raceID, counters)
 }
 
-// BroadcastToRoom sends a message to all clients in a specific room
-func (h *Hub) BroadcastToRoom(raceID string, message []byte) {
+// SetReactionSink sets where reaction counts shared by other instances go.
+// Call it before reactions are published.
+func (h *Hub) SetReactionSink(sink func(raceID string, counts map[string]int)) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	h.reactionSink = sink
+}
 
-	if roomClients, ok := h.rooms[raceID]; ok {
-		for client := range roomClients {
-			select {
-			case client.send <- message:
-			default:
-				// Client's send channel is full, close and remove
-				close(client.send)
-				delete(h.clients, client)
-				delete(roomClients, client)
+// PublishReactions shares reaction counts made on this instance with the
+// other instances sharing the hub's broadcaster
+func (h *Hub) PublishReactions(raceID string, counts map[string]int) {
+	if err := h.broadcaster.PublishReactions(raceID, counts); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"race_id": raceID,
+			"error":   err.Error(),
+		}).Warn("Failed to publish chat reactions to other instances")
+	}
+}
+
+// DeliverReactions hands reaction counts shared by another instance to the
+// reaction sink
+func (h *Hub) DeliverReactions(raceID string, counts map[string]int) {
+	h.mu.RLock()
+	sink := h.reactionSink
+	h.mu.RUnlock()
+
+	if sink != nil {
+		sink(raceID, counts)
+	}
+}
+
+// SendToUser sends a message to one user's clients in a room, on every instance
+func (h *Hub) SendToUser(raceID, userID string, msg *WSMessage) {
+	if err := h.broadcaster.PublishToUser(raceID, userID, NewFrame(msg)); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"race_id": raceID,
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Failed to publish chat message to user")
+	}
+}
+
+// DeliverToUser writes a frame to one user's clients in a room on this instance
+func (h *Hub) DeliverToUser(raceID, userID string, frame *Frame) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var counters deliveryCounters
+	for client := range h.rooms[raceID] {
+		if client.userID != nil && *client.userID == userID {
+			if outcome, ok := client.deliver(frame); ok {
+				counters.add(outcome)
 			}
 		}
 	}
+	h.recordCountersLocked(raceID, counters)
+}
+
+// HasUserInRoom reports whether a user has a client in the room on this instance
+func (h *Hub) HasUserInRoom(raceID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.rooms[raceID] {
+		if client.userID != nil && *client.userID == userID {
+			return true
+		}
+	}
+	return false
 }
 
-// GetRoomClientCount returns the number of clients in a room
+// InRoom reports whether a client has joined a room
+func (h *Hub) InRoom(client *Client, roomID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rooms[roomID][client]
+}
+
+// RemoveUserFromRoom removes a user's clients on this instance from a room
+func (h *Hub) RemoveUserFromRoom(roomID, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	roomClients, ok := h.rooms[roomID]
+	if !ok {
+		return
+	}
+	for client := range roomClients {
+		if client.userID != nil && *client.userID == userID {
+			delete(roomClients, client)
+		}
+	}
+	if len(roomClients) == 0 {
+		h.deleteRoomLocked(roomID)
+	}
+}
+
+// GetRoomClientCount returns the number of clients in a room across the cluster
 func (h *Hub) GetRoomClientCount(raceID string) int {
+	return h.GetLocalRoomClientCount(raceID) + h.broadcaster.RemoteClientCount(raceID)
+}
+
+// GetLocalRoomClientCount returns the number of clients in a room connected to
+// this instance
+func (h *Hub) GetLocalRoomClientCount(raceID string) int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -166,3 +324,92 @@ func (h *Hub) GetRoomClientCount(raceID string) int {
 	return 0
 }
 
+// LocalRoomCounts returns the number of local clients in every non-empty room
+func (h *Hub) LocalRoomCounts() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int, len(h.rooms))
+	for raceID, roomClients := range h.rooms {
+		counts[raceID] = len(roomClients)
+	}
+	return counts
+}
+
+// recordDelivery adds a frame sent directly to a client to its room's counters
+func (h *Hub) recordDelivery(roomID string, outcome enqueueOutcome) {
+	var counters deliveryCounters
+	counters.add(outcome)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.recordCountersLocked(roomID, counters)
+}
+
+// recordCountersLocked adds counters to the hub totals and, if the room has
+// local clients, to the room. Callers must hold the read or write lock.
+func (h *Hub) recordCountersLocked(roomID string, counters deliveryCounters) {
+	if counters == (deliveryCounters{}) {
+		return
+	}
+
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	h.totalStats.merge(counters)
+	if _, ok := h.rooms[roomID]; !ok {
+		return
+	}
+	roomStats, ok := h.roomStats[roomID]
+	if !ok {
+		roomStats = &deliveryCounters{}
+		h.roomStats[roomID] = roomStats
+	}
+	roomStats.merge(counters)
+}
+
+// DeliveryStats returns the send queue counters of every local room and the
+// hub as a whole, for tuning queue capacity
+func (h *Hub) DeliveryStats() DeliveryStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	stats := DeliveryStats{
+		QueueSize: sendQueueSize,
+		Totals:    h.totalStats.stats(),
+		Rooms:     make(map[string]RoomDeliveryStats, len(h.rooms)),
+	}
+	stats.Totals.Clients = len(h.clients)
+
+	for roomID, roomClients := range h.rooms {
+		var room RoomDeliveryStats
+		if counters, ok := h.roomStats[roomID]; ok {
+			room = counters.stats()
+		}
+		room.Clients = len(roomClients)
+		for client := range roomClients {
+			queued := client.send.len()
+			room.Queued += queued
+			if queued > room.MaxQueued {
+				room.MaxQueued = queued
+			}
+		}
+		stats.Rooms[roomID] = room
+	}
+
+	for client := range h.clients {
+		queued := client.send.len()
+		stats.Totals.Queued += queued
+		if queued > stats.Totals.MaxQueued {
+			stats.Totals.MaxQueued = queued
+		}
+	}
+	return stats
+}
+
+// Close releases the hub's broadcaster
+func (h *Hub) Close() error {
+	return h.broadcaster.Close()
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // createTestClient creates a mock client for testing
@@ -13,7 +14,7 @@ func createTestClient(hub *Hub, userID *string, username string) *Client {
 	return &Client{
 		hub:      hub,
 		conn:     nil, // Mock connection
-		send:     make(chan []byte, 256),
+		send:     newSendQueue(sendQueueSize),
 		userID:   userID,
 		username: username,
 		isAdmin:  false,
@@ -21,6 +22,40 @@ func createTestClient(hub *Hub, userID *string, username string) *Client {
 	}
 }
 
+// newTestMessage returns a message and the JSON clients receive for it
+func newTestMessage(t *testing.T, msgType string) (*WSMessage, []byte) {
+	t.Helper()
+	msg := &WSMessage{Type: msgType}
+	encoded, err := ProtocolJSON.Encode(msg)
+	require.NoError(t, err)
+	return msg, encoded
+}
+
+// receiveFrame waits up to timeout for the next frame queued for a client
+func receiveFrame(client *Client, timeout time.Duration) ([]byte, bool) {
+	deadline := time.After(timeout)
+	for {
+		client.send.mu.Lock()
+		if len(client.send.frames) > 0 {
+			frame := client.send.frames[0].data
+			client.send.frames = client.send.frames[1:]
+			client.send.mu.Unlock()
+			return frame, true
+		}
+		closed := client.send.closed
+		client.send.mu.Unlock()
+		if closed {
+			return nil, false
+		}
+
+		select {
+		case <-client.send.ready:
+		case <-deadline:
+			return nil, false
+		}
+	}
+}
+
 // TestHub_RegisterClient tests client registration
 func TestHub_RegisterClient(t *testing.T) {
 	hub := NewHub()
@@ -132,18 +167,18 @@ func TestHub_BroadcastToRoom(t *testing.T) {
 
 	t.Run("Broadcast to empty room does nothing", func(t *testing.T) {
 		raceID := "test-race-3"
-		message := []byte("test message")
+		msg, _ := newTestMessage(t, "test message")
 
 		// Should not panic
-		hub.BroadcastToRoom(raceID, message)
+		hub.BroadcastToRoom(raceID, msg)
 	})
 
 	t.Run("Broadcast to non-existent room does nothing", func(t *testing.T) {
 		raceID := "non-existent-race"
-		message := []byte("test message")
+		msg, _ := newTestMessage(t, "test message")
 
 		// Should not panic
-		hub.BroadcastToRoom(raceID, message)
+		hub.BroadcastToRoom(raceID, msg)
 	})
 
 	t.Run("Broadcast to room with clients sends message", func(t *testing.T) {
@@ -159,23 +194,17 @@ func TestHub_BroadcastToRoom(t *testing.T) {
 		hub.joinRoom <- &RoomAction{Client: client2, RaceID: raceID}
 		time.Sleep(10 * time.Millisecond)
 
-		message := []byte("broadcast message")
-		hub.BroadcastToRoom(raceID, message)
+		msg, message := newTestMessage(t, "broadcast message")
+		hub.BroadcastToRoom(raceID, msg)
 
 		// Verify both clients received the message
 		time.Sleep(10 * time.Millisecond)
-		select {
-		case msg := <-client1.send:
-			assert.Equal(t, message, msg)
-		case <-time.After(100 * time.Millisecond):
-			t.Error("Client1 did not receive message")
+		if received, ok := receiveFrame(client1, 100*time.Millisecond); assert.True(t, ok, "Client1 did not receive message") {
+			assert.Equal(t, message, received)
 		}
 
-		select {
-		case msg := <-client2.send:
-			assert.Equal(t, message, msg)
-		case <-time.After(100 * time.Millisecond):
-			t.Error("Client2 did not receive message")
+		if received, ok := receiveFrame(client2, 100*time.Millisecond); assert.True(t, ok, "Client2 did not receive message") {
+			assert.Equal(t, message, received)
 		}
 	})
 
@@ -193,24 +222,17 @@ func TestHub_BroadcastToRoom(t *testing.T) {
 		hub.joinRoom <- &RoomAction{Client: client2, RaceID: raceID2}
 		time.Sleep(10 * time.Millisecond)
 
-		message := []byte("room-specific message")
-		hub.BroadcastToRoom(raceID1, message)
+		msg, message := newTestMessage(t, "room-specific message")
+		hub.BroadcastToRoom(raceID1, msg)
 
 		// Client1 should receive, client2 should not
 		time.Sleep(10 * time.Millisecond)
-		select {
-		case msg := <-client1.send:
-			assert.Equal(t, message, msg)
-		case <-time.After(100 * time.Millisecond):
-			t.Error("Client1 did not receive message")
+		if received, ok := receiveFrame(client1, 100*time.Millisecond); assert.True(t, ok, "Client1 did not receive message") {
+			assert.Equal(t, message, received)
 		}
 
-		select {
-		case <-client2.send:
-			t.Error("Client2 should not receive message from different room")
-		case <-time.After(100 * time.Millisecond):
-			// Expected - client2 should not receive
-		}
+		_, received := receiveFrame(client2, 100*time.Millisecond)
+		assert.False(t, received, "Client2 should not receive message from different room")
 	})
 }
 
@@ -399,23 +421,17 @@ func TestHub_Run(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// Broadcast a message to the room
-		message := []byte("broadcast test")
-		hub.BroadcastToRoom(raceID, message)
+		msg, message := newTestMessage(t, "broadcast test")
+		hub.BroadcastToRoom(raceID, msg)
 
 		// Verify both clients received the broadcast
 		time.Sleep(10 * time.Millisecond)
-		select {
-		case msg := <-client1.send:
-			assert.Equal(t, message, msg, "Client1 should receive broadcast")
-		case <-time.After(100 * time.Millisecond):
-			t.Error("Client1 did not receive broadcast message")
+		if received, ok := receiveFrame(client1, 100*time.Millisecond); assert.True(t, ok, "Client1 did not receive broadcast message") {
+			assert.Equal(t, message, received, "Client1 should receive broadcast")
 		}
 
-		select {
-		case msg := <-client2.send:
-			assert.Equal(t, message, msg, "Client2 should receive broadcast")
-		case <-time.After(100 * time.Millisecond):
-			t.Error("Client2 did not receive broadcast message")
+		if received, ok := receiveFrame(client2, 100*time.Millisecond); assert.True(t, ok, "Client2 did not receive broadcast message") {
+			assert.Equal(t, message, received, "Client2 should receive broadcast")
 		}
 	})
 
@@ -441,3 +457,100 @@ func TestHub_Run(t *testing.T) {
 		assert.False(t, exists, "Client should be unregistered")
 	})
 }
+
+func TestHub_SendToUser(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	alice := "user-alice"
+	bob := "user-bob"
+	aliceClient := createTestClient(hub, &alice, "Alice")
+	aliceOtherTab := createTestClient(hub, &alice, "Alice")
+	bobClient := createTestClient(hub, &bob, "Bob")
+	aliceElsewhere := createTestClient(hub, &alice, "Alice")
+
+	for _, c := range []*Client{aliceClient, aliceOtherTab, bobClient} {
+		hub.JoinRoom(c, "race-1")
+	}
+	hub.JoinRoom(aliceElsewhere, "race-2")
+
+	ping, encoded := newTestMessage(t, "ping")
+	hub.SendToUser("race-1", alice, ping)
+
+	for _, c := range []*Client{aliceClient, aliceOtherTab} {
+		msg, ok := receiveFrame(c, 100*time.Millisecond)
+		require.True(t, ok, "Targeted user did not receive message")
+		assert.Equal(t, encoded, msg)
+	}
+	assert.Zero(t, bobClient.send.len(), "Other users in the room are not sent the message")
+	assert.Zero(t, aliceElsewhere.send.len(), "Only clients in the room receive the message")
+}
+
+func TestHub_PartyRooms(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	alice := "user-alice"
+	bob := "user-bob"
+	aliceClient := createTestClient(hub, &alice, "Alice")
+	bobClient := createTestClient(hub, &bob, "Bob")
+	party := PartyRoomID("abc123")
+
+	hub.JoinRoom(aliceClient, "race-1")
+	hub.JoinRoom(bobClient, "race-1")
+	hub.JoinRoom(aliceClient, party)
+
+	t.Run("Party messages only reach party members", func(t *testing.T) {
+		partyMsg, encoded := newTestMessage(t, "party")
+		hub.BroadcastToRoom(party, partyMsg)
+
+		msg, ok := receiveFrame(aliceClient, 100*time.Millisecond)
+		require.True(t, ok, "Party member did not receive message")
+		assert.Equal(t, encoded, msg)
+		assert.Zero(t, bobClient.send.len())
+		assert.Equal(t, 2, hub.GetLocalRoomClientCount("race-1"), "Race room is unaffected")
+	})
+
+	t.Run("Removing a user from a party keeps them in the race room", func(t *testing.T) {
+		assert.True(t, hub.InRoom(aliceClient, party))
+		hub.RemoveUserFromRoom(party, alice)
+		assert.False(t, hub.InRoom(aliceClient, party))
+		assert.True(t, hub.InRoom(aliceClient, "race-1"))
+	})
+}
+
+func TestHub_DeliveryStats(t *testing.T) {
+	hub := NewHub()
+
+	fast := createTestClient(hub, nil, "Fast")
+	slow := createTestClient(hub, nil, "Slow")
+	slow.send = newSendQueue(1)
+	for _, c := range []*Client{fast, slow} {
+		hub.RegisterClient(c)
+		hub.JoinRoom(c, "race-1")
+	}
+
+	hub.BroadcastToRoom("race-1", NewJoinedWSMessage("Alice"))
+	hub.BroadcastToRoom("race-1", NewMessageWSMessage(testChatMessage()))
+
+	stats := hub.DeliveryStats()
+	assert.Equal(t, sendQueueSize, stats.QueueSize)
+	assert.Equal(t, RoomDeliveryStats{
+		Clients:   2,
+		Queued:    3,
+		MaxQueued: 2,
+		Enqueued:  4,
+		Dropped:   1,
+	}, stats.Rooms["race-1"], "The slow client's joined notice made room for the message")
+	assert.Equal(t, int64(4), stats.Totals.Enqueued)
+	assert.Equal(t, 2, stats.Totals.Clients)
+
+	t.Run("Room counters go with the room", func(t *testing.T) {
+		hub.LeaveRoom(fast, "race-1")
+		hub.LeaveRoom(slow, "race-1")
+
+		stats := hub.DeliveryStats()
+		assert.NotContains(t, stats.Rooms, "race-1")
+		assert.Equal(t, int64(1), stats.Totals.Dropped, "Totals are kept")
+	})
+}
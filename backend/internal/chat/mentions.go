@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+//msgp:tag json
+//msgp:newtime
+
+const (
+	// MaxMentionsPerMessage caps how many users one message can ping
+	MaxMentionsPerMessage = 5
+)
+
+// An @handle must start the message or follow a character that can't be part
+// of a word or email address, so "me@example.com" is not a mention
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.\-]{2,32})`)
+
+// MentionHandle returns the @handle for a display name: lowercase with
+// whitespace removed. It matches how the frontend highlights mentions.
+func MentionHandle(name string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, name))
+}
+
+// ParseMentions returns the distinct lowercase @handles in a message, in
+// order of appearance, up to MaxMentionsPerMessage
+func ParseMentions(message string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		// Trailing punctuation belongs to the sentence ("thanks @anna.")
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if len([]rune(handle)) < 2 || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == MaxMentionsPerMessage {
+			break
+		}
+	}
+
+	return handles
+}
+
+// ResolveMentions picks the user each handle refers to. Handles shared by
+// several users are narrowed to those in the room; handles that stay
+// ambiguous or unknown are dropped, as is the sender.
+func ResolveMentions(handles []string, candidates map[string][]string, inRoom func(userID string) bool, senderID string) []string {
+	var userIDs []string
+	seen := make(map[string]bool)
+
+	for _, handle := range handles {
+		matches := candidates[handle]
+		if len(matches) > 1 && inRoom != nil {
+			var present []string
+			for _, id := range matches {
+				if inRoom(id) {
+					present = append(present, id)
+				}
+			}
+			matches = present
+		}
+		if len(matches) != 1 {
+			continue
+		}
+
+		id := matches[0]
+		if id == senderID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs
+}
+
+// MentionData tells a user they were mentioned in the room
+type MentionData struct {
+	MessageID    string    `json:"message_id"`
+	RaceID       string    `json:"race_id"`
+	FromUserID   *string   `json:"from_user_id,omitempty"`
+	FromUsername string    `json:"from_username"`
+	Message      string    `json:"message"`
+	ReplyToID    *string   `json:"reply_to_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewMentionWSMessage creates the targeted frame sent to a mentioned user
+func NewMentionWSMessage(msg *models.ChatMessage) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeMention),
+		Data: MentionData{
+			MessageID:    msg.ID,
+			RaceID:       msg.RaceID,
+			FromUserID:   msg.UserID,
+			FromUsername: msg.Username,
+			Message:      msg.Message,
+			ReplyToID:    msg.ReplyToID,
+			CreatedAt:    msg.CreatedAt,
+		},
+	}
+}
@@ -0,0 +1,176 @@
+package chat
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// MarshalMsg implements msgp.Marshaler
+func (z *MentionData) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// check for omitted fields
+	zb0001Len := uint32(7)
+	var zb0001Mask uint8 /* 7 bits */
+	_ = zb0001Mask
+	if z.FromUserID == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	if z.ReplyToID == nil {
+		zb0001Len--
+		zb0001Mask |= 0x20
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// string "message_id"
+		o = append(o, 0xaa, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64)
+		o = msgp.AppendString(o, z.MessageID)
+		// string "race_id"
+		o = append(o, 0xa7, 0x72, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64)
+		o = msgp.AppendString(o, z.RaceID)
+		if (zb0001Mask & 0x4) == 0 { // if not omitted
+			// string "from_user_id"
+			o = append(o, 0xac, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64)
+			if z.FromUserID == nil {
+				o = msgp.AppendNil(o)
+			} else {
+				o = msgp.AppendString(o, *z.FromUserID)
+			}
+		}
+		// string "from_username"
+		o = append(o, 0xad, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65)
+		o = msgp.AppendString(o, z.FromUsername)
+		// string "message"
+		o = append(o, 0xa7, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65)
+		o = msgp.AppendString(o, z.Message)
+		if (zb0001Mask & 0x20) == 0 { // if not omitted
+			// string "reply_to_id"
+			o = append(o, 0xab, 0x72, 0x65, 0x70, 0x6c, 0x79, 0x5f, 0x74, 0x6f, 0x5f, 0x69, 0x64)
+			if z.ReplyToID == nil {
+				o = msgp.AppendNil(o)
+			} else {
+				o = msgp.AppendString(o, *z.ReplyToID)
+			}
+		}
+		// string "created_at"
+		o = append(o, 0xaa, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74)
+		o = msgp.AppendTimeExt(o, z.CreatedAt)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *MentionData) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "message_id":
+			z.MessageID, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "MessageID")
+				return
+			}
+		case "race_id":
+			z.RaceID, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "RaceID")
+				return
+			}
+		case "from_user_id":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.FromUserID = nil
+			} else {
+				if z.FromUserID == nil {
+					z.FromUserID = new(string)
+				}
+				*z.FromUserID, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "FromUserID")
+					return
+				}
+			}
+		case "from_username":
+			z.FromUsername, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "FromUsername")
+				return
+			}
+		case "message":
+			z.Message, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Message")
+				return
+			}
+		case "reply_to_id":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.ReplyToID = nil
+			} else {
+				if z.ReplyToID == nil {
+					z.ReplyToID = new(string)
+				}
+				*z.ReplyToID, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "ReplyToID")
+					return
+				}
+			}
+		case "created_at":
+			z.CreatedAt, bts, err = msgp.ReadTimeBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "CreatedAt")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *MentionData) Msgsize() (s int) {
+	s = 1 + 11 + msgp.StringPrefixSize + len(z.MessageID) + 8 + msgp.StringPrefixSize + len(z.RaceID) + 13
+	if z.FromUserID == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.StringPrefixSize + len(*z.FromUserID)
+	}
+	s += 14 + msgp.StringPrefixSize + len(z.FromUsername) + 8 + msgp.StringPrefixSize + len(z.Message) + 12
+	if z.ReplyToID == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.StringPrefixSize + len(*z.ReplyToID)
+	}
+	s += 11 + msgp.TimeSize
+	return
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected []string
+	}{
+		{"Single mention", "@Anna what a sprint", []string{"anna"}},
+		{"Mention mid-sentence with punctuation", "great call, @Jonas.V. You were right", []string{"jonas.v"}},
+		{"Duplicates collapse", "@anna @ANNA @anna!", []string{"anna"}},
+		{"Email addresses are not mentions", "mail me@example.com", nil},
+		{"Too short", "@a", nil},
+		{"Unicode handles", "@Zoë allez!", []string{"zoë"}},
+		{"No mentions", "what a finish", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseMentions(tt.message))
+		})
+	}
+
+	t.Run("Caps mentions per message", func(t *testing.T) {
+		handles := ParseMentions("@aa @bb @cc @dd @ee @ff @gg")
+		assert.Len(t, handles, MaxMentionsPerMessage)
+		assert.Equal(t, "aa", handles[0])
+	})
+}
+
+func TestMentionHandle(t *testing.T) {
+	assert.Equal(t, "annavanderbreggen", MentionHandle("Anna van der Breggen"))
+	assert.Equal(t, "tadej", MentionHandle("Tadej"))
+}
+
+func TestResolveMentions(t *testing.T) {
+	candidates := map[string][]string{
+		"anna":  {"user-anna"},
+		"tom":   {"user-tom-1", "user-tom-2"},
+		"self":  {"user-sender"},
+		"ghost": {},
+	}
+	inRoom := func(userID string) bool { return userID == "user-tom-2" }
+
+	t.Run("Resolves unique handles and skips the sender", func(t *testing.T) {
+		ids := ResolveMentions([]string{"anna", "self", "unknown", "ghost"}, candidates, inRoom, "user-sender")
+		assert.Equal(t, []string{"user-anna"}, ids)
+	})
+
+	t.Run("Ambiguous handles prefer users in the room", func(t *testing.T) {
+		ids := ResolveMentions([]string{"tom"}, candidates, inRoom, "user-sender")
+		assert.Equal(t, []string{"user-tom-2"}, ids)
+	})
+
+	t.Run("Handles that stay ambiguous are dropped", func(t *testing.T) {
+		ids := ResolveMentions([]string{"tom"}, candidates, func(string) bool { return true }, "user-sender")
+		assert.Empty(t, ids)
+	})
+}
@@ -5,8 +5,15 @@ import (
 	"time"
 
 	"github.com/cyclingstream/backend/internal/models"
+	"github.com/tinylib/msgp/msgp"
 )
 
+//msgp:tag json
+//msgp:newtime
+//msgp:ignore MessageType WSMessage PollMessageData
+//msgp:replace models.ChatEmoteSpan with:emoteSpanData
+//msgp:replace models.ChatMessageReference with:messageReferenceData
+
 // MessageType represents the type of WebSocket message
 type MessageType string
 
@@ -21,11 +28,52 @@ const (
 	MessageTypePollAnnouncement MessageType = "poll_announcement"
 	MessageTypePollUpdate       MessageType = "poll_update"
 	MessageTypePollClosed       MessageType = "poll_closed"
+	MessageTypeHistory          MessageType = "history"
+	MessageTypeMention          MessageType = "mention"
+
+	// Presence
+	MessageTypePresenceSnapshot MessageType = "presence_snapshot"
+	MessageTypeTyping           MessageType = "typing"
+
+	// Reactions: clients send single reactions, the server broadcasts
+	// per-room counts once per ReactionSummaryInterval
+	MessageTypeReaction        MessageType = "reaction"
+	MessageTypeReactionSummary MessageType = "reaction_summary"
+
+	// Private chat: whispers go both ways, party frames are server -> client
+	MessageTypeWhisper      MessageType = "whisper"
+	MessageTypePartyMessage MessageType = "party_message"
+	MessageTypePartyLeft    MessageType = "party_left"
+
+	// Moderator commands (client -> server, admin only)
+	MessageTypeDeleteMessage MessageType = "delete_message"
+	MessageTypeTimeoutUser   MessageType = "timeout_user"
+	MessageTypeBanUser       MessageType = "ban_user"
+	MessageTypeUnbanUser     MessageType = "unban_user"
+	MessageTypeSlowMode      MessageType = "slow_mode"
+
+	// Moderation events (server -> room)
+	MessageTypeMessageDeleted    MessageType = "message_deleted"
+	MessageTypeUserRestricted    MessageType = "user_restricted"
+	MessageTypeRestrictionLifted MessageType = "restriction_lifted"
+	MessageTypeSlowModeUpdated   MessageType = "slow_mode_updated"
+
+	// Pins and announcements: admins send pin_message, unpin_message and
+	// announcement; the server sends announcement and pin_updated to the room
+	MessageTypePinMessage   MessageType = "pin_message"
+	MessageTypeUnpinMessage MessageType = "unpin_message"
+	MessageTypeAnnouncement MessageType = "announcement"
+	MessageTypePinUpdated   MessageType = "pin_updated"
+
+	// Stream status changes (server -> room)
+	MessageTypeStreamStatus MessageType = "stream_status"
 )
 
-// WSMessage represents a WebSocket message
+// WSMessage represents a WebSocket message. V is the protocol version; the
+// server sets it on every frame it sends.
 type WSMessage struct {
 	Type string      `json:"type"`
+	V    int         `json:"v,omitempty"`
 	Data interface{} `json:"data,omitempty"`
 }
 
@@ -40,16 +88,54 @@ type ChatMessageData struct {
 	Role         string    `json:"role,omitempty"`
 	Badges       []string  `json:"badges,omitempty"`
 	SpecialEmote bool      `json:"special_emote,omitempty"`
+	Announcement bool      `json:"announcement,omitempty"`
+
+	Emotes    []models.ChatEmoteSpan       `json:"emotes,omitempty"`
+	ReplyToID *string                      `json:"reply_to_id,omitempty"`
+	ReplyTo   *models.ChatMessageReference `json:"reply_to,omitempty"`
+	Mentions  []string                     `json:"mentions,omitempty"`
+}
+
+// emoteSpanData and messageReferenceData mirror models.ChatEmoteSpan and
+// models.ChatMessageReference for the generated MessagePack code
+type emoteSpanData struct {
+	Code     string  `json:"code"`
+	Start    int     `json:"start"`
+	End      int     `json:"end"`
+	ImageURL *string `json:"image_url,omitempty"`
+}
+
+type messageReferenceData struct {
+	ID       string  `json:"id"`
+	UserID   *string `json:"user_id,omitempty"`
+	Username string  `json:"username"`
+	Message  string  `json:"message"`
+	Deleted  bool    `json:"deleted,omitempty"`
 }
 
 // PollMessageData represents poll updates sent over WebSocket.
 type PollMessageData struct {
-	*Poll `json:"poll"`
+	*models.ChatPoll `json:"poll"`
+}
+
+// HistoryData carries recent messages and the pinned message to a client
+// that just joined. NextBefore is the cursor for loading older messages via
+// the history endpoint.
+type HistoryData struct {
+	Messages   []ChatMessageData  `json:"messages"`
+	HasMore    bool               `json:"has_more"`
+	NextBefore string             `json:"next_before,omitempty"`
+	Pinned     *PinnedMessageData `json:"pinned,omitempty"`
 }
 
-// ErrorData represents error data in WebSocket messages
+// ErrorData represents error data in WebSocket messages. Rate limit errors
+// also carry a code, the tokens left in the sender's bucket and the number of
+// seconds to wait before retrying.
 type ErrorData struct {
-	Message string `json:"message"`
+	Message    string `json:"message"`
+	Code       string `json:"code,omitempty"`
+	Remaining  *int   `json:"remaining,omitempty"`
+	RetryAfter *int   `json:"retry_after,omitempty"`
 }
 
 // UserActionData represents user join/leave data
@@ -57,44 +143,78 @@ type UserActionData struct {
 	Username string `json:"username"`
 }
 
-// SendMessageData represents data sent by client to send a message
+// SendMessageData represents data sent by client to send a message. Room
+// names a party room ("party:<code>") the client joined; empty sends to the
+// race room.
 type SendMessageData struct {
-	Message string `json:"message"`
+	Message   string  `json:"message"`
+	ReplyToID *string `json:"reply_to_id,omitempty"`
+	Room      string  `json:"room,omitempty"`
 }
 
 // NewMessageWSMessage creates a WebSocket message for a chat message
 func NewMessageWSMessage(msg *models.ChatMessage) *WSMessage {
 	return &WSMessage{
 		Type: string(MessageTypeMessage),
-		Data: ChatMessageData{
-			ID:           msg.ID,
-			RaceID:       msg.RaceID,
-			UserID:       msg.UserID,
-			Username:     msg.Username,
-			Message:      msg.Message,
-			CreatedAt:    msg.CreatedAt,
-			Role:         msg.Role,
-			Badges:       msg.Badges,
-			SpecialEmote: msg.SpecialEmote,
-		},
+		Data: newChatMessageData(msg),
+	}
+}
+
+// NewHistoryWSMessage creates a WebSocket message with recent chat history,
+// oldest message first, and the race's pinned message (nil when none)
+func NewHistoryWSMessage(messages []*models.ChatMessage, hasMore bool, pin *models.ChatPin) *WSMessage {
+	data := HistoryData{
+		Messages: make([]ChatMessageData, 0, len(messages)),
+		HasMore:  hasMore,
+		Pinned:   newPinnedMessageData(pin),
+	}
+	for _, msg := range messages {
+		data.Messages = append(data.Messages, newChatMessageData(msg))
+	}
+	if hasMore && len(messages) > 0 {
+		data.NextBefore = messages[0].ID
+	}
+
+	return &WSMessage{
+		Type: string(MessageTypeHistory),
+		Data: data,
+	}
+}
+
+func newChatMessageData(msg *models.ChatMessage) ChatMessageData {
+	return ChatMessageData{
+		ID:           msg.ID,
+		RaceID:       msg.RaceID,
+		UserID:       msg.UserID,
+		Username:     msg.Username,
+		Message:      msg.Message,
+		CreatedAt:    msg.CreatedAt,
+		Role:         msg.Role,
+		Badges:       msg.Badges,
+		SpecialEmote: msg.SpecialEmote,
+		Announcement: msg.Announcement,
+		Emotes:       msg.Emotes,
+		ReplyToID:    msg.ReplyToID,
+		ReplyTo:      msg.ReplyTo,
+		Mentions:     msg.Mentions,
 	}
 }
 
-func NewPollAnnouncementMessage(poll *Poll) *WSMessage {
+func NewPollAnnouncementMessage(poll *models.ChatPoll) *WSMessage {
 	return &WSMessage{
 		Type: string(MessageTypePollAnnouncement),
 		Data: poll,
 	}
 }
 
-func NewPollUpdateMessage(poll *Poll) *WSMessage {
+func NewPollUpdateMessage(poll *models.ChatPoll) *WSMessage {
 	return &WSMessage{
 		Type: string(MessageTypePollUpdate),
 		Data: poll,
 	}
 }
 
-func NewPollClosedMessage(poll *Poll) *WSMessage {
+func NewPollClosedMessage(poll *models.ChatPoll) *WSMessage {
 	return &WSMessage{
 		Type: string(MessageTypePollClosed),
 		Data: poll,
@@ -111,6 +231,21 @@ func NewErrorWSMessage(message string) *WSMessage {
 	}
 }
 
+// NewRateLimitedWSMessage creates an error message for a message refused by the rate limiter
+func NewRateLimitedWSMessage(message string, result RateLimitResult) *WSMessage {
+	remaining := result.Remaining
+	retryAfter := int((result.RetryAfter + time.Second - 1) / time.Second)
+	return &WSMessage{
+		Type: string(MessageTypeError),
+		Data: ErrorData{
+			Message:    message,
+			Code:       result.Reason,
+			Remaining:  &remaining,
+			RetryAfter: &retryAfter,
+		},
+	}
+}
+
 // NewJoinedWSMessage creates a WebSocket message for user joined
 func NewJoinedWSMessage(username string) *WSMessage {
 	return &WSMessage{
@@ -140,11 +275,32 @@ func NewPongWSMessage() *WSMessage {
 
 // UnmarshalWSMessage unmarshals JSON to WSMessage
 func UnmarshalWSMessage(data []byte) (*WSMessage, error) {
-	var msg WSMessage
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return nil, err
+	return ProtocolJSON.Decode(data)
+}
+
+// ParseData decodes the payload of a WSMessage into dst. Payloads read from a
+// client are decoded straight from their wire form.
+func ParseData(msg *WSMessage, dst interface{}) error {
+	switch data := msg.Data.(type) {
+	case json.RawMessage:
+		return json.Unmarshal(data, dst)
+	case msgp.Raw:
+		if unmarshaler, ok := dst.(msgp.Unmarshaler); ok {
+			_, err := unmarshaler.UnmarshalMsg(data)
+			return err
+		}
+		dataBytes, err := data.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(dataBytes, dst)
+	}
+
+	dataBytes, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
 	}
-	return &msg, nil
+	return json.Unmarshal(dataBytes, dst)
 }
 
 // ParseSendMessageData parses SendMessageData from WSMessage
@@ -153,13 +309,8 @@ func ParseSendMessageData(msg *WSMessage) (*SendMessageData, error) {
 		return nil, nil
 	}
 
-	dataBytes, err := json.Marshal(msg.Data)
-	if err != nil {
-		return nil, err
-	}
-
 	var data SendMessageData
-	if err := json.Unmarshal(dataBytes, &data); err != nil {
+	if err := ParseData(msg, &data); err != nil {
 		return nil, err
 	}
 
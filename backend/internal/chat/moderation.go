@@ -0,0 +1,148 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:ignore SlowMode
+
+const (
+	// How long a room's slow mode setting is trusted before it is reloaded,
+	// so changes made on another instance take effect
+	slowModeRefreshInterval = 30 * time.Second
+)
+
+// DeleteMessageData is sent by moderators to remove a message
+type DeleteMessageData struct {
+	MessageID string `json:"message_id"`
+}
+
+// TimeoutUserData is sent by moderators to silence a user for a while
+type TimeoutUserData struct {
+	UserID          string  `json:"user_id"`
+	DurationSeconds int     `json:"duration_seconds"`
+	Reason          *string `json:"reason,omitempty"`
+}
+
+// BanUserData is sent by moderators to ban a user from the race chat,
+// or from every race when Global is set
+type BanUserData struct {
+	UserID string  `json:"user_id"`
+	Reason *string `json:"reason,omitempty"`
+	Global bool    `json:"global,omitempty"`
+}
+
+// UnbanUserData is sent by moderators to lift a user's timeouts and bans in
+// the race. Global bans stay in place.
+type UnbanUserData struct {
+	UserID string `json:"user_id"`
+}
+
+// SlowModeData sets or announces the slow mode interval (0 disables it)
+type SlowModeData struct {
+	Seconds int `json:"seconds"`
+}
+
+// MessageDeletedData tells clients to hide a message
+type MessageDeletedData struct {
+	MessageID string `json:"message_id"`
+	RaceID    string `json:"race_id"`
+}
+
+// UserRestrictedData tells clients a user was timed out or banned
+type UserRestrictedData struct {
+	UserID    string     `json:"user_id"`
+	Kind      string     `json:"kind"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Reason    *string    `json:"reason,omitempty"`
+}
+
+// RestrictionLiftedData tells clients a user may chat again
+type RestrictionLiftedData struct {
+	UserID string `json:"user_id"`
+}
+
+// NewMessageDeletedWSMessage creates a WebSocket message for a deleted chat message
+func NewMessageDeletedWSMessage(raceID, messageID string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeMessageDeleted),
+		Data: MessageDeletedData{
+			MessageID: messageID,
+			RaceID:    raceID,
+		},
+	}
+}
+
+// NewUserRestrictedWSMessage creates a WebSocket message for a timeout or ban
+func NewUserRestrictedWSMessage(userID, kind string, expiresAt *time.Time, reason *string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeUserRestricted),
+		Data: UserRestrictedData{
+			UserID:    userID,
+			Kind:      kind,
+			ExpiresAt: expiresAt,
+			Reason:    reason,
+		},
+	}
+}
+
+// NewRestrictionLiftedWSMessage creates a WebSocket message for a lifted timeout or ban
+func NewRestrictionLiftedWSMessage(userID string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeRestrictionLifted),
+		Data: RestrictionLiftedData{
+			UserID: userID,
+		},
+	}
+}
+
+// NewSlowModeUpdatedWSMessage creates a WebSocket message announcing the slow mode interval
+func NewSlowModeUpdatedWSMessage(seconds int) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeSlowModeUpdated),
+		Data: SlowModeData{
+			Seconds: seconds,
+		},
+	}
+}
+
+// SlowMode caches each room's slow mode interval. The interval is enforced
+// by RateLimiter as a per-room override of the sender's bucket.
+type SlowMode struct {
+	mu        sync.Mutex
+	intervals map[string]time.Duration // raceID -> interval
+	loadedAt  map[string]time.Time     // raceID -> when interval was set
+}
+
+// NewSlowMode creates a new slow mode cache
+func NewSlowMode() *SlowMode {
+	return &SlowMode{
+		intervals: make(map[string]time.Duration),
+		loadedAt:  make(map[string]time.Time),
+	}
+}
+
+// SetInterval records the slow mode interval for a room (0 disables it)
+func (s *SlowMode) SetInterval(raceID string, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.intervals[raceID] = interval
+	s.loadedAt[raceID] = time.Now()
+}
+
+// Interval returns the room's slow mode interval and whether it is fresh
+// enough to use without reloading it
+func (s *SlowMode) Interval(raceID string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loadedAt, ok := s.loadedAt[raceID]
+	if !ok || time.Since(loadedAt) > slowModeRefreshInterval {
+		return s.intervals[raceID], false
+	}
+	return s.intervals[raceID], true
+}
@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowMode_Interval(t *testing.T) {
+	sm := NewSlowMode()
+
+	interval, fresh := sm.Interval("race-1")
+	assert.Zero(t, interval)
+	assert.False(t, fresh, "Unknown rooms need loading")
+
+	sm.SetInterval("race-1", 30*time.Second)
+	interval, fresh = sm.Interval("race-1")
+	assert.Equal(t, 30*time.Second, interval)
+	assert.True(t, fresh)
+}
+
+func TestParseData(t *testing.T) {
+	msg := &WSMessage{
+		Type: string(MessageTypeTimeoutUser),
+		Data: map[string]interface{}{
+			"user_id":          "user-1",
+			"duration_seconds": 120,
+		},
+	}
+
+	var data TimeoutUserData
+	require.NoError(t, ParseData(msg, &data))
+	assert.Equal(t, "user-1", data.UserID)
+	assert.Equal(t, 120, data.DurationSeconds)
+	assert.Nil(t, data.Reason)
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+//msgp:tag json
+//msgp:newtime
+
+// PinMessageData is sent by admins to pin a message to the top of the chat
+type PinMessageData struct {
+	MessageID string `json:"message_id"`
+}
+
+// AnnouncementData is sent by admins to post an announcement, pinning it
+// when Pin is set
+type AnnouncementData struct {
+	Message string `json:"message"`
+	Pin     bool   `json:"pin,omitempty"`
+}
+
+// PinnedMessageData is the message pinned to the top of the chat
+type PinnedMessageData struct {
+	Message  ChatMessageData `json:"message"`
+	PinnedAt time.Time       `json:"pinned_at"`
+}
+
+// PinUpdatedData tells clients which message is pinned. Pinned is null when
+// the pin was removed.
+type PinUpdatedData struct {
+	RaceID string             `json:"race_id"`
+	Pinned *PinnedMessageData `json:"pinned"`
+}
+
+// NewAnnouncementWSMessage creates a WebSocket message for an announcement
+func NewAnnouncementWSMessage(msg *models.ChatMessage) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeAnnouncement),
+		Data: newChatMessageData(msg),
+	}
+}
+
+// NewPinUpdatedWSMessage creates a WebSocket message announcing a race's
+// pinned message, or that it was unpinned when pin is nil
+func NewPinUpdatedWSMessage(raceID string, pin *models.ChatPin) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypePinUpdated),
+		Data: PinUpdatedData{
+			RaceID: raceID,
+			Pinned: newPinnedMessageData(pin),
+		},
+	}
+}
+
+func newPinnedMessageData(pin *models.ChatPin) *PinnedMessageData {
+	if pin == nil || pin.Message == nil {
+		return nil
+	}
+	return &PinnedMessageData{
+		Message:  newChatMessageData(pin.Message),
+		PinnedAt: pin.PinnedAt,
+	}
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testChatPin() *models.ChatPin {
+	announcement := testChatMessage()
+	announcement.Announcement = true
+	announcement.Message = "Stage profile: 3 cat-1 climbs"
+	return &models.ChatPin{
+		RaceID:   "race-1",
+		Message:  announcement,
+		PinnedAt: time.Date(2026, 7, 14, 15, 45, 0, 0, time.UTC),
+	}
+}
+
+func TestNewPinUpdatedWSMessage(t *testing.T) {
+	t.Run("Pinned message", func(t *testing.T) {
+		encoded, err := ProtocolJSON.Encode(NewPinUpdatedWSMessage("race-1", testChatPin()))
+		require.NoError(t, err)
+
+		var decoded struct {
+			Type string         `json:"type"`
+			Data PinUpdatedData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		assert.Equal(t, "pin_updated", decoded.Type)
+		require.NotNil(t, decoded.Data.Pinned)
+		assert.Equal(t, "Stage profile: 3 cat-1 climbs", decoded.Data.Pinned.Message.Message)
+		assert.True(t, decoded.Data.Pinned.Message.Announcement)
+	})
+
+	t.Run("Unpinning sends a null pin", func(t *testing.T) {
+		encoded, err := ProtocolJSON.Encode(NewPinUpdatedWSMessage("race-1", nil))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"pin_updated","v":2,"data":{"race_id":"race-1","pinned":null}}`, string(encoded))
+
+		msgpack, err := ProtocolMsgpack.Encode(NewPinUpdatedWSMessage("race-1", nil))
+		require.NoError(t, err)
+		assert.JSONEq(t, string(encoded), msgpackAsJSON(t, msgpack))
+	})
+}
+
+func TestNewHistoryWSMessage_Pin(t *testing.T) {
+	withPin := NewHistoryWSMessage([]*models.ChatMessage{testChatMessage()}, false, testChatPin())
+	local := NewFrame(withPin)
+	localJSON, err := local.JSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(localJSON), `"pinned":{"message":{`)
+
+	// Pins survive the trip through other instances
+	localMsgpack, err := local.Encode(ProtocolMsgpack)
+	require.NoError(t, err)
+	remoteMsgpack, err := NewJSONFrame(localJSON).Encode(ProtocolMsgpack)
+	require.NoError(t, err)
+	assert.Equal(t, localMsgpack, remoteMsgpack)
+
+	withoutPin, err := ProtocolJSON.Encode(NewHistoryWSMessage(nil, false, nil))
+	require.NoError(t, err)
+	assert.NotContains(t, string(withoutPin), "pinned")
+}
@@ -3,70 +3,127 @@ package chat
 import (
 	"errors"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
 	"github.com/google/uuid"
 )
 
-// PollOption represents an individual poll option.
-type PollOption struct {
-	ID    string `json:"id"`
-	Label string `json:"label"`
-	Votes int    `json:"votes"`
-}
+const (
+	// How often the background closer looks for expired polls
+	pollCloseCheckInterval = 1 * time.Second
+)
 
-// Poll represents an active or closed poll in chat.
-type Poll struct {
-	ID         string       `json:"id"`
-	RaceID     string       `json:"race_id"`
-	Question   string       `json:"question"`
-	Options    []PollOption `json:"options"`
-	TotalVotes int          `json:"total_votes"`
-	CreatedAt  time.Time    `json:"created_at"`
-	ClosesAt   *time.Time   `json:"closes_at,omitempty"`
-	Closed     bool         `json:"closed"`
+// PollSpec describes a poll to create.
+type PollSpec struct {
+	Question   string
+	Options    []string
+	Type       string // models.ChatPollType*, defaults to single
+	MaxChoices int    // multi polls only, defaults to every option
+	Duration   time.Duration
+	CreatedBy  *string
 }
 
-// PollManager keeps polls in memory per race.
+// PollManager runs polls on top of a PollStore. It keeps no polls of its own,
+// so votes and closes work on any instance and tallies count every ballot.
+// Several polls may be open in a race at once.
 type PollManager struct {
-	mu        sync.RWMutex
-	polls     map[string]*Poll
-	raceIndex map[string]string            // raceID -> pollID
-	votes     map[string]map[string]string // pollID -> userID -> optionID
+	store PollStore
+
+	stopCloser chan struct{}
+	closerDone chan struct{}
 }
 
-func NewPollManager() *PollManager {
-	return &PollManager{
-		polls:     make(map[string]*Poll),
-		raceIndex: make(map[string]string),
-		votes:     make(map[string]map[string]string),
+// NewPollManager creates a poll manager. store may be nil, in which case
+// polls only live in memory.
+func NewPollManager(store PollStore) *PollManager {
+	if store == nil {
+		store = newMemoryPollStore()
 	}
+	return &PollManager{store: store}
 }
 
-// CreatePoll starts a new poll for a race, replacing any active poll.
-func (pm *PollManager) CreatePoll(raceID, question string, optionLabels []string, duration time.Duration) (*Poll, error) {
-	if len(optionLabels) < 2 {
-		return nil, errors.New("poll requires at least two options")
+// Start runs the background closer, which closes polls once ClosesAt has
+// passed and hands each closed poll to onClosed.
+func (pm *PollManager) Start(onClosed func(poll *models.ChatPoll)) {
+	pm.stopCloser = make(chan struct{})
+	pm.closerDone = make(chan struct{})
+
+	go func() {
+		defer close(pm.closerDone)
+		ticker := time.NewTicker(pollCloseCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				pm.closeExpired(onClosed)
+			case <-pm.stopCloser:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background closer
+func (pm *PollManager) Stop() {
+	if pm.stopCloser == nil {
+		return
 	}
+	close(pm.stopCloser)
+	<-pm.closerDone
+	pm.stopCloser = nil
+}
 
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+// closeExpired closes the open polls, of every instance, whose ClosesAt has
+// passed. Polls that fail to close stay open and are retried on the next tick.
+func (pm *PollManager) closeExpired(onClosed func(poll *models.ChatPoll)) {
+	polls, err := pm.store.ListOpen("")
+	if err != nil {
+		logger.WithError(err).Warn("Failed to list open polls")
+		return
+	}
 
-	poll := &Poll{
+	now := time.Now()
+	for _, poll := range polls {
+		if poll.ClosesAt == nil || poll.ClosesAt.After(now) {
+			continue
+		}
+		closed, closedNow, err := pm.closePoll(poll.ID)
+		if err != nil {
+			logger.WithError(err).WithField("poll_id", poll.ID).Warn("Failed to close expired poll")
+			continue
+		}
+		// Another instance may have closed it already and announced it
+		if closedNow && onClosed != nil {
+			onClosed(closed)
+		}
+	}
+}
+
+// CreatePoll starts a new poll for a race.
+func (pm *PollManager) CreatePoll(raceID string, spec PollSpec) (*models.ChatPoll, error) {
+	poll := &models.ChatPoll{
 		ID:        uuid.NewString(),
 		RaceID:    raceID,
-		Question:  question,
-		Options:   make([]PollOption, 0, len(optionLabels)),
+		Question:  strings.TrimSpace(spec.Question),
+		Type:      spec.Type,
+		Options:   make([]models.ChatPollOption, 0, len(spec.Options)),
+		CreatedBy: spec.CreatedBy,
 		CreatedAt: time.Now().UTC(),
 	}
 
-	for _, label := range optionLabels {
+	if poll.Question == "" {
+		return nil, errors.New("poll question is required")
+	}
+
+	for _, label := range spec.Options {
 		label = strings.TrimSpace(label)
 		if label == "" {
 			continue
 		}
-		poll.Options = append(poll.Options, PollOption{
+		poll.Options = append(poll.Options, models.ChatPollOption{
 			ID:    uuid.NewString(),
 			Label: label,
 		})
@@ -76,112 +133,229 @@ func (pm *PollManager) CreatePoll(raceID, question string, optionLabels []string
 		return nil, errors.New("poll requires at least two valid options")
 	}
 
-	if duration > 0 {
-		closesAt := poll.CreatedAt.Add(duration)
+	switch poll.Type {
+	case "":
+		poll.Type = models.ChatPollTypeSingle
+	case models.ChatPollTypeSingle, models.ChatPollTypeRanked:
+	case models.ChatPollTypeMulti:
+		poll.MaxChoices = spec.MaxChoices
+		if poll.MaxChoices <= 0 || poll.MaxChoices > len(poll.Options) {
+			poll.MaxChoices = len(poll.Options)
+		}
+	default:
+		return nil, errors.New("invalid poll type")
+	}
+
+	if spec.Duration > 0 {
+		closesAt := poll.CreatedAt.Add(spec.Duration)
 		poll.ClosesAt = &closesAt
 	}
 
-	// Close existing poll in this race
-	if existingID, ok := pm.raceIndex[raceID]; ok {
-		if existing, exists := pm.polls[existingID]; exists {
-			existing.Closed = true
-		}
+	if err := pm.store.CreatePoll(poll); err != nil {
+		return nil, err
 	}
 
-	pm.polls[poll.ID] = poll
-	pm.raceIndex[raceID] = poll.ID
-	pm.votes[poll.ID] = make(map[string]string)
+	return clonePoll(poll), nil
+}
 
+// Vote records or replaces a user's ballot and returns the poll with the
+// store's running tallies, which count every stored ballot. Single polls take
+// exactly one option, multi polls up to MaxChoices, and ranked polls take
+// options in order of preference.
+func (pm *PollManager) Vote(pollID, userID string, optionIDs []string) (*models.ChatPoll, error) {
+	if userID == "" {
+		return nil, errors.New("user_id required")
+	}
+
+	poll, err := pm.store.GetPoll(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll == nil || poll.Closed {
+		return nil, errors.New("poll not active")
+	}
+	if err := validateBallot(poll, optionIDs); err != nil {
+		return nil, err
+	}
+
+	saved, err := pm.store.SaveBallot(pollID, userID, append([]string(nil), optionIDs...))
+	if err != nil {
+		return nil, err
+	}
+	// The poll closed since it was loaded
+	if !saved {
+		return nil, errors.New("poll not active")
+	}
+
+	if err := pm.tally(poll); err != nil {
+		return nil, err
+	}
 	return poll, nil
 }
 
-// Vote records a vote for the given user/option.
-func (pm *PollManager) Vote(pollID, userID, optionID string) (*Poll, error) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+// ClosePoll closes an open poll and returns its final tallies.
+func (pm *PollManager) ClosePoll(pollID string) (*models.ChatPoll, error) {
+	poll, _, err := pm.closePoll(pollID)
+	return poll, err
+}
 
-	poll, ok := pm.polls[pollID]
-	if !ok || poll.Closed {
-		return nil, errors.New("poll not active")
+// closePoll closes a poll and reports whether this call was the one that
+// closed it in the store. A poll that's already closed is returned with its
+// final tallies.
+func (pm *PollManager) closePoll(pollID string) (*models.ChatPoll, bool, error) {
+	poll, err := pm.store.GetPoll(pollID)
+	if err != nil {
+		return nil, false, err
+	}
+	if poll == nil {
+		return nil, false, errors.New("poll not found")
+	}
+	if poll.Closed {
+		return poll, false, nil
 	}
 
-	if userID == "" {
-		return nil, errors.New("user_id required")
+	if err := pm.tally(poll); err != nil {
+		return nil, false, err
 	}
+	closedAt := time.Now().UTC()
+	poll.ClosedAt = &closedAt
+	poll.Closed = true
 
-	optionIndex := -1
-	for idx, opt := range poll.Options {
-		if opt.ID == optionID {
-			optionIndex = idx
-			break
+	closedNow, err := pm.store.ClosePoll(poll)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return poll, closedNow, nil
+}
+
+// GetActivePolls returns the open polls for a race with their tallies, oldest first.
+func (pm *PollManager) GetActivePolls(raceID string) ([]*models.ChatPoll, error) {
+	polls, err := pm.store.ListOpen(raceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, poll := range polls {
+		if err := pm.tally(poll); err != nil {
+			return nil, err
 		}
 	}
+	return polls, nil
+}
 
-	if optionIndex == -1 {
-		return nil, errors.New("invalid option")
+// GetPoll returns a poll with its tallies, or nil if there's no such poll.
+func (pm *PollManager) GetPoll(pollID string) (*models.ChatPoll, error) {
+	poll, err := pm.store.GetPoll(pollID)
+	if err != nil || poll == nil {
+		return nil, err
 	}
+	if !poll.Closed {
+		if err := pm.tally(poll); err != nil {
+			return nil, err
+		}
+	}
+	return poll, nil
+}
 
-	if pm.votes[pollID] == nil {
-		pm.votes[pollID] = make(map[string]string)
+// History returns the most recent polls for a race, newest first. Open polls
+// carry their live tallies; closed polls carry their final tallies.
+func (pm *PollManager) History(raceID string, limit int) ([]*models.ChatPoll, error) {
+	polls, err := pm.store.ListByRace(raceID, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	if prevOption, voted := pm.votes[pollID][userID]; voted {
-		if prevOption == optionID {
-			return poll, nil
+	for _, poll := range polls {
+		if poll.Closed {
+			continue
 		}
-		for i := range poll.Options {
-			if poll.Options[i].ID == prevOption && poll.Options[i].Votes > 0 {
-				poll.Options[i].Votes--
-				poll.TotalVotes--
-				break
-			}
+		if err := pm.tally(poll); err != nil {
+			return nil, err
 		}
 	}
 
-	pm.votes[pollID][userID] = optionID
-	poll.Options[optionIndex].Votes++
-	poll.TotalVotes++
+	return polls, nil
+}
 
-	return poll, nil
+// tally sets an open poll's tallies from the store's vote counts, which
+// include the ballots cast through other instances
+func (pm *PollManager) tally(poll *models.ChatPoll) error {
+	counts, voters, err := pm.store.CountVotes(poll.ID)
+	if err != nil {
+		return err
+	}
+	tallyPoll(poll, counts, voters)
+	return nil
 }
 
-// ClosePoll terminates an active poll and removes it from the race index.
-func (pm *PollManager) ClosePoll(pollID string) (*Poll, error) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+func validateBallot(poll *models.ChatPoll, optionIDs []string) error {
+	if len(optionIDs) == 0 {
+		return errors.New("at least one option is required")
+	}
 
-	poll, ok := pm.polls[pollID]
-	if !ok {
-		return nil, errors.New("poll not found")
+	seen := make(map[string]struct{}, len(optionIDs))
+	for _, id := range optionIDs {
+		if pollOptionIndex(poll, id) == -1 {
+			return errors.New("invalid option")
+		}
+		if _, dup := seen[id]; dup {
+			return errors.New("duplicate option")
+		}
+		seen[id] = struct{}{}
 	}
 
-	poll.Closed = true
-	if currentID, ok := pm.raceIndex[poll.RaceID]; ok && currentID == poll.ID {
-		delete(pm.raceIndex, poll.RaceID)
+	switch poll.Type {
+	case models.ChatPollTypeMulti:
+		if len(optionIDs) > poll.MaxChoices {
+			return errors.New("too many options selected")
+		}
+	case models.ChatPollTypeRanked:
+		// Partial rankings are allowed
+	default:
+		if len(optionIDs) != 1 {
+			return errors.New("exactly one option is required")
+		}
 	}
-	delete(pm.votes, poll.ID)
 
-	return poll, nil
+	return nil
 }
 
-// GetActivePoll returns the active poll for a race, if any.
-func (pm *PollManager) GetActivePoll(raceID string) *Poll {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-
-	if pollID, ok := pm.raceIndex[raceID]; ok {
-		if poll, exists := pm.polls[pollID]; exists && !poll.Closed {
-			return poll
+// tallyPoll sets a poll's tallies from its vote counts. Ranked polls score
+// len(options)-rank Borda points per ballot and count first preferences as
+// votes; other polls count every selection.
+func tallyPoll(poll *models.ChatPoll, counts []models.ChatPollVoteCount, voters int) {
+	for i := range poll.Options {
+		poll.Options[i].Votes = 0
+		poll.Options[i].Score = 0
+	}
+	poll.TotalVotes = voters
+	for _, count := range counts {
+		idx := pollOptionIndex(poll, count.OptionID)
+		if idx == -1 {
+			continue
 		}
+		if poll.Type == models.ChatPollTypeRanked {
+			poll.Options[idx].Score += count.Ballots * (len(poll.Options) - count.Rank)
+			if count.Rank == 0 {
+				poll.Options[idx].Votes += count.Ballots
+			}
+			continue
+		}
+		poll.Options[idx].Votes += count.Ballots
 	}
-	return nil
 }
 
-// GetPoll returns the poll by ID if present.
-func (pm *PollManager) GetPoll(pollID string) (*Poll, bool) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+func pollOptionIndex(poll *models.ChatPoll, optionID string) int {
+	for idx, opt := range poll.Options {
+		if opt.ID == optionID {
+			return idx
+		}
+	}
+	return -1
+}
 
-	poll, ok := pm.polls[pollID]
-	return poll, ok
+func clonePoll(poll *models.ChatPoll) *models.ChatPoll {
+	c := *poll
+	c.Options = append([]models.ChatPollOption(nil), poll.Options...)
+	return &c
 }
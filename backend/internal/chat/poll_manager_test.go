@@ -0,0 +1,317 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingCloseStore fails to close polls until fail is cleared
+type failingCloseStore struct {
+	*memoryPollStore
+	fail bool
+}
+
+func (s *failingCloseStore) ClosePoll(poll *models.ChatPoll) (bool, error) {
+	if s.fail {
+		return false, errors.New("connection reset")
+	}
+	return s.memoryPollStore.ClosePoll(poll)
+}
+
+func optionIDs(poll *models.ChatPoll, indexes ...int) []string {
+	ids := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		ids = append(ids, poll.Options[idx].ID)
+	}
+	return ids
+}
+
+func TestPollManager_SingleChoice(t *testing.T) {
+	pm := NewPollManager(nil)
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Winner?", Options: []string{"A", "B", " "}})
+	require.NoError(t, err)
+	assert.Equal(t, models.ChatPollTypeSingle, poll.Type)
+	assert.Len(t, poll.Options, 2, "Blank options are dropped")
+
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0, 1))
+	assert.Error(t, err, "Single polls take one option")
+
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0))
+	require.NoError(t, err)
+	updated, err := pm.Vote(poll.ID, "user-1", optionIDs(poll, 1))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, updated.TotalVotes, "Changing a vote does not add a voter")
+	assert.Equal(t, 0, updated.Options[0].Votes)
+	assert.Equal(t, 1, updated.Options[1].Votes)
+}
+
+func TestPollManager_MultiChoice(t *testing.T) {
+	pm := NewPollManager(nil)
+	poll, err := pm.CreatePoll("race-1", PollSpec{
+		Question:   "Who makes the top 3?",
+		Options:    []string{"A", "B", "C"},
+		Type:       models.ChatPollTypeMulti,
+		MaxChoices: 2,
+	})
+	require.NoError(t, err)
+
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0, 1, 2))
+	assert.Error(t, err, "More than MaxChoices is rejected")
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0, 0))
+	assert.Error(t, err, "Duplicate options are rejected")
+
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0, 1))
+	require.NoError(t, err)
+	updated, err := pm.Vote(poll.ID, "user-2", optionIDs(poll, 1))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, updated.TotalVotes)
+	assert.Equal(t, []int{1, 2, 0}, []int{updated.Options[0].Votes, updated.Options[1].Votes, updated.Options[2].Votes})
+}
+
+func TestPollManager_Ranked(t *testing.T) {
+	pm := NewPollManager(nil)
+	poll, err := pm.CreatePoll("race-1", PollSpec{
+		Question: "Rank the favourites",
+		Options:  []string{"A", "B", "C"},
+		Type:     models.ChatPollTypeRanked,
+	})
+	require.NoError(t, err)
+
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0, 1, 2))
+	require.NoError(t, err)
+	updated, err := pm.Vote(poll.ID, "user-2", optionIDs(poll, 1, 0))
+	require.NoError(t, err)
+
+	// Borda: 3 points for first, 2 for second, 1 for third
+	assert.Equal(t, 5, updated.Options[0].Score)
+	assert.Equal(t, 5, updated.Options[1].Score)
+	assert.Equal(t, 1, updated.Options[2].Score)
+	assert.Equal(t, 1, updated.Options[0].Votes, "Votes counts first preferences")
+	assert.Equal(t, 1, updated.Options[1].Votes)
+}
+
+func TestMemoryPollStore_CountVotes(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Rank", Options: []string{"A", "B"}, Type: models.ChatPollTypeRanked})
+	require.NoError(t, err)
+
+	_, err = store.SaveBallot(poll.ID, "user-1", optionIDs(poll, 0, 1))
+	require.NoError(t, err)
+	_, err = store.SaveBallot(poll.ID, "user-2", optionIDs(poll, 0))
+	require.NoError(t, err)
+	// Replacing a ballot moves its counts
+	_, err = store.SaveBallot(poll.ID, "user-1", optionIDs(poll, 1, 0))
+	require.NoError(t, err)
+
+	counts, voters, err := store.CountVotes(poll.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, voters)
+	assert.ElementsMatch(t, []models.ChatPollVoteCount{
+		{OptionID: poll.Options[0].ID, Rank: 0, Ballots: 1},
+		{OptionID: poll.Options[0].ID, Rank: 1, Ballots: 1},
+		{OptionID: poll.Options[1].ID, Rank: 0, Ballots: 1},
+	}, counts)
+}
+
+func TestPollManager_InvalidType(t *testing.T) {
+	pm := NewPollManager(nil)
+	_, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}, Type: "approval"})
+	assert.Error(t, err)
+}
+
+func TestPollManager_MultiplePollsPerRace(t *testing.T) {
+	pm := NewPollManager(nil)
+	first, err := pm.CreatePoll("race-1", PollSpec{Question: "One", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+	second, err := pm.CreatePoll("race-1", PollSpec{Question: "Two", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+	_, err = pm.CreatePoll("race-2", PollSpec{Question: "Other", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+
+	active, err := pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, first.ID, active[0].ID)
+	assert.Equal(t, second.ID, active[1].ID)
+
+	_, err = pm.ClosePoll(first.ID)
+	require.NoError(t, err)
+	active, err = pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+
+	_, err = pm.Vote(first.ID, "user-1", optionIDs(first, 0))
+	assert.Error(t, err, "Closed polls reject votes")
+}
+
+func TestPollManager_ReturnsCopies(t *testing.T) {
+	pm := NewPollManager(nil)
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+
+	poll.Options[0].Votes = 42
+	stored, err := pm.GetPoll(poll.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.Options[0].Votes)
+}
+
+func TestPollManager_SharesPollsThroughStore(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+	// Another instance sharing the store
+	other := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 1))
+	require.NoError(t, err)
+
+	active, err := other.GetActivePolls("race-1")
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, 1, active[0].Options[1].Votes)
+
+	// Tallies count the ballots cast through every instance
+	updated, err := other.Vote(poll.ID, "user-2", optionIDs(poll, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.TotalVotes)
+	assert.Equal(t, []int{1, 1}, []int{updated.Options[0].Votes, updated.Options[1].Votes})
+
+	// Re-voting on another instance replaces the stored ballot
+	updated, err = other.Vote(poll.ID, "user-1", optionIDs(poll, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.TotalVotes)
+	assert.Equal(t, 2, updated.Options[0].Votes)
+
+	closed, err := other.ClosePoll(poll.ID)
+	require.NoError(t, err)
+	assert.True(t, closed.Closed)
+
+	_, err = pm.Vote(poll.ID, "user-3", optionIDs(poll, 1))
+	assert.Error(t, err, "Polls closed on another instance reject votes")
+	active, err = pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+func TestPollManager_CloseUsesStoredBallots(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0))
+	require.NoError(t, err)
+
+	// A ballot cast through another instance
+	_, err = store.SaveBallot(poll.ID, "user-2", optionIDs(poll, 1))
+	require.NoError(t, err)
+
+	closed, err := pm.ClosePoll(poll.ID)
+	require.NoError(t, err)
+	assert.True(t, closed.Closed)
+	assert.NotNil(t, closed.ClosedAt)
+	assert.Equal(t, 2, closed.TotalVotes)
+
+	history, err := pm.History("race-1", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Closed)
+	assert.Equal(t, 2, history[0].TotalVotes)
+}
+
+func TestPollManager_HistoryOverlaysLiveTallies(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}})
+	require.NoError(t, err)
+	_, err = pm.Vote(poll.ID, "user-1", optionIDs(poll, 0))
+	require.NoError(t, err)
+
+	history, err := pm.History("race-1", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.False(t, history[0].Closed)
+	assert.Equal(t, 1, history[0].Options[0].Votes)
+}
+
+func TestPollManager_BackgroundCloser(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{
+		Question: "Q",
+		Options:  []string{"A", "B"},
+		Duration: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	closed := make(chan *models.ChatPoll, 1)
+	pm.Start(func(p *models.ChatPoll) { closed <- p })
+	defer pm.Stop()
+
+	select {
+	case p := <-closed:
+		assert.Equal(t, poll.ID, p.ID)
+		assert.True(t, p.Closed)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected expired poll to be closed")
+	}
+
+	active, err := pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+func TestPollManager_CloserSkipsPollsClosedElsewhere(t *testing.T) {
+	store := newMemoryPollStore()
+	pm := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}, Duration: time.Millisecond})
+	require.NoError(t, err)
+
+	// Another instance closed the poll first
+	store.polls[poll.ID].Closed = true
+
+	var announced int
+	time.Sleep(5 * time.Millisecond)
+	pm.closeExpired(func(*models.ChatPoll) { announced++ })
+
+	assert.Zero(t, announced)
+	active, err := pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+func TestPollManager_CloserRetriesFailedCloses(t *testing.T) {
+	store := &failingCloseStore{memoryPollStore: newMemoryPollStore(), fail: true}
+	pm := NewPollManager(store)
+
+	poll, err := pm.CreatePoll("race-1", PollSpec{Question: "Q", Options: []string{"A", "B"}, Duration: time.Millisecond})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	var announced []*models.ChatPoll
+	onClosed := func(p *models.ChatPoll) { announced = append(announced, p) }
+
+	pm.closeExpired(onClosed)
+	assert.Empty(t, announced)
+	active, err := pm.GetActivePolls("race-1")
+	require.NoError(t, err)
+	assert.Len(t, active, 1, "A poll that failed to close stays open")
+
+	store.fail = false
+	pm.closeExpired(onClosed)
+	require.Len(t, announced, 1)
+	assert.Equal(t, poll.ID, announced[0].ID)
+	assert.True(t, announced[0].Closed)
+}
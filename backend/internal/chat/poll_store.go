@@ -0,0 +1,144 @@
+package chat
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// PollStore persists polls and ballots. It is shared by every instance, so it
+// holds the only copy of which polls are open and how users voted.
+// It is implemented by repository.ChatPollRepository.
+type PollStore interface {
+	CreatePoll(poll *models.ChatPoll) error
+	// GetPoll returns a poll, or nil
+	GetPoll(pollID string) (*models.ChatPoll, error)
+	// SaveBallot records or replaces a ballot, updating the poll's vote
+	// counts, and returns false if the poll is closed or missing
+	SaveBallot(pollID, userID string, optionIDs []string) (bool, error)
+	// CountVotes returns the poll's vote counts and number of voters
+	CountVotes(pollID string) ([]models.ChatPollVoteCount, int, error)
+	// ClosePoll stores the final tallies and returns false if the poll was already closed
+	ClosePoll(poll *models.ChatPoll) (bool, error)
+	// ListOpen returns the open polls of a race, or of every race when raceID
+	// is empty, oldest first
+	ListOpen(raceID string) ([]*models.ChatPoll, error)
+	ListByRace(raceID string, limit int) ([]*models.ChatPoll, error)
+}
+
+// memoryPollStore keeps polls in memory for single-instance deployments.
+type memoryPollStore struct {
+	mu      sync.Mutex
+	polls   map[string]*models.ChatPoll
+	ballots map[string]map[string][]string
+	counts  map[string]map[pollVoteKey]int
+}
+
+// pollVoteKey is an option at a position on ballots
+type pollVoteKey struct {
+	optionID string
+	rank     int
+}
+
+func newMemoryPollStore() *memoryPollStore {
+	return &memoryPollStore{
+		polls:   make(map[string]*models.ChatPoll),
+		ballots: make(map[string]map[string][]string),
+		counts:  make(map[string]map[pollVoteKey]int),
+	}
+}
+
+func (s *memoryPollStore) CreatePoll(poll *models.ChatPoll) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.polls[poll.ID] = clonePoll(poll)
+	s.ballots[poll.ID] = make(map[string][]string)
+	s.counts[poll.ID] = make(map[pollVoteKey]int)
+	return nil
+}
+
+func (s *memoryPollStore) GetPoll(pollID string) (*models.ChatPoll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	poll, ok := s.polls[pollID]
+	if !ok {
+		return nil, nil
+	}
+	return clonePoll(poll), nil
+}
+
+func (s *memoryPollStore) SaveBallot(pollID, userID string, optionIDs []string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	poll, ok := s.polls[pollID]
+	if !ok || poll.Closed {
+		return false, nil
+	}
+	counts := s.counts[pollID]
+	for rank, id := range s.ballots[pollID][userID] {
+		key := pollVoteKey{optionID: id, rank: rank}
+		if counts[key]--; counts[key] == 0 {
+			delete(counts, key)
+		}
+	}
+	for rank, id := range optionIDs {
+		counts[pollVoteKey{optionID: id, rank: rank}]++
+	}
+	s.ballots[pollID][userID] = append([]string(nil), optionIDs...)
+	return true, nil
+}
+
+func (s *memoryPollStore) CountVotes(pollID string) ([]models.ChatPollVoteCount, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make([]models.ChatPollVoteCount, 0, len(s.counts[pollID]))
+	for key, ballots := range s.counts[pollID] {
+		counts = append(counts, models.ChatPollVoteCount{OptionID: key.optionID, Rank: key.rank, Ballots: ballots})
+	}
+	return counts, len(s.ballots[pollID]), nil
+}
+
+func (s *memoryPollStore) ClosePoll(poll *models.ChatPoll) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.polls[poll.ID]
+	if !ok || stored.Closed {
+		return false, nil
+	}
+	s.polls[poll.ID] = clonePoll(poll)
+	return true, nil
+}
+
+func (s *memoryPollStore) ListOpen(raceID string) ([]*models.ChatPoll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	polls := []*models.ChatPoll{}
+	for _, poll := range s.polls {
+		if !poll.Closed && (raceID == "" || poll.RaceID == raceID) {
+			polls = append(polls, clonePoll(poll))
+		}
+	}
+	sort.Slice(polls, func(i, j int) bool {
+		return polls[i].CreatedAt.Before(polls[j].CreatedAt)
+	})
+	return polls, nil
+}
+
+func (s *memoryPollStore) ListByRace(raceID string, limit int) ([]*models.ChatPoll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	polls := []*models.ChatPoll{}
+	for _, poll := range s.polls {
+		if poll.RaceID == raceID {
+			polls = append(polls, clonePoll(poll))
+		}
+	}
+	sort.Slice(polls, func(i, j int) bool {
+		return polls[i].CreatedAt.After(polls[j].CreatedAt)
+	})
+	if len(polls) > limit {
+		polls = polls[:limit]
+	}
+	return polls, nil
+}
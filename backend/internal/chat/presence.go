@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//msgp:tag json
+//msgp:ignore typingThrottle
+
+const (
+	// How often one user's typing indicator is relayed to a room
+	TypingThrottleInterval = 3 * time.Second
+)
+
+// PresenceUser is an authenticated user connected to a room. Users with
+// several tabs open are listed once.
+type PresenceUser struct {
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	Role        string   `json:"role"`
+	Badges      []string `json:"badges"`
+	Connections int      `json:"connections"`
+}
+
+// PresenceSnapshotData lists who is in a room. Users holds the authenticated
+// users connected to this instance; Total counts every connection across the
+// cluster, anonymous ones included.
+type PresenceSnapshotData struct {
+	RaceID    string         `json:"race_id"`
+	Users     []PresenceUser `json:"users"`
+	Anonymous int            `json:"anonymous"`
+	Total     int            `json:"total"`
+}
+
+// TypingData is relayed to a room while a user is typing
+type TypingData struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// NewPresenceSnapshotWSMessage creates a presence snapshot frame
+func NewPresenceSnapshotWSMessage(snapshot *PresenceSnapshotData) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypePresenceSnapshot),
+		Data: snapshot,
+	}
+}
+
+// NewTypingWSMessage creates a typing indicator frame
+func NewTypingWSMessage(userID, username string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeTyping),
+		Data: TypingData{
+			UserID:   userID,
+			Username: username,
+		},
+	}
+}
+
+// typingThrottle remembers when each user's typing indicator was last relayed
+// to a room
+type typingThrottle struct {
+	mu        sync.Mutex
+	last      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newTypingThrottle() *typingThrottle {
+	return &typingThrottle{last: make(map[string]time.Time)}
+}
+
+// allow reports whether a user's typing indicator may be relayed now
+func (t *typingThrottle) allow(roomID, userID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Forget users who stopped typing
+	if now.Sub(t.lastPrune) >= TypingThrottleInterval {
+		for key, at := range t.last {
+			if now.Sub(at) >= TypingThrottleInterval {
+				delete(t.last, key)
+			}
+		}
+		t.lastPrune = now
+	}
+
+	key := roomID + "\x00" + userID
+	if at, ok := t.last[key]; ok && now.Sub(at) < TypingThrottleInterval {
+		return false
+	}
+	t.last[key] = now
+	return true
+}
+
+// RoomPresence lists the authenticated users connected to a room on this
+// instance, sorted by username, and counts its anonymous connections
+func (h *Hub) RoomPresence(roomID string) *PresenceSnapshotData {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snapshot := &PresenceSnapshotData{RaceID: roomID, Users: []PresenceUser{}}
+	byUser := make(map[string]int)
+	for client := range h.rooms[roomID] {
+		if client.userID == nil {
+			snapshot.Anonymous++
+			continue
+		}
+
+		if i, ok := byUser[*client.userID]; ok {
+			snapshot.Users[i].Connections++
+			continue
+		}
+		byUser[*client.userID] = len(snapshot.Users)
+		badges := client.badges
+		if badges == nil {
+			badges = []string{}
+		}
+		snapshot.Users = append(snapshot.Users, PresenceUser{
+			UserID:      *client.userID,
+			Username:    client.username,
+			Role:        client.role,
+			Badges:      badges,
+			Connections: 1,
+		})
+	}
+
+	sort.Slice(snapshot.Users, func(i, j int) bool {
+		a, b := strings.ToLower(snapshot.Users[i].Username), strings.ToLower(snapshot.Users[j].Username)
+		if a != b {
+			return a < b
+		}
+		return snapshot.Users[i].UserID < snapshot.Users[j].UserID
+	})
+	snapshot.Total = len(h.rooms[roomID]) + h.broadcaster.RemoteClientCount(roomID)
+	return snapshot
+}
+
+// BroadcastTyping relays a user's typing indicator to a room, at most once
+// per TypingThrottleInterval. It reports whether the indicator was sent.
+func (h *Hub) BroadcastTyping(roomID, userID, username string) bool {
+	if !h.typing.allow(roomID, userID, time.Now()) {
+		return false
+	}
+	h.BroadcastToRoom(roomID, NewTypingWSMessage(userID, username))
+	return true
+}
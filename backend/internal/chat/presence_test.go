@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_RoomPresence(t *testing.T) {
+	hub := NewHub()
+
+	alice := "user-alice"
+	bob := "user-bob"
+	aliceTab := createTestClient(hub, &alice, "alice")
+	aliceTab.SetRoleAndBadges("subscriber", []string{"sub"})
+	aliceOtherTab := createTestClient(hub, &alice, "alice")
+	aliceOtherTab.SetRoleAndBadges("subscriber", []string{"sub"})
+	bobClient := createTestClient(hub, &bob, "Bob")
+	bobClient.SetRoleAndBadges("mod", []string{"mod"})
+	anonymous := createTestClient(hub, nil, "Anonymous")
+
+	for _, c := range []*Client{aliceTab, aliceOtherTab, bobClient, anonymous} {
+		hub.JoinRoom(c, "race-1")
+	}
+
+	presence := hub.RoomPresence("race-1")
+	assert.Equal(t, "race-1", presence.RaceID)
+	assert.Equal(t, []PresenceUser{
+		{UserID: alice, Username: "alice", Role: "subscriber", Badges: []string{"sub"}, Connections: 2},
+		{UserID: bob, Username: "Bob", Role: "mod", Badges: []string{"mod"}, Connections: 1},
+	}, presence.Users, "Users are listed once, sorted by name")
+	assert.Equal(t, 1, presence.Anonymous)
+	assert.Equal(t, 4, presence.Total)
+
+	t.Run("Empty rooms list no users", func(t *testing.T) {
+		presence := hub.RoomPresence("race-2")
+		assert.NotNil(t, presence.Users)
+		assert.Empty(t, presence.Users)
+		assert.Zero(t, presence.Total)
+	})
+}
+
+func TestTypingThrottle(t *testing.T) {
+	throttle := newTypingThrottle()
+	now := time.Date(2026, 7, 14, 15, 0, 0, 0, time.UTC)
+
+	assert.True(t, throttle.allow("race-1", "user-1", now))
+	assert.False(t, throttle.allow("race-1", "user-1", now.Add(time.Second)), "Repeats within the interval are dropped")
+	assert.True(t, throttle.allow("race-1", "user-2", now.Add(time.Second)), "Users are throttled separately")
+	assert.True(t, throttle.allow("race-2", "user-1", now.Add(time.Second)), "Rooms are throttled separately")
+	assert.True(t, throttle.allow("race-1", "user-1", now.Add(TypingThrottleInterval)))
+
+	t.Run("Users who stopped typing are forgotten", func(t *testing.T) {
+		throttle.allow("race-1", "user-3", now.Add(5*TypingThrottleInterval))
+		assert.Len(t, throttle.last, 1)
+	})
+}
+
+func TestHub_BroadcastTyping(t *testing.T) {
+	hub := NewHub()
+	alice := "user-alice"
+	viewer := createTestClient(hub, nil, "Viewer")
+	hub.JoinRoom(viewer, "race-1")
+
+	assert.True(t, hub.BroadcastTyping("race-1", alice, "Alice"))
+	assert.False(t, hub.BroadcastTyping("race-1", alice, "Alice"))
+
+	frame, ok := receiveFrame(viewer, 0)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"type":"typing","v":2,"data":{"user_id":"user-alice","username":"Alice"}}`, string(frame))
+	_, ok = receiveFrame(viewer, 0)
+	assert.False(t, ok, "Throttled indicators are not sent")
+}
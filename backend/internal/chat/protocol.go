@@ -0,0 +1,258 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Frame payload types are encoded with generated msgp code. Run go generate
+// after changing any of them.
+//go:generate msgp -file message.go -o message_gen.go -io=false -tests=false -unexported
+//go:generate msgp -file moderation.go -o moderation_gen.go -io=false -tests=false
+//go:generate msgp -file mentions.go -o mentions_gen.go -io=false -tests=false
+//go:generate msgp -file rooms.go -o rooms_gen.go -io=false -tests=false
+//go:generate msgp -file reactions.go -o reactions_gen.go -io=false -tests=false
+//go:generate msgp -file presence.go -o presence_gen.go -io=false -tests=false
+//go:generate msgp -file pins.go -o pins_gen.go -io=false -tests=false
+//go:generate msgp -file stream_status.go -o stream_status_gen.go -io=false -tests=false
+
+// ProtocolVersion is the version stamped on every frame the server sends as
+// "v". Clients that don't send a version are treated as version 1, whose
+// frames are the same JSON without the field.
+const ProtocolVersion = 2
+
+// WebSocket subprotocols a client may request with Sec-WebSocket-Protocol.
+// Clients that request none get JSON.
+const (
+	SubprotocolJSON    = "cs.v2+json"
+	SubprotocolMsgpack = "cs.v2+msgpack"
+)
+
+// Subprotocols lists the subprotocols the chat endpoint accepts, preferred first
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// Protocol is the encoding a client negotiated for its frames
+type Protocol int
+
+const (
+	// ProtocolJSON sends JSON text frames
+	ProtocolJSON Protocol = iota
+	// ProtocolMsgpack sends each frame as a MessagePack binary message with
+	// the same field names as JSON. Times use the MessagePack timestamp extension.
+	ProtocolMsgpack
+
+	protocolCount
+)
+
+// ProtocolForSubprotocol returns the protocol for a negotiated subprotocol
+func ProtocolForSubprotocol(subprotocol string) Protocol {
+	if subprotocol == SubprotocolMsgpack {
+		return ProtocolMsgpack
+	}
+	return ProtocolJSON
+}
+
+func (p Protocol) String() string {
+	if p == ProtocolMsgpack {
+		return SubprotocolMsgpack
+	}
+	return SubprotocolJSON
+}
+
+// wsMessageType returns the WebSocket message type frames are written with
+func (p Protocol) wsMessageType() int {
+	if p == ProtocolMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// Encode serializes a message in the protocol, stamped with ProtocolVersion
+func (p Protocol) Encode(msg *WSMessage) ([]byte, error) {
+	out := *msg
+	out.V = ProtocolVersion
+
+	if p == ProtocolMsgpack {
+		return out.appendMsgpack(nil)
+	}
+	return json.Marshal(&out)
+}
+
+// Decode parses a frame sent by a client. Data is kept in its encoded form
+// (json.RawMessage or msgp.Raw) until ParseData decodes it.
+func (p Protocol) Decode(data []byte) (*WSMessage, error) {
+	if p == ProtocolMsgpack {
+		return decodeMsgpackWSMessage(data)
+	}
+
+	var envelope struct {
+		Type string          `json:"type"`
+		V    int             `json:"v"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	msg := &WSMessage{Type: envelope.Type, V: envelope.V}
+	if len(envelope.Data) > 0 {
+		msg.Data = envelope.Data
+	}
+	return msg, nil
+}
+
+// appendMsgpack writes the message as a map with the same keys as its JSON form
+func (m *WSMessage) appendMsgpack(b []byte) ([]byte, error) {
+	fields := uint32(1)
+	if m.V != 0 {
+		fields++
+	}
+	if m.Data != nil {
+		fields++
+	}
+
+	b = msgp.AppendMapHeader(b, fields)
+	b = msgp.AppendString(b, "type")
+	b = msgp.AppendString(b, m.Type)
+	if m.V != 0 {
+		b = msgp.AppendString(b, "v")
+		b = msgp.AppendInt(b, m.V)
+	}
+	if m.Data == nil {
+		return b, nil
+	}
+
+	b = msgp.AppendString(b, "data")
+	return appendMsgpackData(b, m.Data)
+}
+
+// appendMsgpackData encodes a frame payload with its generated msgp code.
+// Payloads without it, such as polls, go through their JSON form.
+func appendMsgpackData(b []byte, data interface{}) ([]byte, error) {
+	if marshaler, ok := msgpMarshaler(data); ok {
+		return marshaler.MarshalMsg(b)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return msgp.AppendIntf(b, generic)
+}
+
+// msgpMarshaler returns the generated encoder for a payload. Payloads are
+// usually stored by value while the generated methods have pointer receivers.
+func msgpMarshaler(data interface{}) (msgp.Marshaler, bool) {
+	if marshaler, ok := data.(msgp.Marshaler); ok {
+		return marshaler, true
+	}
+
+	value := reflect.ValueOf(data)
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	marshaler, ok := ptr.Interface().(msgp.Marshaler)
+	return marshaler, ok
+}
+
+func decodeMsgpackWSMessage(b []byte) (*WSMessage, error) {
+	fields, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &WSMessage{}
+	for ; fields > 0; fields-- {
+		var key []byte
+		key, b, err = msgp.ReadMapKeyZC(b)
+		if err != nil {
+			return nil, err
+		}
+
+		switch string(key) {
+		case "type":
+			msg.Type, b, err = msgp.ReadStringBytes(b)
+		case "v":
+			msg.V, b, err = msgp.ReadIntBytes(b)
+		case "data":
+			var raw msgp.Raw
+			b, err = raw.UnmarshalMsg(b)
+			if err == nil && !msgp.IsNil(raw) {
+				msg.Data = raw
+			}
+		default:
+			b, err = msgp.Skip(b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q field: %w", key, err)
+		}
+	}
+
+	if msg.Type == "" {
+		return nil, errors.New("missing message type")
+	}
+	return msg, nil
+}
+
+// frameDataTypes maps server frame types to their payload structs. Frames
+// that arrive already encoded as JSON (from other instances) are decoded into
+// them so MessagePack clients get the same encoding as for local frames.
+var frameDataTypes = map[MessageType]func() interface{}{
+	MessageTypeMessage:           func() interface{} { return &ChatMessageData{} },
+	MessageTypeHistory:           func() interface{} { return &HistoryData{} },
+	MessageTypeError:             func() interface{} { return &ErrorData{} },
+	MessageTypeJoined:            func() interface{} { return &UserActionData{} },
+	MessageTypeLeft:              func() interface{} { return &UserActionData{} },
+	MessageTypeMention:           func() interface{} { return &MentionData{} },
+	MessageTypeReactionSummary:   func() interface{} { return &ReactionSummaryData{} },
+	MessageTypePresenceSnapshot:  func() interface{} { return &PresenceSnapshotData{} },
+	MessageTypeTyping:            func() interface{} { return &TypingData{} },
+	MessageTypeWhisper:           func() interface{} { return &PrivateMessageData{} },
+	MessageTypePartyMessage:      func() interface{} { return &PrivateMessageData{} },
+	MessageTypePartyLeft:         func() interface{} { return &PartyLeftData{} },
+	MessageTypeMessageDeleted:    func() interface{} { return &MessageDeletedData{} },
+	MessageTypeUserRestricted:    func() interface{} { return &UserRestrictedData{} },
+	MessageTypeRestrictionLifted: func() interface{} { return &RestrictionLiftedData{} },
+	MessageTypeSlowModeUpdated:   func() interface{} { return &SlowModeData{} },
+	MessageTypeAnnouncement:      func() interface{} { return &ChatMessageData{} },
+	MessageTypePinUpdated:        func() interface{} { return &PinUpdatedData{} },
+	MessageTypeStreamStatus:      func() interface{} { return &StreamStatusData{} },
+}
+
+// decodeServerFrame turns a JSON frame back into a message with a typed payload
+func decodeServerFrame(data []byte) (*WSMessage, error) {
+	msg, err := ProtocolJSON.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := msg.Data.(json.RawMessage)
+	if !ok {
+		return msg, nil
+	}
+
+	newData, known := frameDataTypes[MessageType(msg.Type)]
+	if !known {
+		var generic interface{}
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return nil, err
+		}
+		msg.Data = generic
+		return msg, nil
+	}
+
+	typed := newData()
+	if err := json.Unmarshal(raw, typed); err != nil {
+		return nil, err
+	}
+	msg.Data = typed
+	return msg, nil
+}
@@ -0,0 +1,199 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func testChatMessage() *models.ChatMessage {
+	userID := "user-1"
+	imageURL := "https://cdn.example.com/allez.png"
+	return &models.ChatMessage{
+		ID:        "msg-1",
+		RaceID:    "race-1",
+		UserID:    &userID,
+		Username:  "Alice",
+		Message:   "allez :allez:",
+		CreatedAt: time.Date(2026, 7, 14, 15, 42, 7, 123456789, time.UTC),
+		Badges:    []string{"subscriber"},
+		Emotes:    []models.ChatEmoteSpan{{Code: "allez", Start: 6, End: 13, ImageURL: &imageURL}},
+		ReplyTo:   &models.ChatMessageReference{ID: "msg-0", Username: "Bob", Message: "go"},
+	}
+}
+
+func msgpackAsJSON(t *testing.T, b []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := msgp.UnmarshalAsJSON(&buf, b)
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestProtocolForSubprotocol(t *testing.T) {
+	assert.Equal(t, ProtocolMsgpack, ProtocolForSubprotocol(SubprotocolMsgpack))
+	assert.Equal(t, ProtocolJSON, ProtocolForSubprotocol(SubprotocolJSON))
+	assert.Equal(t, ProtocolJSON, ProtocolForSubprotocol(""), "Clients that negotiate nothing get JSON")
+}
+
+func TestProtocolJSON(t *testing.T) {
+	t.Run("Frames carry the protocol version", func(t *testing.T) {
+		encoded, err := ProtocolJSON.Encode(NewJoinedWSMessage("Alice"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"joined","v":2,"data":{"username":"Alice"}}`, string(encoded))
+	})
+
+	t.Run("Client frames without a version are accepted", func(t *testing.T) {
+		msg, err := ProtocolJSON.Decode([]byte(`{"type":"send_message","data":{"message":"Go!","room":"party:abc"}}`))
+		require.NoError(t, err)
+		assert.Zero(t, msg.V)
+
+		data, err := ParseSendMessageData(msg)
+		require.NoError(t, err)
+		assert.Equal(t, "Go!", data.Message)
+		assert.Equal(t, "party:abc", data.Room)
+	})
+
+	t.Run("Invalid JSON is rejected", func(t *testing.T) {
+		_, err := ProtocolJSON.Decode([]byte("not-json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestProtocolMsgpack(t *testing.T) {
+	t.Run("Typed payloads round trip", func(t *testing.T) {
+		sent := NewMessageWSMessage(testChatMessage())
+		encoded, err := ProtocolMsgpack.Encode(sent)
+		require.NoError(t, err)
+
+		msg, err := ProtocolMsgpack.Decode(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, string(MessageTypeMessage), msg.Type)
+		assert.Equal(t, ProtocolVersion, msg.V)
+
+		var data ChatMessageData
+		require.NoError(t, ParseData(msg, &data))
+		expected := sent.Data.(ChatMessageData)
+		assert.True(t, expected.CreatedAt.Equal(data.CreatedAt))
+		data.CreatedAt = expected.CreatedAt
+		assert.Equal(t, expected, data)
+	})
+
+	t.Run("Keys match the JSON field names", func(t *testing.T) {
+		encoded, err := ProtocolMsgpack.Encode(NewJoinedWSMessage("Alice"))
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"type":"joined","v":2,"data":{"username":"Alice"}}`, msgpackAsJSON(t, encoded))
+	})
+
+	t.Run("Payloads without generated code fall back to their JSON form", func(t *testing.T) {
+		poll := &models.ChatPoll{ID: "poll-1", RaceID: "race-1", Question: "Who wins?"}
+		encoded, err := ProtocolMsgpack.Encode(NewPollAnnouncementMessage(poll))
+		require.NoError(t, err)
+
+		msg, err := ProtocolMsgpack.Decode(encoded)
+		require.NoError(t, err)
+		var decoded models.ChatPoll
+		require.NoError(t, ParseData(msg, &decoded))
+		assert.Equal(t, "poll-1", decoded.ID)
+		assert.Equal(t, "Who wins?", decoded.Question)
+	})
+
+	t.Run("Client frames decode straight into their payload", func(t *testing.T) {
+		b := msgp.AppendMapHeader(nil, 2)
+		b = msgp.AppendString(b, "type")
+		b = msgp.AppendString(b, string(MessageTypeReaction))
+		b = msgp.AppendString(b, "data")
+		b, err := ReactionData{Emote: "allez"}.MarshalMsg(b)
+		require.NoError(t, err)
+
+		msg, err := ProtocolMsgpack.Decode(b)
+		require.NoError(t, err)
+		var data ReactionData
+		require.NoError(t, ParseData(msg, &data))
+		assert.Equal(t, "allez", data.Emote)
+	})
+
+	t.Run("Frames without a type are rejected", func(t *testing.T) {
+		b := msgp.AppendMapHeader(nil, 1)
+		b = msgp.AppendString(b, "v")
+		b = msgp.AppendInt(b, 2)
+		_, err := ProtocolMsgpack.Decode(b)
+		assert.Error(t, err)
+
+		_, err = ProtocolMsgpack.Decode([]byte(`{"type":"ping"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestFrame(t *testing.T) {
+	msg := NewMessageWSMessage(testChatMessage())
+
+	t.Run("Each protocol is encoded once", func(t *testing.T) {
+		frame := NewFrame(msg)
+		first, err := frame.Encode(ProtocolMsgpack)
+		require.NoError(t, err)
+		second, err := frame.Encode(ProtocolMsgpack)
+		require.NoError(t, err)
+		assert.Same(t, &first[0], &second[0])
+	})
+
+	t.Run("Frames from other instances encode like local ones", func(t *testing.T) {
+		local := NewFrame(msg)
+		localJSON, err := local.JSON()
+		require.NoError(t, err)
+
+		remote := NewJSONFrame(localJSON)
+		remoteJSON, err := remote.JSON()
+		require.NoError(t, err)
+		assert.Equal(t, localJSON, remoteJSON, "JSON is passed through")
+
+		localMsgpack, err := local.Encode(ProtocolMsgpack)
+		require.NoError(t, err)
+		remoteMsgpack, err := remote.Encode(ProtocolMsgpack)
+		require.NoError(t, err)
+		assert.Equal(t, localMsgpack, remoteMsgpack)
+	})
+
+	t.Run("Unknown frame types are transcoded generically", func(t *testing.T) {
+		remote := NewJSONFrame([]byte(`{"type":"custom","v":2,"data":{"n":1}}`))
+		encoded, err := remote.Encode(ProtocolMsgpack)
+		require.NoError(t, err)
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(msgpackAsJSON(t, encoded)), &decoded))
+		assert.Equal(t, "custom", decoded["type"])
+		assert.Equal(t, map[string]interface{}{"n": float64(1)}, decoded["data"])
+	})
+}
+
+func TestHub_DeliversInEachClientsProtocol(t *testing.T) {
+	hub := NewHub()
+	jsonClient := createTestClient(hub, nil, "JSON")
+	msgpackClient := createTestClient(hub, nil, "Msgpack")
+	msgpackClient.protocol = ProtocolMsgpack
+
+	hub.JoinRoom(jsonClient, "race-1")
+	hub.JoinRoom(msgpackClient, "race-1")
+
+	msg := NewJoinedWSMessage("Alice")
+	hub.BroadcastToRoom("race-1", msg)
+
+	expectedJSON, err := ProtocolJSON.Encode(msg)
+	require.NoError(t, err)
+	expectedMsgpack, err := ProtocolMsgpack.Encode(msg)
+	require.NoError(t, err)
+
+	received, ok := receiveFrame(jsonClient, 0)
+	require.True(t, ok)
+	assert.Equal(t, expectedJSON, received)
+	received, ok = receiveFrame(msgpackClient, 0)
+	require.True(t, ok)
+	assert.Equal(t, expectedMsgpack, received)
+}
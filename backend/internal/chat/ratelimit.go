@@ -1,32 +1,133 @@
 package chat
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
 
+// Rate limit tiers. Each sender is limited by the bucket of their role.
 const (
-	// ChatRateLimitMaxMessages is the maximum number of messages allowed per window
-	ChatRateLimitMaxMessages = 100 // Increased from 10 to 100 for testing
-	// ChatRateLimitWindow is the time window for rate limiting
-	ChatRateLimitWindow = 1 * time.Minute
+	RateTierAnonymous  = "anonymous"
+	RateTierUser       = "user"
+	RateTierSubscriber = "subscriber"
+	RateTierAdmin      = "admin"
 )
 
-// RateLimiter tracks message rates per user/IP
+// Reasons a message can be refused by the rate limiter
+const (
+	RateLimitReasonBucket    = "rate_limited"
+	RateLimitReasonSlowMode  = "slow_mode"
+	RateLimitReasonDuplicate = "duplicate"
+)
+
+const (
+	// How often idle buckets and stale history are dropped
+	rateLimitCleanupInterval = 5 * time.Minute
+	// Slow mode history older than this can't block a message any more
+	slowModeHistoryTTL = time.Hour
+)
+
+// RateLimitTier is a token bucket: Burst messages can be sent back to back,
+// and one token is refilled every Refill
+type RateLimitTier struct {
+	Burst  int
+	Refill time.Duration
+}
+
+// RateLimitConfig configures the chat rate limiter
+type RateLimitConfig struct {
+	Tiers map[string]RateLimitTier
+	// DuplicateWindow rejects a repeat of the sender's previous message
+	// within this window (0 disables it)
+	DuplicateWindow time.Duration
+}
+
+// DefaultRateLimitConfig returns the production defaults
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Tiers: map[string]RateLimitTier{
+			RateTierAnonymous:  {Burst: 2, Refill: 10 * time.Second},
+			RateTierUser:       {Burst: 5, Refill: 2 * time.Second},
+			RateTierSubscriber: {Burst: 8, Refill: time.Second},
+			RateTierAdmin:      {Burst: 30, Refill: 200 * time.Millisecond},
+		},
+		DuplicateWindow: 30 * time.Second,
+	}
+}
+
+// RateLimitRequest describes a message about to be sent
+type RateLimitRequest struct {
+	// Identifier is the sender's user ID (or IP for anonymous senders)
+	Identifier string
+	// Tier selects the sender's bucket; unknown tiers use RateTierUser
+	Tier string
+	// Room and SlowMode override the bucket for rooms in slow mode: one
+	// message per SlowMode interval per sender
+	Room     string
+	SlowMode time.Duration
+	// Message is compared with the sender's previous message. Leave it empty
+	// to skip duplicate suppression.
+	Message string
+}
+
+// RateLimitResult is the limiter's decision. Remaining is the number of
+// tokens left in the sender's bucket; RetryAfter is set when the message is
+// refused.
+type RateLimitResult struct {
+	Allowed    bool
+	Reason     string
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+	tier       RateLimitTier
+}
+
+type lastMessage struct {
+	text   string
+	sentAt time.Time
+}
+
+// RateLimiter limits chat messages per sender with token buckets, enforces
+// room slow mode and suppresses repeated messages
 type RateLimiter struct {
-	// Map of identifier (user_id or IP) to timestamps of messages
-	entries map[string][]time.Time
-	mu      sync.RWMutex
+	config RateLimitConfig
+
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket         // identifier -> bucket
+	lastSent map[string]map[string]time.Time // room -> identifier -> last message in slow mode
+	previous map[string]lastMessage          // identifier -> previous message
+
 	// Cleanup ticker
 	cleanupTicker *time.Ticker
 	stopCleanup   chan bool
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter() *RateLimiter {
+// NewRateLimiter creates a new rate limiter. Tiers missing from the config
+// fall back to the defaults.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	defaults := DefaultRateLimitConfig()
+	tiers := make(map[string]RateLimitTier, len(defaults.Tiers))
+	for name, tier := range defaults.Tiers {
+		tiers[name] = tier
+	}
+	for name, tier := range config.Tiers {
+		if tier.Burst > 0 && tier.Refill > 0 {
+			tiers[name] = tier
+		}
+	}
+	config.Tiers = tiers
+
 	rl := &RateLimiter{
-		entries:       make(map[string][]time.Time),
-		cleanupTicker: time.NewTicker(5 * time.Minute),
+		config:        config,
+		buckets:       make(map[string]*tokenBucket),
+		lastSent:      make(map[string]map[string]time.Time),
+		previous:      make(map[string]lastMessage),
+		cleanupTicker: time.NewTicker(rateLimitCleanupInterval),
 		stopCleanup:   make(chan bool),
 	}
 
@@ -36,25 +137,32 @@ func NewRateLimiter() *RateLimiter {
 	return rl
 }
 
-// cleanup removes old entries periodically
+// cleanup removes full buckets and stale history periodically
 func (rl *RateLimiter) cleanup() {
 	for {
 		select {
 		case <-rl.cleanupTicker.C:
 			rl.mu.Lock()
 			now := time.Now()
-			for key, timestamps := range rl.entries {
-				// Remove timestamps older than the window
-				var valid []time.Time
-				for _, ts := range timestamps {
-					if now.Sub(ts) < ChatRateLimitWindow {
-						valid = append(valid, ts)
+			for key, bucket := range rl.buckets {
+				bucket.refill(now)
+				if bucket.tokens >= float64(bucket.tier.Burst) {
+					delete(rl.buckets, key)
+				}
+			}
+			for room, users := range rl.lastSent {
+				for key, sentAt := range users {
+					if now.Sub(sentAt) >= slowModeHistoryTTL {
+						delete(users, key)
 					}
 				}
-				if len(valid) == 0 {
-					delete(rl.entries, key)
-				} else {
-					rl.entries[key] = valid
+				if len(users) == 0 {
+					delete(rl.lastSent, room)
+				}
+			}
+			for key, prev := range rl.previous {
+				if now.Sub(prev.sentAt) >= rl.config.DuplicateWindow {
+					delete(rl.previous, key)
 				}
 			}
 			rl.mu.Unlock()
@@ -70,64 +178,115 @@ func (rl *RateLimiter) Stop() {
 	close(rl.stopCleanup)
 }
 
-// CheckRateLimit checks if a user/IP can send a message
-// Returns true if allowed, false if rate limited
-func (rl *RateLimiter) CheckRateLimit(identifier string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	timestamps, exists := rl.entries[identifier]
+func (rl *RateLimiter) tier(name string) RateLimitTier {
+	if tier, ok := rl.config.Tiers[name]; ok {
+		return tier
+	}
+	return rl.config.Tiers[RateTierUser]
+}
 
-	if !exists {
-		// First message from this identifier
-		rl.entries[identifier] = []time.Time{now}
-		return true
+// bucket returns the sender's bucket, resizing it if their tier changed
+func (rl *RateLimiter) bucket(identifier string, tier RateLimitTier, now time.Time) *tokenBucket {
+	b, ok := rl.buckets[identifier]
+	if !ok {
+		b = &tokenBucket{tokens: float64(tier.Burst), lastRefill: now, tier: tier}
+		rl.buckets[identifier] = b
+		return b
 	}
 
-	// Remove timestamps outside the window
-	var valid []time.Time
-	for _, ts := range timestamps {
-		if now.Sub(ts) < ChatRateLimitWindow {
-			valid = append(valid, ts)
+	b.refill(now)
+	if b.tier != tier {
+		b.tier = tier
+		if b.tokens > float64(tier.Burst) {
+			b.tokens = float64(tier.Burst)
 		}
 	}
+	return b
+}
 
-	// Check if we're at the limit
-	if len(valid) >= ChatRateLimitMaxMessages {
-		return false
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastRefill)
+	if elapsed <= 0 {
+		return
 	}
+	b.tokens += float64(elapsed) / float64(b.tier.Refill)
+	if b.tokens > float64(b.tier.Burst) {
+		b.tokens = float64(b.tier.Burst)
+	}
+	b.lastRefill = now
+}
 
-	// Add current timestamp
-	valid = append(valid, now)
-	rl.entries[identifier] = valid
-	return true
+// wait returns how long until the bucket holds a whole token
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.tier.Refill))
 }
 
-// GetRemainingMessages returns the number of messages remaining in the current window
-func (rl *RateLimiter) GetRemainingMessages(identifier string) int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+// normalizeForDuplicate makes trivially different repeats compare equal
+func normalizeForDuplicate(message string) string {
+	return strings.ToLower(strings.Join(strings.Fields(message), " "))
+}
+
+// Check decides whether a message may be sent and, if so, records it.
+// Refused messages don't use up tokens.
+func (rl *RateLimiter) Check(req RateLimitRequest) RateLimitResult {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
 	now := time.Now()
-	timestamps, exists := rl.entries[identifier]
+	b := rl.bucket(req.Identifier, rl.tier(req.Tier), now)
+	remaining := int(b.tokens)
 
-	if !exists {
-		return ChatRateLimitMaxMessages
+	if req.Room != "" {
+		if req.SlowMode <= 0 {
+			delete(rl.lastSent[req.Room], req.Identifier)
+		} else if last, ok := rl.lastSent[req.Room][req.Identifier]; ok {
+			if wait := req.SlowMode - now.Sub(last); wait > 0 {
+				return RateLimitResult{Reason: RateLimitReasonSlowMode, Remaining: remaining, RetryAfter: wait}
+			}
+		}
 	}
 
-	// Count valid timestamps
-	count := 0
-	for _, ts := range timestamps {
-		if now.Sub(ts) < ChatRateLimitWindow {
-			count++
+	normalized := ""
+	if req.Message != "" && rl.config.DuplicateWindow > 0 {
+		normalized = normalizeForDuplicate(req.Message)
+		if prev, ok := rl.previous[req.Identifier]; ok && prev.text == normalized {
+			if wait := rl.config.DuplicateWindow - now.Sub(prev.sentAt); wait > 0 {
+				return RateLimitResult{Reason: RateLimitReasonDuplicate, Remaining: remaining, RetryAfter: wait}
+			}
 		}
 	}
 
-	remaining := ChatRateLimitMaxMessages - count
-	if remaining < 0 {
-		return 0
+	if b.tokens < 1 {
+		return RateLimitResult{Reason: RateLimitReasonBucket, Remaining: 0, RetryAfter: b.wait()}
+	}
+
+	b.tokens--
+	if req.Room != "" && req.SlowMode > 0 {
+		if rl.lastSent[req.Room] == nil {
+			rl.lastSent[req.Room] = make(map[string]time.Time)
+		}
+		rl.lastSent[req.Room][req.Identifier] = now
 	}
-	return remaining
+	if normalized != "" {
+		rl.previous[req.Identifier] = lastMessage{text: normalized, sentAt: now}
+	}
+
+	return RateLimitResult{Allowed: true, Remaining: int(b.tokens)}
 }
 
+// Remaining returns the number of whole tokens left in a sender's bucket
+func (rl *RateLimiter) Remaining(identifier, tier string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	t := rl.tier(tier)
+	b, ok := rl.buckets[identifier]
+	if !ok {
+		return t.Burst
+	}
+	b.refill(time.Now())
+	return int(b.tokens)
+}
@@ -0,0 +1,234 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+)
+
+//msgp:tag json
+//msgp:ignore ReactionStore ReactionAggregator
+
+const (
+	// ReactionSummaryInterval is how often each room's reaction counts are broadcast
+	ReactionSummaryInterval = 1 * time.Second
+	// MaxReactionsPerSecond caps the reactions counted from one connection per
+	// summary interval; the rest are dropped
+	MaxReactionsPerSecond = 5
+)
+
+// ReactionStore persists the per-minute hype timeline. Counts are added to
+// whatever is already stored for the minute, so several instances can write
+// the same minute. It is implemented by repository.ChatReactionRepository.
+type ReactionStore interface {
+	AddHype(raceID string, minute time.Time, counts map[string]int) error
+}
+
+// ReactionData is sent by a client to react with an emote
+type ReactionData struct {
+	Emote string `json:"emote"`
+}
+
+// ReactionSummaryData carries the reactions a room received during the last
+// interval, counted per emote
+type ReactionSummaryData struct {
+	RaceID        string         `json:"race_id"`
+	Counts        map[string]int `json:"counts"`
+	Total         int            `json:"total"`
+	WindowSeconds int            `json:"window_seconds"`
+}
+
+// NewReactionSummaryWSMessage creates a reaction summary frame
+func NewReactionSummaryWSMessage(raceID string, counts map[string]int) *WSMessage {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return &WSMessage{
+		Type: string(MessageTypeReactionSummary),
+		Data: ReactionSummaryData{
+			RaceID:        raceID,
+			Counts:        counts,
+			Total:         total,
+			WindowSeconds: int(ReactionSummaryInterval / time.Second),
+		},
+	}
+}
+
+type hypeMinute struct {
+	minute time.Time
+	counts map[string]int
+}
+
+// ReactionAggregator counts reactions in memory. Every interval it shares
+// each room's counts with the other instances and hands the room's counts
+// from every instance to a callback for delivery, and it writes per-minute
+// totals of its own reactions to a ReactionStore once each minute is over.
+// Reactions are never stored one by one.
+type ReactionAggregator struct {
+	mu        sync.Mutex
+	store     ReactionStore
+	pending   map[string]map[string]int // raceID -> emote -> count this interval
+	remote    map[string]map[string]int // counts shared by other instances this interval
+	perClient map[*Client]int           // reactions counted this interval
+	hype      map[string]*hypeMinute    // raceID -> current minute
+	finished  []finishedHypeMinute      // minutes waiting to be stored
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type finishedHypeMinute struct {
+	raceID string
+	hypeMinute
+}
+
+// NewReactionAggregator creates a reaction aggregator. store may be nil, in
+// which case the hype timeline isn't kept.
+func NewReactionAggregator(store ReactionStore) *ReactionAggregator {
+	return &ReactionAggregator{
+		store:     store,
+		pending:   make(map[string]map[string]int),
+		remote:    make(map[string]map[string]int),
+		perClient: make(map[*Client]int),
+		hype:      make(map[string]*hypeMinute),
+	}
+}
+
+// Add counts a reaction from a client in a race. It returns false when the
+// client has already used up this interval's reactions.
+func (a *ReactionAggregator) Add(client *Client, raceID, emote string) bool {
+	return a.add(client, raceID, emote, time.Now())
+}
+
+func (a *ReactionAggregator) add(client *Client, raceID, emote string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.perClient[client] >= MaxReactionsPerSecond {
+		return false
+	}
+	a.perClient[client]++
+
+	if a.pending[raceID] == nil {
+		a.pending[raceID] = make(map[string]int)
+	}
+	a.pending[raceID][emote]++
+
+	minute := now.Truncate(time.Minute)
+	current := a.hype[raceID]
+	if current == nil || !current.minute.Equal(minute) {
+		if current != nil {
+			a.finished = append(a.finished, finishedHypeMinute{raceID: raceID, hypeMinute: *current})
+		}
+		current = &hypeMinute{minute: minute, counts: make(map[string]int)}
+		a.hype[raceID] = current
+	}
+	current.counts[emote]++
+
+	return true
+}
+
+// AddRemote counts reactions another instance shared, for the next summary.
+// They are stored in the hype timeline by the instance they were made on.
+func (a *ReactionAggregator) AddRemote(raceID string, counts map[string]int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.remote[raceID] == nil {
+		a.remote[raceID] = make(map[string]int)
+	}
+	for emote, n := range counts {
+		a.remote[raceID][emote] += n
+	}
+}
+
+// flush returns each room's counts made on this instance since the last
+// flush, each room's summary of those and the counts other instances shared,
+// and the minutes that are over, and resets the interval
+func (a *ReactionAggregator) flush(now time.Time, all bool) (local, summaries map[string]map[string]int, finished []finishedHypeMinute) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	local = a.pending
+	summaries = a.remote
+	a.pending = make(map[string]map[string]int)
+	a.remote = make(map[string]map[string]int)
+	a.perClient = make(map[*Client]int)
+
+	for raceID, counts := range local {
+		if summaries[raceID] == nil {
+			summaries[raceID] = make(map[string]int, len(counts))
+		}
+		for emote, n := range counts {
+			summaries[raceID][emote] += n
+		}
+	}
+
+	minute := now.Truncate(time.Minute)
+	for raceID, current := range a.hype {
+		if all || current.minute.Before(minute) {
+			a.finished = append(a.finished, finishedHypeMinute{raceID: raceID, hypeMinute: *current})
+			delete(a.hype, raceID)
+		}
+	}
+	finished = a.finished
+	a.finished = nil
+
+	return local, summaries, finished
+}
+
+func (a *ReactionAggregator) storeHype(minutes []finishedHypeMinute) {
+	if a.store == nil {
+		return
+	}
+	for _, m := range minutes {
+		if err := a.store.AddHype(m.raceID, m.minute, m.counts); err != nil {
+			logger.WithError(err).WithField("race_id", m.raceID).Warn("Failed to store chat hype minute")
+		}
+	}
+}
+
+// Start shares each room's reactions with the other instances every interval
+// through share, hands each room's summary to onSummary for delivery to this
+// instance's clients, and stores finished hype minutes. Other instances'
+// counts come in through AddRemote.
+func (a *ReactionAggregator) Start(share, onSummary func(raceID string, counts map[string]int)) {
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(ReactionSummaryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				local, summaries, finished := a.flush(now, false)
+				for raceID, counts := range local {
+					share(raceID, counts)
+				}
+				for raceID, counts := range summaries {
+					onSummary(raceID, counts)
+				}
+				a.storeHype(finished)
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops broadcasting and stores the minutes still in progress
+func (a *ReactionAggregator) Stop() {
+	if a.stop != nil {
+		close(a.stop)
+		<-a.done
+		a.stop = nil
+	}
+
+	_, _, finished := a.flush(time.Now(), true)
+	a.storeHype(finished)
+}
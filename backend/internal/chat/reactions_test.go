@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type storedHype struct {
+	raceID string
+	minute time.Time
+	counts map[string]int
+}
+
+type fakeReactionStore struct {
+	mu     sync.Mutex
+	stored []storedHype
+}
+
+func (s *fakeReactionStore) AddHype(raceID string, minute time.Time, counts map[string]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored = append(s.stored, storedHype{raceID: raceID, minute: minute, counts: counts})
+	return nil
+}
+
+func TestReactionAggregator_Summaries(t *testing.T) {
+	agg := NewReactionAggregator(nil)
+	client1, client2 := &Client{}, &Client{}
+	now := time.Date(2026, 7, 14, 15, 30, 10, 0, time.UTC)
+
+	agg.add(client1, "race-1", "fire", now)
+	agg.add(client1, "race-1", "fire", now)
+	agg.add(client2, "race-1", "clap", now)
+	agg.add(client2, "race-2", "fire", now)
+
+	local, summaries, _ := agg.flush(now.Add(time.Second), false)
+	assert.Equal(t, map[string]int{"fire": 2, "clap": 1}, summaries["race-1"])
+	assert.Equal(t, map[string]int{"fire": 1}, summaries["race-2"])
+	assert.Equal(t, summaries, local)
+
+	local, summaries, _ = agg.flush(now.Add(2*time.Second), false)
+	assert.Empty(t, summaries, "Counts reset after each interval")
+	assert.Empty(t, local)
+}
+
+func TestReactionAggregator_RemoteCounts(t *testing.T) {
+	store := &fakeReactionStore{}
+	agg := NewReactionAggregator(store)
+	now := time.Date(2026, 7, 14, 15, 30, 10, 0, time.UTC)
+
+	agg.add(&Client{}, "race-1", "fire", now)
+	agg.AddRemote("race-1", map[string]int{"fire": 3, "clap": 1})
+	agg.AddRemote("race-1", map[string]int{"clap": 2})
+	agg.AddRemote("race-2", map[string]int{"fire": 4})
+
+	local, summaries, _ := agg.flush(now.Add(time.Second), false)
+	assert.Equal(t, map[string]map[string]int{"race-1": {"fire": 1}}, local, "Only local counts are shared")
+	assert.Equal(t, map[string]int{"fire": 4, "clap": 3}, summaries["race-1"], "One summary of every instance's counts")
+	assert.Equal(t, map[string]int{"fire": 4}, summaries["race-2"])
+
+	_, summaries, _ = agg.flush(now.Add(2*time.Second), false)
+	assert.Empty(t, summaries)
+
+	agg.Stop()
+	require.Len(t, store.stored, 1)
+	assert.Equal(t, map[string]int{"fire": 1}, store.stored[0].counts, "Remote counts are stored by their own instance")
+}
+
+func TestReactionAggregator_PerClientCap(t *testing.T) {
+	agg := NewReactionAggregator(nil)
+	client := &Client{}
+	now := time.Now()
+
+	for i := 0; i < MaxReactionsPerSecond; i++ {
+		require.True(t, agg.add(client, "race-1", "fire", now))
+	}
+	assert.False(t, agg.add(client, "race-1", "fire", now))
+	assert.True(t, agg.add(&Client{}, "race-1", "fire", now), "Other clients are unaffected")
+
+	agg.flush(now.Add(time.Second), false)
+	assert.True(t, agg.add(client, "race-1", "fire", now.Add(time.Second)), "The cap resets each interval")
+}
+
+func TestReactionAggregator_HypeMinutes(t *testing.T) {
+	store := &fakeReactionStore{}
+	agg := NewReactionAggregator(store)
+	client := &Client{}
+	minute := time.Date(2026, 7, 14, 15, 30, 0, 0, time.UTC)
+
+	agg.add(client, "race-1", "fire", minute.Add(10*time.Second))
+	agg.add(client, "race-1", "clap", minute.Add(20*time.Second))
+
+	_, _, finished := agg.flush(minute.Add(30*time.Second), false)
+	assert.Empty(t, finished, "The current minute is still open")
+
+	_, _, finished = agg.flush(minute.Add(61*time.Second), false)
+	require.Len(t, finished, 1)
+	assert.Equal(t, "race-1", finished[0].raceID)
+	assert.Equal(t, minute, finished[0].minute)
+	assert.Equal(t, map[string]int{"fire": 1, "clap": 1}, finished[0].counts)
+
+	t.Run("A new minute closes the previous one", func(t *testing.T) {
+		agg.add(client, "race-1", "fire", minute.Add(2*time.Minute))
+		agg.add(client, "race-1", "fire", minute.Add(3*time.Minute))
+
+		_, _, finished := agg.flush(minute.Add(3*time.Minute+time.Second), false)
+		require.Len(t, finished, 1)
+		assert.Equal(t, minute.Add(2*time.Minute), finished[0].minute)
+	})
+
+	t.Run("Stop stores minutes in progress", func(t *testing.T) {
+		agg.Stop()
+		require.Len(t, store.stored, 1)
+		assert.Equal(t, minute.Add(3*time.Minute), store.stored[0].minute)
+	})
+}
+
+func TestNewReactionSummaryWSMessage(t *testing.T) {
+	msg := NewReactionSummaryWSMessage("race-1", map[string]int{"fire": 3, "clap": 2})
+
+	data, ok := msg.Data.(ReactionSummaryData)
+	require.True(t, ok)
+	assert.Equal(t, string(MessageTypeReactionSummary), msg.Type)
+	assert.Equal(t, 5, data.Total)
+	assert.Equal(t, 1, data.WindowSeconds)
+}
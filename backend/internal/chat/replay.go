@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// ReplayMessage is a chat message annotated with its offset from the start
+// of the stream, so VOD players can show it at the matching video time
+type ReplayMessage struct {
+	*models.ChatMessage
+	OffsetMS int64 `json:"offset_ms"`
+}
+
+// ReplayBucket groups replay messages whose offsets fall in
+// [Offset, Offset+bucket size) seconds from the start of the stream
+type ReplayBucket struct {
+	Offset   int             `json:"offset"`
+	Messages []ReplayMessage `json:"messages"`
+}
+
+// BucketReplayMessages groups chronologically ordered messages into buckets
+// aligned to multiples of size from the stream start. Only non-empty buckets
+// are returned. Messages sent before the stream started land in bucket 0.
+func BucketReplayMessages(messages []*models.ChatMessage, streamStart time.Time, size time.Duration) []ReplayBucket {
+	buckets := []ReplayBucket{}
+	if size <= 0 {
+		return buckets
+	}
+
+	for _, msg := range messages {
+		offset := msg.CreatedAt.Sub(streamStart)
+		if offset < 0 {
+			offset = 0
+		}
+		bucketOffset := int((offset / size) * size / time.Second)
+
+		if n := len(buckets); n == 0 || buckets[n-1].Offset != bucketOffset {
+			buckets = append(buckets, ReplayBucket{Offset: bucketOffset})
+		}
+		last := &buckets[len(buckets)-1]
+		last.Messages = append(last.Messages, ReplayMessage{
+			ChatMessage: msg,
+			OffsetMS:    offset.Milliseconds(),
+		})
+	}
+
+	return buckets
+}
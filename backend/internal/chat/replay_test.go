@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketReplayMessages(t *testing.T) {
+	start := time.Date(2025, 7, 14, 12, 0, 0, 0, time.UTC)
+	at := func(id string, offset time.Duration) *models.ChatMessage {
+		return &models.ChatMessage{ID: id, CreatedAt: start.Add(offset)}
+	}
+
+	t.Run("Groups messages by aligned offset", func(t *testing.T) {
+		messages := []*models.ChatMessage{
+			at("a", 1500*time.Millisecond),
+			at("b", 9*time.Second),
+			at("c", 10*time.Second),
+			at("d", 95*time.Second),
+		}
+
+		buckets := BucketReplayMessages(messages, start, 10*time.Second)
+		require.Len(t, buckets, 3, "Empty buckets are skipped")
+
+		assert.Equal(t, 0, buckets[0].Offset)
+		require.Len(t, buckets[0].Messages, 2)
+		assert.Equal(t, "a", buckets[0].Messages[0].ID)
+		assert.Equal(t, int64(1500), buckets[0].Messages[0].OffsetMS)
+
+		assert.Equal(t, 10, buckets[1].Offset)
+		assert.Equal(t, 90, buckets[2].Offset)
+		assert.Equal(t, int64(95000), buckets[2].Messages[0].OffsetMS)
+	})
+
+	t.Run("Messages before the stream start land in the first bucket", func(t *testing.T) {
+		buckets := BucketReplayMessages([]*models.ChatMessage{at("early", -time.Minute)}, start, 10*time.Second)
+		require.Len(t, buckets, 1)
+		assert.Equal(t, 0, buckets[0].Offset)
+		assert.Zero(t, buckets[0].Messages[0].OffsetMS)
+	})
+
+	t.Run("No messages returns an empty slice", func(t *testing.T) {
+		buckets := BucketReplayMessages(nil, start, 10*time.Second)
+		assert.NotNil(t, buckets)
+		assert.Empty(t, buckets)
+	})
+}
@@ -0,0 +1,211 @@
+package chat
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// RetentionStore reads and deletes old chat messages for the retention job
+type RetentionStore interface {
+	// TryLock keeps other replicas from purging at the same time
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+	ForEachMessageBefore(cutoff time.Time, fn func(*models.ChatTranscriptEntry) error) error
+	DeleteMessagesBefore(cutoff time.Time) (int64, error)
+	CreatePurge(purge *models.ChatRetentionPurge) error
+}
+
+// RetentionConfig controls how long chat messages are kept
+type RetentionConfig struct {
+	// Messages older than MaxAge are archived and deleted
+	MaxAge time.Duration
+	// How often the job runs
+	Interval time.Duration
+	// Where archives are written
+	ArchiveDir string
+}
+
+// RetentionJob periodically archives chat messages older than the retention
+// period to a gzipped JSONL file and then deletes them. Messages are only
+// deleted once their archive is safely on disk, and every purge is recorded.
+type RetentionJob struct {
+	store RetentionStore
+	cfg   RetentionConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRetentionJob creates a chat retention job
+func NewRetentionJob(store RetentionStore, cfg RetentionConfig) *RetentionJob {
+	return &RetentionJob{store: store, cfg: cfg}
+}
+
+// Start runs the job now and then every interval
+func (j *RetentionJob) Start() {
+	j.stop = make(chan struct{})
+	j.done = make(chan struct{})
+
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := j.Run(); err != nil {
+				logger.WithError(err).Error("Chat retention run failed")
+			}
+
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the job, waiting for a run in progress to finish
+func (j *RetentionJob) Stop() {
+	if j.stop != nil {
+		close(j.stop)
+		<-j.done
+		j.stop = nil
+	}
+}
+
+// Run archives and deletes the messages older than the retention period. It
+// returns the recorded purge, or nil when there was nothing to purge or
+// another replica is already purging.
+func (j *RetentionJob) Run() (*models.ChatRetentionPurge, error) {
+	return j.run(time.Now())
+}
+
+func (j *RetentionJob) run(now time.Time) (*models.ChatRetentionPurge, error) {
+	unlock, ok, err := j.store.TryLock(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		logger.Debug("Chat retention is running on another instance")
+		return nil, nil
+	}
+	defer unlock()
+
+	cutoff := now.Add(-j.cfg.MaxAge).UTC()
+	purge := &models.ChatRetentionPurge{
+		Cutoff:     cutoff,
+		RaceCounts: make(map[string]int),
+		ArchivePath: filepath.Join(j.cfg.ArchiveDir,
+			fmt.Sprintf("chat-%s.jsonl.gz", cutoff.Format("20060102T150405Z"))),
+	}
+
+	if err := j.archive(purge); err != nil {
+		return nil, err
+	}
+	if purge.MessageCount == 0 {
+		return nil, nil
+	}
+
+	deleted, deleteErr := j.store.DeleteMessagesBefore(cutoff)
+	purge.DeletedCount = int(deleted)
+	if err := j.store.CreatePurge(purge); err != nil {
+		return nil, err
+	}
+	if deleteErr != nil {
+		return purge, deleteErr
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"cutoff":        cutoff,
+		"archive":       purge.ArchivePath,
+		"message_count": purge.MessageCount,
+		"deleted_count": purge.DeletedCount,
+		"races":         len(purge.RaceCounts),
+	}).Info("Purged old chat messages")
+	return purge, nil
+}
+
+// archive writes the messages older than the purge's cutoff to its archive
+// path and counts them. The archive only appears under its final name once
+// it is complete and synced; nothing is written if there are no messages.
+func (j *RetentionJob) archive(purge *models.ChatRetentionPurge) error {
+	if err := os.MkdirAll(j.cfg.ArchiveDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create chat archive directory: %w", err)
+	}
+
+	partial := purge.ArchivePath + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create chat archive: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			file.Close()
+			os.Remove(partial)
+		}
+	}()
+
+	counted := &countingWriter{w: file}
+	gz := gzip.NewWriter(counted)
+	transcript, err := NewTranscriptWriter(gz, TranscriptFormatJSONL)
+	if err != nil {
+		return err
+	}
+
+	err = j.store.ForEachMessageBefore(purge.Cutoff, func(entry *models.ChatTranscriptEntry) error {
+		if err := transcript.Write(entry); err != nil {
+			return fmt.Errorf("failed to write chat archive: %w", err)
+		}
+		purge.MessageCount++
+		purge.RaceCounts[entry.RaceID]++
+		createdAt := entry.CreatedAt
+		if purge.OldestMessageAt == nil {
+			purge.OldestMessageAt = &createdAt
+		}
+		purge.NewestMessageAt = &createdAt
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if purge.MessageCount == 0 {
+		return nil
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write chat archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync chat archive: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close chat archive: %w", err)
+	}
+	if err := os.Rename(partial, purge.ArchivePath); err != nil {
+		return fmt.Errorf("failed to move chat archive into place: %w", err)
+	}
+	keep = true
+	purge.ArchiveBytes = counted.n
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
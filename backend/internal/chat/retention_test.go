@@ -0,0 +1,169 @@
+package chat
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRetentionStore struct {
+	messages  []*models.ChatTranscriptEntry
+	locked    bool
+	deleteErr error
+	purges    []*models.ChatRetentionPurge
+}
+
+func (s *fakeRetentionStore) TryLock(ctx context.Context) (func(), bool, error) {
+	if s.locked {
+		return nil, false, nil
+	}
+	s.locked = true
+	return func() { s.locked = false }, true, nil
+}
+
+func (s *fakeRetentionStore) ForEachMessageBefore(cutoff time.Time, fn func(*models.ChatTranscriptEntry) error) error {
+	for _, msg := range s.messages {
+		if msg.CreatedAt.Before(cutoff) {
+			if err := fn(msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *fakeRetentionStore) DeleteMessagesBefore(cutoff time.Time) (int64, error) {
+	if s.deleteErr != nil {
+		return 0, s.deleteErr
+	}
+	var kept []*models.ChatTranscriptEntry
+	for _, msg := range s.messages {
+		if !msg.CreatedAt.Before(cutoff) {
+			kept = append(kept, msg)
+		}
+	}
+	deleted := len(s.messages) - len(kept)
+	s.messages = kept
+	return int64(deleted), nil
+}
+
+func (s *fakeRetentionStore) CreatePurge(purge *models.ChatRetentionPurge) error {
+	s.purges = append(s.purges, purge)
+	return nil
+}
+
+func readArchive(t *testing.T, path string) []models.ChatTranscriptEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	var entries []models.ChatTranscriptEntry
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var entry models.ChatTranscriptEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func TestRetentionJob_Run(t *testing.T) {
+	now := time.Date(2026, 7, 14, 15, 0, 0, 0, time.UTC)
+	message := func(id, raceID string, age time.Duration) *models.ChatTranscriptEntry {
+		return &models.ChatTranscriptEntry{ID: id, RaceID: raceID, CreatedAt: now.Add(-age), Username: "Alice", Message: id}
+	}
+	newJob := func(store RetentionStore, dir string) *RetentionJob {
+		return NewRetentionJob(store, RetentionConfig{MaxAge: 30 * 24 * time.Hour, Interval: time.Hour, ArchiveDir: dir})
+	}
+
+	t.Run("Old messages are archived, deleted and recorded", func(t *testing.T) {
+		dir := t.TempDir()
+		store := &fakeRetentionStore{messages: []*models.ChatTranscriptEntry{
+			message("old-1", "race-a", 40*24*time.Hour),
+			message("old-2", "race-b", 35*24*time.Hour),
+			message("old-3", "race-a", 31*24*time.Hour),
+			message("recent", "race-a", 24*time.Hour),
+		}}
+
+		purge, err := newJob(store, dir).run(now)
+		require.NoError(t, err)
+		require.NotNil(t, purge)
+
+		assert.Equal(t, now.Add(-30*24*time.Hour), purge.Cutoff)
+		assert.Equal(t, 3, purge.MessageCount)
+		assert.Equal(t, 3, purge.DeletedCount)
+		assert.Equal(t, map[string]int{"race-a": 2, "race-b": 1}, purge.RaceCounts)
+		assert.Equal(t, now.Add(-40*24*time.Hour), *purge.OldestMessageAt)
+		assert.Equal(t, now.Add(-31*24*time.Hour), *purge.NewestMessageAt)
+		assert.Equal(t, []*models.ChatRetentionPurge{purge}, store.purges)
+
+		info, err := os.Stat(purge.ArchivePath)
+		require.NoError(t, err)
+		assert.Equal(t, info.Size(), purge.ArchiveBytes)
+		assert.Equal(t, dir, filepath.Dir(purge.ArchivePath))
+
+		archived := readArchive(t, purge.ArchivePath)
+		require.Len(t, archived, 3)
+		assert.Equal(t, "old-1", archived[0].ID)
+		assert.Equal(t, "old-3", archived[2].ID)
+
+		require.Len(t, store.messages, 1)
+		assert.Equal(t, "recent", store.messages[0].ID)
+		assert.False(t, store.locked, "The lock is released")
+	})
+
+	t.Run("Nothing is written when no messages are old enough", func(t *testing.T) {
+		dir := t.TempDir()
+		store := &fakeRetentionStore{messages: []*models.ChatTranscriptEntry{message("recent", "race-a", time.Hour)}}
+
+		purge, err := newJob(store, dir).run(now)
+		require.NoError(t, err)
+		assert.Nil(t, purge)
+		assert.Empty(t, store.purges)
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("Nothing happens while another instance holds the lock", func(t *testing.T) {
+		store := &fakeRetentionStore{
+			messages: []*models.ChatTranscriptEntry{message("old", "race-a", 40*24*time.Hour)},
+			locked:   true,
+		}
+
+		purge, err := newJob(store, t.TempDir()).run(now)
+		require.NoError(t, err)
+		assert.Nil(t, purge)
+		assert.Len(t, store.messages, 1)
+	})
+
+	t.Run("A failed delete is still recorded and the archive kept", func(t *testing.T) {
+		store := &fakeRetentionStore{
+			messages:  []*models.ChatTranscriptEntry{message("old", "race-a", 40*24*time.Hour)},
+			deleteErr: errors.New("connection reset"),
+		}
+
+		purge, err := newJob(store, t.TempDir()).run(now)
+		assert.Error(t, err)
+		require.NotNil(t, purge)
+		assert.Equal(t, 1, purge.MessageCount)
+		assert.Zero(t, purge.DeletedCount)
+		assert.Len(t, store.purges, 1)
+		assert.FileExists(t, purge.ArchivePath)
+	})
+}
@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+//msgp:tag json
+//msgp:newtime
+
+// Room types a client can join. Race rooms are keyed in the hub by the bare
+// race ID; other rooms are keyed as "<type>:<id>".
+const (
+	RoomTypeRace  = "race"
+	RoomTypeParty = "party"
+)
+
+// MaxPartyRoomsPerClient caps how many party rooms one connection can join
+const MaxPartyRoomsPerClient = 3
+
+// RoomID returns the hub key for a room
+func RoomID(roomType, id string) string {
+	if roomType == RoomTypeRace {
+		return id
+	}
+	return roomType + ":" + id
+}
+
+// PartyRoomID returns the hub key for a party room
+func PartyRoomID(code string) string {
+	return RoomID(RoomTypeParty, code)
+}
+
+// ParseRoomName splits a client-facing room name such as "race:<id>" or
+// "party:<code>" into its type and ID
+func ParseRoomName(name string) (roomType, id string, ok bool) {
+	roomType, id, found := strings.Cut(strings.TrimSpace(name), ":")
+	if !found || id == "" {
+		return "", "", false
+	}
+	switch roomType {
+	case RoomTypeRace, RoomTypeParty:
+		return roomType, id, true
+	}
+	return "", "", false
+}
+
+// WhisperData is sent by a client to whisper to another user in the race
+type WhisperData struct {
+	ToUserID string `json:"to_user_id"`
+	Message  string `json:"message"`
+}
+
+// PrivateMessageData carries a whisper or party message. Room is set for
+// party messages; ToUserID for whispers.
+type PrivateMessageData struct {
+	ID           string    `json:"id"`
+	RaceID       string    `json:"race_id"`
+	Room         string    `json:"room,omitempty"`
+	FromUserID   string    `json:"from_user_id"`
+	FromUsername string    `json:"from_username"`
+	ToUserID     *string   `json:"to_user_id,omitempty"`
+	Message      string    `json:"message"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PartyLeftData tells a client it is no longer in a party room
+type PartyLeftData struct {
+	Room string `json:"room"`
+}
+
+func newPrivateMessageData(msg *models.ChatPrivateMessage, room string) PrivateMessageData {
+	return PrivateMessageData{
+		ID:           msg.ID,
+		RaceID:       msg.RaceID,
+		Room:         room,
+		FromUserID:   msg.SenderID,
+		FromUsername: msg.Username,
+		ToUserID:     msg.RecipientID,
+		Message:      msg.Message,
+		CreatedAt:    msg.CreatedAt,
+	}
+}
+
+// NewWhisperWSMessage creates the frame delivered to both ends of a whisper
+func NewWhisperWSMessage(msg *models.ChatPrivateMessage) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeWhisper),
+		Data: newPrivateMessageData(msg, ""),
+	}
+}
+
+// NewPartyMessageWSMessage creates the frame broadcast to a party room
+func NewPartyMessageWSMessage(msg *models.ChatPrivateMessage, code string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypePartyMessage),
+		Data: newPrivateMessageData(msg, PartyRoomID(code)),
+	}
+}
+
+// NewPartyLeftWSMessage tells a client it left a party room
+func NewPartyLeftWSMessage(code string) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypePartyLeft),
+		Data: PartyLeftData{Room: PartyRoomID(code)},
+	}
+}
@@ -0,0 +1,37 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoomName(t *testing.T) {
+	tests := []struct {
+		name     string
+		roomType string
+		id       string
+		ok       bool
+	}{
+		{"race:8f6c1c2e", RoomTypeRace, "8f6c1c2e", true},
+		{"party:abc123", RoomTypeParty, "abc123", true},
+		{" party:abc123 ", RoomTypeParty, "abc123", true},
+		{"party:", "", "", false},
+		{"abc123", "", "", false},
+		{"lobby:main", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomType, id, ok := ParseRoomName(tt.name)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.roomType, roomType)
+			assert.Equal(t, tt.id, id)
+		})
+	}
+}
+
+func TestRoomID(t *testing.T) {
+	assert.Equal(t, "race-1", RoomID(RoomTypeRace, "race-1"), "Race rooms keep their race ID key")
+	assert.Equal(t, "party:abc123", PartyRoomID("abc123"))
+}
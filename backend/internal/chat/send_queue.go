@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Frames a client may have waiting to be written
+	sendQueueSize = 256
+
+	// How long a client may stay behind, dropping frames, before it is
+	// disconnected
+	slowConsumerGrace = 10 * time.Second
+)
+
+// frameClass says what a client's queue may do with a frame when the client
+// falls behind
+type frameClass int
+
+const (
+	// frameCritical frames are only lost if the queue has nothing else to drop
+	frameCritical frameClass = iota
+	// frameDroppable frames, such as joined/left notices, are dropped first,
+	// oldest first
+	frameDroppable
+	// frameCoalesced frames replace a queued frame with the same key, so
+	// only the latest poll state is sent. They can be dropped like
+	// frameDroppable frames.
+	frameCoalesced
+)
+
+type queuedFrame struct {
+	data  []byte
+	class frameClass
+	key   string
+}
+
+// enqueueOutcome reports what happened to a frame handed to a sendQueue
+type enqueueOutcome struct {
+	queued     bool
+	coalesced  bool
+	dropped    int
+	disconnect bool
+}
+
+// sendQueue holds a client's outgoing frames. Unlike a channel it can drop or
+// replace frames that are already queued.
+type sendQueue struct {
+	mu       sync.Mutex
+	frames   []queuedFrame
+	capacity int
+	// signalled when frames are queued; closed with the queue
+	ready chan struct{}
+	// close code to send the client, 0 for a normal close
+	closeCode int
+	closed    bool
+	// when the queue filled up; cleared once the client drains it before it
+	// fills again
+	behindSince time.Time
+}
+
+func newSendQueue(capacity int) *sendQueue {
+	return &sendQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push queues a frame, making room by dropping non-critical frames when the
+// queue is full. A client that has been behind for longer than
+// slowConsumerGrace is disconnected with CloseTryAgainLater.
+func (q *sendQueue) push(frame queuedFrame, now time.Time) enqueueOutcome {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return enqueueOutcome{dropped: 1}
+	}
+
+	if frame.class == frameCoalesced {
+		for i := len(q.frames) - 1; i >= 0; i-- {
+			if q.frames[i].class == frameCoalesced && q.frames[i].key == frame.key {
+				q.frames[i].data = frame.data
+				return enqueueOutcome{queued: true, coalesced: true}
+			}
+		}
+	}
+
+	if len(q.frames) < q.capacity {
+		q.frames = append(q.frames, frame)
+		q.signal()
+		return enqueueOutcome{queued: true}
+	}
+
+	if q.behindSince.IsZero() {
+		q.behindSince = now
+	}
+	if now.Sub(q.behindSince) >= slowConsumerGrace {
+		dropped := len(q.frames) + 1
+		q.closeLocked(closeCodeSlowConsumer)
+		return enqueueOutcome{dropped: dropped, disconnect: true}
+	}
+
+	for i, queued := range q.frames {
+		if queued.class != frameCritical {
+			copy(q.frames[i:], q.frames[i+1:])
+			q.frames[len(q.frames)-1] = frame
+			return enqueueOutcome{queued: true, dropped: 1}
+		}
+	}
+	return enqueueOutcome{dropped: 1}
+}
+
+// drain removes and returns every queued frame. closed is true once the queue
+// has been closed and emptied, with the close code to send the client.
+func (q *sendQueue) drain() (frames [][]byte, closeCode int, closed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	frames = make([][]byte, len(q.frames))
+	for i, frame := range q.frames {
+		frames[i] = frame.data
+	}
+	// A client that empties a full queue hasn't caught up, it has just
+	// taken another batch
+	if len(q.frames) < q.capacity {
+		q.behindSince = time.Time{}
+	}
+	q.frames = q.frames[:0]
+	return frames, q.closeCode, q.closed && len(frames) == 0
+}
+
+// len returns the number of queued frames
+func (q *sendQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.frames)
+}
+
+// close stops the queue accepting frames. Frames already queued are still
+// written.
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closeLocked(0)
+}
+
+func (q *sendQueue) closeLocked(code int) {
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.closeCode = code
+	if code != 0 {
+		// The client won't catch up, so don't bother writing what's left
+		q.frames = nil
+	}
+	close(q.ready)
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,145 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queuedData(q *sendQueue) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	data := make([]string, len(q.frames))
+	for i, frame := range q.frames {
+		data[i] = string(frame.data)
+	}
+	return data
+}
+
+func TestSendQueue_Backpressure(t *testing.T) {
+	now := time.Date(2026, 7, 14, 15, 0, 0, 0, time.UTC)
+	critical := func(data string) queuedFrame { return queuedFrame{data: []byte(data)} }
+	droppable := func(data string) queuedFrame { return queuedFrame{data: []byte(data), class: frameDroppable} }
+	pollUpdate := func(data, pollID string) queuedFrame {
+		return queuedFrame{data: []byte(data), class: frameCoalesced, key: "poll:" + pollID}
+	}
+
+	t.Run("Oldest non-critical frame is dropped first", func(t *testing.T) {
+		q := newSendQueue(3)
+		q.push(critical("msg-1"), now)
+		q.push(droppable("joined-1"), now)
+		q.push(droppable("joined-2"), now)
+
+		outcome := q.push(critical("msg-2"), now)
+		assert.Equal(t, enqueueOutcome{queued: true, dropped: 1}, outcome)
+		assert.Equal(t, []string{"msg-1", "joined-2", "msg-2"}, queuedData(q))
+	})
+
+	t.Run("Poll updates replace queued updates for the same poll", func(t *testing.T) {
+		q := newSendQueue(10)
+		q.push(pollUpdate("poll-a-1", "a"), now)
+		q.push(pollUpdate("poll-b-1", "b"), now)
+		q.push(critical("msg"), now)
+
+		outcome := q.push(pollUpdate("poll-a-2", "a"), now)
+		assert.Equal(t, enqueueOutcome{queued: true, coalesced: true}, outcome)
+		assert.Equal(t, []string{"poll-a-2", "poll-b-1", "msg"}, queuedData(q))
+	})
+
+	t.Run("Frames are dropped when only critical frames are queued", func(t *testing.T) {
+		q := newSendQueue(1)
+		q.push(critical("msg-1"), now)
+
+		assert.Equal(t, enqueueOutcome{dropped: 1}, q.push(droppable("joined"), now))
+		assert.Equal(t, enqueueOutcome{dropped: 1}, q.push(critical("msg-2"), now))
+		assert.Equal(t, []string{"msg-1"}, queuedData(q))
+	})
+
+	t.Run("Client is disconnected after staying behind for the grace period", func(t *testing.T) {
+		q := newSendQueue(1)
+		q.push(critical("msg-1"), now)
+		q.push(critical("msg-2"), now)
+
+		// Taking a full queue doesn't count as catching up
+		q.drain()
+		q.push(critical("msg-3"), now.Add(time.Second))
+		outcome := q.push(critical("msg-4"), now.Add(slowConsumerGrace))
+		assert.Equal(t, enqueueOutcome{dropped: 2, disconnect: true}, outcome)
+
+		frames, closeCode, closed := q.drain()
+		assert.Empty(t, frames, "Frames left for a slow client are discarded")
+		assert.True(t, closed)
+		assert.Equal(t, closeCodeSlowConsumer, closeCode)
+
+		// The write pump is woken up to close the connection
+		for range q.ready {
+		}
+		assert.Equal(t, enqueueOutcome{dropped: 1}, q.push(critical("msg-5"), now))
+	})
+
+	t.Run("Catching up resets the grace period", func(t *testing.T) {
+		q := newSendQueue(2)
+		q.push(critical("msg-1"), now)
+		q.push(critical("msg-2"), now)
+		q.push(critical("msg-3"), now)
+
+		q.drain()
+		q.push(critical("msg-4"), now)
+		q.drain()
+
+		q.push(critical("msg-5"), now.Add(slowConsumerGrace))
+		q.push(critical("msg-6"), now.Add(slowConsumerGrace))
+		outcome := q.push(critical("msg-7"), now.Add(slowConsumerGrace))
+		assert.False(t, outcome.disconnect)
+	})
+
+	t.Run("Closing keeps queued frames for the write pump", func(t *testing.T) {
+		q := newSendQueue(2)
+		q.push(critical("msg-1"), now)
+		q.close()
+
+		frames, _, closed := q.drain()
+		assert.Equal(t, [][]byte{[]byte("msg-1")}, frames)
+		assert.False(t, closed)
+
+		frames, closeCode, closed := q.drain()
+		assert.Empty(t, frames)
+		assert.True(t, closed)
+		assert.Zero(t, closeCode)
+	})
+}
+
+func TestFrame_Classification(t *testing.T) {
+	poll := &models.ChatPoll{ID: "poll-1"}
+
+	tests := []struct {
+		name  string
+		frame *Frame
+		class frameClass
+		key   string
+	}{
+		{"Chat messages are critical", NewFrame(NewMessageWSMessage(testChatMessage())), frameCritical, ""},
+		{"Joined notices are droppable", NewFrame(NewJoinedWSMessage("Alice")), frameDroppable, ""},
+		{"Left notices are droppable", NewFrame(NewLeftWSMessage("Alice")), frameDroppable, ""},
+		{"Poll updates are coalesced per poll", NewFrame(NewPollUpdateMessage(poll)), frameCoalesced, "poll:poll-1"},
+		{"Closing a poll is critical", NewFrame(NewPollClosedMessage(poll)), frameCritical, ""},
+		{"Pin updates replace each other", NewFrame(NewPinUpdatedWSMessage("race-1", nil)), frameCoalesced, "pin"},
+		{"Announcements are critical", NewFrame(NewAnnouncementWSMessage(testChatMessage())), frameCritical, ""},
+		{"Stream status changes are coalesced per feed", NewFrame(NewStreamStatusWSMessage(&models.StreamStatusChange{StreamID: "stream-1", RaceID: "race-1", From: "pre_show", To: "live"})), frameCoalesced, "stream_status:stream-1"},
+		{"Frames from other instances are classified", NewJSONFrame([]byte(`{"type":"poll_update","data":{"id":"poll-1"}}`)), frameCoalesced, "poll:poll-1"},
+		{"Stream status frames from other instances are classified", NewJSONFrame([]byte(`{"type":"stream_status","data":{"stream_id":"stream-2"}}`)), frameCoalesced, "stream_status:stream-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queued, err := tt.frame.queued(ProtocolJSON)
+			require.NoError(t, err)
+			assert.Equal(t, tt.class, queued.class)
+			assert.Equal(t, tt.key, queued.key)
+		})
+	}
+}
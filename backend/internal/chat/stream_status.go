@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+//msgp:tag json
+//msgp:newtime
+
+// StreamStatusData tells the room that a stream (feed) of the race changed
+// status. The race's status is the status of its primary feed.
+type StreamStatusData struct {
+	RaceID         string    `json:"race_id"`
+	StreamID       string    `json:"stream_id"`
+	Name           string    `json:"name"`
+	IsPrimary      bool      `json:"is_primary"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// NewStreamStatusWSMessage creates a WebSocket message for a stream status
+// change
+func NewStreamStatusWSMessage(change *models.StreamStatusChange) *WSMessage {
+	return &WSMessage{
+		Type: string(MessageTypeStreamStatus),
+		Data: StreamStatusData{
+			RaceID:         change.RaceID,
+			StreamID:       change.StreamID,
+			Name:           change.Name,
+			IsPrimary:      change.IsPrimary,
+			Status:         change.To,
+			PreviousStatus: change.From,
+			ChangedAt:      change.ChangedAt,
+		},
+	}
+}
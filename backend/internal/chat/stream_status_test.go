@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStreamStatusWSMessage(t *testing.T) {
+	msg := NewStreamStatusWSMessage(&models.StreamStatusChange{
+		StreamID:  "stream-1",
+		RaceID:    "race-1",
+		Name:      "Main",
+		IsPrimary: true,
+		From:      models.StreamStatusPreShow,
+		To:        models.StreamStatusLive,
+		ChangedAt: time.Date(2026, 7, 14, 13, 0, 0, 0, time.UTC),
+	})
+
+	local := NewFrame(msg)
+	localJSON, err := local.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"stream_status","v":2,"data":{"race_id":"race-1","stream_id":"stream-1","name":"Main","is_primary":true,"status":"live","previous_status":"pre_show","changed_at":"2026-07-14T13:00:00Z"}}`, string(localJSON))
+
+	// Frames from other instances encode the same for MessagePack clients
+	localMsgpack, err := local.Encode(ProtocolMsgpack)
+	require.NoError(t, err)
+	remoteMsgpack, err := NewJSONFrame(localJSON).Encode(ProtocolMsgpack)
+	require.NoError(t, err)
+	assert.Equal(t, localMsgpack, remoteMsgpack)
+}
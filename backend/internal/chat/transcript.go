@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// Chat transcript formats
+const (
+	TranscriptFormatCSV   = "csv"
+	TranscriptFormatJSONL = "jsonl"
+)
+
+// Columns of a CSV transcript
+var transcriptCSVHeader = []string{
+	"created_at", "id", "race_id", "user_id", "username", "role", "badges",
+	"message", "announcement", "reply_to_id", "deleted_at", "deleted_by",
+}
+
+// TranscriptWriter writes chat messages one at a time as a transcript
+type TranscriptWriter interface {
+	Write(entry *models.ChatTranscriptEntry) error
+	// Flush writes buffered entries to the underlying writer
+	Flush() error
+}
+
+// NewTranscriptWriter creates a transcript writer for a format. CSV
+// transcripts start with a header row; JSONL transcripts hold one JSON
+// object per line.
+func NewTranscriptWriter(w io.Writer, format string) (TranscriptWriter, error) {
+	switch format {
+	case TranscriptFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(transcriptCSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvTranscriptWriter{w: cw}, nil
+	case TranscriptFormatJSONL:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonlTranscriptWriter{enc: enc}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcript format %q", format)
+	}
+}
+
+// TranscriptContentType returns the MIME type of a transcript format
+func TranscriptContentType(format string) string {
+	if format == TranscriptFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+type csvTranscriptWriter struct {
+	w *csv.Writer
+}
+
+func (t *csvTranscriptWriter) Write(entry *models.ChatTranscriptEntry) error {
+	return t.w.Write([]string{
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.ID,
+		entry.RaceID,
+		stringOrEmpty(entry.UserID),
+		csvSafe(entry.Username),
+		entry.Role,
+		strings.Join(entry.Badges, ";"),
+		csvSafe(entry.Message),
+		strconv.FormatBool(entry.Announcement),
+		stringOrEmpty(entry.ReplyToID),
+		timeOrEmpty(entry.DeletedAt),
+		stringOrEmpty(entry.DeletedBy),
+	})
+}
+
+func (t *csvTranscriptWriter) Flush() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+type jsonlTranscriptWriter struct {
+	enc *json.Encoder
+}
+
+func (t *jsonlTranscriptWriter) Write(entry *models.ChatTranscriptEntry) error {
+	return t.enc.Encode(entry)
+}
+
+func (t *jsonlTranscriptWriter) Flush() error {
+	return nil
+}
+
+// csvSafe stops spreadsheets from treating text typed by users as a formula
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timeOrEmpty(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTranscriptEntries() []*models.ChatTranscriptEntry {
+	userID := "user-1"
+	deletedBy := "admin"
+	createdAt := time.Date(2026, 7, 14, 15, 0, 0, 0, time.UTC)
+	deletedAt := createdAt.Add(time.Minute)
+
+	return []*models.ChatTranscriptEntry{
+		{
+			ID:        "msg-1",
+			RaceID:    "race-1",
+			CreatedAt: createdAt,
+			UserID:    &userID,
+			Username:  "Alice",
+			Role:      "subscriber",
+			Badges:    []string{"subscriber", "founder"},
+			Message:   "Allez, allez, \"Pogi\"!",
+		},
+		{
+			ID:        "msg-2",
+			RaceID:    "race-1",
+			CreatedAt: createdAt.Add(time.Second),
+			Username:  "Anonymous",
+			Message:   "=HYPERLINK(\"http://example.com\")",
+			DeletedAt: &deletedAt,
+			DeletedBy: &deletedBy,
+		},
+	}
+}
+
+func TestTranscriptWriter_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	transcript, err := NewTranscriptWriter(&buf, TranscriptFormatCSV)
+	require.NoError(t, err)
+	for _, entry := range testTranscriptEntries() {
+		require.NoError(t, transcript.Write(entry))
+	}
+	require.NoError(t, transcript.Flush())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, transcriptCSVHeader, records[0])
+	assert.Equal(t, []string{
+		"2026-07-14T15:00:00Z", "msg-1", "race-1", "user-1", "Alice", "subscriber",
+		"subscriber;founder", "Allez, allez, \"Pogi\"!", "false", "", "", "",
+	}, records[1])
+
+	// Formulas typed into chat are not run by spreadsheets
+	assert.Equal(t, "'=HYPERLINK(\"http://example.com\")", records[2][7])
+	assert.Equal(t, "2026-07-14T15:01:00Z", records[2][10])
+	assert.Equal(t, "admin", records[2][11])
+}
+
+func TestTranscriptWriter_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	transcript, err := NewTranscriptWriter(&buf, TranscriptFormatJSONL)
+	require.NoError(t, err)
+	for _, entry := range testTranscriptEntries() {
+		require.NoError(t, transcript.Write(entry))
+	}
+	require.NoError(t, transcript.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var entry models.ChatTranscriptEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "msg-2", entry.ID)
+	assert.Equal(t, "=HYPERLINK(\"http://example.com\")", entry.Message, "JSONL keeps messages as sent")
+	require.NotNil(t, entry.DeletedBy)
+	assert.Equal(t, "admin", *entry.DeletedBy)
+}
+
+func TestTranscriptWriter_UnknownFormat(t *testing.T) {
+	_, err := NewTranscriptWriter(&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+}
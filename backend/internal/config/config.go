@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -24,6 +25,87 @@ type Config struct {
 	FrontendURL         string
 	XP                  *XPConfig
 	Bunny               *BunnyConfig
+	Chat                *ChatConfig
+	Stream              *StreamConfig
+}
+
+type StreamConfig struct {
+	// PublishCallbackSecret must be sent as the secret query parameter of the
+	// nginx-rtmp publish callbacks (empty accepts callbacks without one)
+	PublishCallbackSecret string
+	// KeyRotationOverlap is how long a rotated-out stream key keeps working
+	KeyRotationOverlap time.Duration
+	// PreShowLead is how long before a race's start its stream enters pre-show
+	PreShowLead time.Duration
+	// SchedulerInterval is how often due pre-shows are checked
+	SchedulerInterval time.Duration
+	// PlaybackTokenSecret signs the tokens of HLS playback URLs
+	PlaybackTokenSecret string
+	// PlaybackTokenTTL is how long a playback token is valid; players
+	// refresh it before it expires
+	PlaybackTokenTTL time.Duration
+	// PlaybackOriginHosts are the hosts of the nginx origin that checks
+	// playback tokens; only URLs on them are signed
+	PlaybackOriginHosts []string
+	// ProbeInterval is how often the playback sources of pre-show and live
+	// streams are health checked
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds each playlist fetch of a health check
+	ProbeTimeout time.Duration
+	// ProbeFailures is how many failed checks in a row mark a source degraded
+	ProbeFailures int
+	// DVRWindow is how far back viewers can rewind a live stream that
+	// doesn't set its own window (0 disables DVR)
+	DVRWindow time.Duration
+	// DVRMaxWindow caps the DVR window a stream can set
+	DVRMaxWindow time.Duration
+	// DVRPollInterval is how often the origin playlists of live streams are
+	// read to record their segments
+	DVRPollInterval time.Duration
+	// VODSettleDelay is how long a stream stays ended before its recording is
+	// registered as a replay, so an encoder that reconnects resumes it
+	VODSettleDelay time.Duration
+	// VODInterval is how often ended streams are checked for replays
+	VODInterval time.Duration
+	// VODSegmentDir, when set, is a directory laid out like the origin's
+	// hls_path that replays are read from instead of the recorded segments
+	VODSegmentDir string
+}
+
+type ChatConfig struct {
+	// Broadcaster selects how room messages reach other API replicas: memory or postgres
+	Broadcaster string
+	// InstanceID identifies this replica on the chat backplane (defaults to a random ID)
+	InstanceID string
+	// RateLimit holds the per-role message buckets and duplicate suppression window
+	RateLimit ChatRateLimitConfig
+	// Retention controls when old messages are archived and deleted
+	Retention ChatRetentionConfig
+}
+
+type ChatRetentionConfig struct {
+	// Days messages are kept before being archived and deleted (0 keeps them forever)
+	Days int
+	// Interval between retention runs
+	Interval time.Duration
+	// ArchiveDir is where gzipped transcripts of purged messages are written
+	ArchiveDir string
+}
+
+// ChatRateLimitTier is a token bucket: Burst messages can be sent back to
+// back, and one token is refilled every Refill
+type ChatRateLimitTier struct {
+	Burst  int
+	Refill time.Duration
+}
+
+type ChatRateLimitConfig struct {
+	Anonymous  ChatRateLimitTier
+	User       ChatRateLimitTier
+	Subscriber ChatRateLimitTier
+	Admin      ChatRateLimitTier
+	// DuplicateWindow rejects a repeat of the sender's previous message within this window (0 disables it)
+	DuplicateWindow time.Duration
 }
 
 type BunnyConfig struct {
@@ -55,6 +137,8 @@ func Load() (*Config, error) {
 		FrontendURL:         getEnv("FRONTEND_URL", "http://localhost:3000"),
 		XP:                  LoadXPConfig(),
 		Bunny:               LoadBunnyConfig(),
+		Chat:                LoadChatConfig(),
+		Stream:              LoadStreamConfig(),
 	}
 
 	// Validate configuration
@@ -139,6 +223,86 @@ func (c *Config) Validate(isProduction bool) error {
 		}
 	}
 
+	// Chat backplane
+	if c.Chat != nil && c.Chat.Broadcaster != "memory" && c.Chat.Broadcaster != "postgres" {
+		errors = append(errors, "CHAT_BROADCASTER must be one of: memory, postgres")
+	}
+	if c.Chat != nil {
+		tiers := []struct {
+			name string
+			tier ChatRateLimitTier
+		}{
+			{"ANONYMOUS", c.Chat.RateLimit.Anonymous},
+			{"USER", c.Chat.RateLimit.User},
+			{"SUBSCRIBER", c.Chat.RateLimit.Subscriber},
+			{"ADMIN", c.Chat.RateLimit.Admin},
+		}
+		for _, t := range tiers {
+			if t.tier.Burst < 1 || t.tier.Refill <= 0 {
+				errors = append(errors, fmt.Sprintf("CHAT_RATE_%s_BURST must be at least 1 and CHAT_RATE_%s_REFILL a positive duration", t.name, t.name))
+			}
+		}
+		if c.Chat.RateLimit.DuplicateWindow < 0 {
+			errors = append(errors, "CHAT_DUPLICATE_WINDOW must not be negative")
+		}
+		if c.Chat.Retention.Days < 0 {
+			errors = append(errors, "CHAT_RETENTION_DAYS must not be negative")
+		}
+		if c.Chat.Retention.Days > 0 {
+			if c.Chat.Retention.Interval < time.Minute {
+				errors = append(errors, "CHAT_RETENTION_INTERVAL must be at least 1m")
+			}
+			if c.Chat.Retention.ArchiveDir == "" {
+				errors = append(errors, "CHAT_ARCHIVE_DIR is required when CHAT_RETENTION_DAYS is set")
+			}
+		}
+	}
+
+	// RTMP publish callbacks, stream keys, the stream scheduler and playback tokens
+	if c.Stream != nil && c.Stream.KeyRotationOverlap < 0 {
+		errors = append(errors, "STREAM_KEY_ROTATION_OVERLAP must not be negative")
+	}
+	if c.Stream != nil && c.Stream.PreShowLead < 0 {
+		errors = append(errors, "STREAM_PRESHOW_LEAD must not be negative")
+	}
+	if c.Stream != nil && c.Stream.SchedulerInterval <= 0 {
+		errors = append(errors, "STREAM_SCHEDULER_INTERVAL must be positive")
+	}
+	if c.Stream != nil && c.Stream.PlaybackTokenTTL < time.Minute {
+		errors = append(errors, "PLAYBACK_TOKEN_TTL must be at least 1m")
+	}
+	if c.Stream != nil && c.Stream.ProbeInterval <= 0 {
+		errors = append(errors, "STREAM_PROBE_INTERVAL must be positive")
+	}
+	if c.Stream != nil && (c.Stream.ProbeTimeout <= 0 || c.Stream.ProbeTimeout > c.Stream.ProbeInterval) {
+		errors = append(errors, "STREAM_PROBE_TIMEOUT must be positive and no longer than STREAM_PROBE_INTERVAL")
+	}
+	if c.Stream != nil && c.Stream.ProbeFailures < 1 {
+		errors = append(errors, "STREAM_PROBE_FAILURES must be at least 1")
+	}
+	if c.Stream != nil && (c.Stream.DVRMaxWindow < 0 || c.Stream.DVRWindow < 0 || c.Stream.DVRWindow > c.Stream.DVRMaxWindow) {
+		errors = append(errors, "STREAM_DVR_WINDOW must be between 0 and STREAM_DVR_MAX_WINDOW")
+	}
+	if c.Stream != nil && c.Stream.DVRPollInterval <= 0 {
+		errors = append(errors, "STREAM_DVR_POLL_INTERVAL must be positive")
+	}
+	if c.Stream != nil && c.Stream.VODSettleDelay < 0 {
+		errors = append(errors, "VOD_SETTLE_DELAY must not be negative")
+	}
+	if c.Stream != nil && c.Stream.VODInterval <= 0 {
+		errors = append(errors, "VOD_RECORDING_INTERVAL must be positive")
+	}
+	if c.Stream != nil && isProduction && (c.Stream.PlaybackTokenSecret == "change-me-in-production" || len(c.Stream.PlaybackTokenSecret) < 32) {
+		errors = append(errors, "PLAYBACK_TOKEN_SECRET must be a secure random string (at least 32 characters) in production")
+	}
+	if c.Stream != nil && isProduction && len(c.Stream.PlaybackOriginHosts) == 0 {
+		errors = append(errors, "PLAYBACK_ORIGIN_HOSTS must list the hosts of the HLS origin in production")
+	}
+	// Without it anyone who can reach /auth/stream can publish and end streams
+	if c.Stream != nil && isProduction && (c.Stream.PublishCallbackSecret == "" || c.Stream.PublishCallbackSecret == "change-me") {
+		errors = append(errors, "RTMP_CALLBACK_SECRET must be set to a secret shared with nginx-rtmp in production")
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("validation errors:\n  - %s", strings.Join(errors, "\n  - "))
 	}
@@ -168,6 +332,25 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsList splits a comma-separated variable, dropping empty entries
+func getEnvAsList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func LoadBunnyConfig() *BunnyConfig {
 	return &BunnyConfig{
 		APIKey:    getEnv("BUNNY_API_KEY", ""),
@@ -175,3 +358,51 @@ func LoadBunnyConfig() *BunnyConfig {
 		BaseURL:   getEnv("BUNNY_API_BASE_URL", "https://video.bunnycdn.com/library"),
 	}
 }
+
+func LoadStreamConfig() *StreamConfig {
+	return &StreamConfig{
+		PublishCallbackSecret: getEnv("RTMP_CALLBACK_SECRET", ""),
+		KeyRotationOverlap:    getEnvAsDuration("STREAM_KEY_ROTATION_OVERLAP", 10*time.Minute),
+		PreShowLead:           getEnvAsDuration("STREAM_PRESHOW_LEAD", 15*time.Minute),
+		SchedulerInterval:     getEnvAsDuration("STREAM_SCHEDULER_INTERVAL", time.Minute),
+		PlaybackTokenSecret:   getEnv("PLAYBACK_TOKEN_SECRET", "change-me-in-production"),
+		PlaybackTokenTTL:      getEnvAsDuration("PLAYBACK_TOKEN_TTL", 10*time.Minute),
+		PlaybackOriginHosts:   getEnvAsList("PLAYBACK_ORIGIN_HOSTS", "localhost"),
+		ProbeInterval:         getEnvAsDuration("STREAM_PROBE_INTERVAL", 30*time.Second),
+		ProbeTimeout:          getEnvAsDuration("STREAM_PROBE_TIMEOUT", 5*time.Second),
+		ProbeFailures:         getEnvAsInt("STREAM_PROBE_FAILURES", 2),
+		DVRWindow:             getEnvAsDuration("STREAM_DVR_WINDOW", 2*time.Hour),
+		DVRMaxWindow:          getEnvAsDuration("STREAM_DVR_MAX_WINDOW", 6*time.Hour),
+		DVRPollInterval:       getEnvAsDuration("STREAM_DVR_POLL_INTERVAL", 4*time.Second),
+		VODSettleDelay:        getEnvAsDuration("VOD_SETTLE_DELAY", 2*time.Minute),
+		VODInterval:           getEnvAsDuration("VOD_RECORDING_INTERVAL", time.Minute),
+		VODSegmentDir:         getEnv("VOD_SEGMENT_DIR", ""),
+	}
+}
+
+func LoadChatConfig() *ChatConfig {
+	return &ChatConfig{
+		Broadcaster: strings.ToLower(getEnv("CHAT_BROADCASTER", "memory")),
+		InstanceID:  getEnv("CHAT_INSTANCE_ID", ""),
+		RateLimit: ChatRateLimitConfig{
+			Anonymous:       loadChatRateLimitTier("ANONYMOUS", 2, 10*time.Second),
+			User:            loadChatRateLimitTier("USER", 5, 2*time.Second),
+			Subscriber:      loadChatRateLimitTier("SUBSCRIBER", 8, time.Second),
+			Admin:           loadChatRateLimitTier("ADMIN", 30, 200*time.Millisecond),
+			DuplicateWindow: getEnvAsDuration("CHAT_DUPLICATE_WINDOW", 30*time.Second),
+		},
+		Retention: ChatRetentionConfig{
+			Days:       getEnvAsInt("CHAT_RETENTION_DAYS", 0),
+			Interval:   getEnvAsDuration("CHAT_RETENTION_INTERVAL", 24*time.Hour),
+			ArchiveDir: getEnv("CHAT_ARCHIVE_DIR", "./data/chat-archives"),
+		},
+	}
+}
+
+// loadChatRateLimitTier reads CHAT_RATE_<TIER>_BURST and CHAT_RATE_<TIER>_REFILL
+func loadChatRateLimitTier(name string, burst int, refill time.Duration) ChatRateLimitTier {
+	return ChatRateLimitTier{
+		Burst:  getEnvAsInt("CHAT_RATE_"+name+"_BURST", burst),
+		Refill: getEnvAsDuration("CHAT_RATE_"+name+"_REFILL", refill),
+	}
+}
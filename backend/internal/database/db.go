@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/cyclingstream/backend/internal/logger"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const (
@@ -16,6 +16,7 @@ const (
 
 type DB struct {
 	*sql.DB
+	dsn string
 }
 
 func New(dsn string) (*DB, error) {
@@ -45,13 +46,20 @@ func New(dsn string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, dsn: dsn}, nil
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
+// NewListener opens a dedicated LISTEN/NOTIFY connection using the same DSN as
+// the pool. Listener connections are not part of the pool and must be closed
+// by the caller.
+func (db *DB) NewListener(minReconnect, maxReconnect time.Duration, eventCallback pq.EventCallbackType) *pq.Listener {
+	return pq.NewListener(db.dsn, minReconnect, maxReconnect, eventCallback)
+}
+
 // QueryWithLogging wraps sql.DB.Query with slow query logging
 func (db *DB) QueryWithLogging(query string, args ...interface{}) (*sql.Rows, error) {
 	start := time.Now()
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
@@ -11,19 +12,36 @@ import (
 )
 
 type AdminHandler struct {
-	raceRepo    *repository.RaceRepository
-	streamRepo  *repository.StreamRepository
-	revenueRepo *repository.RevenueRepository
+	raceRepo      *repository.RaceRepository
+	streamRepo    *repository.StreamRepository
+	revenueRepo   *repository.RevenueRepository
+	streamKeyRepo *repository.StreamKeyRepository
+	providerRepo  *repository.StreamProviderRepository
+	// Default time a rotated-out stream key keeps working
+	keyRotationOverlap time.Duration
+	// Longest DVR window a stream can set
+	maxDVRWindow   time.Duration
+	statusListener streamStatusListener
 }
 
-func NewAdminHandler(raceRepo *repository.RaceRepository, streamRepo *repository.StreamRepository, revenueRepo *repository.RevenueRepository) *AdminHandler {
+func NewAdminHandler(raceRepo *repository.RaceRepository, streamRepo *repository.StreamRepository, revenueRepo *repository.RevenueRepository, streamKeyRepo *repository.StreamKeyRepository, providerRepo *repository.StreamProviderRepository, keyRotationOverlap, maxDVRWindow time.Duration) *AdminHandler {
 	return &AdminHandler{
-		raceRepo:    raceRepo,
-		streamRepo:  streamRepo,
-		revenueRepo: revenueRepo,
+		raceRepo:           raceRepo,
+		streamRepo:         streamRepo,
+		revenueRepo:        revenueRepo,
+		streamKeyRepo:      streamKeyRepo,
+		providerRepo:       providerRepo,
+		keyRotationOverlap: keyRotationOverlap,
+		maxDVRWindow:       maxDVRWindow,
 	}
 }
 
+// SetStatusListener sets the function told about stream status changes made
+// by admins
+func (h *AdminHandler) SetStatusListener(listener func(*models.StreamStatusChange)) {
+	h.statusListener = listener
+}
+
 type CreateRaceRequest struct {
 	Name                string     `json:"name"`
 	Description         *string    `json:"description"`
@@ -283,13 +301,18 @@ func (h *AdminHandler) DeleteRace(c *fiber.Ctx) error {
 	})
 }
 
-type UpdateStreamRequest struct {
-	OriginURL  *string `json:"origin_url"`
-	CDNURL     *string `json:"cdn_url"`
-	StreamKey  *string `json:"stream_key"`
-	Status     string  `json:"status"`
+// streamSourceRequest is where a stream's video comes from
+type streamSourceRequest struct {
 	StreamType string  `json:"stream_type"`
 	SourceID   *string `json:"source_id"`
+	OriginURL  *string `json:"origin_url"`
+	CDNURL     *string `json:"cdn_url"`
+}
+
+type UpdateStreamRequest struct {
+	streamSourceRequest
+	StreamKey *string `json:"stream_key"` // refused: keys are generated by POST /admin/races/:id/stream/keys
+	Status    string  `json:"status"`
 }
 
 func (h *AdminHandler) UpdateStream(c *fiber.Ctx) error {
@@ -307,56 +330,21 @@ func (h *AdminHandler) UpdateStream(c *fiber.Ctx) error {
 	if !parseBody(c, &req) {
 		return nil
 	}
-
-	// Validate status
-	validStatuses := map[string]bool{
-		"offline":  true,
-		"live":     true,
-		"upcoming": true,
-	}
-	if !validStatuses[req.Status] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status. Must be one of: offline, live, upcoming",
-		})
-	}
-
-	// Validate stream type
-	if req.StreamType == "" {
-		req.StreamType = "hls"
-	}
-	validTypes := map[string]bool{
-		"hls":     true,
-		"youtube": true,
-	}
-	if !validTypes[req.StreamType] {
+	if req.StreamKey != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid stream type. Must be one of: hls, youtube",
+			"error": "stream_key can no longer be set; generate one with POST /admin/races/:id/stream/keys",
 		})
 	}
 
-	// Validate source_id for youtube
-	if req.StreamType == "youtube" && (req.SourceID == nil || *req.SourceID == "") {
+	// Validate status (optional: new streams start scheduled)
+	if req.Status != "" && !models.IsValidStreamStatus(req.Status) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Source ID is required for YouTube streams",
+			"error": invalidStreamStatusMessage,
 		})
 	}
 
-	// Sanitize URLs if provided
-	if req.OriginURL != nil {
-		sanitized := middleware.SanitizeString(*req.OriginURL, 500)
-		req.OriginURL = &sanitized
-	}
-	if req.CDNURL != nil {
-		sanitized := middleware.SanitizeString(*req.CDNURL, 500)
-		req.CDNURL = &sanitized
-	}
-	if req.StreamKey != nil {
-		sanitized := middleware.SanitizeString(*req.StreamKey, 255)
-		req.StreamKey = &sanitized
-	}
-	if req.SourceID != nil {
-		sanitized := middleware.SanitizeString(*req.SourceID, 255)
-		req.SourceID = &sanitized
+	if !req.validate(c) {
+		return nil
 	}
 
 	stream := &models.Stream{
@@ -366,18 +354,38 @@ func (h *AdminHandler) UpdateStream(c *fiber.Ctx) error {
 		SourceID:   req.SourceID,
 		OriginURL:  req.OriginURL,
 		CDNURL:     req.CDNURL,
-		StreamKey:  req.StreamKey,
 	}
 
-	if err := h.streamRepo.CreateOrUpdate(stream); err != nil {
+	err := h.streamRepo.CreateOrUpdate(stream)
+	if errors.Is(err, repository.ErrStreamNameTaken) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Another stream of this race is named Main",
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update stream",
 		})
 	}
 
+	// An existing stream keeps its status, which has to go through a
+	// transition
+	if req.Status != "" && stream.Status != req.Status {
+		if done := h.transitionStream(c, raceID, "", req.Status); done {
+			return nil
+		}
+		if stream, ok = loadStreamOr404(c, h.streamRepo, raceID, "Stream not found"); !ok {
+			return nil
+		}
+	}
+
 	return c.Status(fiber.StatusOK).JSON(stream)
 }
 
+// UpdateStreamStatus moves a race's primary stream, or the feed given by
+// streamId, to a new status (admin only)
+// PUT /admin/races/:id/stream/status
+// PUT /admin/races/:id/streams/:streamId/status
 func (h *AdminHandler) UpdateStreamStatus(c *fiber.Ctx) error {
 	raceID, ok := requireParam(c, "id", "Race ID is required")
 	if !ok {
@@ -389,6 +397,13 @@ func (h *AdminHandler) UpdateStreamStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	streamID := c.Params("streamId")
+	if streamID != "" && !middleware.ValidateUUID(streamID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid stream ID format",
+		})
+	}
+
 	var req struct {
 		Status string `json:"status"`
 	}
@@ -397,33 +412,101 @@ func (h *AdminHandler) UpdateStreamStatus(c *fiber.Ctx) error {
 	}
 
 	// Validate status
-	validStatuses := map[string]bool{
-		"offline":  true,
-		"live":     true,
-		"upcoming": true,
-	}
-	if !validStatuses[req.Status] {
+	if !models.IsValidStreamStatus(req.Status) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status. Must be one of: offline, live, upcoming",
+			"error": invalidStreamStatusMessage,
 		})
 	}
 
-	if err := h.streamRepo.UpdateStatus(raceID, req.Status); err != nil {
-		if err.Error() == "stream not found" {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Stream not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update stream status",
-		})
+	if done := h.transitionStream(c, raceID, streamID, req.Status); done {
+		return nil
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Stream status updated successfully",
+		"status":  req.Status,
 	})
 }
 
+const invalidStreamStatusMessage = "Invalid status. Must be one of: scheduled, pre_show, live, ended, vod_ready, failed"
+
+// validate checks the stream type and source, defaulting the type to hls,
+// and sanitizes them. It sends an error response and returns false when they
+// are invalid.
+func (req *streamSourceRequest) validate(c *fiber.Ctx) bool {
+	// Validate stream type
+	if req.StreamType == "" {
+		req.StreamType = "hls"
+	}
+	validTypes := map[string]bool{
+		"hls":     true,
+		"youtube": true,
+	}
+	if !validTypes[req.StreamType] {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid stream type. Must be one of: hls, youtube",
+		})
+		return false
+	}
+
+	// Validate source_id for youtube
+	if req.StreamType == "youtube" && (req.SourceID == nil || *req.SourceID == "") {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Source ID is required for YouTube streams",
+		})
+		return false
+	}
+
+	// Sanitize URLs if provided
+	if req.OriginURL != nil {
+		sanitized := middleware.SanitizeString(*req.OriginURL, 500)
+		req.OriginURL = &sanitized
+	}
+	if req.CDNURL != nil {
+		sanitized := middleware.SanitizeString(*req.CDNURL, 500)
+		req.CDNURL = &sanitized
+	}
+	if req.SourceID != nil {
+		sanitized := middleware.SanitizeString(*req.SourceID, 255)
+		req.SourceID = &sanitized
+	}
+	return true
+}
+
+// transitionStream moves a race's stream, the primary one unless streamID is
+// given, to status and announces the change. It sends an error response and
+// returns true when the transition failed.
+func (h *AdminHandler) transitionStream(c *fiber.Ctx, raceID, streamID, status string) bool {
+	var change *models.StreamStatusChange
+	var err error
+	if streamID == "" {
+		change, err = h.streamRepo.UpdateStatus(raceID, status)
+	} else {
+		change, err = h.streamRepo.UpdateFeedStatus(raceID, streamID, status)
+	}
+	if errors.Is(err, repository.ErrStreamNotFound) {
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stream not found",
+		})
+		return true
+	}
+	if errors.Is(err, repository.ErrInvalidStreamTransition) {
+		_ = c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+		return true
+	}
+	if err != nil {
+		_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update stream status",
+		})
+		return true
+	}
+
+	h.statusListener.notify(change)
+	return false
+}
+
 // GetRevenue gets all revenue data, optionally filtered by year and month
 func (h *AdminHandler) GetRevenue(c *fiber.Ctx) error {
 	var year, month *int
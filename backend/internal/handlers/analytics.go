@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/cyclingstream/backend/internal/middleware"
 	"github.com/cyclingstream/backend/internal/models"
 	"github.com/cyclingstream/backend/internal/repository"
 	"github.com/gofiber/fiber/v2"
@@ -214,6 +215,7 @@ func (h *AnalyticsHandler) GetRevenueAnalytics(c *fiber.Ctx) error {
 }
 
 // GetStreamAnalytics computes per-stream metrics (overall) from playback events.
+// race_id limits the results to the feeds of one race.
 func (h *AnalyticsHandler) GetStreamAnalytics(c *fiber.Ctx) error {
 	streamID := c.Query("stream_id")
 	var since *time.Time
@@ -225,14 +227,31 @@ func (h *AnalyticsHandler) GetStreamAnalytics(c *fiber.Ctx) error {
 
 	// If no specific stream is requested, aggregate for all streams with events.
 	if streamID == "" {
-		streams, err := h.streamRepo.GetAll()
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "failed to load streams"})
+		var streamIDs []string
+		if raceID := c.Query("race_id"); raceID != "" {
+			if !middleware.ValidateUUID(raceID) {
+				return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "invalid race_id"})
+			}
+			feeds, err := h.streamRepo.ListByRaceID(raceID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "failed to load streams"})
+			}
+			for _, s := range feeds {
+				streamIDs = append(streamIDs, s.ID)
+			}
+		} else {
+			streams, err := h.streamRepo.GetAll()
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "failed to load streams"})
+			}
+			for _, s := range streams {
+				streamIDs = append(streamIDs, s.ID)
+			}
 		}
 
-		results := make([]models.StreamStats, 0, len(streams))
-		for _, s := range streams {
-			stats, err := h.aggregator.AggregateStream(c.Context(), s.ID, since)
+		results := make([]models.StreamStats, 0, len(streamIDs))
+		for _, id := range streamIDs {
+			stats, err := h.aggregator.AggregateStream(c.Context(), id, since)
 			if err != nil {
 				// skip streams with no events or transient errors, but keep going
 				continue
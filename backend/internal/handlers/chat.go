@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
@@ -18,6 +17,13 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	defaultChatHistoryLimit = 50
+	maxChatHistoryLimit     = 100
+	// Messages sent in the history frame when a client joins
+	joinHistoryLimit = 50
+)
+
 type ChatHandler struct {
 	chatRepo        *repository.ChatRepository
 	raceRepo        *repository.RaceRepository
@@ -28,6 +34,15 @@ type ChatHandler struct {
 	rateLimiter     *chat.RateLimiter
 	missionTriggers *services.MissionTriggers
 	pollManager     *chat.PollManager
+	moderationRepo  *repository.ChatModerationRepository
+	emoteRepo       *repository.ChatEmoteRepository
+	emotes          *chat.EmoteRegistry
+	partyRepo       *repository.ChatPartyRepository
+	reactionRepo    *repository.ChatReactionRepository
+	reactions       *chat.ReactionAggregator
+	retentionRepo   *repository.ChatRetentionRepository
+	slowMode        *chat.SlowMode
+	filters         *chat.FilterCache
 }
 
 func NewChatHandler(
@@ -40,6 +55,13 @@ func NewChatHandler(
 	rateLimiter *chat.RateLimiter,
 	missionTriggers *services.MissionTriggers,
 	pollManager *chat.PollManager,
+	moderationRepo *repository.ChatModerationRepository,
+	emoteRepo *repository.ChatEmoteRepository,
+	emotes *chat.EmoteRegistry,
+	partyRepo *repository.ChatPartyRepository,
+	reactionRepo *repository.ChatReactionRepository,
+	reactions *chat.ReactionAggregator,
+	retentionRepo *repository.ChatRetentionRepository,
 ) *ChatHandler {
 	return &ChatHandler{
 		chatRepo:        chatRepo,
@@ -51,6 +73,15 @@ func NewChatHandler(
 		rateLimiter:     rateLimiter,
 		missionTriggers: missionTriggers,
 		pollManager:     pollManager,
+		moderationRepo:  moderationRepo,
+		emoteRepo:       emoteRepo,
+		emotes:          emotes,
+		partyRepo:       partyRepo,
+		reactionRepo:    reactionRepo,
+		reactions:       reactions,
+		retentionRepo:   retentionRepo,
+		slowMode:        chat.NewSlowMode(),
+		filters:         chat.NewFilterCache(),
 	}
 }
 
@@ -87,6 +118,8 @@ func (h *ChatHandler) HandleWebSocket(c *fiber.Ctx) error {
 	// Get user info from locals (set by middleware)
 	userID, _ := c.Locals("user_id").(string)
 	isAdmin, _ := c.Locals("is_admin").(bool)
+	// Party rooms authorized by ChatAuthMiddleware
+	extraRooms, _ := c.Locals("chat_rooms").([]string)
 
 	// Verify race exists
 	race, err := h.raceRepo.GetByID(raceID)
@@ -123,7 +156,8 @@ func (h *ChatHandler) HandleWebSocket(c *fiber.Ctx) error {
 		})
 	}
 
-	// Upgrade connection
+	// Upgrade connection. Clients may negotiate MessagePack frames with the
+	// Sec-WebSocket-Protocol header; the default is JSON.
 	return websocket.New(func(conn *websocket.Conn) {
 		var username string
 		var userIDPtr *string
@@ -135,24 +169,42 @@ func (h *ChatHandler) HandleWebSocket(c *fiber.Ctx) error {
 			user, err := h.userRepo.GetByID(userID)
 			if err == nil && user != nil {
 				currentUser = user
-				if user.Name != nil && *user.Name != "" {
-					username = *user.Name
-				} else {
-					// Use email as fallback
-					username = user.Email
-				}
-			} else {
-				username = "User"
 			}
+			username = chatDisplayName(currentUser, "User")
 		} else {
 			username = "Anonymous"
 		}
 
+		// Reactions and typing indicators reuse ticket and restriction lookups
+		// for the connection
+		reactionCache := &reactionAccess{}
+
 		// Create message handler
 		messageHandler := func(client *chat.Client, msg *chat.WSMessage) {
+			if msg.Type == string(chat.MessageTypeReaction) {
+				h.handleReaction(client, client.RaceID(), msg, currentUser, reactionCache)
+				return
+			}
+
 			// Handle send_message type
 			if msg.Type == string(chat.MessageTypeSendMessage) {
 				h.handleSendMessage(client, client.RaceID(), msg, userIDPtr, username, currentUser)
+				return
+			}
+
+			if msg.Type == string(chat.MessageTypeTyping) {
+				h.handleTyping(client, client.RaceID(), userIDPtr, username, reactionCache)
+				return
+			}
+
+			if msg.Type == string(chat.MessageTypeWhisper) {
+				h.handleWhisper(client, client.RaceID(), msg, userIDPtr, username, currentUser)
+				return
+			}
+
+			// Moderator commands (admin only)
+			if isModerationCommand(msg.Type) {
+				h.handleModerationCommand(client, msg)
 			}
 		}
 
@@ -160,33 +212,55 @@ func (h *ChatHandler) HandleWebSocket(c *fiber.Ctx) error {
 		onClose := func(client *chat.Client) {
 			// Send left message to room
 			leftMsg := chat.NewLeftWSMessage(username)
-			if leftBytes, err := json.Marshal(leftMsg); err == nil {
-				h.hub.BroadcastToRoom(client.RaceID(), leftBytes)
-			}
+			h.hub.BroadcastToRoom(client.RaceID(), leftMsg)
 
 			// Leave room - use method directly since channels are package-private
 			h.hub.LeaveRoom(client, client.RaceID())
+			for _, room := range extraRooms {
+				h.hub.LeaveRoom(client, room)
+			}
 		}
 
 		// Create client with message handler and onClose callback
 		client := chat.NewClient(h.hub, conn, userIDPtr, username, isAdmin, raceID, messageHandler, onClose)
+		// The subscription is looked up once per connection; the client's
+		// role, badges, rate limit and emotes all follow from it
+		client.SetSubscribed(!isAdmin && h.hasActiveSubscription(currentUser))
+		if userIDPtr != nil {
+			client.SetRoleAndBadges(chatRoleAndBadges(currentUser, isAdmin, client.IsSubscribed()))
+		}
 
 		// Start client (registers with hub and starts pumps)
 		// Note: Start() registers the client and then blocks on readPump()
 		// We need to join the room before readPump blocks, so we do it here
 		// JoinRoom is thread-safe and doesn't require the client to be registered first
 		h.hub.JoinRoom(client, raceID)
+		for _, room := range extraRooms {
+			h.hub.JoinRoom(client, room)
+		}
+
+		h.sendJoinHistory(client, raceID)
+		h.sendPresenceSnapshot(client, raceID)
+
+		// Catch late joiners up on polls already running
+		if h.pollManager != nil {
+			polls, err := h.pollManager.GetActivePolls(raceID)
+			if err != nil {
+				logger.WithError(err).WithField("race_id", raceID).Warn("Failed to load active polls")
+			}
+			for _, poll := range polls {
+				client.Send(chat.NewPollAnnouncementMessage(poll))
+			}
+		}
 
 		// Send joined message to room
 		joinedMsg := chat.NewJoinedWSMessage(username)
-		if joinedBytes, err := json.Marshal(joinedMsg); err == nil {
-			h.hub.BroadcastToRoom(client.RaceID(), joinedBytes)
-		}
+		h.hub.BroadcastToRoom(client.RaceID(), joinedMsg)
 
 		// Start client (registers with hub and starts pumps)
 		// readPump will block until connection closes
 		client.Start()
-	})(c)
+	}, websocket.Config{Subprotocols: chat.Subprotocols})(c)
 }
 
 // handleSendMessage processes a send_message request
@@ -205,19 +279,13 @@ func (h *ChatHandler) handleSendMessage(client *chat.Client, raceID string, msg
 			"user_id":       userID,
 			"client_race":   client.RaceID(),
 		}).Warn("Rejected chat message with invalid race_id")
-		errorMsg := chat.NewErrorWSMessage("Invalid race")
-		if errorBytes, marshalErr := json.Marshal(errorMsg); marshalErr == nil {
-			client.SendMessage(errorBytes)
-		}
+		sendWSError(client, "Invalid race")
 		return
 	}
 
 	// Anonymous users cannot send messages
 	if userID == nil {
-		errorMsg := chat.NewErrorWSMessage("Authentication required to send messages")
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
-		}
+		sendWSError(client, "Authentication required to send messages")
 		return
 	}
 
@@ -225,38 +293,57 @@ func (h *ChatHandler) handleSendMessage(client *chat.Client, raceID string, msg
 	sendData, err := chat.ParseSendMessageData(msg)
 	if err != nil {
 		logger.WithError(err).Error("Failed to parse send message data")
-		errorMsg := chat.NewErrorWSMessage("Invalid message data")
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
-		}
+		sendWSError(client, "Invalid message data")
 		return
 	}
 
 	if sendData == nil {
-		errorMsg := chat.NewErrorWSMessage("Invalid message format")
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
-		}
+		sendWSError(client, "Invalid message format")
 		return
 	}
 
 	// Validate message
 	validatedMessage, err := chat.ValidateMessage(sendData.Message)
 	if err != nil {
-		errorMsg := chat.NewErrorWSMessage(err.Error())
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
+		sendWSError(client, err.Error())
+		return
+	}
+
+	if sendData.Room != "" {
+		roomType, roomRef, ok := chat.ParseRoomName(sendData.Room)
+		if !ok || (roomType == chat.RoomTypeRace && roomRef != raceID) {
+			sendWSError(client, "Invalid chat room")
+			return
+		}
+		if roomType == chat.RoomTypeParty {
+			h.handlePartyMessage(client, raceID, strings.ToLower(roomRef), *userID, username, user, validatedMessage)
+			return
 		}
+	}
+
+	// Enforce timeouts and bans
+	if reason, allowed := h.checkSendAllowed(client, raceID, *userID); !allowed {
+		sendWSError(client, reason)
 		return
 	}
 
-	// Check rate limit
-	identifier := *userID
-	if !h.rateLimiter.CheckRateLimit(identifier) {
-		errorMsg := chat.NewErrorWSMessage("Rate limit exceeded. Please wait before sending another message.")
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
+	isAdmin := client != nil && client.IsAdmin()
+
+	// Run the race's content filter. Masked text replaces the message;
+	// flagged messages are sent and queued for review after they're stored.
+	originalMessage := validatedMessage
+	var filtered chat.FilterResult
+	if !isAdmin {
+		filtered = h.messageFilter(raceID).Run(validatedMessage)
+		if filtered.Action == models.ChatFilterReject {
+			sendWSError(client, "Your message was blocked by the chat filter.")
+			return
 		}
+		validatedMessage = filtered.Message
+	}
+
+	// Enforce the sender's rate limit, slow mode and duplicate suppression
+	if !h.checkRateLimit(client, raceID, *userID, user, validatedMessage) {
 		return
 	}
 
@@ -268,7 +355,27 @@ func (h *ChatHandler) handleSendMessage(client *chat.Client, raceID string, msg
 		Message:  validatedMessage,
 	}
 
-	h.applyMessageMetadata(chatMsg, user, client != nil && client.IsAdmin())
+	if sendData.ReplyToID != nil && *sendData.ReplyToID != "" {
+		replyTo, err := h.resolveReplyTarget(raceID, *sendData.ReplyToID)
+		if err != nil {
+			var validationErr *chat.ValidationError
+			if errors.As(err, &validationErr) {
+				sendWSError(client, validationErr.Message)
+			} else {
+				logger.WithError(err).WithField("race_id", raceID).Error("Failed to load reply target")
+				sendWSError(client, "Failed to send message. Please try again.")
+			}
+			return
+		}
+		chatMsg.ReplyToID = &replyTo.ID
+		chatMsg.ReplyTo = replyTo
+	}
+
+	chatMsg.Mentions = h.resolveMentions(raceID, *userID, validatedMessage)
+
+	chatMsg.Emotes = h.parseMessageEmotes(raceID, validatedMessage, user, isAdmin, client.IsSubscribed())
+
+	setMessageMetadata(chatMsg, client.Role(), client.Badges())
 
 	// Save to database with retry logic for transient errors
 	var dbErr error
@@ -309,17 +416,18 @@ func (h *ChatHandler) handleSendMessage(client *chat.Client, raceID string, msg
 		}).Error("Failed to create chat message in database after retries")
 
 		// Send a generic error message to the client to avoid leaking internal details
-		errorMsg := chat.NewErrorWSMessage("Failed to send message. Please try again.")
-		if errorBytes, err := json.Marshal(errorMsg); err == nil {
-			client.SendMessage(errorBytes)
-		}
+		sendWSError(client, "Failed to send message. Please try again.")
 		return
 	}
 
 	// Broadcast to room
 	wsMsg := chat.NewMessageWSMessage(chatMsg)
-	if wsBytes, err := json.Marshal(wsMsg); err == nil {
-		h.hub.BroadcastToRoom(raceID, wsBytes)
+	h.hub.BroadcastToRoom(raceID, wsMsg)
+
+	h.notifyMentions(chatMsg)
+
+	if filtered.Action == models.ChatFilterFlag {
+		h.queueFlaggedMessage(chatMsg, originalMessage, filtered.Matches)
 	}
 
 	// Trigger mission progress updates for chat messages
@@ -357,29 +465,33 @@ func (h *ChatHandler) GetChatHistory(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get limit and offset from query params
-	limit := 50
-	offset := 0
-
+	limit := defaultChatHistoryLimit
 	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxChatHistoryLimit {
 			limit = parsed
 		}
 	}
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
-			offset = parsed
+	// before is a message ID or an RFC3339 timestamp; pages end just before it
+	var cursor *models.ChatHistoryCursor
+	if before := c.Query("before"); before != "" {
+		parsed, err := parseChatHistoryCursor(before)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid before parameter: expected a message ID or RFC3339 timestamp",
+			})
 		}
+		cursor = parsed
 	}
 
-	messages, err := h.chatRepo.GetByRaceID(raceID, limit, offset)
+	// Fetch one extra message to know whether an older page exists
+	messages, err := h.chatRepo.GetBeforeByRaceID(raceID, cursor, limit+1)
 	if err != nil {
 		logger.WithFields(map[string]interface{}{
 			"error":   err.Error(),
 			"race_id": raceID,
 			"limit":   limit,
-			"offset":  offset,
+			"before":  c.Query("before"),
 		}).Error("Failed to get chat history")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch chat history",
@@ -391,15 +503,58 @@ func (h *ChatHandler) GetChatHistory(c *fiber.Ctx) error {
 		messages = []*models.ChatMessage{}
 	}
 
+	hasMore := len(messages) > limit
+	if hasMore {
+		// Messages are oldest first, so the extra one is at the front
+		messages = messages[1:]
+	}
+
 	h.hydrateMessageMetadata(messages)
 
+	var nextBefore *string
+	if hasMore && len(messages) > 0 {
+		nextBefore = &messages[0].ID
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"messages": messages,
-		"limit":    limit,
-		"offset":   offset,
+		"messages":    messages,
+		"limit":       limit,
+		"has_more":    hasMore,
+		"next_before": nextBefore,
 	})
 }
 
+func parseChatHistoryCursor(before string) (*models.ChatHistoryCursor, error) {
+	if _, err := uuid.Parse(before); err == nil {
+		return &models.ChatHistoryCursor{MessageID: before}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, before)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ChatHistoryCursor{Before: t}, nil
+}
+
+// sendJoinHistory sends the latest messages and the pinned message to a
+// client that just joined.
+// The client joins the room first so nothing sent in between is missed;
+// clients de-duplicate by message ID.
+func (h *ChatHandler) sendJoinHistory(client *chat.Client, raceID string) {
+	messages, err := h.chatRepo.GetRecentByRaceID(raceID, joinHistoryLimit+1)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Warn("Failed to load chat history for joining client")
+		return
+	}
+
+	hasMore := len(messages) > joinHistoryLimit
+	if hasMore {
+		messages = messages[1:]
+	}
+	h.hydrateMessageMetadata(messages)
+
+	client.Send(chat.NewHistoryWSMessage(messages, hasMore, h.currentPin(raceID)))
+}
+
 // GetChatStats returns chat statistics for a race
 func (h *ChatHandler) GetChatStats(c *fiber.Ctx) error {
 	raceID, ok := requireParam(c, "id", "Race ID is required")
@@ -429,18 +584,25 @@ func (h *ChatHandler) applyMessageMetadata(msg *models.ChatMessage, user *models
 		return
 	}
 
+	role, badges := h.resolveRoleAndBadges(user, isAdmin)
+	setMessageMetadata(msg, role, badges)
+}
+
+// setMessageMetadata sets the sender's role and badges on a message
+func setMessageMetadata(msg *models.ChatMessage, role string, badges []string) {
 	if msg.Badges == nil {
 		msg.Badges = []string{}
 	}
 
-	role, badges := h.resolveRoleAndBadges(user, isAdmin)
 	msg.Role = role
 	if len(msg.Badges) == 0 && len(badges) > 0 {
 		msg.Badges = badges
 	} else if len(badges) > 0 {
 		msg.Badges = dedupeStrings(append(msg.Badges, badges...))
 	}
-	msg.SpecialEmote = chat.IsSpecialEmoteMessage(msg.Message)
+	if len(msg.Emotes) > 0 {
+		msg.SpecialEmote = chat.IsEmoteOnly(msg.Message, msg.Emotes)
+	}
 }
 
 func (h *ChatHandler) hydrateMessageMetadata(messages []*models.ChatMessage) {
@@ -448,6 +610,8 @@ func (h *ChatHandler) hydrateMessageMetadata(messages []*models.ChatMessage) {
 		return
 	}
 
+	h.attachThreadContext(messages)
+
 	missing := make(map[string]struct{})
 	for _, msg := range messages {
 		if msg == nil || msg.UserID == nil || msg.Role != "" {
@@ -501,6 +665,28 @@ func (h *ChatHandler) hydrateMessageMetadata(messages []*models.ChatMessage) {
 }
 
 func (h *ChatHandler) resolveRoleAndBadges(user *models.User, isAdmin bool) (string, []string) {
+	if isAdmin || user == nil {
+		return chatRoleAndBadges(user, isAdmin, false)
+	}
+	return chatRoleAndBadges(user, isAdmin, h.hasActiveSubscription(user))
+}
+
+// hasActiveSubscription reports whether the user has an active subscription
+func (h *ChatHandler) hasActiveSubscription(user *models.User) bool {
+	if h.entitlementRepo == nil || user == nil || user.ID == "" {
+		return false
+	}
+	ok, err := h.entitlementRepo.HasActiveSubscription(user.ID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to check subscription for chat role")
+		return false
+	}
+	return ok
+}
+
+// chatRoleAndBadges returns the user's chat role and badges given whether
+// they have an active subscription
+func chatRoleAndBadges(user *models.User, isAdmin, subscribed bool) (string, []string) {
 	if isAdmin {
 		return "mod", []string{"mod"}
 	}
@@ -514,17 +700,7 @@ func (h *ChatHandler) resolveRoleAndBadges(user *models.User, isAdmin bool) (str
 		badges = append(badges, "vip")
 	}
 
-	hasSubscription := false
-	if h.entitlementRepo != nil && user.ID != "" {
-		if ok, err := h.entitlementRepo.HasActiveSubscription(user.ID); err == nil && ok {
-			hasSubscription = true
-			badges = append(badges, "sub")
-		} else if err != nil {
-			logger.WithError(err).Warn("Failed to check subscription for chat role")
-		}
-	}
-
-	if !hasSubscription && user.Points >= 500 {
+	if subscribed || user.Points >= 500 {
 		badges = append(badges, "sub")
 	}
 
@@ -542,6 +718,18 @@ func (h *ChatHandler) resolveRoleAndBadges(user *models.User, isAdmin bool) (str
 	return role, dedupeStrings(badges)
 }
 
+// chatDisplayName is the name a user chats under: their name, or their email
+// when they have none. fallback is used when there is no user.
+func chatDisplayName(user *models.User, fallback string) string {
+	if user == nil {
+		return fallback
+	}
+	if user.Name != nil && *user.Name != "" {
+		return *user.Name
+	}
+	return user.Email
+}
+
 func containsString(items []string, target string) bool {
 	for _, item := range items {
 		if item == target {
@@ -571,13 +759,23 @@ func dedupeStrings(items []string) []string {
 type createPollRequest struct {
 	Question        string   `json:"question"`
 	Options         []string `json:"options"`
+	Type            string   `json:"type,omitempty"`
+	MaxChoices      int      `json:"max_choices,omitempty"`
 	DurationSeconds *int     `json:"duration_seconds,omitempty"`
 }
 
+// votePollRequest accepts option_id for single-choice polls, or option_ids
+// for multi-select and ranked polls (ranked in order of preference)
 type votePollRequest struct {
-	OptionID string `json:"option_id"`
+	OptionID  string   `json:"option_id"`
+	OptionIDs []string `json:"option_ids"`
 }
 
+const (
+	defaultPollHistoryLimit = 20
+	maxPollHistoryLimit     = 100
+)
+
 func (h *ChatHandler) CreatePoll(c *fiber.Ctx) error {
 	if h.pollManager == nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Poll manager unavailable"})
@@ -601,14 +799,23 @@ func (h *ChatHandler) CreatePoll(c *fiber.Ctx) error {
 		duration = time.Duration(*req.DurationSeconds) * time.Second
 	}
 
-	poll, err := h.pollManager.CreatePoll(raceID, req.Question, req.Options, duration)
+	spec := chat.PollSpec{
+		Question:   req.Question,
+		Options:    req.Options,
+		Type:       req.Type,
+		MaxChoices: req.MaxChoices,
+		Duration:   duration,
+	}
+	if userID, _ := c.Locals("user_id").(string); userID != "" {
+		spec.CreatedBy = &userID
+	}
+
+	poll, err := h.pollManager.CreatePoll(raceID, spec)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: err.Error()})
 	}
 
-	if pollBytes, err := json.Marshal(chat.NewPollAnnouncementMessage(poll)); err == nil {
-		h.hub.BroadcastToRoom(raceID, pollBytes)
-	}
+	h.broadcastWS(raceID, chat.NewPollAnnouncementMessage(poll))
 
 	return c.Status(fiber.StatusCreated).JSON(poll)
 }
@@ -636,23 +843,24 @@ func (h *ChatHandler) CastPollVote(c *fiber.Ctx) error {
 	if !parseBody(c, &req) {
 		return nil
 	}
-	if req.OptionID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "option_id is required"})
+	optionIDs := req.OptionIDs
+	if len(optionIDs) == 0 && req.OptionID != "" {
+		optionIDs = []string{req.OptionID}
+	}
+	if len(optionIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "option_id or option_ids is required"})
 	}
 
-	poll, exists := h.pollManager.GetPoll(pollID)
-	if !exists || poll.RaceID != raceID {
-		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Poll not found"})
+	if !h.requireActivePoll(c, raceID, pollID) {
+		return nil
 	}
 
-	updated, err := h.pollManager.Vote(pollID, userID, req.OptionID)
+	updated, err := h.pollManager.Vote(pollID, userID, optionIDs)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: err.Error()})
 	}
 
-	if pollBytes, err := json.Marshal(chat.NewPollUpdateMessage(updated)); err == nil {
-		h.hub.BroadcastToRoom(raceID, pollBytes)
-	}
+	h.broadcastWS(raceID, chat.NewPollUpdateMessage(updated))
 
 	return c.Status(fiber.StatusOK).JSON(updated)
 }
@@ -671,18 +879,88 @@ func (h *ChatHandler) ClosePoll(c *fiber.Ctx) error {
 		return nil
 	}
 
+	if !h.requireActivePoll(c, raceID, pollID) {
+		return nil
+	}
+
 	poll, err := h.pollManager.ClosePoll(pollID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: err.Error()})
 	}
 
-	if pollBytes, err := json.Marshal(chat.NewPollClosedMessage(poll)); err == nil {
-		h.hub.BroadcastToRoom(raceID, pollBytes)
-	}
+	h.broadcastWS(raceID, chat.NewPollClosedMessage(poll))
 
 	return c.Status(fiber.StatusOK).JSON(poll)
 }
 
+// requireActivePoll checks that the poll is open and belongs to the race. It
+// sends an error response and returns false when it isn't.
+func (h *ChatHandler) requireActivePoll(c *fiber.Ctx, raceID, pollID string) bool {
+	poll, err := h.pollManager.GetPoll(pollID)
+	if err != nil {
+		logger.WithError(err).WithField("poll_id", pollID).Error("Failed to load poll")
+		_ = c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to load poll"})
+		return false
+	}
+	if poll == nil || poll.Closed || poll.RaceID != raceID {
+		_ = c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Poll not found"})
+		return false
+	}
+	return true
+}
+
+// GetPollHistory returns recent polls for a race with their tallies
+// GET /races/:id/chat/polls?limit=20
+func (h *ChatHandler) GetPollHistory(c *fiber.Ctx) error {
+	if h.pollManager == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Poll manager unavailable"})
+	}
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+
+	limit := defaultPollHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid limit parameter"})
+		}
+		if parsed > maxPollHistoryLimit {
+			parsed = maxPollHistoryLimit
+		}
+		limit = parsed
+	}
+
+	polls, err := h.pollManager.History(raceID, limit)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to load poll history")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to retrieve polls"})
+	}
+
+	return c.JSON(fiber.Map{
+		"polls": polls,
+		"total": len(polls),
+	})
+}
+
+// BroadcastPollClosed announces a poll closed by the background closer
+func (h *ChatHandler) BroadcastPollClosed(poll *models.ChatPoll) {
+	h.broadcastWS(poll.RaceID, chat.NewPollClosedMessage(poll))
+}
+
+// GetChatDeliveryStats returns the send queue counters of this instance's
+// chat rooms and of the hub as a whole (admin only)
+// GET /admin/chat/delivery-stats
+func (h *ChatHandler) GetChatDeliveryStats(c *fiber.Ctx) error {
+	return c.JSON(h.hub.DeliveryStats())
+}
+
+// BroadcastStreamStatus tells a race's chat room that its stream changed status
+func (h *ChatHandler) BroadcastStreamStatus(change *models.StreamStatusChange) {
+	h.broadcastWS(change.RaceID, chat.NewStreamStatusWSMessage(change))
+}
+
 // isRetryableDBError checks if a database error is retryable (transient)
 // Returns true for connection errors, deadlocks, and other transient issues
 func isRetryableDBError(err error) bool {
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type chatEmoteRequest struct {
+	Code           string  `json:"code"`
+	ImageURL       *string `json:"image_url"`
+	RaceID         *string `json:"race_id"`
+	MinLevel       int     `json:"min_level"`
+	RequiresTicket bool    `json:"requires_ticket"`
+}
+
+// parseMessageEmotes finds the emotes in a message that the sender may use.
+// subscribed is whether the sender had an active subscription when they
+// connected.
+func (h *ChatHandler) parseMessageEmotes(raceID, message string, user *models.User, isAdmin, subscribed bool) []models.ChatEmoteSpan {
+	if h.emotes == nil {
+		return nil
+	}
+	if isAdmin {
+		return h.emotes.ParseEmotes(raceID, message, nil)
+	}
+
+	level := 0
+	if user != nil {
+		level = user.Level
+	}
+
+	// The ticket lookup only runs when the message uses a ticket-gated emote
+	ticketChecked, hasTicket := false, false
+	return h.emotes.ParseEmotes(raceID, message, func(emote *models.ChatEmote) bool {
+		if emote.RequiresTicket && !ticketChecked && user != nil {
+			ticketChecked = true
+			hasTicket = h.hasRaceTicket(user.ID, raceID, subscribed)
+		}
+		return chat.CanUseEmote(emote, level, hasTicket)
+	})
+}
+
+// hasRaceTicket reports whether a user has an active subscription, given as
+// subscribed, or an entitlement for the race
+func (h *ChatHandler) hasRaceTicket(userID, raceID string, subscribed bool) bool {
+	if subscribed {
+		return true
+	}
+	if h.entitlementRepo == nil || userID == "" {
+		return false
+	}
+
+	entitlement, err := h.entitlementRepo.GetByUserAndRace(userID, raceID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to check race entitlement for chat emotes")
+		return false
+	}
+	return entitlement != nil
+}
+
+// GetRaceEmotes returns the emotes available in a race's chat
+// GET /races/:id/chat/emotes
+func (h *ChatHandler) GetRaceEmotes(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	emotes := []*models.ChatEmote{}
+	if h.emotes != nil {
+		emotes = h.emotes.Available(raceID)
+	}
+
+	return c.JSON(fiber.Map{"emotes": emotes})
+}
+
+// ListChatEmotes returns every emote, optionally only those of one race
+// GET /admin/chat/emotes?race_id=
+func (h *ChatHandler) ListChatEmotes(c *fiber.Ctx) error {
+	emotes, err := h.emoteRepo.ListAll()
+	if err != nil {
+		logger.WithError(err).Error("Failed to list chat emotes")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch emotes"})
+	}
+
+	raceID := c.Query("race_id")
+	filtered := make([]*models.ChatEmote, 0, len(emotes))
+	for _, emote := range emotes {
+		if raceID == "" || (emote.RaceID != nil && *emote.RaceID == raceID) {
+			filtered = append(filtered, emote)
+		}
+	}
+
+	return c.JSON(fiber.Map{"emotes": filtered})
+}
+
+// CreateChatEmote adds an emote
+// POST /admin/chat/emotes
+func (h *ChatHandler) CreateChatEmote(c *fiber.Ctx) error {
+	var req chatEmoteRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	emote := &models.ChatEmote{}
+	if msg := h.applyEmoteRequest(emote, &req); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: msg})
+	}
+	if emote.ImageURL == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Image URL is required"})
+	}
+	if createdBy, _ := c.Locals("user_id").(string); createdBy != "" {
+		emote.CreatedBy = &createdBy
+	}
+
+	if err := h.emoteRepo.Create(emote); err != nil {
+		return respondEmoteError(c, err)
+	}
+
+	h.refreshEmotes()
+	return c.Status(fiber.StatusCreated).JSON(emote)
+}
+
+// UpdateChatEmote replaces an emote's code, image and gating
+// PUT /admin/chat/emotes/:emoteId
+func (h *ChatHandler) UpdateChatEmote(c *fiber.Ctx) error {
+	emoteID, ok := requireParam(c, "emoteId", "Emote ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(emoteID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid emote ID"})
+	}
+
+	var req chatEmoteRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	emote, err := h.emoteRepo.GetByID(emoteID)
+	if err != nil {
+		logger.WithError(err).WithField("emote_id", emoteID).Error("Failed to get chat emote")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch emote"})
+	}
+	if emote == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Emote not found"})
+	}
+
+	// Built-in emotes have no image; custom emotes must keep one
+	hadImage := emote.ImageURL != nil
+	if msg := h.applyEmoteRequest(emote, &req); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: msg})
+	}
+	if hadImage && emote.ImageURL == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Image URL is required"})
+	}
+
+	updated, err := h.emoteRepo.Update(emote)
+	if err != nil {
+		return respondEmoteError(c, err)
+	}
+	if !updated {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Emote not found"})
+	}
+
+	h.refreshEmotes()
+	return c.JSON(emote)
+}
+
+// DeleteChatEmote removes an emote. Messages that already used it keep their spans.
+// DELETE /admin/chat/emotes/:emoteId
+func (h *ChatHandler) DeleteChatEmote(c *fiber.Ctx) error {
+	emoteID, ok := requireParam(c, "emoteId", "Emote ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(emoteID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid emote ID"})
+	}
+
+	deleted, err := h.emoteRepo.Delete(emoteID)
+	if err != nil {
+		logger.WithError(err).WithField("emote_id", emoteID).Error("Failed to delete chat emote")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to delete emote"})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Emote not found"})
+	}
+
+	h.refreshEmotes()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// applyEmoteRequest validates a create/update request and copies it onto
+// emote. It returns a user-facing message when the request is invalid.
+func (h *ChatHandler) applyEmoteRequest(emote *models.ChatEmote, req *chatEmoteRequest) string {
+	code, ok := chat.NormalizeEmoteCode(req.Code)
+	if !ok {
+		return "Code must be 2-32 letters, digits or underscores"
+	}
+	if req.MinLevel < 0 {
+		return "Minimum level cannot be negative"
+	}
+
+	var imageURL *string
+	if req.ImageURL != nil && strings.TrimSpace(*req.ImageURL) != "" {
+		trimmed := strings.TrimSpace(*req.ImageURL)
+		if !isValidEmoteImageURL(trimmed) {
+			return "Image URL must be an http(s) URL or an absolute path"
+		}
+		imageURL = &trimmed
+	}
+
+	var raceID *string
+	if req.RaceID != nil && *req.RaceID != "" {
+		if _, err := uuid.Parse(*req.RaceID); err != nil {
+			return "Invalid race ID"
+		}
+		race, err := h.raceRepo.GetByID(*req.RaceID)
+		if err != nil || race == nil {
+			return "Race not found"
+		}
+		raceID = req.RaceID
+	}
+
+	emote.Code = code
+	emote.ImageURL = imageURL
+	emote.RaceID = raceID
+	emote.MinLevel = req.MinLevel
+	emote.RequiresTicket = req.RequiresTicket
+	return ""
+}
+
+func isValidEmoteImageURL(raw string) bool {
+	if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") {
+		return true
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+// refreshEmotes reloads the emote cache after a change
+func (h *ChatHandler) refreshEmotes() {
+	if h.emotes == nil {
+		return
+	}
+	if err := h.emotes.Refresh(); err != nil {
+		logger.WithError(err).Warn("Failed to refresh chat emotes")
+	}
+}
+
+func respondEmoteError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, repository.ErrChatEmoteCodeTaken) {
+		return c.Status(fiber.StatusConflict).JSON(APIError{Error: "An emote with this code already exists"})
+	}
+	logger.WithError(err).Error("Failed to save chat emote")
+	return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to save emote"})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRetentionPurgesLimit = 20
+	maxRetentionPurgesLimit     = 100
+)
+
+// ExportChatTranscript streams a race's full chat transcript, deleted
+// messages included, as CSV or JSONL (admin only)
+// GET /admin/races/:id/chat/export?format=csv|jsonl
+func (h *ChatHandler) ExportChatTranscript(c *fiber.Ctx) error {
+	raceIDParam, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceIDParam); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	format := c.Query("format", chat.TranscriptFormatCSV)
+	if format != chat.TranscriptFormatCSV && format != chat.TranscriptFormatJSONL {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "format must be csv or jsonl"})
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceIDParam); !ok {
+		return nil
+	}
+
+	// The body is written after the handler returns, when Fiber may have
+	// reused the parameter's buffer
+	raceID := string([]byte(raceIDParam))
+
+	c.Set(fiber.HeaderContentType, chat.TranscriptContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="chat-%s.%s"`, raceID, format))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		transcript, err := chat.NewTranscriptWriter(w, format)
+		if err == nil {
+			err = h.chatRepo.ForEachTranscriptEntry(raceID, func(entry *models.ChatTranscriptEntry) error {
+				return transcript.Write(entry)
+			})
+		}
+		if err == nil {
+			err = transcript.Flush()
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			// The status has been sent, so the download is just cut short
+			logger.WithError(err).WithField("race_id", raceID).Error("Failed to export chat transcript")
+		}
+	})
+	return nil
+}
+
+// ListChatRetentionPurges returns the most recent runs of the chat retention
+// job (admin only)
+// GET /admin/chat/retention/purges?limit=20
+func (h *ChatHandler) ListChatRetentionPurges(c *fiber.Ctx) error {
+	if h.retentionRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Chat retention unavailable"})
+	}
+
+	limit := defaultRetentionPurgesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxRetentionPurgesLimit {
+			limit = parsed
+		}
+	}
+
+	purges, err := h.retentionRepo.ListPurges(limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list chat retention purges")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to list retention purges"})
+	}
+
+	return c.JSON(fiber.Map{"purges": purges})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultModerationQueueLimit = 50
+	maxModerationQueueLimit     = 200
+)
+
+type reviewQueueItemRequest struct {
+	Status string `json:"status"`
+}
+
+// messageFilter returns the race's content filter, reloading its config from
+// the database when the cached filter is stale
+func (h *ChatHandler) messageFilter(raceID string) *chat.Filter {
+	filter, fresh := h.filters.Get(raceID)
+	if fresh {
+		return filter
+	}
+
+	cfg := chat.DefaultFilterConfig()
+	if h.moderationRepo != nil {
+		stored, err := h.moderationRepo.GetFilterConfig(raceID)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to load chat filter config")
+			if filter != nil {
+				return filter
+			}
+		} else if stored != nil {
+			cfg = *stored
+		}
+	}
+
+	filter = chat.NewFilterFromConfig(cfg)
+	h.filters.Set(raceID, filter)
+	return filter
+}
+
+// queueFlaggedMessage adds a message flagged by the content filter to the
+// moderation queue. original is the text as sent, before masking.
+func (h *ChatHandler) queueFlaggedMessage(msg *models.ChatMessage, original string, matches []models.ChatFilterMatch) {
+	if h.moderationRepo == nil {
+		return
+	}
+
+	item := &models.ChatModerationQueueItem{
+		RaceID:    msg.RaceID,
+		MessageID: &msg.ID,
+		UserID:    msg.UserID,
+		Username:  msg.Username,
+		Message:   original,
+		Matches:   matches,
+	}
+	if err := h.moderationRepo.CreateQueueItem(item); err != nil {
+		logger.WithError(err).WithField("message_id", msg.ID).Error("Failed to queue flagged chat message")
+	}
+}
+
+// GetChatFilter returns a race's content filter config
+// GET /races/:id/chat/moderation/filter
+func (h *ChatHandler) GetChatFilter(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	stored, err := h.moderationRepo.GetFilterConfig(raceID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	cfg := chat.DefaultFilterConfig()
+	if stored != nil {
+		cfg = *stored
+	}
+
+	return c.JSON(fiber.Map{
+		"config":     cfg,
+		"is_default": stored == nil,
+	})
+}
+
+// SetChatFilter replaces a race's content filter config
+// PUT /races/:id/chat/moderation/filter
+func (h *ChatHandler) SetChatFilter(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	var cfg models.ChatFilterConfig
+	if !parseBody(c, &cfg) {
+		return nil
+	}
+	if err := chat.ValidateFilterConfig(&cfg); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: err.Error()})
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	if err := h.moderationRepo.SetFilterConfig(raceID, &cfg, moderatorID); err != nil {
+		return respondModerationError(c, err)
+	}
+	h.filters.Set(raceID, chat.NewFilterFromConfig(cfg))
+
+	return c.JSON(fiber.Map{
+		"config":     cfg,
+		"is_default": false,
+	})
+}
+
+// ResetChatFilter puts a race back on the default content filter
+// DELETE /races/:id/chat/moderation/filter
+func (h *ChatHandler) ResetChatFilter(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	if err := h.moderationRepo.SetFilterConfig(raceID, nil, moderatorID); err != nil {
+		return respondModerationError(c, err)
+	}
+	h.filters.Set(raceID, chat.NewFilterFromConfig(chat.DefaultFilterConfig()))
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListModerationQueue returns messages flagged by the content filter, newest first
+// GET /admin/chat/moderation-queue?status=pending&race_id=uuid&limit=50
+func (h *ChatHandler) ListModerationQueue(c *fiber.Ctx) error {
+	if h.moderationRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Moderation unavailable"})
+	}
+
+	status := c.Query("status", models.ChatQueuePending)
+	switch status {
+	case models.ChatQueuePending, models.ChatQueueApproved, models.ChatQueueRemoved:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Status must be one of: pending, approved, removed"})
+	}
+
+	raceID := c.Query("race_id")
+	if raceID != "" {
+		if _, err := uuid.Parse(raceID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+		}
+	}
+
+	limit := defaultModerationQueueLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxModerationQueueLimit {
+			limit = parsed
+		}
+	}
+
+	items, err := h.moderationRepo.ListQueue(raceID, status, limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list moderation queue")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch moderation queue"})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// ReviewModerationQueueItem approves a flagged message or removes it from the chat
+// POST /admin/chat/moderation-queue/:itemId/review
+func (h *ChatHandler) ReviewModerationQueueItem(c *fiber.Ctx) error {
+	if h.moderationRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Moderation unavailable"})
+	}
+
+	itemID, ok := requireParam(c, "itemId", "Item ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(itemID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid item ID"})
+	}
+
+	var req reviewQueueItemRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	if req.Status != models.ChatQueueApproved && req.Status != models.ChatQueueRemoved {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Status must be approved or removed"})
+	}
+
+	item, err := h.moderationRepo.GetQueueItem(itemID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	if item == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Queue item not found"})
+	}
+	if item.Status != models.ChatQueuePending {
+		return c.Status(fiber.StatusConflict).JSON(APIError{Error: "Queue item already reviewed"})
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	reviewed, err := h.moderationRepo.ReviewQueueItem(itemID, req.Status, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	if !reviewed {
+		return c.Status(fiber.StatusConflict).JSON(APIError{Error: "Queue item already reviewed"})
+	}
+
+	// The message may already be gone if a moderator deleted it directly
+	if req.Status == models.ChatQueueRemoved && item.MessageID != nil {
+		if err := h.deleteMessage(item.RaceID, *item.MessageID, moderatorID); err != nil && !isModerationNotFound(err) {
+			return respondModerationError(c, err)
+		}
+	}
+
+	item, err = h.moderationRepo.GetQueueItem(itemID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	return c.JSON(item)
+}
@@ -3,10 +3,8 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"io"
-	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -33,10 +31,10 @@ func setupTestApp(t *testing.T) (*fiber.App, *ChatHandler, func()) {
 	raceRepo := repository.NewRaceRepository(db)
 	streamRepo := repository.NewStreamRepository(db)
 	userRepo := repository.NewUserRepository(db)
-	rateLimiter := chat.NewRateLimiter()
+	rateLimiter := chat.NewRateLimiter(chat.DefaultRateLimitConfig())
 	defer rateLimiter.Stop()
 
-	handler := NewChatHandler(chatRepo, raceRepo, streamRepo, userRepo, hub, rateLimiter)
+	handler := NewChatHandler(chatRepo, raceRepo, streamRepo, userRepo, nil, hub, rateLimiter, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	app := fiber.New()
 	
@@ -132,8 +130,8 @@ func TestChatHandler_GetChatHistory_Integration(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		// Get first 3
-		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=3&offset=0", nil)
+		// Get newest 3
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=3", nil)
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
@@ -146,9 +144,29 @@ func TestChatHandler_GetChatHistory_Integration(t *testing.T) {
 		require.NoError(t, err)
 
 		messages := result["messages"].([]interface{})
-		assert.LessOrEqual(t, len(messages), 3)
+		assert.Len(t, messages, 3)
 		assert.Equal(t, float64(3), result["limit"])
-		assert.Equal(t, float64(0), result["offset"])
+		assert.Equal(t, true, result["has_more"])
+		nextBefore, ok := result["next_before"].(string)
+		require.True(t, ok)
+
+		// Older page continues from the cursor
+		req = httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=3&before="+nextBefore, nil)
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		var older map[string]interface{}
+		err = json.Unmarshal(body, &older)
+		require.NoError(t, err)
+
+		olderMessages := older["messages"].([]interface{})
+		assert.Len(t, olderMessages, 2)
+		assert.Equal(t, false, older["has_more"])
+		assert.Nil(t, older["next_before"])
 	})
 
 	t.Run("Invalid race ID returns empty array", func(t *testing.T) {
@@ -181,7 +199,7 @@ func TestChatHandler_GetChatHistory_Integration(t *testing.T) {
 	})
 
 	t.Run("Pagination with invalid limit returns default", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=invalid&offset=0", nil)
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=invalid", nil)
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
@@ -197,25 +215,15 @@ func TestChatHandler_GetChatHistory_Integration(t *testing.T) {
 		assert.Equal(t, float64(50), result["limit"])
 	})
 
-	t.Run("Pagination with negative offset uses 0", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=10&offset=-5", nil)
+	t.Run("Invalid before cursor returns 400", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=10&before=yesterday", nil)
 		resp, err := app.Test(req)
 		require.NoError(t, err)
-		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
-
-		body, err := io.ReadAll(resp.Body)
-		require.NoError(t, err)
-
-		var result map[string]interface{}
-		err = json.Unmarshal(body, &result)
-		require.NoError(t, err)
-
-		// Should use 0 for negative offset
-		assert.Equal(t, float64(0), result["offset"])
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 	})
 
 	t.Run("Pagination with limit over max uses max", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=200&offset=0", nil)
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/history?limit=200", nil)
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
@@ -234,7 +242,7 @@ func TestChatHandler_GetChatHistory_Integration(t *testing.T) {
 
 // TestChatHandler_GetChatStats_Integration tests chat stats endpoint with real database
 func TestChatHandler_GetChatStats_Integration(t *testing.T) {
-	app, handler, cleanup := setupTestApp(t)
+	app, _, cleanup := setupTestApp(t)
 	defer cleanup()
 
 	// Setup test data
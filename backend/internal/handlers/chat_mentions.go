@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// Length of the quoted text shown above a reply
+	replyPreviewLength = 100
+
+	defaultRepliesLimit = 50
+	maxRepliesLimit     = 200
+)
+
+// errReplyTargetMissing is returned when a reply points at a message that
+// doesn't exist in the room or has been deleted
+var errReplyTargetMissing = &chat.ValidationError{Message: "The message you are replying to is no longer available"}
+
+// resolveReplyTarget loads the message being replied to and returns its preview
+func (h *ChatHandler) resolveReplyTarget(raceID, replyToID string) (*models.ChatMessageReference, error) {
+	if _, err := uuid.Parse(replyToID); err != nil {
+		return nil, errReplyTargetMissing
+	}
+
+	parent, err := h.chatRepo.GetByID(raceID, replyToID)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, errReplyTargetMissing
+	}
+
+	return newMessageReference(parent.ID, parent.UserID, parent.Username, parent.Message), nil
+}
+
+func newMessageReference(id string, userID *string, username, message string) *models.ChatMessageReference {
+	if runes := []rune(message); len(runes) > replyPreviewLength {
+		message = string(runes[:replyPreviewLength]) + "…"
+	}
+	return &models.ChatMessageReference{
+		ID:       id,
+		UserID:   userID,
+		Username: username,
+		Message:  message,
+	}
+}
+
+// resolveMentions turns the @handles in a message into user IDs. Lookup
+// failures only cost the pings, so they are logged and ignored.
+func (h *ChatHandler) resolveMentions(raceID, senderID, message string) []string {
+	handles := chat.ParseMentions(message)
+	if len(handles) == 0 || h.userRepo == nil {
+		return nil
+	}
+
+	candidates, err := h.userRepo.FindByMentionHandles(handles)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Warn("Failed to resolve chat mentions")
+		return nil
+	}
+
+	return chat.ResolveMentions(handles, candidates, func(userID string) bool {
+		return h.hub.HasUserInRoom(raceID, userID)
+	}, senderID)
+}
+
+// notifyMentions stores mention rows and sends each mentioned user a
+// targeted mention frame in the room
+func (h *ChatHandler) notifyMentions(msg *models.ChatMessage) {
+	if len(msg.Mentions) == 0 {
+		return
+	}
+
+	if err := h.chatRepo.CreateMentions(msg.RaceID, msg.ID, msg.Mentions); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":      err.Error(),
+			"message_id": msg.ID,
+		}).Warn("Failed to store chat mentions")
+	}
+
+	mentionMsg := chat.NewMentionWSMessage(msg)
+	for _, userID := range msg.Mentions {
+		h.hub.SendToUser(msg.RaceID, userID, mentionMsg)
+	}
+}
+
+// attachThreadContext fills in reply previews and mentions for stored messages
+func (h *ChatHandler) attachThreadContext(messages []*models.ChatMessage) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(messages))
+	var replyIDs []string
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+		if msg.ReplyToID != nil {
+			replyIDs = append(replyIDs, *msg.ReplyToID)
+		}
+	}
+
+	if len(replyIDs) > 0 {
+		refs, err := h.chatRepo.GetReferences(replyIDs)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to load chat reply previews")
+		} else {
+			for _, msg := range messages {
+				if msg.ReplyToID == nil {
+					continue
+				}
+				if ref, ok := refs[*msg.ReplyToID]; ok {
+					msg.ReplyTo = newMessageReference(ref.ID, ref.UserID, ref.Username, ref.Message)
+					msg.ReplyTo.Deleted = ref.Deleted
+				}
+			}
+		}
+	}
+
+	mentions, err := h.chatRepo.GetMentions(ids)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to load chat mentions")
+		return
+	}
+	for _, msg := range messages {
+		msg.Mentions = mentions[msg.ID]
+	}
+}
+
+// GetMessageReplies returns the replies to a chat message, oldest first
+// GET /races/:id/chat/messages/:messageId/replies?limit=50
+func (h *ChatHandler) GetMessageReplies(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	messageID, ok := requireParam(c, "messageId", "Message ID is required")
+	if !ok {
+		return nil
+	}
+
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+	if _, err := uuid.Parse(messageID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid message ID"})
+	}
+
+	limit := defaultRepliesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxRepliesLimit {
+			limit = parsed
+		}
+	}
+
+	parent, err := h.chatRepo.GetByID(raceID, messageID)
+	if err != nil {
+		logger.WithError(err).WithField("message_id", messageID).Error("Failed to get chat message")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch message"})
+	}
+	if parent == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Message not found"})
+	}
+
+	replies, err := h.chatRepo.GetReplies(raceID, messageID, limit)
+	if err != nil {
+		logger.WithError(err).WithField("message_id", messageID).Error("Failed to get chat replies")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch replies"})
+	}
+	if replies == nil {
+		replies = []*models.ChatMessage{}
+	}
+
+	all := append([]*models.ChatMessage{parent}, replies...)
+	h.hydrateMessageMetadata(all)
+
+	return c.JSON(fiber.Map{
+		"message": parent,
+		"replies": replies,
+	})
+}
@@ -0,0 +1,559 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultChatTimeout  = 10 * time.Minute
+	maxChatTimeout      = 7 * 24 * time.Hour
+	maxSlowModeInterval = 10 * time.Minute
+)
+
+// chatModerationError is a moderation failure caused by the request itself.
+// Its message is safe to show to the moderator.
+type chatModerationError struct {
+	status  int
+	message string
+}
+
+func (e *chatModerationError) Error() string {
+	return e.message
+}
+
+func badModerationRequest(message string) error {
+	return &chatModerationError{status: fiber.StatusBadRequest, message: message}
+}
+
+// respondModerationError writes a moderation error as a JSON response
+func respondModerationError(c *fiber.Ctx, err error) error {
+	var modErr *chatModerationError
+	if errors.As(err, &modErr) {
+		return c.Status(modErr.status).JSON(APIError{Error: modErr.message})
+	}
+	logger.WithError(err).Error("Chat moderation failed")
+	return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Moderation action failed"})
+}
+
+// isModerationNotFound reports whether a moderation action failed because its target is gone
+func isModerationNotFound(err error) bool {
+	var modErr *chatModerationError
+	return errors.As(err, &modErr) && modErr.status == fiber.StatusNotFound
+}
+
+// broadcastWS sends a WebSocket message to a race room
+func (h *ChatHandler) broadcastWS(raceID string, msg *chat.WSMessage) {
+	h.hub.BroadcastToRoom(raceID, msg)
+}
+
+// sendWSError sends an error frame to a single client
+func sendWSError(client *chat.Client, message string) {
+	client.Send(chat.NewErrorWSMessage(message))
+}
+
+func (h *ChatHandler) deleteMessage(raceID, messageID, moderatorID string) error {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return badModerationRequest("Invalid message ID")
+	}
+
+	deleted, err := h.chatRepo.SoftDelete(raceID, messageID, moderatorID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &chatModerationError{status: fiber.StatusNotFound, message: "Message not found"}
+	}
+
+	h.broadcastWS(raceID, chat.NewMessageDeletedWSMessage(raceID, messageID))
+	h.unpinDeletedMessage(raceID, messageID)
+	return nil
+}
+
+func (h *ChatHandler) restrictUser(raceID, userID, kind string, duration time.Duration, reason *string, global bool, moderatorID string) (*models.ChatRestriction, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, badModerationRequest("Invalid user ID")
+	}
+
+	restriction := &models.ChatRestriction{
+		UserID:      userID,
+		Kind:        kind,
+		Reason:      reason,
+		ModeratorID: &moderatorID,
+	}
+	if !global {
+		restriction.RaceID = &raceID
+	}
+
+	if kind == models.ChatRestrictionTimeout {
+		if duration <= 0 {
+			duration = defaultChatTimeout
+		}
+		if duration > maxChatTimeout {
+			return nil, badModerationRequest(fmt.Sprintf("Timeout cannot exceed %s", maxChatTimeout))
+		}
+		expiresAt := time.Now().UTC().Add(duration)
+		restriction.ExpiresAt = &expiresAt
+	}
+
+	if err := h.moderationRepo.CreateRestriction(restriction); err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":      raceID,
+		"user_id":      userID,
+		"kind":         kind,
+		"global":       global,
+		"moderator_id": moderatorID,
+	}).Info("Chat user restricted")
+
+	h.broadcastWS(raceID, chat.NewUserRestrictedWSMessage(userID, kind, restriction.ExpiresAt, reason))
+	return restriction, nil
+}
+
+func (h *ChatHandler) liftRestriction(raceID, userID, moderatorID string) (int64, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return 0, badModerationRequest("Invalid user ID")
+	}
+
+	revoked, err := h.moderationRepo.RevokeActive(raceID, userID, moderatorID)
+	if err != nil {
+		return 0, err
+	}
+
+	if revoked > 0 {
+		h.broadcastWS(raceID, chat.NewRestrictionLiftedWSMessage(userID))
+	}
+	return revoked, nil
+}
+
+func (h *ChatHandler) setSlowMode(raceID string, seconds int, moderatorID string) (*models.ChatRoomSettings, error) {
+	interval := time.Duration(seconds) * time.Second
+	if seconds < 0 || interval > maxSlowModeInterval {
+		return nil, badModerationRequest(fmt.Sprintf("Slow mode must be between 0 and %d seconds", int(maxSlowModeInterval.Seconds())))
+	}
+
+	settings, err := h.moderationRepo.SetSlowMode(raceID, seconds, moderatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.slowMode.SetInterval(raceID, interval)
+	h.broadcastWS(raceID, chat.NewSlowModeUpdatedWSMessage(seconds))
+	return settings, nil
+}
+
+// slowModeInterval returns the room's slow mode interval, reloading it from
+// the database when the cached value is stale
+func (h *ChatHandler) slowModeInterval(raceID string) time.Duration {
+	interval, fresh := h.slowMode.Interval(raceID)
+	if fresh || h.moderationRepo == nil {
+		return interval
+	}
+
+	settings, err := h.moderationRepo.GetRoomSettings(raceID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to load chat room settings")
+		return interval
+	}
+
+	interval = time.Duration(settings.SlowModeSeconds) * time.Second
+	h.slowMode.SetInterval(raceID, interval)
+	return interval
+}
+
+// checkSendAllowed enforces timeouts and bans for a user about to send a
+// message. It returns a user-facing reason when the message is refused.
+func (h *ChatHandler) checkSendAllowed(client *chat.Client, raceID, userID string) (string, bool) {
+	if client != nil && client.IsAdmin() {
+		return "", true
+	}
+
+	return h.checkRestrictions(raceID, userID)
+}
+
+// rateLimitTier picks the sender's rate limit bucket from their role and
+// whether they had an active subscription when they connected
+func rateLimitTier(user *models.User, isAdmin, subscribed bool) string {
+	if isAdmin {
+		return chat.RateTierAdmin
+	}
+	if user == nil {
+		return chat.RateTierAnonymous
+	}
+	if subscribed {
+		return chat.RateTierSubscriber
+	}
+	return chat.RateTierUser
+}
+
+// checkRateLimit applies the sender's token bucket, duplicate suppression and,
+// when raceID is set, the room's slow mode. Admins skip slow mode and may
+// repeat themselves. It sends an error frame with the remaining tokens and
+// retry delay and returns false when the message is refused.
+func (h *ChatHandler) checkRateLimit(client *chat.Client, raceID, userID string, user *models.User, message string) bool {
+	isAdmin := client != nil && client.IsAdmin()
+	subscribed := client != nil && client.IsSubscribed()
+	req := chat.RateLimitRequest{
+		Identifier: userID,
+		Tier:       rateLimitTier(user, isAdmin, subscribed),
+	}
+	if !isAdmin {
+		req.Message = message
+		if raceID != "" && h.slowMode != nil {
+			req.Room = raceID
+			req.SlowMode = h.slowModeInterval(raceID)
+		}
+	}
+
+	result := h.rateLimiter.Check(req)
+	if result.Allowed {
+		return true
+	}
+
+	var reason string
+	switch result.Reason {
+	case chat.RateLimitReasonSlowMode:
+		reason = fmt.Sprintf("Slow mode is on. Try again in %s.", result.RetryAfter.Round(time.Second))
+	case chat.RateLimitReasonDuplicate:
+		reason = "You already sent that message. Please wait before repeating it."
+	default:
+		reason = "Rate limit exceeded. Please wait before sending another message."
+	}
+
+	client.Send(chat.NewRateLimitedWSMessage(reason, result))
+	return false
+}
+
+// handleModerationCommand processes moderator commands sent over the WebSocket
+func (h *ChatHandler) handleModerationCommand(client *chat.Client, msg *chat.WSMessage) {
+	if !client.IsAdmin() {
+		sendWSError(client, "Moderator access required")
+		return
+	}
+	if h.moderationRepo == nil {
+		sendWSError(client, "Moderation unavailable")
+		return
+	}
+
+	raceID := client.RaceID()
+	moderatorID := ""
+	if client.UserID() != nil {
+		moderatorID = *client.UserID()
+	}
+
+	var err error
+	switch chat.MessageType(msg.Type) {
+	case chat.MessageTypeDeleteMessage:
+		var data chat.DeleteMessageData
+		if err = chat.ParseData(msg, &data); err == nil {
+			err = h.deleteMessage(raceID, data.MessageID, moderatorID)
+		}
+
+	case chat.MessageTypeTimeoutUser:
+		var data chat.TimeoutUserData
+		if err = chat.ParseData(msg, &data); err == nil {
+			duration := time.Duration(data.DurationSeconds) * time.Second
+			_, err = h.restrictUser(raceID, data.UserID, models.ChatRestrictionTimeout, duration, data.Reason, false, moderatorID)
+		}
+
+	case chat.MessageTypeBanUser:
+		var data chat.BanUserData
+		if err = chat.ParseData(msg, &data); err == nil {
+			_, err = h.restrictUser(raceID, data.UserID, models.ChatRestrictionBan, 0, data.Reason, data.Global, moderatorID)
+		}
+
+	case chat.MessageTypeUnbanUser:
+		var data chat.UnbanUserData
+		if err = chat.ParseData(msg, &data); err == nil {
+			_, err = h.liftRestriction(raceID, data.UserID, moderatorID)
+		}
+
+	case chat.MessageTypeSlowMode:
+		var data chat.SlowModeData
+		if err = chat.ParseData(msg, &data); err == nil {
+			_, err = h.setSlowMode(raceID, data.Seconds, moderatorID)
+		}
+
+	case chat.MessageTypePinMessage:
+		var data chat.PinMessageData
+		if err = chat.ParseData(msg, &data); err == nil {
+			_, err = h.pinMessage(raceID, data.MessageID, moderatorID)
+		}
+
+	case chat.MessageTypeUnpinMessage:
+		_, err = h.unpinMessage(raceID, moderatorID)
+
+	case chat.MessageTypeAnnouncement:
+		var data chat.AnnouncementData
+		if err = chat.ParseData(msg, &data); err == nil {
+			_, _, err = h.postAnnouncement(raceID, data.Message, data.Pin, moderatorID)
+		}
+	}
+
+	if err == nil {
+		return
+	}
+
+	var modErr *chatModerationError
+	if errors.As(err, &modErr) {
+		sendWSError(client, modErr.message)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"error":   err.Error(),
+		"race_id": raceID,
+		"type":    msg.Type,
+	}).Error("Chat moderation command failed")
+	sendWSError(client, "Moderation action failed")
+}
+
+func isModerationCommand(msgType string) bool {
+	switch chat.MessageType(msgType) {
+	case chat.MessageTypeDeleteMessage,
+		chat.MessageTypeTimeoutUser,
+		chat.MessageTypeBanUser,
+		chat.MessageTypeUnbanUser,
+		chat.MessageTypeSlowMode,
+		chat.MessageTypePinMessage,
+		chat.MessageTypeUnpinMessage,
+		chat.MessageTypeAnnouncement:
+		return true
+	}
+	return false
+}
+
+type timeoutUserRequest struct {
+	UserID          string  `json:"user_id"`
+	DurationSeconds int     `json:"duration_seconds"`
+	Reason          *string `json:"reason"`
+}
+
+type banUserRequest struct {
+	UserID string  `json:"user_id"`
+	Reason *string `json:"reason"`
+	Global bool    `json:"global"`
+}
+
+type slowModeRequest struct {
+	Seconds int `json:"seconds"`
+}
+
+// requireModerationRace validates the race path parameter for moderation endpoints
+func (h *ChatHandler) requireModerationRace(c *fiber.Ctx) (string, bool) {
+	if h.moderationRepo == nil {
+		_ = c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Moderation unavailable"})
+		return "", false
+	}
+
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return "", false
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		_ = c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+		return "", false
+	}
+	return raceID, true
+}
+
+// GetModerationState returns active restrictions and room settings for a race
+func (h *ChatHandler) GetModerationState(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	restrictions, err := h.moderationRepo.ListActiveByRace(raceID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	settings, err := h.moderationRepo.GetRoomSettings(raceID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"restrictions": restrictions,
+		"settings":     settings,
+	})
+}
+
+// DeleteChatMessage hides a message and tells clients in the room to remove it
+func (h *ChatHandler) DeleteChatMessage(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	messageID, ok := requireParam(c, "messageId", "Message ID is required")
+	if !ok {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	if err := h.deleteMessage(raceID, messageID, moderatorID); err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// TimeoutChatUser prevents a user from sending messages in a race for a while
+func (h *ChatHandler) TimeoutChatUser(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	var req timeoutUserRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	restriction, err := h.restrictUser(raceID, req.UserID, models.ChatRestrictionTimeout, duration, req.Reason, false, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(restriction)
+}
+
+// BanChatUser bans a user from a race's chat, or from all chats when global is set
+func (h *ChatHandler) BanChatUser(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	var req banUserRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	restriction, err := h.restrictUser(raceID, req.UserID, models.ChatRestrictionBan, 0, req.Reason, req.Global, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(restriction)
+}
+
+// LiftChatRestriction revokes a user's active timeouts and bans for a race.
+// Global bans stay in place; see LiftGlobalChatRestriction.
+func (h *ChatHandler) LiftChatRestriction(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	userID, ok := requireParam(c, "userId", "User ID is required")
+	if !ok {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	revoked, err := h.liftRestriction(raceID, userID, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	if revoked == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "No active restriction for user"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+// LiftGlobalChatRestriction revokes a user's global bans, which lifting a
+// race's restrictions leaves in place (admin only)
+// DELETE /admin/chat/restrictions/:userId
+func (h *ChatHandler) LiftGlobalChatRestriction(c *fiber.Ctx) error {
+	if h.moderationRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Moderation unavailable"})
+	}
+
+	userID, ok := requireParam(c, "userId", "User ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid user ID"})
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	revoked, err := h.moderationRepo.RevokeGlobal(userID, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	if revoked == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "No active global restriction for user"})
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":      userID,
+		"moderator_id": moderatorID,
+	}).Info("Global chat restriction lifted")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+// SetChatSlowMode sets the minimum interval between messages per user
+func (h *ChatHandler) SetChatSlowMode(c *fiber.Ctx) error {
+	raceID, ok := h.requireModerationRace(c)
+	if !ok {
+		return nil
+	}
+
+	var req slowModeRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	settings, err := h.setSlowMode(raceID, req.Seconds, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settings)
+}
+
+// checkRestrictions refuses users who are timed out or banned in the race
+func (h *ChatHandler) checkRestrictions(raceID, userID string) (string, bool) {
+	if h.moderationRepo == nil {
+		return "", true
+	}
+
+	restriction, err := h.moderationRepo.GetActiveRestriction(raceID, userID)
+	if err != nil {
+		// Moderation lookups failing should not take chat down
+		logger.WithError(err).Warn("Failed to check chat restrictions")
+		return "", true
+	}
+	if restriction != nil {
+		if restriction.ExpiresAt == nil {
+			return "You are banned from this chat", false
+		}
+		wait := time.Until(*restriction.ExpiresAt).Round(time.Second)
+		return fmt.Sprintf("You are timed out. Try again in %s.", wait), false
+	}
+
+	return "", true
+}
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	partyInviteCodeLength   = 8
+	partyInviteCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // no look-alike characters
+	maxPartyNameLength      = 100
+	maxPartyMembers         = 50
+
+	defaultPrivateHistoryLimit = 50
+	maxPrivateHistoryLimit     = 200
+)
+
+type createPartyRequest struct {
+	Name string `json:"name"`
+}
+
+// generatePartyInviteCode returns a random invite code
+func generatePartyInviteCode() (string, error) {
+	code := make([]byte, partyInviteCodeLength)
+	max := big.NewInt(int64(len(partyInviteCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = partyInviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func privateHistoryLimit(c *fiber.Ctx) int {
+	limit := defaultPrivateHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxPrivateHistoryLimit {
+			limit = parsed
+		}
+	}
+	return limit
+}
+
+// loadPartyOr404 fetches a race's party by the :code param and sends an error
+// response when it can't be loaded
+func (h *ChatHandler) loadPartyOr404(c *fiber.Ctx, raceID string) (*models.ChatParty, bool) {
+	code, ok := requireParam(c, "code", "Invite code is required")
+	if !ok {
+		return nil, false
+	}
+
+	party, err := h.partyRepo.GetPartyByCode(raceID, strings.ToLower(code))
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to get chat party")
+		_ = c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch party"})
+		return nil, false
+	}
+	if party == nil {
+		_ = c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Party not found"})
+		return nil, false
+	}
+
+	return party, true
+}
+
+// CreateChatParty creates a watch party in a race and returns its invite code
+// POST /races/:id/chat/parties
+func (h *ChatHandler) CreateChatParty(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	var req createPartyRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxPartyNameLength {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Party name must be between 1 and 100 characters"})
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceID); !ok {
+		return nil
+	}
+
+	party := &models.ChatParty{RaceID: raceID, Name: name, OwnerID: userID}
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := generatePartyInviteCode()
+		if err != nil {
+			logger.WithError(err).Error("Failed to generate party invite code")
+			return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create party"})
+		}
+		party.InviteCode = code
+
+		err = h.partyRepo.CreateParty(party)
+		if errors.Is(err, repository.ErrChatPartyCodeTaken) {
+			continue
+		}
+		if err != nil {
+			logger.WithError(err).WithField("race_id", raceID).Error("Failed to create chat party")
+			return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create party"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(party)
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create party"})
+}
+
+// ListChatParties returns the parties the current user belongs to in a race
+// GET /races/:id/chat/parties
+func (h *ChatHandler) ListChatParties(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	parties, err := h.partyRepo.ListPartiesForUser(raceID, userID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to list chat parties")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch parties"})
+	}
+	if parties == nil {
+		parties = []*models.ChatParty{}
+	}
+
+	return c.JSON(fiber.Map{"parties": parties})
+}
+
+// JoinChatParty adds the current user to a party using its invite code
+// POST /races/:id/chat/parties/:code/members
+func (h *ChatHandler) JoinChatParty(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	party, ok := h.loadPartyOr404(c, raceID)
+	if !ok {
+		return nil
+	}
+	if party.MemberCount >= maxPartyMembers {
+		return c.Status(fiber.StatusConflict).JSON(APIError{Error: "Party is full"})
+	}
+
+	added, err := h.partyRepo.AddMember(party.ID, userID)
+	if err != nil {
+		logger.WithError(err).WithField("party_id", party.ID).Error("Failed to join chat party")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to join party"})
+	}
+	if added {
+		party.MemberCount++
+	}
+
+	return c.JSON(party)
+}
+
+// LeaveChatParty removes the current user from a party and drops their
+// connections on this instance from the party room
+// DELETE /races/:id/chat/parties/:code/members/me
+func (h *ChatHandler) LeaveChatParty(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	party, ok := h.loadPartyOr404(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	removed, err := h.partyRepo.RemoveMember(party.ID, userID)
+	if err != nil {
+		logger.WithError(err).WithField("party_id", party.ID).Error("Failed to leave chat party")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to leave party"})
+	}
+	if !removed {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Not a member of this party"})
+	}
+
+	h.hub.RemoveUserFromRoom(chat.PartyRoomID(party.InviteCode), userID)
+	h.hub.SendToUser(raceID, userID, chat.NewPartyLeftWSMessage(party.InviteCode))
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetChatPartyMessages returns a party's latest messages, oldest first. Members only.
+// GET /races/:id/chat/parties/:code/messages?limit=50
+func (h *ChatHandler) GetChatPartyMessages(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	party, ok := h.loadPartyOr404(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	member, err := h.partyRepo.IsPartyMember(raceID, party.InviteCode, userID)
+	if err != nil {
+		logger.WithError(err).WithField("party_id", party.ID).Error("Failed to check chat party membership")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch messages"})
+	}
+	if !member {
+		return c.Status(fiber.StatusForbidden).JSON(APIError{Error: "Not a member of this party"})
+	}
+
+	messages, err := h.partyRepo.ListPartyMessages(party.ID, privateHistoryLimit(c))
+	if err != nil {
+		logger.WithError(err).WithField("party_id", party.ID).Error("Failed to get chat party messages")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch messages"})
+	}
+	if messages == nil {
+		messages = []*models.ChatPrivateMessage{}
+	}
+
+	return c.JSON(fiber.Map{
+		"party":    party,
+		"messages": messages,
+	})
+}
+
+// GetWhispers returns the latest whispers between the current user and another user in a race
+// GET /races/:id/chat/whispers/:userId?limit=50
+func (h *ChatHandler) GetWhispers(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	otherUserID, ok := requireParam(c, "userId", "User ID is required")
+	if !ok {
+		return nil
+	}
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+	if _, err := uuid.Parse(otherUserID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid user ID"})
+	}
+
+	messages, err := h.partyRepo.ListWhispers(raceID, userID, otherUserID, privateHistoryLimit(c))
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to get whispers")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch whispers"})
+	}
+	if messages == nil {
+		messages = []*models.ChatPrivateMessage{}
+	}
+
+	return c.JSON(fiber.Map{"messages": messages})
+}
+
+// handlePartyMessage stores a message sent to a party room the client joined
+// and broadcasts it to the room. Party messages skip the public room's slow
+// mode, replies and mentions.
+func (h *ChatHandler) handlePartyMessage(client *chat.Client, raceID, code, userID, username string, user *models.User, message string) {
+	if h.partyRepo == nil || !h.hub.InRoom(client, chat.PartyRoomID(code)) {
+		sendWSError(client, "You are not in this party")
+		return
+	}
+
+	if !client.IsAdmin() {
+		if reason, allowed := h.checkRestrictions(raceID, userID); !allowed {
+			sendWSError(client, reason)
+			return
+		}
+	}
+	if !h.checkRateLimit(client, "", userID, user, message) {
+		return
+	}
+
+	// Membership is checked again so members who left can't keep posting
+	member, err := h.partyRepo.IsPartyMember(raceID, code, userID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to check chat party membership")
+		sendWSError(client, "Failed to send message. Please try again.")
+		return
+	}
+	if !member {
+		sendWSError(client, "You are not in this party")
+		return
+	}
+
+	party, err := h.partyRepo.GetPartyByCode(raceID, code)
+	if err != nil || party == nil {
+		logger.WithField("race_id", raceID).Error("Failed to load chat party")
+		sendWSError(client, "Failed to send message. Please try again.")
+		return
+	}
+
+	msg := &models.ChatPrivateMessage{
+		RaceID:   raceID,
+		PartyID:  &party.ID,
+		SenderID: userID,
+		Username: username,
+		Message:  message,
+	}
+	if err := h.partyRepo.CreateMessage(msg); err != nil {
+		logger.WithError(err).WithField("party_id", party.ID).Error("Failed to store party message")
+		sendWSError(client, "Failed to send message. Please try again.")
+		return
+	}
+
+	h.broadcastWS(chat.PartyRoomID(code), chat.NewPartyMessageWSMessage(msg, code))
+}
+
+// handleWhisper stores a whisper and delivers it to the recipient's and the
+// sender's clients in the race room
+func (h *ChatHandler) handleWhisper(client *chat.Client, raceID string, msg *chat.WSMessage, userID *string, username string, user *models.User) {
+	if userID == nil {
+		sendWSError(client, "Authentication required to send messages")
+		return
+	}
+	if h.partyRepo == nil {
+		sendWSError(client, "Whispers are not available")
+		return
+	}
+
+	var data chat.WhisperData
+	if err := chat.ParseData(msg, &data); err != nil {
+		sendWSError(client, "Invalid message data")
+		return
+	}
+	if _, err := uuid.Parse(data.ToUserID); err != nil || data.ToUserID == *userID {
+		sendWSError(client, "Invalid whisper recipient")
+		return
+	}
+
+	validatedMessage, err := chat.ValidateMessage(data.Message)
+	if err != nil {
+		sendWSError(client, err.Error())
+		return
+	}
+
+	if !client.IsAdmin() {
+		if reason, allowed := h.checkRestrictions(raceID, *userID); !allowed {
+			sendWSError(client, reason)
+			return
+		}
+	}
+	if !h.checkRateLimit(client, "", *userID, user, validatedMessage) {
+		return
+	}
+
+	recipient, err := h.userRepo.GetByID(data.ToUserID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to load whisper recipient")
+		sendWSError(client, "Failed to send message. Please try again.")
+		return
+	}
+	if recipient == nil {
+		sendWSError(client, "Invalid whisper recipient")
+		return
+	}
+
+	whisper := &models.ChatPrivateMessage{
+		RaceID:      raceID,
+		SenderID:    *userID,
+		RecipientID: &recipient.ID,
+		Username:    username,
+		Message:     validatedMessage,
+	}
+	if err := h.partyRepo.CreateMessage(whisper); err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to store whisper")
+		sendWSError(client, "Failed to send message. Please try again.")
+		return
+	}
+
+	whisperMsg := chat.NewWhisperWSMessage(whisper)
+	h.hub.SendToUser(raceID, recipient.ID, whisperMsg)
+	h.hub.SendToUser(raceID, *userID, whisperMsg)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type pinMessageRequest struct {
+	MessageID string `json:"message_id"`
+}
+
+type announcementRequest struct {
+	Message string `json:"message"`
+	Pin     bool   `json:"pin"`
+}
+
+// pinMessage pins a visible message of the race and tells the room
+func (h *ChatHandler) pinMessage(raceID, messageID, moderatorID string) (*models.ChatPin, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, badModerationRequest("Invalid message ID")
+	}
+
+	message, err := h.chatRepo.GetByID(raceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, &chatModerationError{status: fiber.StatusNotFound, message: "Message not found"}
+	}
+	h.hydrateMessageMetadata([]*models.ChatMessage{message})
+
+	return h.setPin(message, moderatorID)
+}
+
+func (h *ChatHandler) setPin(message *models.ChatMessage, moderatorID string) (*models.ChatPin, error) {
+	pin, err := h.chatRepo.SetPin(message, moderatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":      message.RaceID,
+		"message_id":   message.ID,
+		"moderator_id": moderatorID,
+	}).Info("Chat message pinned")
+
+	h.broadcastWS(message.RaceID, chat.NewPinUpdatedWSMessage(message.RaceID, pin))
+	return pin, nil
+}
+
+// unpinMessage removes the race's pin, if any, and tells the room
+func (h *ChatHandler) unpinMessage(raceID, moderatorID string) (bool, error) {
+	unpinned, err := h.chatRepo.DeletePin(raceID, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if unpinned {
+		logger.WithFields(map[string]interface{}{
+			"race_id":      raceID,
+			"moderator_id": moderatorID,
+		}).Info("Chat message unpinned")
+		h.broadcastWS(raceID, chat.NewPinUpdatedWSMessage(raceID, nil))
+	}
+	return unpinned, nil
+}
+
+// unpinDeletedMessage removes the pin of a message that was just deleted
+func (h *ChatHandler) unpinDeletedMessage(raceID, messageID string) {
+	unpinned, err := h.chatRepo.DeletePin(raceID, &messageID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Warn("Failed to unpin deleted chat message")
+		return
+	}
+	if unpinned {
+		h.broadcastWS(raceID, chat.NewPinUpdatedWSMessage(raceID, nil))
+	}
+}
+
+// postAnnouncement stores an announcement from the race team and sends it
+// to the room, pinning it when pin is set. Announcements skip the rate
+// limiter, slow mode and the content filter.
+func (h *ChatHandler) postAnnouncement(raceID, text string, pin bool, adminID string) (*models.ChatMessage, *models.ChatPin, error) {
+	validated, err := chat.ValidateMessage(text)
+	if err != nil {
+		return nil, nil, badModerationRequest(err.Error())
+	}
+
+	// Admin tokens are not always tied to a user row
+	var user *models.User
+	if adminID != "" {
+		if user, err = h.userRepo.GetByID(adminID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	message := &models.ChatMessage{
+		RaceID:       raceID,
+		Username:     chatDisplayName(user, "Admin"),
+		Message:      validated,
+		Announcement: true,
+	}
+	if user != nil {
+		message.UserID = &user.ID
+	}
+	message.Emotes = h.parseMessageEmotes(raceID, validated, user, true, false)
+	h.applyMessageMetadata(message, user, true)
+
+	if err := h.chatRepo.Create(message); err != nil {
+		return nil, nil, err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":    raceID,
+		"message_id": message.ID,
+		"admin_id":   adminID,
+	}).Info("Chat announcement posted")
+
+	h.broadcastWS(raceID, chat.NewAnnouncementWSMessage(message))
+
+	if !pin {
+		return message, nil, nil
+	}
+	pinned, err := h.setPin(message, adminID)
+	if err != nil {
+		return message, nil, err
+	}
+	return message, pinned, nil
+}
+
+// currentPin returns the race's pinned message, or nil when there is none or
+// it can't be loaded
+func (h *ChatHandler) currentPin(raceID string) *models.ChatPin {
+	pin, err := h.chatRepo.GetPin(raceID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Warn("Failed to load pinned chat message")
+		return nil
+	}
+	if pin != nil {
+		h.hydrateMessageMetadata([]*models.ChatMessage{pin.Message})
+	}
+	return pin
+}
+
+// GetChatPin returns the message pinned in a race's chat
+// GET /races/:id/chat/pin
+func (h *ChatHandler) GetChatPin(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	pin, err := h.chatRepo.GetPin(raceID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch pinned chat message")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch pinned message"})
+	}
+	if pin != nil {
+		h.hydrateMessageMetadata([]*models.ChatMessage{pin.Message})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"pinned": pin})
+}
+
+// PinChatMessage pins a message to the top of a race's chat (admin only)
+// PUT /races/:id/chat/pin
+func (h *ChatHandler) PinChatMessage(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	var req pinMessageRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	pin, err := h.pinMessage(raceID, req.MessageID, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(pin)
+}
+
+// UnpinChatMessage removes the pinned message of a race's chat (admin only)
+// DELETE /races/:id/chat/pin
+func (h *ChatHandler) UnpinChatMessage(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	moderatorID, _ := c.Locals("user_id").(string)
+	unpinned, err := h.unpinMessage(raceID, moderatorID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+	if !unpinned {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "No pinned message"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PostChatAnnouncement posts a highlighted announcement to a race's chat
+// (admin only)
+// POST /races/:id/chat/announcements
+func (h *ChatHandler) PostChatAnnouncement(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	var req announcementRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceID); !ok {
+		return nil
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	message, pin, err := h.postAnnouncement(raceID, req.Message, req.Pin, adminID)
+	if err != nil {
+		return respondModerationError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": message,
+		"pinned":  pin,
+	})
+}
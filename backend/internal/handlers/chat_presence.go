@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetChatPresence lists the authenticated users connected to a race's chat,
+// once per user however many tabs they have open
+// GET /races/:id/chat/presence
+func (h *ChatHandler) GetChatPresence(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(h.hub.RoomPresence(raceID))
+}
+
+// sendPresenceSnapshot tells a client who is in its race room
+func (h *ChatHandler) sendPresenceSnapshot(client *chat.Client, raceID string) {
+	client.Send(chat.NewPresenceSnapshotWSMessage(h.hub.RoomPresence(raceID)))
+}
+
+// handleTyping relays a typing indicator to the race room. Anonymous and
+// restricted users are ignored, and the hub throttles each user.
+func (h *ChatHandler) handleTyping(client *chat.Client, raceID string, userID *string, username string, access *reactionAccess) {
+	if userID == nil {
+		return
+	}
+	if !client.IsAdmin() && access.isRestricted(h, raceID, *userID) {
+		return
+	}
+
+	h.hub.BroadcastTyping(raceID, *userID, username)
+}
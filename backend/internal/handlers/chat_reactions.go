@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultHypePeaks = 5
+	maxHypePeaks     = 50
+
+	// reactionRestrictionTTL is how long a connection's ban/timeout check is
+	// reused for reactions, so reactions don't hit the database each time
+	reactionRestrictionTTL = 30 * time.Second
+)
+
+// reactionAccess caches per-connection lookups made for reactions and typing
+// indicators
+type reactionAccess struct {
+	ticketChecked bool
+	hasTicket     bool
+
+	restrictionCheckedAt time.Time
+	restricted           bool
+}
+
+// isRestricted reports whether the user is banned or timed out, checking at
+// most once per reactionRestrictionTTL
+func (a *reactionAccess) isRestricted(h *ChatHandler, raceID, userID string) bool {
+	if time.Since(a.restrictionCheckedAt) > reactionRestrictionTTL {
+		_, allowed := h.checkRestrictions(raceID, userID)
+		a.restricted = !allowed
+		a.restrictionCheckedAt = time.Now()
+	}
+	return a.restricted
+}
+
+// handleReaction counts a reaction towards the room's next summary. Unknown
+// emotes and emotes the user may not use are refused; reactions over the
+// per-connection cap are dropped silently.
+func (h *ChatHandler) handleReaction(client *chat.Client, raceID string, msg *chat.WSMessage, user *models.User, access *reactionAccess) {
+	if h.reactions == nil || h.emotes == nil {
+		return
+	}
+
+	var data chat.ReactionData
+	if err := chat.ParseData(msg, &data); err != nil || data.Emote == "" {
+		sendWSError(client, "Invalid reaction")
+		return
+	}
+
+	emote := h.emotes.Lookup(raceID, data.Emote)
+	if emote == nil {
+		sendWSError(client, "Unknown emote")
+		return
+	}
+
+	if !client.IsAdmin() {
+		level := 0
+		if user != nil {
+			level = user.Level
+		}
+		if emote.RequiresTicket && !access.ticketChecked && user != nil {
+			access.ticketChecked = true
+			access.hasTicket = h.hasRaceTicket(user.ID, raceID, client.IsSubscribed())
+		}
+		if !chat.CanUseEmote(emote, level, access.hasTicket) {
+			sendWSError(client, "You can't use this emote")
+			return
+		}
+
+		if user != nil && access.isRestricted(h, raceID, user.ID) {
+			return
+		}
+	}
+
+	h.reactions.Add(client, raceID, emote.Code)
+}
+
+// BroadcastReactionSummary sends a room's reaction counts for the last
+// interval to the room's clients on this instance. It is the
+// ReactionAggregator's summary callback; the counts already include the
+// other instances' reactions, which send their own clients the same summary.
+func (h *ChatHandler) BroadcastReactionSummary(raceID string, counts map[string]int) {
+	h.hub.DeliverToRoom(raceID, chat.NewFrame(chat.NewReactionSummaryWSMessage(raceID, counts)))
+}
+
+// GetChatHype returns a race's per-minute reaction timeline and its busiest minutes
+// GET /admin/races/:id/chat/hype?top=5
+func (h *ChatHandler) GetChatHype(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+	if h.reactionRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(APIError{Error: "Hype timeline unavailable"})
+	}
+
+	race, ok := loadRaceOr404(c, h.raceRepo, raceID)
+	if !ok {
+		return nil
+	}
+
+	top := defaultHypePeaks
+	if topStr := c.Query("top"); topStr != "" {
+		if parsed, err := strconv.Atoi(topStr); err == nil && parsed > 0 && parsed <= maxHypePeaks {
+			top = parsed
+		}
+	}
+
+	timeline, err := h.reactionRepo.GetHypeTimeline(raceID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch chat hype timeline")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch hype timeline"})
+	}
+
+	// Offsets are relative to when the stream went live, so peaks can be
+	// found in the recording; the race start is the fallback
+	start := race.StartDate
+	stream, err := h.streamRepo.GetByRaceID(raceID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to fetch stream for chat hype offsets")
+	} else if stream != nil && stream.StartedAt != nil {
+		start = stream.StartedAt
+	}
+	if start != nil {
+		for _, m := range timeline {
+			offset := int(m.Minute.Sub(*start) / time.Second)
+			m.OffsetSeconds = &offset
+		}
+	}
+
+	peaks := make([]*models.ChatHypeMinute, len(timeline))
+	copy(peaks, timeline)
+	sort.SliceStable(peaks, func(i, j int) bool {
+		return peaks[i].Total > peaks[j].Total
+	})
+	if len(peaks) > top {
+		peaks = peaks[:top]
+	}
+
+	return c.JSON(fiber.Map{
+		"race_id":  raceID,
+		"timeline": timeline,
+		"peaks":    peaks,
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/chat"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultReplayWindowSeconds = 300
+	maxReplayWindowSeconds     = 600
+	defaultReplayBucketSeconds = 10
+	maxReplayBucketSeconds     = 60
+	// Upper bound on messages returned for one window
+	maxReplayMessages = 2000
+)
+
+// GetReplayChat returns chat messages for a window of a race's recording,
+// bucketed by offset from when the stream went live. VOD players fetch the
+// window around the current video time and show each bucket as playback
+// reaches it.
+// GET /races/:id/chat/replay?from=0&duration=300&bucket=10
+func (h *ChatHandler) GetReplayChat(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+
+	if _, err := uuid.Parse(raceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+
+	from, ok := replayQueryInt(c, "from", 0, 0, -1)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid from parameter"})
+	}
+	duration, ok := replayQueryInt(c, "duration", defaultReplayWindowSeconds, 1, maxReplayWindowSeconds)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid duration parameter"})
+	}
+	bucketSize, ok := replayQueryInt(c, "bucket", defaultReplayBucketSeconds, 1, maxReplayBucketSeconds)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid bucket parameter"})
+	}
+
+	race, ok := loadRaceOr404(c, h.raceRepo, raceID)
+	if !ok {
+		return nil
+	}
+
+	stream, ok := loadStreamOr404(c, h.streamRepo, raceID, "Stream not found for this race")
+	if !ok {
+		return nil
+	}
+
+	// Streams that predate session tracking fall back to the race start
+	streamStart := stream.StartedAt
+	if streamStart == nil {
+		streamStart = race.StartDate
+	}
+	if streamStart == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Chat replay is not available for this race"})
+	}
+
+	windowStart := streamStart.Add(time.Duration(from) * time.Second)
+	windowEnd := windowStart.Add(time.Duration(duration) * time.Second)
+
+	messages, err := h.chatRepo.GetBetweenByRaceID(raceID, windowStart, windowEnd, maxReplayMessages+1)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":   err.Error(),
+			"race_id": raceID,
+			"from":    from,
+		}).Error("Failed to get replay chat")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch replay chat"})
+	}
+
+	to := from + duration
+	if len(messages) > maxReplayMessages {
+		// Too busy to return the whole window: stop at the last complete
+		// bucket so the next request picks up exactly where this one ends
+		messages = messages[:maxReplayMessages]
+		lastOffset := int(messages[len(messages)-1].CreatedAt.Sub(*streamStart) / time.Second)
+		cut := lastOffset - lastOffset%bucketSize
+		if cut > from {
+			cutAt := streamStart.Add(time.Duration(cut) * time.Second)
+			for len(messages) > 0 && !messages[len(messages)-1].CreatedAt.Before(cutAt) {
+				messages = messages[:len(messages)-1]
+			}
+			to = cut
+		} else {
+			to = lastOffset + 1
+		}
+	}
+
+	h.hydrateMessageMetadata(messages)
+
+	// nil once the window passes the end of a finished stream
+	var nextFrom *int
+	if stream.Status == "live" || stream.EndedAt == nil || streamStart.Add(time.Duration(to)*time.Second).Before(*stream.EndedAt) {
+		nextFrom = &to
+	}
+
+	return c.JSON(fiber.Map{
+		"race_id":        raceID,
+		"stream_start":   streamStart,
+		"from":           from,
+		"to":             to,
+		"bucket_seconds": bucketSize,
+		"buckets":        chat.BucketReplayMessages(messages, *streamStart, time.Duration(bucketSize)*time.Second),
+		"next_from":      nextFrom,
+	})
+}
+
+// replayQueryInt parses an integer query parameter, returning def when it is
+// absent. max < 0 means no upper bound; larger values are clamped to max.
+func replayQueryInt(c *fiber.Ctx, key string, def, min, max int) (int, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, true
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min {
+		return 0, false
+	}
+	if max >= 0 && value > max {
+		value = max
+	}
+	return value, true
+}
@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/cyclingstream/backend/internal/chat"
 	"github.com/cyclingstream/backend/internal/database"
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,11 +14,12 @@ var (
 )
 
 type HealthHandler struct {
-	db *database.DB
+	db  *database.DB
+	hub *chat.Hub
 }
 
-func NewHealthHandler(db *database.DB) *HealthHandler {
-	return &HealthHandler{db: db}
+func NewHealthHandler(db *database.DB, hub *chat.Hub) *HealthHandler {
+	return &HealthHandler{db: db, hub: hub}
 }
 
 func (h *HealthHandler) GetHealth(c *fiber.Ctx) error {
@@ -58,17 +60,28 @@ func (h *HealthHandler) GetHealth(c *fiber.Ctx) error {
 		statusCode = fiber.StatusServiceUnavailable
 	}
 
+	services := fiber.Map{
+		"database": fiber.Map{
+			"status": dbStatus,
+			"latency_ms": dbLatency,
+		},
+	}
+	// Chat send queue totals, for tuning queue capacity. Room IDs include
+	// party invite codes, so the per-room counters are admin only.
+	if h.hub != nil {
+		stats := h.hub.DeliveryStats()
+		services["chat"] = fiber.Map{
+			"queue_size": stats.QueueSize,
+			"totals":     stats.Totals,
+		}
+	}
+
 	return c.Status(statusCode).JSON(fiber.Map{
 		"status": overallStatus,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"uptime_seconds": int64(uptime.Seconds()),
 		"response_time_ms": responseTime,
-		"services": fiber.Map{
-			"database": fiber.Map{
-				"status": dbStatus,
-				"latency_ms": dbLatency,
-			},
-		},
+		"services": services,
 		"system": fiber.Map{
 			"go_version": runtime.Version(),
 			"num_goroutines": runtime.NumGoroutine(),
@@ -1,17 +1,20 @@
 package handlers
 
 import (
+	"encoding/json"
 	"io"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/cyclingstream/backend/internal/chat"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthHandler_GetHealth(t *testing.T) {
 	app := fiber.New()
-	handler := NewHealthHandler(nil) // nil is acceptable for health check
+	handler := NewHealthHandler(nil, nil) // nil is acceptable for health check
 	app.Get("/health", handler.GetHealth)
 
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -24,3 +27,26 @@ func TestHealthHandler_GetHealth(t *testing.T) {
 	assert.Contains(t, string(body), "ok")
 }
 
+
+func TestHealthHandler_ChatDeliveryStats(t *testing.T) {
+	app := fiber.New()
+	handler := NewHealthHandler(nil, chat.NewHub())
+	app.Get("/health", handler.GetHealth)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Services struct {
+			Chat map[string]json.RawMessage `json:"chat"`
+		} `json:"services"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Services.Chat)
+	assert.JSONEq(t, "256", string(body.Services.Chat["queue_size"]))
+	assert.Contains(t, body.Services.Chat, "totals")
+	// Room IDs include party invite codes
+	assert.NotContains(t, body.Services.Chat, "rooms")
+}
@@ -1,22 +1,38 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/cyclingstream/backend/internal/models"
 	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/cyclingstream/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 type RaceHandler struct {
 	raceRepo        *repository.RaceRepository
 	streamRepo      *repository.StreamRepository
+	providerRepo    *repository.StreamProviderRepository
+	segmentRepo     *repository.StreamSegmentRepository
+	vodRepo         *repository.VODAssetRepository
+	timelineRepo    *repository.RaceTimelineRepository
 	entitlementRepo *repository.EntitlementRepository
+	playbackSigner  *services.PlaybackSigner
+	// DVR window of streams that don't set their own
+	dvrWindow time.Duration
 }
 
-func NewRaceHandler(raceRepo *repository.RaceRepository, streamRepo *repository.StreamRepository, entitlementRepo *repository.EntitlementRepository) *RaceHandler {
+func NewRaceHandler(raceRepo *repository.RaceRepository, streamRepo *repository.StreamRepository, providerRepo *repository.StreamProviderRepository, segmentRepo *repository.StreamSegmentRepository, vodRepo *repository.VODAssetRepository, timelineRepo *repository.RaceTimelineRepository, entitlementRepo *repository.EntitlementRepository, playbackSigner *services.PlaybackSigner, dvrWindow time.Duration) *RaceHandler {
 	return &RaceHandler{
 		raceRepo:        raceRepo,
 		streamRepo:      streamRepo,
+		providerRepo:    providerRepo,
+		segmentRepo:     segmentRepo,
+		vodRepo:         vodRepo,
+		timelineRepo:    timelineRepo,
 		entitlementRepo: entitlementRepo,
+		playbackSigner:  playbackSigner,
+		dvrWindow:       dvrWindow,
 	}
 }
 
@@ -51,6 +67,9 @@ func (h *RaceHandler) GetRaceByID(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(race)
 }
 
+// GetRaceStream returns the race's feeds, each with its status and, when the
+// viewer may watch it, its playback URL. The top-level fields describe the
+// default feed: the primary one, or else the first the viewer may watch.
 func (h *RaceHandler) GetRaceStream(c *fiber.Ctx) error {
 	id, ok := requireParam(c, "id", "Race ID is required")
 	if !ok {
@@ -63,60 +82,69 @@ func (h *RaceHandler) GetRaceStream(c *fiber.Ctx) error {
 		return nil
 	}
 
-	// Check if race requires login
-	if race.RequiresLogin {
-		userID, ok := requireUserID(c, "Authentication required to access this stream")
-		if !ok {
-			return nil
-		}
-		_ = userID // User ID is validated, continue
+	streams, err := h.streamRepo.ListByRaceID(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{
+			Error: "Failed to fetch stream",
+		})
+	}
+	if len(streams) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{
+			Error: "Stream not found for this race",
+		})
 	}
 
-	// Check if user has access (if race is paid)
-	if !race.IsFree {
-		userID, ok := c.Locals("user_id").(string)
-		if !ok || userID == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":            "Authentication required",
-				"requires_payment": true,
-			})
+	access := h.newStreamAccess(race, c)
+	var defaultFeed fiber.Map
+	var firstDenial *streamDenial
+	feeds := make([]fiber.Map, 0, len(streams))
+	for _, stream := range streams {
+		feed := fiber.Map{
+			"stream_id":   stream.ID,
+			"name":        stream.Name,
+			"is_primary":  stream.IsPrimary,
+			"access":      stream.Access,
+			"status":      stream.Status,
+			"stream_type": stream.StreamType,
+			"provider":    stream.StreamType,
 		}
 
-		hasAccess, err := h.entitlementRepo.HasAccess(userID, id)
+		denial, err := access.check(stream)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to check access",
 			})
 		}
-
-		if !hasAccess {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error":            "Payment required to access this race",
-				"requires_payment": true,
-			})
+		if denial != nil {
+			feed["locked"] = true
+			feed["requires_login"] = denial.requiresLogin
+			feed["requires_payment"] = denial.requiresPayment
+			feeds = append(feeds, feed)
+			if firstDenial == nil {
+				firstDenial = denial
+			}
+			continue
 		}
-	}
 
-	// Get stream for race
-	stream, ok := loadStreamOr404(c, h.streamRepo, id, "Stream not found for this race")
-	if !ok {
-		return nil
+		feed["locked"] = false
+		feed["source_id"] = stream.SourceID
+		if !h.addPlaybackURL(c, feed, stream, access.userID) {
+			return nil
+		}
+		feeds = append(feeds, feed)
+		if defaultFeed == nil {
+			defaultFeed = feed
+		}
 	}
 
-	// Return stream info (prefer CDN URL if available)
-	response := fiber.Map{
-		"stream_id":   stream.ID,
-		"status":      stream.Status,
-		"stream_type": stream.StreamType,
-		"provider":    stream.StreamType,
-		"source_id":   stream.SourceID,
+	// Nothing to watch: refuse as for a race with a single feed
+	if defaultFeed == nil {
+		return firstDenial.respond(c)
 	}
 
-	if stream.CDNURL != nil && *stream.CDNURL != "" {
-		response["cdn_url"] = *stream.CDNURL
-	} else if stream.OriginURL != nil && *stream.OriginURL != "" {
-		response["origin_url"] = *stream.OriginURL
+	response := fiber.Map{"feeds": feeds}
+	for k, v := range defaultFeed {
+		response[k] = v
 	}
-
 	return c.Status(fiber.StatusOK).JSON(response)
 }
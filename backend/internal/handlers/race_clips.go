@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// clipPlaylistPattern matches clip playlist file names, clip-<id>.m3u8,
+	// next to the stream's live playlist on the origin
+	clipPlaylistPattern = "clip-*.m3u8"
+	clipPlaylistPrefix  = "clip-"
+	clipPlaylistSuffix  = ".m3u8"
+
+	defaultClipSeconds = 60
+	maxClipSeconds     = 300
+)
+
+// CreateClipRequest creates a clip of a feed's recording. A clip of a marker
+// starts at the marker and is titled by it unless they're given.
+type CreateClipRequest struct {
+	MarkerID string   `json:"marker_id"`
+	Title    string   `json:"title"`
+	Start    *float64 `json:"start_offset_seconds"`
+	End      *float64 `json:"end_offset_seconds"`
+}
+
+// clipURL returns the URL of the clip's playlist on the origin, or "" when
+// the stream isn't served from the HLS origin
+func clipURL(stream *models.Stream, clipID string) string {
+	return originPlaylistURL(stream, clipPlaylistPrefix+clipID+clipPlaylistSuffix)
+}
+
+// CreateClip cuts a clip from a feed's recording, the primary feed unless
+// stream_id is given, for the viewer to share. Viewers need access to the
+// feed. Clips are at most five minutes long and begin at a segment boundary.
+// POST /races/:id/clips?stream_id=
+func (h *RaceHandler) CreateClip(c *fiber.Ctx) error {
+	userID, ok := requireUserID(c, "Authentication required")
+	if !ok {
+		return nil
+	}
+
+	var req CreateClipRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	if req.MarkerID != "" && !middleware.ValidateUUID(req.MarkerID) {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid marker ID"})
+	}
+	if req.MarkerID == "" && req.Start == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "start_offset_seconds or marker_id is required"})
+	}
+
+	stream, _, ok := h.watchableFeed(c)
+	if !ok {
+		return nil
+	}
+	if originScope(stream) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "This feed can't be clipped"})
+	}
+
+	clip := &models.RaceClip{RaceID: stream.RaceID, StreamID: stream.ID, CreatedBy: &userID}
+	if req.MarkerID != "" {
+		marker, err := h.timelineRepo.GetMarker(stream.RaceID, req.MarkerID)
+		if err != nil {
+			logger.WithError(err).WithField("marker_id", req.MarkerID).Error("Failed to load race marker")
+			return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create clip"})
+		}
+		if marker == nil || marker.StreamID != stream.ID {
+			return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Marker not found for this stream"})
+		}
+		clip.MarkerID = &marker.ID
+		clip.Title = marker.Title
+		clip.Start = marker.Offset
+	}
+	if req.Start != nil {
+		clip.Start = *req.Start
+	}
+	clip.End = clip.Start + defaultClipSeconds
+	if req.End != nil {
+		clip.End = *req.End
+	}
+	if title := middleware.SanitizeString(req.Title, 100); title != "" {
+		clip.Title = title
+	}
+
+	if clip.Title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Title is required"})
+	}
+	if clip.Start < 0 || clip.End <= clip.Start {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "end_offset_seconds must be after start_offset_seconds"})
+	}
+	if clip.End-clip.Start > maxClipSeconds {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Clips can be at most 300 seconds long"})
+	}
+
+	segments, err := h.segmentRepo.ListAll(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load recorded segments")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create clip"})
+	}
+	if len(services.ClipSegments(segments, clip.Start, clip.End)) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "The clip is outside the recording"})
+	}
+	var recorded float64
+	for _, segment := range segments {
+		recorded += segment.Duration
+	}
+	if clip.End > recorded {
+		clip.End = recorded
+	}
+
+	if err := h.timelineRepo.CreateClip(clip); err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to create clip")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to create clip"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(clip)
+}
+
+// GetClip returns a clip with a clip URL for the viewer, who needs the same
+// access as to the clipped feed. Shared clip links open with it.
+// GET /clips/:id
+func (h *RaceHandler) GetClip(c *fiber.Ctx) error {
+	id, ok := requireParam(c, "id", "Clip ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(id) {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid clip ID"})
+	}
+
+	clip, err := h.timelineRepo.GetClip(id)
+	if err != nil {
+		logger.WithError(err).WithField("clip_id", id).Error("Failed to load clip")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch clip"})
+	}
+	if clip == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Clip not found"})
+	}
+
+	race, ok := loadRaceOr404(c, h.raceRepo, clip.RaceID)
+	if !ok {
+		return nil
+	}
+	stream, ok := loadFeedOr404(c, h.streamRepo, clip.RaceID, clip.StreamID, "Clip not found")
+	if !ok {
+		return nil
+	}
+
+	access := h.newStreamAccess(race, c)
+	denial, err := access.check(stream)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check access",
+		})
+	}
+	if denial != nil {
+		return denial.respond(c)
+	}
+
+	rawURL := clipURL(stream, clip.ID)
+	if rawURL == "" {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Clip not found"})
+	}
+	signed, expiresAt, err := h.playbackSigner.SignURL(rawURL, race.ID, access.userID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", race.ID).Error("Failed to sign playback URL")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to prepare playback URL",
+		})
+	}
+
+	response := fiber.Map{
+		"clip":        clip,
+		"race_name":   race.Name,
+		"stream_name": stream.Name,
+		"clip_url":    signed,
+	}
+	if !expiresAt.IsZero() {
+		response["token_expires_at"] = expiresAt
+		// The URL is tied to this viewer
+		c.Set(fiber.HeaderCacheControl, "no-store")
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// ClipPlaylist serves a clip's playlist for nginx, which proxies
+// /hls/t/<token>/<stream>/clip-<id>.m3u8 here. The playlist lists the
+// recorded segments from the clip's start to its end and is ended.
+// GET /playback/clip
+func (h *RaceHandler) ClipPlaylist(c *fiber.Ctx) error {
+	stream, name, ok := h.tokenStream(c, clipPlaylistPattern)
+	if !ok {
+		return nil
+	}
+	clipID := strings.TrimSuffix(strings.TrimPrefix(name, clipPlaylistPrefix), clipPlaylistSuffix)
+	if !middleware.ValidateUUID(clipID) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	clip, err := h.timelineRepo.GetClip(clipID)
+	if err != nil {
+		logger.WithError(err).WithField("clip_id", clipID).Error("Failed to load clip")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if clip == nil || clip.StreamID != stream.ID {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	segments, err := h.segmentRepo.ListAll(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load clip segments")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	segments = services.ClipSegments(segments, clip.Start, clip.End)
+	if len(segments) == 0 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	playlist := dvrPlaylist(segments, 0, true)
+	playlist.PlaylistType = "VOD"
+	c.Set(fiber.HeaderContentType, "application/vnd.apple.mpegurl")
+	// The URL carries the viewer's token, and the clip won't change
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return c.Send(playlist.Encode())
+}
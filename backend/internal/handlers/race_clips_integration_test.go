@@ -0,0 +1,94 @@
+// +build integration
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_Clips_Integration(t *testing.T) {
+	app, handler, db := setupRaceTestApp(t)
+	app.Post("/races/:id/clips", handler.CreateClip)
+	app.Get("/playback/clip", handler.ClipPlaylist)
+
+	raceID := testutil.CreateTestRace(t, db, "Clips Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+	userID := testutil.CreateTestUser(t, db, "clipper@test.com", "password123", "Clipper")
+	defer testutil.CleanupUsers(t, db, []string{userID})
+
+	feed := createTestFeed(t, db, raceID, "main", models.StreamAccessRace, true)
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	// Six minutes recorded
+	recordTestSegments(t, db, feed.ID, start, 60)
+
+	createClip := func(t *testing.T, userID, body string) (int, models.RaceClip) {
+		req := httptest.NewRequest("POST", "/races/"+raceID+"/clips", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var clip models.RaceClip
+		if resp.StatusCode == fiber.StatusCreated {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&clip))
+		}
+		return resp.StatusCode, clip
+	}
+
+	t.Run("Refused", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID string
+			body   string
+			status int
+		}{
+			{"anonymous", "", `{"title": "Attack", "start_offset_seconds": 14}`, fiber.StatusUnauthorized},
+			{"no start or marker", userID, `{"title": "Attack"}`, fiber.StatusBadRequest},
+			{"longer than five minutes", userID, `{"title": "Attack", "start_offset_seconds": 14, "end_offset_seconds": 314.5}`, fiber.StatusBadRequest},
+			{"after the recording", userID, `{"title": "Attack", "start_offset_seconds": 400}`, fiber.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, _ := createClip(t, tt.userID, tt.body)
+				assert.Equal(t, tt.status, status)
+			})
+		}
+	})
+
+	t.Run("Five minutes from within a segment", func(t *testing.T) {
+		status, clip := createClip(t, userID, `{"title": "Attack", "start_offset_seconds": 14, "end_offset_seconds": 314}`)
+		require.Equal(t, fiber.StatusCreated, status)
+		assert.Equal(t, 14.0, clip.Start)
+		assert.Equal(t, 314.0, clip.End)
+
+		status, playlist := getPlaylist(t, app, handler, "/playback/clip", feed, "clip-"+clip.ID+".m3u8")
+		require.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n"+
+			"#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-MEDIA-SEQUENCE:2\n"+
+			testPlaylistSegments(start, 2, 52)+
+			"#EXT-X-ENDLIST\n", playlist,
+			"The clip runs from the start of the segment it starts in to the segment it ends in")
+	})
+
+	t.Run("Ends with the recording", func(t *testing.T) {
+		status, clip := createClip(t, userID, `{"title": "Sprint", "start_offset_seconds": 330}`)
+		require.Equal(t, fiber.StatusCreated, status)
+		assert.Equal(t, 360.0, clip.End)
+
+		status, playlist := getPlaylist(t, app, handler, "/playback/clip", feed, "clip-"+clip.ID+".m3u8")
+		require.Equal(t, fiber.StatusOK, status)
+		assert.Contains(t, playlist, "#EXT-X-MEDIA-SEQUENCE:55\n")
+		assert.True(t, strings.HasSuffix(playlist, "59.ts\n#EXT-X-ENDLIST\n"))
+	})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSuggestionBucketSeconds = 60
+	minSuggestionBucketSeconds     = 10
+	maxSuggestionBucketSeconds     = 600
+	defaultMarkerSuggestions       = 5
+	maxMarkerSuggestions           = 20
+)
+
+// RaceMarkerRequest creates or updates a race timeline marker
+type RaceMarkerRequest struct {
+	StreamID   string   `json:"stream_id"` // defaults to the primary feed
+	Offset     *float64 `json:"offset_seconds"`
+	MarkerType string   `json:"marker_type"`
+	Title      string   `json:"title"`
+	KmToGo     *float64 `json:"km_to_go"`
+}
+
+// validate checks and sanitizes the marker's fields. km_to_go markers are
+// titled by their distance when they have no title. It sends an error
+// response and returns false when they are invalid.
+func (req *RaceMarkerRequest) validate(c *fiber.Ctx) bool {
+	if req.StreamID != "" && !middleware.ValidateUUID(req.StreamID) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid stream ID format",
+		})
+		return false
+	}
+	if req.Offset == nil || *req.Offset < 0 {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "offset_seconds is required and must not be negative",
+		})
+		return false
+	}
+	if !models.IsValidMarkerType(req.MarkerType) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid marker_type. Must be one of: start, attack, breakaway, crash, kom, sprint, km_to_go, finish, other",
+		})
+		return false
+	}
+	if req.KmToGo != nil && (*req.KmToGo < 0 || *req.KmToGo >= 10000) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "km_to_go must be between 0 and 9999",
+		})
+		return false
+	}
+	if req.MarkerType == models.MarkerTypeKmToGo && req.KmToGo == nil {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "km_to_go is required for km_to_go markers",
+		})
+		return false
+	}
+
+	req.Title = middleware.SanitizeString(req.Title, 100)
+	if req.Title == "" && req.MarkerType == models.MarkerTypeKmToGo {
+		req.Title = strconv.FormatFloat(*req.KmToGo, 'f', -1, 64) + " km to go"
+	}
+	if req.Title == "" {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Title is required",
+		})
+		return false
+	}
+	return true
+}
+
+// requestedMarker loads the request's feed of race raceID and returns the marker. It
+// sends an error response and returns false when the feed isn't found.
+func (h *RaceHandler) requestedMarker(c *fiber.Ctx, raceID string, req *RaceMarkerRequest) (*models.RaceMarker, bool) {
+	stream, ok := loadFeedOr404(c, h.streamRepo, raceID, req.StreamID, "Stream not found")
+	if !ok {
+		return nil, false
+	}
+	return &models.RaceMarker{
+		RaceID:     raceID,
+		StreamID:   stream.ID,
+		Offset:     *req.Offset,
+		MarkerType: req.MarkerType,
+		Title:      req.Title,
+		KmToGo:     req.KmToGo,
+	}, true
+}
+
+// GetRaceMarkers returns a race's timeline markers in order, of one feed when
+// stream_id is given, for players to show as chapters
+// GET /races/:id/markers?stream_id=
+func (h *RaceHandler) GetRaceMarkers(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid race ID"})
+	}
+	streamID := c.Query("stream_id")
+	if streamID != "" && !middleware.ValidateUUID(streamID) {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid stream ID"})
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceID); !ok {
+		return nil
+	}
+
+	markers, err := h.timelineRepo.ListMarkers(raceID, streamID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to list race markers")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch markers"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"race_id": raceID,
+		"markers": markers,
+	})
+}
+
+// CreateRaceMarker adds a marker to a race's timeline (admin only)
+// POST /admin/races/:id/markers
+func (h *RaceHandler) CreateRaceMarker(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	var req RaceMarkerRequest
+	if !parseBody(c, &req) || !req.validate(c) {
+		return nil
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceID); !ok {
+		return nil
+	}
+	marker, ok := h.requestedMarker(c, raceID, &req)
+	if !ok {
+		return nil
+	}
+
+	if err := h.timelineRepo.CreateMarker(marker); err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to create race marker")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create marker",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(marker)
+}
+
+// UpdateRaceMarker replaces one of a race's markers (admin only)
+// PUT /admin/races/:id/markers/:markerId
+func (h *RaceHandler) UpdateRaceMarker(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	markerID := c.Params("markerId")
+	if !middleware.ValidateUUID(raceID) || !middleware.ValidateUUID(markerID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race or marker ID format",
+		})
+	}
+
+	var req RaceMarkerRequest
+	if !parseBody(c, &req) || !req.validate(c) {
+		return nil
+	}
+
+	marker, ok := h.requestedMarker(c, raceID, &req)
+	if !ok {
+		return nil
+	}
+	marker.ID = markerID
+
+	found, err := h.timelineRepo.UpdateMarker(marker)
+	if err != nil {
+		logger.WithError(err).WithField("marker_id", markerID).Error("Failed to update race marker")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update marker",
+		})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Marker not found",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(marker)
+}
+
+// DeleteRaceMarker removes one of a race's markers (admin only)
+// DELETE /admin/races/:id/markers/:markerId
+func (h *RaceHandler) DeleteRaceMarker(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	markerID := c.Params("markerId")
+	if !middleware.ValidateUUID(raceID) || !middleware.ValidateUUID(markerID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race or marker ID format",
+		})
+	}
+
+	found, err := h.timelineRepo.DeleteMarker(raceID, markerID)
+	if err != nil {
+		logger.WithError(err).WithField("marker_id", markerID).Error("Failed to delete race marker")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete marker",
+		})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Marker not found",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SuggestRaceMarkers suggests markers for a feed, the primary one unless
+// stream_id is given, where chat messages or playback starts spiked during
+// its recording. Admins add the ones they want with CreateRaceMarker (admin
+// only).
+// GET /admin/races/:id/markers/suggestions?stream_id=&bucket=60&limit=5
+func (h *RaceHandler) SuggestRaceMarkers(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	streamID := c.Query("stream_id")
+	if !middleware.ValidateUUID(raceID) || (streamID != "" && !middleware.ValidateUUID(streamID)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race or stream ID format",
+		})
+	}
+	bucketSeconds, ok := replayQueryInt(c, "bucket", defaultSuggestionBucketSeconds, minSuggestionBucketSeconds, maxSuggestionBucketSeconds)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid bucket parameter"})
+	}
+	limit, ok := replayQueryInt(c, "limit", defaultMarkerSuggestions, 1, maxMarkerSuggestions)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid limit parameter"})
+	}
+
+	stream, ok := loadFeedOr404(c, h.streamRepo, raceID, streamID, "Stream not found")
+	if !ok {
+		return nil
+	}
+
+	segments, err := h.segmentRepo.ListAll(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load recorded segments")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to suggest markers"})
+	}
+	if len(segments) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Stream has no recording"})
+	}
+
+	last := segments[len(segments)-1]
+	from, to := segments[0].ProgramDateTime, last.ProgramDateTime.Add(time.Duration(last.Duration*float64(time.Second)))
+	buckets, err := h.timelineRepo.ActivityBuckets(raceID, stream.ID, from, to, time.Duration(bucketSeconds)*time.Second)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to count viewer activity")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to suggest markers"})
+	}
+	markers, err := h.timelineRepo.ListMarkers(raceID, stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to list race markers")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to suggest markers"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"stream_id":      stream.ID,
+		"bucket_seconds": bucketSeconds,
+		"suggestions":    services.SuggestMarkers(segments, buckets, markers, limit),
+	})
+}
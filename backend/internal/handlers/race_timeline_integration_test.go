@@ -0,0 +1,74 @@
+// +build integration
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_SuggestRaceMarkers_Integration(t *testing.T) {
+	app, handler, db := setupRaceTestApp(t)
+	app.Get("/admin/races/:id/markers/suggestions", handler.SuggestRaceMarkers)
+
+	raceID := testutil.CreateTestRace(t, db, "Markers Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	feed := createTestFeed(t, db, raceID, "main", models.StreamAccessRace, true)
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	// Half an hour recorded
+	recordTestSegments(t, db, feed.ID, start, 300)
+
+	chat := func(at time.Time, count int) {
+		for i := 0; i < count; i++ {
+			_, err := db.Exec(`INSERT INTO chat_messages (race_id, username, message, created_at) VALUES ($1, 'fan', 'Allez!', $2)`, raceID, at)
+			require.NoError(t, err)
+		}
+	}
+	play := func(at time.Time, count int) {
+		for i := 0; i < count; i++ {
+			_, err := db.Exec(`INSERT INTO playback_events (stream_id, client_id, event_type, created_at) VALUES ($1, $2, 'play', $3)`,
+				feed.ID, fmt.Sprintf("client-%d", i), at)
+			require.NoError(t, err)
+		}
+	}
+	for minute := 0; minute < 30; minute++ {
+		chat(start.Add(time.Duration(minute)*time.Minute+10*time.Second), 2)
+	}
+	chat(start.Add(10*time.Minute+20*time.Second), 40) // crash
+	play(start.Add(20*time.Minute+5*time.Second), 20)  // attack, viewers tuning in
+
+	suggest := func(t *testing.T) []float64 {
+		resp, err := app.Test(httptest.NewRequest("GET", "/admin/races/"+raceID+"/markers/suggestions", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body struct {
+			StreamID    string                    `json:"stream_id"`
+			Suggestions []models.MarkerSuggestion `json:"suggestions"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, feed.ID, body.StreamID)
+		offsets := make([]float64, 0, len(body.Suggestions))
+		for _, s := range body.Suggestions {
+			offsets = append(offsets, s.Offset)
+		}
+		return offsets
+	}
+
+	assert.ElementsMatch(t, []float64{10*60 - 30, 20*60 - 30}, suggest(t), "Suggestions lead their spike")
+
+	marker := &models.RaceMarker{RaceID: raceID, StreamID: feed.ID, Offset: 20*60 - 20, MarkerType: "attack", Title: "Attack"}
+	require.NoError(t, repository.NewRaceTimelineRepository(db).CreateMarker(marker))
+	assert.Equal(t, []float64{10*60 - 30}, suggest(t), "Marked spikes aren't suggested again")
+}
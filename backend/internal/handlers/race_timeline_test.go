@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_RaceMarkerValidation(t *testing.T) {
+	app := fiber.New()
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, nil, time.Hour)
+	app.Post("/admin/races/:id/markers", handler.CreateRaceMarker)
+	app.Put("/admin/races/:id/markers/:markerId", handler.UpdateRaceMarker)
+	app.Delete("/admin/races/:id/markers/:markerId", handler.DeleteRaceMarker)
+	app.Get("/admin/races/:id/markers/suggestions", handler.SuggestRaceMarkers)
+	app.Get("/races/:id/markers", handler.GetRaceMarkers)
+
+	raceID := "8a4b3c2d-1e0f-4a5b-8c7d-6e5f4a3b2c1d"
+	markers := "/admin/races/" + raceID + "/markers"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"invalid race id", "POST", "/admin/races/not-a-uuid/markers", `{"offset_seconds": 10, "marker_type": "attack", "title": "Attack"}`},
+		{"missing offset", "POST", markers, `{"marker_type": "attack", "title": "Attack"}`},
+		{"negative offset", "POST", markers, `{"offset_seconds": -1, "marker_type": "attack", "title": "Attack"}`},
+		{"invalid marker type", "POST", markers, `{"offset_seconds": 10, "marker_type": "puncture", "title": "Puncture"}`},
+		{"missing title", "POST", markers, `{"offset_seconds": 10, "marker_type": "attack", "title": "  "}`},
+		{"km to go without distance", "POST", markers, `{"offset_seconds": 10, "marker_type": "km_to_go"}`},
+		{"negative distance", "POST", markers, `{"offset_seconds": 10, "marker_type": "km_to_go", "km_to_go": -5}`},
+		{"invalid stream id", "POST", markers, `{"stream_id": "feed", "offset_seconds": 10, "marker_type": "attack", "title": "Attack"}`},
+		{"update invalid marker id", "PUT", markers + "/not-a-uuid", `{"offset_seconds": 10, "marker_type": "attack", "title": "Attack"}`},
+		{"delete invalid marker id", "DELETE", markers + "/not-a-uuid", ""},
+		{"suggestions invalid stream id", "GET", markers + "/suggestions?stream_id=feed", ""},
+		{"suggestions bucket too small", "GET", markers + "/suggestions?bucket=1", ""},
+		{"suggestions invalid limit", "GET", markers + "/suggestions?limit=0", ""},
+		{"list invalid stream id", "GET", "/races/" + raceID + "/markers?stream_id=feed", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
+
+func TestRaceMarkerRequest_KmToGoTitle(t *testing.T) {
+	km, offset := 12.5, 3600.0
+	tests := []struct {
+		name string
+		req  RaceMarkerRequest
+		want string
+	}{
+		{"titled by distance", RaceMarkerRequest{Offset: &offset, MarkerType: "km_to_go", KmToGo: &km}, "12.5 km to go"},
+		{"given title kept", RaceMarkerRequest{Offset: &offset, MarkerType: "km_to_go", KmToGo: &km, Title: "Flamme rouge"}, "Flamme rouge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			req := tt.req
+			app.Get("/", func(c *fiber.Ctx) error {
+				if !req.validate(c) {
+					return nil
+				}
+				return c.SendString(req.Title)
+			})
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.want, req.Title)
+		})
+	}
+}
@@ -54,4 +54,27 @@ func loadStreamOr404(c *fiber.Ctx, streamRepo *repository.StreamRepository, race
 	return stream, true
 }
 
+// loadFeedOr404 fetches a stream of the race by ID, or the race's primary
+// stream when streamID is empty, like loadStreamOr404.
+func loadFeedOr404(c *fiber.Ctx, streamRepo *repository.StreamRepository, raceID, streamID string, notFoundMessage string) (*models.Stream, bool) {
+	if streamID == "" {
+		return loadStreamOr404(c, streamRepo, raceID, notFoundMessage)
+	}
 
+	stream, err := streamRepo.GetFeed(raceID, streamID)
+	if err != nil {
+		_ = c.Status(fiber.StatusInternalServerError).JSON(APIError{
+			Error: "Failed to fetch stream",
+		})
+		return nil, false
+	}
+
+	if stream == nil {
+		_ = c.Status(fiber.StatusNotFound).JSON(APIError{
+			Error: notFoundMessage,
+		})
+		return nil, false
+	}
+
+	return stream, true
+}
@@ -1,33 +1,80 @@
 package handlers
 
 import (
+	"github.com/cyclingstream/backend/internal/models"
 	"github.com/cyclingstream/backend/internal/repository"
 	"github.com/gofiber/fiber/v2"
 )
 
+// streamStatusListener is told about stream status changes, e.g. to announce
+// them in the race's chat
+type streamStatusListener func(*models.StreamStatusChange)
+
+func (l streamStatusListener) notify(change *models.StreamStatusChange) {
+	if l != nil && change != nil {
+		l(change)
+	}
+}
+
 type StreamHandler struct {
 	streamRepo *repository.StreamRepository
+	// Shared secret expected on nginx-rtmp publish callbacks
+	publishSecret  string
+	statusListener streamStatusListener
 }
 
-func NewStreamHandler(streamRepo *repository.StreamRepository) *StreamHandler {
+func NewStreamHandler(streamRepo *repository.StreamRepository, publishSecret string) *StreamHandler {
 	return &StreamHandler{
-		streamRepo: streamRepo,
+		streamRepo:    streamRepo,
+		publishSecret: publishSecret,
 	}
 }
 
+// SetStatusListener sets the function told about status changes caused by
+// the ingest callbacks
+func (h *StreamHandler) SetStatusListener(listener func(*models.StreamStatusChange)) {
+	h.statusListener = listener
+}
+
 func (h *StreamHandler) GetStreamStatus(c *fiber.Ctx) error {
 	raceID, ok := requireParam(c, "id", "Race ID is required")
 	if !ok {
 		return nil
 	}
 
-	stream, ok := loadStreamOr404(c, h.streamRepo, raceID, "Stream not found")
-	if !ok {
-		return nil
+	streams, err := h.streamRepo.ListByRaceID(raceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{
+			Error: "Failed to fetch stream",
+		})
+	}
+	if len(streams) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{
+			Error: "Stream not found",
+		})
+	}
+
+	feeds := make([]fiber.Map, 0, len(streams))
+	for _, stream := range streams {
+		feeds = append(feeds, fiber.Map{
+			"stream_id":         stream.ID,
+			"name":              stream.Name,
+			"is_primary":        stream.IsPrimary,
+			"status":            stream.Status,
+			"previous_status":   stream.PreviousStatus,
+			"status_changed_at": stream.StatusChangedAt,
+		})
 	}
 
+	// Viewers poll this to follow the stream, so it must not be cached. The
+	// race's status is its primary feed's, which is listed first.
+	primary := streams[0]
+	c.Set(fiber.HeaderCacheControl, "no-cache")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status": stream.Status,
+		"status":            primary.Status,
+		"previous_status":   primary.PreviousStatus,
+		"status_changed_at": primary.StatusChangedAt,
+		"feeds":             feeds,
 	})
 }
 
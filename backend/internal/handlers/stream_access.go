@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamDenial is why a viewer may not watch a feed
+type streamDenial struct {
+	requiresLogin   bool
+	requiresPayment bool
+}
+
+// respond sends the error response for the denial
+func (d *streamDenial) respond(c *fiber.Ctx) error {
+	if d.requiresPayment && d.requiresLogin {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":            "Authentication required",
+			"requires_payment": true,
+		})
+	}
+	if d.requiresPayment {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":            "Payment required to access this race",
+			"requires_payment": true,
+		})
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(APIError{
+		Error: "Authentication required to access this stream",
+	})
+}
+
+// streamAccess checks which feeds of a race a viewer may watch. The viewer's
+// entitlement is looked up at most once.
+type streamAccess struct {
+	h        *RaceHandler
+	race     *models.Race
+	userID   string // empty for anonymous viewers
+	entitled *bool
+}
+
+func (h *RaceHandler) newStreamAccess(race *models.Race, c *fiber.Ctx) *streamAccess {
+	userID, _ := c.Locals("user_id").(string)
+	return &streamAccess{h: h, race: race, userID: userID}
+}
+
+// check returns why the viewer may not watch the feed, or nil if they may
+func (a *streamAccess) check(stream *models.Stream) (*streamDenial, error) {
+	var needsLogin, needsEntitlement bool
+	switch stream.Access {
+	case models.StreamAccessPublic:
+	case models.StreamAccessLogin:
+		needsLogin = true
+	case models.StreamAccessPaid:
+		needsLogin, needsEntitlement = true, true
+	default:
+		needsLogin = a.race.RequiresLogin || !a.race.IsFree
+		needsEntitlement = !a.race.IsFree
+	}
+
+	if needsLogin && a.userID == "" {
+		return &streamDenial{requiresLogin: true, requiresPayment: needsEntitlement}, nil
+	}
+	if !needsEntitlement {
+		return nil, nil
+	}
+
+	if a.entitled == nil {
+		// Free races are open to every signed-in viewer
+		entitled, err := a.h.entitlementRepo.HasAccess(a.userID, a.race.ID)
+		if err != nil {
+			return nil, err
+		}
+		a.entitled = &entitled
+	}
+	if !*a.entitled {
+		return &streamDenial{requiresPayment: true}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAccess_Check(t *testing.T) {
+	free := &models.Race{ID: "r1", IsFree: true}
+	members := &models.Race{ID: "r1", IsFree: true, RequiresLogin: true}
+	paid := &models.Race{ID: "r1", PriceCents: 500}
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		race   *models.Race
+		access string
+		userID string
+		// Known entitlement, so the repository isn't needed
+		entitled *bool
+		denial   *streamDenial
+	}{
+		{"free race anonymous", free, models.StreamAccessRace, "", nil, nil},
+		{"login race anonymous", members, models.StreamAccessRace, "", nil, &streamDenial{requiresLogin: true}},
+		{"login race signed in", members, models.StreamAccessRace, "u1", nil, nil},
+		{"paid race anonymous", paid, models.StreamAccessRace, "", nil, &streamDenial{requiresLogin: true, requiresPayment: true}},
+		{"paid race not entitled", paid, models.StreamAccessRace, "u1", &no, &streamDenial{requiresPayment: true}},
+		{"paid race entitled", paid, models.StreamAccessRace, "u1", &yes, nil},
+		{"public feed of paid race", paid, models.StreamAccessPublic, "", nil, nil},
+		{"login feed of paid race", paid, models.StreamAccessLogin, "u1", nil, nil},
+		{"login feed anonymous", free, models.StreamAccessLogin, "", nil, &streamDenial{requiresLogin: true}},
+		{"paid feed of free race anonymous", free, models.StreamAccessPaid, "", nil, &streamDenial{requiresLogin: true, requiresPayment: true}},
+		{"paid feed not entitled", free, models.StreamAccessPaid, "u1", &no, &streamDenial{requiresPayment: true}},
+		{"paid feed entitled", paid, models.StreamAccessPaid, "u1", &yes, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := &streamAccess{race: tt.race, userID: tt.userID, entitled: tt.entitled}
+			denial, err := access.check(&models.Stream{Access: tt.access})
+			require.NoError(t, err)
+			assert.Equal(t, tt.denial, denial)
+		})
+	}
+}
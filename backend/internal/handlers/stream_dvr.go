@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/hls"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// dvrPlaylistName is the DVR playlist's file name, next to the stream's live
+// playlist on the origin so segment URIs resolve the same way
+const dvrPlaylistName = "dvr.m3u8"
+
+// dvrWindowOf returns how far back viewers can rewind the stream
+func (h *RaceHandler) dvrWindowOf(stream *models.Stream) time.Duration {
+	if stream.DVRWindowSeconds != nil {
+		return time.Duration(*stream.DVRWindowSeconds) * time.Second
+	}
+	return h.dvrWindow
+}
+
+// originScope returns the HLS directory the stream's origin playlist is in,
+// as in a playback token's scope, or "" when it isn't served by the origin
+func originScope(stream *models.Stream) string {
+	if stream.OriginURL == nil {
+		return ""
+	}
+	u, err := url.Parse(*stream.OriginURL)
+	if err != nil || !strings.HasPrefix(u.Path, services.HLSPathPrefix) {
+		return ""
+	}
+	// A playlist directly in /hls/ has no directory of its own
+	scope := path.Dir(u.Path) + "/"
+	if scope == services.HLSPathPrefix {
+		return ""
+	}
+	return scope
+}
+
+// dvrURL returns the URL of the stream's DVR playlist on the origin, or ""
+// when the stream has no DVR while live
+func (h *RaceHandler) dvrURL(stream *models.Stream) string {
+	if stream.Status != models.StreamStatusLive || h.dvrWindowOf(stream) <= 0 {
+		return ""
+	}
+	return originPlaylistURL(stream, dvrPlaylistName)
+}
+
+// originPlaylistURL returns the URL of the playlist named name next to the
+// stream's origin playlist, or "" when it isn't served by the origin
+func originPlaylistURL(stream *models.Stream, name string) string {
+	if originScope(stream) == "" {
+		return ""
+	}
+	u, err := url.Parse(*stream.OriginURL)
+	if err != nil {
+		return ""
+	}
+	u.Path = path.Join(path.Dir(u.Path), name)
+	u.RawQuery = ""
+	return u.String()
+}
+
+// tokenStream returns the stream whose playlist nginx proxied here, and the
+// playlist's file name, for playlists matching pattern (as in path.Match)
+// below /hls/t/<token>/<stream>/, with the original request URI in
+// X-Original-URI. The token was only issued to viewers with access to the
+// stream. It sends an error status and returns false when the request has no
+// valid token or the stream isn't found.
+func (h *RaceHandler) tokenStream(c *fiber.Ctx, pattern string) (*models.Stream, string, bool) {
+	requestURI := c.Get("X-Original-URI")
+	if requestURI == "" {
+		_ = c.SendStatus(fiber.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	requestPath, _, _ := strings.Cut(requestURI, "?")
+	requestPath, err := url.PathUnescape(requestPath)
+	if err != nil {
+		_ = c.SendStatus(fiber.StatusForbidden)
+		return nil, "", false
+	}
+	name := path.Base(requestPath)
+	if matched, _ := path.Match(pattern, name); !matched {
+		_ = c.SendStatus(fiber.StatusForbidden)
+		return nil, "", false
+	}
+
+	claims, err := h.playbackSigner.Authorize(requestPath)
+	if err != nil {
+		_ = c.SendStatus(fiber.StatusForbidden)
+		return nil, "", false
+	}
+
+	streams, err := h.streamRepo.ListByRaceID(claims.RaceID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", claims.RaceID).Error("Failed to load streams for playlist")
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+		return nil, "", false
+	}
+	for _, s := range streams {
+		if originScope(s) == claims.Scope {
+			return s, name, true
+		}
+	}
+	_ = c.SendStatus(fiber.StatusNotFound)
+	return nil, "", false
+}
+
+// DVRPlaylist serves the DVR playlist of a stream for nginx, which proxies
+// /hls/t/<token>/<stream>/dvr.m3u8 here. The playlist lists the stream's
+// recorded segments from its DVR window, each with its program date time, so
+// players can rewind and return to the live edge. Its segment URIs are
+// relative and so carry the viewer's token, like those of the live playlist.
+// GET /playback/dvr
+func (h *RaceHandler) DVRPlaylist(c *fiber.Ctx) error {
+	stream, _, ok := h.tokenStream(c, dvrPlaylistName)
+	if !ok {
+		return nil
+	}
+
+	window := h.dvrWindowOf(stream)
+	if window <= 0 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	segments, discontinuities, err := h.segmentRepo.ListWindow(stream.ID, window)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load DVR segments")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if len(segments) == 0 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/vnd.apple.mpegurl")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	return c.Send(dvrPlaylist(segments, discontinuities, stream.Status != models.StreamStatusLive).Encode())
+}
+
+// dvrPlaylist builds the playlist of recorded segments. It is ended once the
+// stream is no longer live.
+func dvrPlaylist(segments []models.StreamSegment, discontinuities int64, ended bool) *hls.MediaPlaylist {
+	playlist := &hls.MediaPlaylist{
+		MediaSequence:         segments[0].Sequence,
+		DiscontinuitySequence: discontinuities,
+		Segments:              make([]hls.Segment, 0, len(segments)),
+		Ended:                 ended,
+	}
+	for _, segment := range segments {
+		playlist.Segments = append(playlist.Segments, hls.Segment{
+			Sequence:        segment.Sequence,
+			URI:             segment.URI,
+			Duration:        segment.Duration,
+			ProgramDateTime: segment.ProgramDateTime,
+			Discontinuity:   segment.Discontinuity,
+		})
+	}
+	return playlist
+}
@@ -0,0 +1,102 @@
+// +build integration
+
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordTestSegments stores count six-second segments of a stream, numbered
+// from 0 and recorded back to back from start. A discontinuity precedes the
+// sequences in discontinuities.
+func recordTestSegments(t *testing.T, db *sql.DB, streamID string, start time.Time, count int, discontinuities ...int64) {
+	segments := make([]models.StreamSegment, count)
+	for i := range segments {
+		segments[i] = models.StreamSegment{
+			StreamID:        streamID,
+			Sequence:        int64(i),
+			URI:             fmt.Sprintf("%d.ts", i),
+			Duration:        6,
+			ProgramDateTime: start.Add(time.Duration(i) * 6 * time.Second),
+		}
+	}
+	for _, sequence := range discontinuities {
+		segments[sequence].Discontinuity = true
+	}
+	require.NoError(t, repository.NewStreamSegmentRepository(db).InsertSegments(segments))
+}
+
+// getPlaylist requests a playlist of the feed with a viewer's token, the way
+// nginx proxies it, and returns the response status and body
+func getPlaylist(t *testing.T, app *fiber.App, handler *RaceHandler, route string, feed *models.Stream, name string) (int, string) {
+	scope := services.HLSPathPrefix + feed.Name + "/"
+	token := handler.playbackSigner.Sign(services.PlaybackClaims{RaceID: feed.RaceID, Scope: scope, ExpiresAt: time.Now().Add(time.Minute)})
+
+	req := httptest.NewRequest("GET", route, nil)
+	req.Header.Set("X-Original-URI", services.PlaybackTokenPrefix+token+"/"+feed.Name+"/"+name)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+// testPlaylistSegments returns the playlist lines of the segments from first
+// to last, as recorded by recordTestSegments from start
+func testPlaylistSegments(start time.Time, first, last int, discontinuities ...int) string {
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		for _, d := range discontinuities {
+			if d == i {
+				b.WriteString("#EXT-X-DISCONTINUITY\n")
+			}
+		}
+		at := start.Add(time.Duration(i) * 6 * time.Second)
+		fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:6.000,\n%d.ts\n", at.Format("2006-01-02T15:04:05.000Z07:00"), i)
+	}
+	return b.String()
+}
+
+func TestRaceHandler_DVRPlaylist_Integration(t *testing.T) {
+	app, handler, db := setupRaceTestApp(t)
+	app.Get("/playback/dvr", handler.DVRPlaylist)
+
+	raceID := testutil.CreateTestRace(t, db, "DVR Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	feed := createTestFeed(t, db, raceID, "main", models.StreamAccessRace, true)
+	// Rewind up to 30 seconds behind the live edge
+	_, err := db.Exec(`UPDATE streams SET dvr_window_seconds = 30 WHERE id = $1`, feed.ID)
+	require.NoError(t, err)
+
+	// Two minutes recorded, with an encoder reconnect at segment 5
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	recordTestSegments(t, db, feed.ID, start, 20, 5)
+
+	status, playlist := getPlaylist(t, app, handler, "/playback/dvr", feed, "dvr.m3u8")
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n"+
+		"#EXT-X-MEDIA-SEQUENCE:14\n#EXT-X-DISCONTINUITY-SEQUENCE:1\n"+
+		testPlaylistSegments(start, 14, 19), playlist,
+		"The window ends at the live edge, which keeps moving")
+
+	_, err = db.Exec(`UPDATE streams SET status = $2 WHERE id = $1`, feed.ID, models.StreamStatusEnded)
+	require.NoError(t, err)
+	status, playlist = getPlaylist(t, app, handler, "/playback/dvr", feed, "dvr.m3u8")
+	require.Equal(t, fiber.StatusOK, status)
+	assert.True(t, strings.HasSuffix(playlist, "19.ts\n#EXT-X-ENDLIST\n"), "Players stop at the end once the stream ends")
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_DVRPlaylistRefusals(t *testing.T) {
+	logger.Init("test")
+	signer := services.NewPlaybackSigner("playback-secret", time.Minute, []string{"origin.example.com"})
+	app := fiber.New()
+	// Requests are refused before the streams are loaded
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, signer, time.Hour)
+	app.Get("/playback/dvr", handler.DVRPlaylist)
+
+	scope := "/hls/race-1/"
+	token := signer.Sign(services.PlaybackClaims{RaceID: "r1", Scope: scope, ExpiresAt: time.Now().Add(time.Minute)})
+	expired := signer.Sign(services.PlaybackClaims{RaceID: "r1", Scope: scope, ExpiresAt: time.Now().Add(-time.Second)})
+
+	tests := []struct {
+		name   string
+		uri    string
+		status int
+	}{
+		{"missing uri", "", fiber.StatusUnauthorized},
+		{"untokenized", "/hls/race-1/dvr.m3u8", fiber.StatusForbidden},
+		{"other stream", services.PlaybackTokenPrefix + token + "/race-2/dvr.m3u8", fiber.StatusForbidden},
+		{"expired", services.PlaybackTokenPrefix + expired + "/race-1/dvr.m3u8", fiber.StatusForbidden},
+		{"not the dvr playlist", services.PlaybackTokenPrefix + token + "/race-1/index.m3u8", fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/playback/dvr", nil)
+			if tt.uri != "" {
+				req.Header.Set("X-Original-URI", tt.uri)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestRaceHandler_DVRURL(t *testing.T) {
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, nil, time.Hour)
+	origin, cdn, off := "http://origin.example.com/hls/race-1/index.m3u8", "https://cdn.example.com/live/index.m3u8", 0
+	flat := "http://origin.example.com/hls/stream.m3u8"
+
+	live := &models.Stream{Status: models.StreamStatusLive, OriginURL: &origin}
+	assert.Equal(t, "http://origin.example.com/hls/race-1/dvr.m3u8", handler.dvrURL(live))
+	assert.Equal(t, time.Hour, handler.dvrWindowOf(live))
+
+	assert.Empty(t, handler.dvrURL(&models.Stream{Status: models.StreamStatusEnded, OriginURL: &origin}), "only while live")
+	assert.Empty(t, handler.dvrURL(&models.Stream{Status: models.StreamStatusLive, OriginURL: &cdn}), "only from the HLS origin")
+	assert.Empty(t, handler.dvrURL(&models.Stream{Status: models.StreamStatusLive, OriginURL: &flat}), "only from a stream directory")
+	assert.Empty(t, handler.dvrURL(&models.Stream{Status: models.StreamStatusLive, OriginURL: &origin, DVRWindowSeconds: &off}), "disabled by the stream")
+}
+
+func TestDVRPlaylist(t *testing.T) {
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	segments := []models.StreamSegment{
+		{Sequence: 30, URI: "30.ts", Duration: 6, ProgramDateTime: start},
+		{Sequence: 31, URI: "31.ts", Duration: 6, ProgramDateTime: start.Add(6 * time.Second), Discontinuity: true},
+	}
+
+	playlist := dvrPlaylist(segments, 2, false)
+	assert.Equal(t, int64(30), playlist.MediaSequence)
+	assert.Equal(t, int64(2), playlist.DiscontinuitySequence)
+	assert.False(t, playlist.Ended, "live playlists keep growing")
+	require.Len(t, playlist.Segments, 2)
+	assert.Equal(t, start, playlist.Segments[0].ProgramDateTime)
+	assert.True(t, playlist.Segments[1].Discontinuity)
+
+	assert.True(t, dvrPlaylist(segments, 0, true).Ended)
+}
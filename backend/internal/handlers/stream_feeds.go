@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StreamFeedRequest creates or updates one of a race's feeds
+type StreamFeedRequest struct {
+	streamSourceRequest
+	Name      string `json:"name"`
+	IsPrimary bool   `json:"is_primary"`
+	Access    string `json:"access"`
+	Status    string `json:"status"` // only when creating; defaults to scheduled
+	// DVRWindowSeconds is how far back viewers can rewind while the feed is
+	// live: unset uses the default window, 0 disables DVR
+	DVRWindowSeconds *int `json:"dvr_window_seconds"`
+}
+
+// validate checks and sanitizes the feed's fields, allowing DVR windows up to
+// maxDVRWindow. It sends an error response and returns false when they are
+// invalid.
+func (req *StreamFeedRequest) validate(c *fiber.Ctx, maxDVRWindow time.Duration) bool {
+	req.Name = middleware.SanitizeString(req.Name, 100)
+	if req.Name == "" {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+		return false
+	}
+
+	if req.Access == "" {
+		req.Access = models.StreamAccessRace
+	}
+	if !models.IsValidStreamAccess(req.Access) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid access. Must be one of: race, public, login, paid",
+		})
+		return false
+	}
+
+	if req.DVRWindowSeconds != nil && (*req.DVRWindowSeconds < 0 || time.Duration(*req.DVRWindowSeconds)*time.Second > maxDVRWindow) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("dvr_window_seconds must be between 0 and %d", int(maxDVRWindow.Seconds())),
+		})
+		return false
+	}
+
+	return req.streamSourceRequest.validate(c)
+}
+
+func (req *StreamFeedRequest) stream(raceID string) *models.Stream {
+	return &models.Stream{
+		RaceID:           raceID,
+		Name:             req.Name,
+		IsPrimary:        req.IsPrimary,
+		Access:           req.Access,
+		Status:           req.Status,
+		StreamType:       req.StreamType,
+		SourceID:         req.SourceID,
+		OriginURL:        req.OriginURL,
+		CDNURL:           req.CDNURL,
+		DVRWindowSeconds: req.DVRWindowSeconds,
+	}
+}
+
+// loadFeedParam loads the feed given by the streamId route parameter, or the
+// race's primary stream on routes without one. It sends an error response and
+// returns false when the feed can't be loaded.
+func (h *AdminHandler) loadFeedParam(c *fiber.Ctx, raceID string) (*models.Stream, bool) {
+	streamID := c.Params("streamId")
+	if streamID != "" && !middleware.ValidateUUID(streamID) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid stream ID format",
+		})
+		return nil, false
+	}
+	return loadFeedOr404(c, h.streamRepo, raceID, streamID, "Stream not found")
+}
+
+// ListStreamFeeds lists a race's feeds, primary first (admin only)
+// GET /admin/races/:id/streams
+func (h *AdminHandler) ListStreamFeeds(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	streams, err := h.streamRepo.ListByRaceID(raceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch streams",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(streams)
+}
+
+// CreateStreamFeed adds a feed to a race. A primary feed takes over from the
+// race's current primary feed; the race's first feed is always primary
+// (admin only).
+// POST /admin/races/:id/streams
+func (h *AdminHandler) CreateStreamFeed(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	var req StreamFeedRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	if req.Status != "" && !models.IsValidStreamStatus(req.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalidStreamStatusMessage,
+		})
+	}
+	if !req.validate(c, h.maxDVRWindow) {
+		return nil
+	}
+
+	if _, ok := loadRaceOr404(c, h.raceRepo, raceID); !ok {
+		return nil
+	}
+
+	stream := req.stream(raceID)
+	err := h.streamRepo.CreateFeed(stream)
+	if errors.Is(err, repository.ErrStreamNameTaken) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Another stream of this race has that name",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create stream",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(stream)
+}
+
+// UpdateStreamFeed updates a feed's name, access, source and DVR window, and
+// makes it primary when is_primary is set. The primary feed can't be demoted
+// directly; another feed has to be made primary instead. Status changes go
+// through PUT /admin/races/:id/streams/:streamId/status (admin only).
+// PUT /admin/races/:id/streams/:streamId
+func (h *AdminHandler) UpdateStreamFeed(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	streamID, ok := requireParam(c, "streamId", "Stream ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) || !middleware.ValidateUUID(streamID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race or stream ID format",
+		})
+	}
+
+	var req StreamFeedRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	if req.Status != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status can't be set here; use PUT /admin/races/:id/streams/:streamId/status",
+		})
+	}
+	if !req.validate(c, h.maxDVRWindow) {
+		return nil
+	}
+
+	stream := req.stream(raceID)
+	stream.ID = streamID
+	err := h.streamRepo.UpdateFeed(stream)
+	switch {
+	case errors.Is(err, repository.ErrStreamNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stream not found",
+		})
+	case errors.Is(err, repository.ErrStreamNameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Another stream of this race has that name",
+		})
+	case errors.Is(err, repository.ErrPrimaryStreamRequired):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update stream",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stream)
+}
@@ -0,0 +1,152 @@
+// +build integration
+
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRaceTestApp creates a race handler on the test database. Requests
+// act as the user in the X-Test-User header, if any.
+func setupRaceTestApp(t *testing.T) (*fiber.App, *RaceHandler, *sql.DB) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	logger.Init("test")
+
+	db := testutil.GetTestDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	signer := services.NewPlaybackSigner("playback-secret", time.Minute, []string{"origin.example.com"})
+	handler := NewRaceHandler(
+		repository.NewRaceRepository(db),
+		repository.NewStreamRepository(db),
+		repository.NewStreamProviderRepository(db),
+		repository.NewStreamSegmentRepository(db),
+		repository.NewVODAssetRepository(db),
+		repository.NewRaceTimelineRepository(db),
+		repository.NewEntitlementRepository(db),
+		signer,
+		time.Hour,
+	)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if userID := c.Get("X-Test-User"); userID != "" {
+			c.Locals("user_id", userID)
+		}
+		return c.Next()
+	})
+	return app, handler, db
+}
+
+// createTestFeed adds a feed to a race, played from its own origin directory
+func createTestFeed(t *testing.T, db *sql.DB, raceID, name, access string, primary bool) *models.Stream {
+	origin := "http://origin.example.com/hls/" + name + "/index.m3u8"
+	stream := &models.Stream{RaceID: raceID, Name: name, Access: access, IsPrimary: primary, Status: models.StreamStatusLive, OriginURL: &origin}
+	require.NoError(t, repository.NewStreamRepository(db).CreateFeed(stream))
+	return stream
+}
+
+func TestRaceHandler_GetRaceStreamFeeds_Integration(t *testing.T) {
+	app, handler, db := setupRaceTestApp(t)
+	app.Get("/races/:id/stream", handler.GetRaceStream)
+
+	raceID := testutil.CreateTestRace(t, db, "Feeds Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+	_, err := db.Exec(`UPDATE races SET is_free = FALSE, price_cents = 999 WHERE id = $1`, raceID)
+	require.NoError(t, err)
+
+	viewer := testutil.CreateTestUser(t, db, "feeds-viewer@test.com", "password123", "Feeds Viewer")
+	ticketHolder := testutil.CreateTestUser(t, db, "feeds-ticket@test.com", "password123", "Feeds Ticket")
+	defer testutil.CleanupUsers(t, db, []string{viewer, ticketHolder})
+	require.NoError(t, repository.NewEntitlementRepository(db).Create(&models.Entitlement{UserID: ticketHolder, RaceID: raceID, Type: "ticket"}))
+
+	main := createTestFeed(t, db, raceID, "main", models.StreamAccessRace, true)
+	finish := createTestFeed(t, db, raceID, "finish", models.StreamAccessPublic, false)
+	onboard := createTestFeed(t, db, raceID, "onboard", models.StreamAccessLogin, false)
+
+	type feed struct {
+		StreamID        string `json:"stream_id"`
+		Name            string `json:"name"`
+		IsPrimary       bool   `json:"is_primary"`
+		Locked          bool   `json:"locked"`
+		RequiresLogin   bool   `json:"requires_login"`
+		RequiresPayment bool   `json:"requires_payment"`
+		OriginURL       string `json:"origin_url"`
+	}
+	get := func(t *testing.T, userID string) (feed, []feed) {
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/stream", nil)
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body struct {
+			feed
+			Feeds []feed `json:"feeds"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Feeds, 3, "Every feed is listed, locked or not")
+		return body.feed, body.Feeds
+	}
+
+	t.Run("Anonymous viewers get the public feed", func(t *testing.T) {
+		def, feeds := get(t, "")
+
+		assert.Equal(t, main.ID, feeds[0].StreamID, "The primary feed comes first")
+		assert.True(t, feeds[0].IsPrimary)
+		assert.False(t, feeds[1].IsPrimary)
+		assert.False(t, feeds[2].IsPrimary)
+
+		byID := map[string]feed{}
+		for _, f := range feeds {
+			byID[f.StreamID] = f
+		}
+		assert.True(t, byID[main.ID].Locked)
+		assert.True(t, byID[main.ID].RequiresPayment)
+		assert.Empty(t, byID[main.ID].OriginURL, "Locked feeds have no playback URL")
+		assert.False(t, byID[finish.ID].Locked)
+		assert.Contains(t, byID[finish.ID].OriginURL, services.PlaybackTokenPrefix)
+		assert.True(t, byID[onboard.ID].Locked)
+		assert.True(t, byID[onboard.ID].RequiresLogin)
+		assert.False(t, byID[onboard.ID].RequiresPayment)
+
+		assert.Equal(t, finish.ID, def.StreamID, "The default feed is the first one the viewer may watch")
+	})
+
+	t.Run("Signed-in viewers without a ticket get the login feed too", func(t *testing.T) {
+		def, feeds := get(t, viewer)
+
+		for _, f := range feeds {
+			assert.Equal(t, f.StreamID == main.ID, f.Locked, f.Name)
+		}
+		assert.Equal(t, finish.ID, def.StreamID)
+	})
+
+	t.Run("Ticket holders get every feed and default to the primary one", func(t *testing.T) {
+		def, feeds := get(t, ticketHolder)
+
+		for _, f := range feeds {
+			assert.False(t, f.Locked, f.Name)
+			assert.Contains(t, f.OriginURL, "/"+f.Name+"/index.m3u8")
+		}
+		assert.Equal(t, main.ID, def.StreamID)
+		assert.True(t, def.IsPrimary)
+	})
+}
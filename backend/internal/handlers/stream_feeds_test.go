@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_StreamFeedValidation(t *testing.T) {
+	app := fiber.New()
+	// Requests are refused before the race or stream is loaded
+	handler := NewAdminHandler(nil, nil, nil, nil, nil, 0, time.Hour)
+	app.Post("/admin/races/:id/streams", handler.CreateStreamFeed)
+	app.Put("/admin/races/:id/streams/:streamId", handler.UpdateStreamFeed)
+	app.Put("/admin/races/:id/streams/:streamId/status", handler.UpdateStreamStatus)
+	app.Get("/admin/races/:id/streams/:streamId/keys", handler.ListStreamKeys)
+
+	raceID := "8a4b3c2d-1e0f-4a5b-8c7d-6e5f4a3b2c1d"
+	streamID := "1f2e3d4c-5b6a-4978-8f6e-5d4c3b2a1f0e"
+	feeds := "/admin/races/" + raceID + "/streams"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"invalid race id", "POST", "/admin/races/not-a-uuid/streams", `{"name": "Helicopter"}`},
+		{"missing name", "POST", feeds, `{"access": "public"}`},
+		{"blank name", "POST", feeds, `{"name": "   "}`},
+		{"invalid access", "POST", feeds, `{"name": "Helicopter", "access": "vip"}`},
+		{"invalid status", "POST", feeds, `{"name": "Helicopter", "status": "paused"}`},
+		{"invalid stream type", "POST", feeds, `{"name": "Helicopter", "stream_type": "dash"}`},
+		{"youtube without source", "POST", feeds, `{"name": "Helicopter", "stream_type": "youtube"}`},
+		{"negative dvr window", "POST", feeds, `{"name": "Helicopter", "dvr_window_seconds": -1}`},
+		{"dvr window over the max", "POST", feeds, `{"name": "Helicopter", "dvr_window_seconds": 3601}`},
+		{"invalid stream id", "PUT", feeds + "/not-a-uuid", `{"name": "Helicopter"}`},
+		{"status on update", "PUT", feeds + "/" + streamID, `{"name": "Helicopter", "status": "live"}`},
+		{"status of invalid stream id", "PUT", feeds + "/not-a-uuid/status", `{"status": "live"}`},
+		{"keys of invalid stream id", "GET", feeds + "/not-a-uuid/keys", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	streamKeyPrefix     = "live_"
+	streamKeyRandomSize = 24 // bytes, hex encoded in the key
+
+	maxStreamKeyOverlap = 24 * time.Hour
+
+	defaultStreamKeyEventsLimit = 50
+	maxStreamKeyEventsLimit     = 200
+)
+
+type createStreamKeyRequest struct {
+	// How long the stream's current keys keep working; defaults to the
+	// configured rotation overlap
+	OverlapSeconds *int `json:"overlap_seconds"`
+}
+
+// generateStreamKey returns a random stream key
+func generateStreamKey() (string, error) {
+	b := make([]byte, streamKeyRandomSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return streamKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// ListStreamKeys lists the keys of a race's stream, without the keys
+// themselves (admin only)
+// GET /admin/races/:id/stream/keys
+// GET /admin/races/:id/streams/:streamId/keys
+func (h *AdminHandler) ListStreamKeys(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	keys, err := h.streamKeyRepo.ListByStream(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to list stream keys")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list stream keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{"keys": keys})
+}
+
+// CreateStreamKey generates a new key for a race's stream and returns it.
+// This is the only time the key is shown. If the stream already has keys
+// this is a rotation: they keep working for the overlap window, so the
+// encoder can be switched over without dropping the broadcast (admin only)
+// POST /admin/races/:id/stream/keys
+// POST /admin/races/:id/streams/:streamId/keys
+func (h *AdminHandler) CreateStreamKey(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	var req createStreamKeyRequest
+	if len(c.Body()) > 0 && !parseBody(c, &req) {
+		return nil
+	}
+
+	overlap := h.keyRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+		if overlap < 0 || overlap > maxStreamKeyOverlap {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "overlap_seconds must be between 0 and 86400",
+			})
+		}
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	key, err := generateStreamKey()
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate stream key")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate stream key",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	streamKey, event, err := h.streamKeyRepo.Issue(stream.ID, key, adminID, overlap)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to create stream key")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create stream key",
+		})
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":       raceID,
+		"stream_id":     stream.ID,
+		"key_id":        streamKey.ID,
+		"action":        event.Action,
+		"admin_id":      adminID,
+		"overlap_until": event.OverlapUntil,
+	}).Info("Stream key issued")
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"key":        key,
+		"stream_key": streamKey,
+		"event":      event,
+	})
+}
+
+// RevokeStreamKey revokes a key of a race's stream at once. An encoder
+// publishing with it is dropped at nginx's next update callback (admin only)
+// DELETE /admin/races/:id/stream/keys/:keyId
+// DELETE /admin/races/:id/streams/:streamId/keys/:keyId
+func (h *AdminHandler) RevokeStreamKey(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	keyID, ok := requireParam(c, "keyId", "Key ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) || !middleware.ValidateUUID(keyID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format",
+		})
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	streamKey, err := h.streamKeyRepo.Revoke(stream.ID, keyID, adminID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to revoke stream key")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke stream key",
+		})
+	}
+	if streamKey == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stream key not found or already revoked",
+		})
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":   raceID,
+		"stream_id": stream.ID,
+		"key_id":    streamKey.ID,
+		"admin_id":  adminID,
+	}).Info("Stream key revoked")
+
+	return c.JSON(streamKey)
+}
+
+// ListStreamKeyEvents returns the audit trail of a race's stream keys: who
+// created, rotated and revoked them and when (admin only)
+// GET /admin/races/:id/stream/keys/events?limit=50
+// GET /admin/races/:id/streams/:streamId/keys/events?limit=50
+func (h *AdminHandler) ListStreamKeyEvents(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	limit := defaultStreamKeyEventsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxStreamKeyEventsLimit {
+			limit = parsed
+		}
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	events, err := h.streamKeyRepo.ListEvents(stream.ID, limit)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", raceID).Error("Failed to list stream key events")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list stream key events",
+		})
+	}
+
+	return c.JSON(fiber.Map{"events": events})
+}
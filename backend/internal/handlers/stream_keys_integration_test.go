@@ -0,0 +1,88 @@
+// +build integration
+
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_RotateStreamKey_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	logger.Init("test")
+
+	db := testutil.GetTestDB(t)
+	defer db.Close()
+
+	streamRepo := repository.NewStreamRepository(db)
+	handler := NewAdminHandler(nil, streamRepo, nil, repository.NewStreamKeyRepository(db), nil, time.Hour, 0)
+	app := fiber.New()
+	app.Post("/admin/races/:id/stream/keys", handler.CreateStreamKey)
+	app.Get("/admin/races/:id/stream/keys", handler.ListStreamKeys)
+
+	raceID := testutil.CreateTestRace(t, db, "Stream Key Rotation Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+	stream := &models.Stream{RaceID: raceID}
+	require.NoError(t, streamRepo.CreateFeed(stream))
+
+	type issued struct {
+		Key       string                `json:"key"`
+		StreamKey models.StreamKey      `json:"stream_key"`
+		Event     models.StreamKeyEvent `json:"event"`
+	}
+	rotate := func(t *testing.T) issued {
+		req := httptest.NewRequest("POST", "/admin/races/"+raceID+"/stream/keys", strings.NewReader(`{"overlap_seconds": 600}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+
+		var body issued
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.True(t, strings.HasPrefix(body.Key, streamKeyPrefix))
+		assert.Equal(t, body.Key[len(body.Key)-4:], body.StreamKey.Hint)
+		return body
+	}
+
+	first := rotate(t)
+	assert.Equal(t, "created", first.Event.Action)
+	second := rotate(t)
+	assert.Equal(t, "rotated", second.Event.Action)
+	require.NotNil(t, second.Event.OverlapUntil)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), *second.Event.OverlapUntil, time.Minute)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/races/"+raceID+"/stream/keys", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	// Later reads show the hints, never the keys
+	assert.NotContains(t, string(body), first.Key)
+	assert.NotContains(t, string(body), second.Key)
+	var listed struct {
+		Keys []models.StreamKey `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(body, &listed))
+	require.Len(t, listed.Keys, 2)
+	assert.Equal(t, second.StreamKey.ID, listed.Keys[0].ID)
+	assert.Equal(t, second.StreamKey.Hint, listed.Keys[0].Hint)
+	assert.Equal(t, "active", listed.Keys[0].Status)
+	assert.Equal(t, first.StreamKey.Hint, listed.Keys[1].Hint)
+	assert.Equal(t, "expiring", listed.Keys[1].Status, "The replaced key works during the overlap")
+}
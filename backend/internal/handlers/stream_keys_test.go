@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateStreamKey(t *testing.T) {
+	key, err := generateStreamKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, streamKeyPrefix))
+	assert.Len(t, key, len(streamKeyPrefix)+2*streamKeyRandomSize)
+
+	other, err := generateStreamKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestAdminHandler_CreateStreamKeyValidation(t *testing.T) {
+	app := fiber.New()
+	handler := NewAdminHandler(nil, nil, nil, nil, nil, 0, 0)
+	app.Post("/admin/races/:id/stream/keys", handler.CreateStreamKey)
+
+	raceID := "8a4b3c2d-1e0f-4a5b-8c7d-6e5f4a3b2c1d"
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"invalid race id", "/admin/races/not-a-uuid/stream/keys", ""},
+		{"negative overlap", "/admin/races/" + raceID + "/stream/keys", `{"overlap_seconds": -1}`},
+		{"overlap over a day", "/admin/races/" + raceID + "/stream/keys", `{"overlap_seconds": 86401}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// playbackSource is somewhere a feed can be played from
+type playbackSource struct {
+	Provider   string `json:"provider"`
+	StreamType string `json:"stream_type"`
+	URL        string `json:"url,omitempty"`
+	SourceID   string `json:"source_id,omitempty"`
+	Health     string `json:"health"`
+}
+
+// playbackSources lists where a feed can be played from, best first: its
+// providers in priority order with degraded ones last, or else its own
+// YouTube, CDN and origin sources, which aren't health checked.
+func playbackSources(stream *models.Stream, providers []models.StreamProvider) []playbackSource {
+	sources := make([]playbackSource, 0, 3)
+	if len(providers) == 0 {
+		if stream.StreamType == "youtube" && stream.SourceID != nil && *stream.SourceID != "" {
+			sources = append(sources, playbackSource{Provider: "youtube", StreamType: "youtube", SourceID: *stream.SourceID, Health: models.ProviderHealthUnknown})
+		}
+		if stream.CDNURL != nil && *stream.CDNURL != "" {
+			sources = append(sources, playbackSource{Provider: "cdn", StreamType: "hls", URL: *stream.CDNURL, Health: models.ProviderHealthUnknown})
+		}
+		if stream.OriginURL != nil && *stream.OriginURL != "" {
+			sources = append(sources, playbackSource{Provider: "origin", StreamType: "hls", URL: *stream.OriginURL, Health: models.ProviderHealthUnknown})
+		}
+		return sources
+	}
+
+	for _, p := range providers {
+		source := playbackSource{Provider: p.Provider, StreamType: p.StreamType, Health: p.HealthStatus}
+		if p.StreamType == "youtube" {
+			source.SourceID = p.ProviderVideoID
+		} else if p.ProviderURL != nil {
+			source.URL = *p.ProviderURL
+		}
+		sources = append(sources, source)
+	}
+	// Degraded sources are kept as a last resort
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Health != models.ProviderHealthDegraded && sources[j].Health == models.ProviderHealthDegraded
+	})
+	return sources
+}
+
+// defaultPlaybackURL picks the URL players use by default: the first HLS
+// source that isn't degraded, or else the first HLS source. It is reported as
+// origin_url when it is the stream's origin and as cdn_url otherwise. Streams
+// whose sources have no HLS URL fall back to their CDN, then origin URL.
+func defaultPlaybackURL(stream *models.Stream, sources []playbackSource) (field, rawURL string) {
+	for _, source := range sources {
+		if source.URL == "" {
+			continue
+		}
+		if rawURL == "" {
+			rawURL = source.URL
+		}
+		if source.Health != models.ProviderHealthDegraded {
+			rawURL = source.URL
+			break
+		}
+	}
+
+	if rawURL == "" {
+		if stream.CDNURL != nil && *stream.CDNURL != "" {
+			return "cdn_url", *stream.CDNURL
+		}
+		if stream.OriginURL != nil && *stream.OriginURL != "" {
+			return "origin_url", *stream.OriginURL
+		}
+		return "", ""
+	}
+	if stream.OriginURL != nil && *stream.OriginURL == rawURL {
+		return "origin_url", rawURL
+	}
+	return "cdn_url", rawURL
+}
+
+// addPlaybackURL adds the stream's playback URL to response, preferring its
+// healthiest source (see defaultPlaybackURL), its playback sources, its DVR playlist while it is live and its replay
+// once it is vod_ready, with a token for the viewer on URLs served from the
+// HLS origin. It sends an error response and returns false when loading the
+// sources or signing fails.
+func (h *RaceHandler) addPlaybackURL(c *fiber.Ctx, response fiber.Map, stream *models.Stream, userID string) bool {
+	providers, err := h.providerRepo.ListSources(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load playback sources")
+		_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to prepare playback URL",
+		})
+		return false
+	}
+
+	var expiresAt time.Time
+	sign := func(rawURL string) (string, bool) {
+		signed, exp, err := h.playbackSigner.SignURL(rawURL, stream.RaceID, userID)
+		if err != nil {
+			logger.WithError(err).WithField("race_id", stream.RaceID).Error("Failed to sign playback URL")
+			_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to prepare playback URL",
+			})
+			return "", false
+		}
+		if !exp.IsZero() {
+			expiresAt = exp
+		}
+		return signed, true
+	}
+
+	sources := playbackSources(stream, providers)
+	if field, rawURL := defaultPlaybackURL(stream, sources); field != "" {
+		signed, ok := sign(rawURL)
+		if !ok {
+			return false
+		}
+		response[field] = signed
+	}
+
+	for i := range sources {
+		if sources[i].URL == "" {
+			continue
+		}
+		signed, ok := sign(sources[i].URL)
+		if !ok {
+			return false
+		}
+		sources[i].URL = signed
+	}
+	response["sources"] = sources
+
+	if dvrURL := h.dvrURL(stream); dvrURL != "" {
+		signed, ok := sign(dvrURL)
+		if !ok {
+			return false
+		}
+		response["dvr_url"] = signed
+		response["dvr_window_seconds"] = int(h.dvrWindowOf(stream).Seconds())
+	}
+
+	if replayURL := h.replayURL(stream); replayURL != "" {
+		replay, err := h.vodRepo.GetReadyByStreamID(stream.ID)
+		if err != nil {
+			logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load replay")
+			_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to prepare playback URL",
+			})
+			return false
+		}
+		if replay != nil {
+			signed, ok := sign(replayURL)
+			if !ok {
+				return false
+			}
+			response["replay_url"] = signed
+			response["replay"] = replay
+		}
+	}
+
+	if !expiresAt.IsZero() {
+		response["token_expires_at"] = expiresAt
+		// The URLs are tied to this viewer
+		c.Set(fiber.HeaderCacheControl, "no-store")
+	}
+	return true
+}
+
+// watchableFeed loads the feed of race :id given by stream_id, or its primary
+// feed, and checks the viewer may watch it. It sends an error response and
+// returns false when the feed isn't found or the viewer is refused.
+func (h *RaceHandler) watchableFeed(c *fiber.Ctx) (*models.Stream, *streamAccess, bool) {
+	id, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil, nil, false
+	}
+	streamID := c.Query("stream_id")
+	if streamID != "" && !middleware.ValidateUUID(streamID) {
+		_ = c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid stream ID"})
+		return nil, nil, false
+	}
+
+	race, ok := loadRaceOr404(c, h.raceRepo, id)
+	if !ok {
+		return nil, nil, false
+	}
+
+	stream, ok := loadFeedOr404(c, h.streamRepo, id, streamID, "Stream not found for this race")
+	if !ok {
+		return nil, nil, false
+	}
+
+	access := h.newStreamAccess(race, c)
+	denial, err := access.check(stream)
+	if err != nil {
+		_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check access",
+		})
+		return nil, nil, false
+	}
+	if denial != nil {
+		_ = denial.respond(c)
+		return nil, nil, false
+	}
+	return stream, access, true
+}
+
+// RefreshPlaybackToken re-checks the viewer's access and returns the playback
+// URL of a feed, the primary one unless stream_id is given, with a new token.
+// Players call it before the token expires so long races keep playing.
+// POST /races/:id/stream/token?stream_id=
+func (h *RaceHandler) RefreshPlaybackToken(c *fiber.Ctx) error {
+	stream, access, ok := h.watchableFeed(c)
+	if !ok {
+		return nil
+	}
+
+	response := fiber.Map{"stream_id": stream.ID}
+	if !h.addPlaybackURL(c, response, stream, access.userID) {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// AuthorizePlayback checks the token of an HLS request for nginx's
+// auth_request, which passes the original request URI in X-Original-URI.
+// nginx serves the file on a 2xx and refuses it on 401 or 403. The token's
+// user is returned in X-Playback-User for nginx to log.
+// GET /playback/authorize
+func (h *RaceHandler) AuthorizePlayback(c *fiber.Ctx) error {
+	requestURI := c.Get("X-Original-URI")
+	if requestURI == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	requestPath, _, _ := strings.Cut(requestURI, "?")
+	requestPath, err := url.PathUnescape(requestPath)
+	if err != nil {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	claims, err := h.playbackSigner.Authorize(requestPath)
+	if err != nil {
+		// Expired tokens are routine when a player stops refreshing
+		if !errors.Is(err, services.ErrPlaybackTokenExpired) {
+			logger.WithField("path", requestPath).Debug("Refused HLS request with invalid playback token")
+		}
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if claims.UserID != "" {
+		c.Set("X-Playback-User", claims.UserID)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_AuthorizePlayback(t *testing.T) {
+	logger.Init("test")
+	signer := services.NewPlaybackSigner("playback-secret", time.Minute, []string{"origin.example.com"})
+	app := fiber.New()
+	// Tokens are checked without the database
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, signer, time.Hour)
+	app.Get("/playback/authorize", handler.AuthorizePlayback)
+
+	scope := "/hls/race-1/"
+	token := signer.Sign(services.PlaybackClaims{RaceID: "r1", UserID: "u1", Scope: scope, ExpiresAt: time.Now().Add(time.Minute)})
+	expired := signer.Sign(services.PlaybackClaims{RaceID: "r1", UserID: "u1", Scope: scope, ExpiresAt: time.Now().Add(-time.Second)})
+
+	tests := []struct {
+		name   string
+		uri    string
+		status int
+		user   string
+	}{
+		{"playlist", services.PlaybackTokenPrefix + token + "/race-1/index.m3u8", fiber.StatusNoContent, "u1"},
+		{"segment with query", services.PlaybackTokenPrefix + token + "/race-1/seg-1.ts?_HLS_msn=4", fiber.StatusNoContent, "u1"},
+		{"missing uri", "", fiber.StatusUnauthorized, ""},
+		{"untokenized", "/hls/race-1/index.m3u8", fiber.StatusForbidden, ""},
+		{"other stream", services.PlaybackTokenPrefix + token + "/race-2/index.m3u8", fiber.StatusForbidden, ""},
+		{"encoded traversal", services.PlaybackTokenPrefix + token + "/race-1/%2e%2e/race-2/index.m3u8", fiber.StatusForbidden, ""},
+		{"expired", services.PlaybackTokenPrefix + expired + "/race-1/index.m3u8", fiber.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/playback/authorize", nil)
+			if tt.uri != "" {
+				req.Header.Set("X-Original-URI", tt.uri)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, tt.user, resp.Header.Get("X-Playback-User"))
+		})
+	}
+}
+
+func TestPlaybackSources(t *testing.T) {
+	cdn, origin, video := "https://cdn.example.com/hls/race-1/index.m3u8", "http://origin.example.com/hls/race-1/index.m3u8", "dQw4w9WgXcQ"
+	stream := &models.Stream{StreamType: "hls", CDNURL: &cdn, OriginURL: &origin}
+
+	// Without providers the stream's own URLs are the sources
+	sources := playbackSources(stream, nil)
+	require.Len(t, sources, 2)
+	assert.Equal(t, playbackSource{Provider: "cdn", StreamType: "hls", URL: cdn, Health: models.ProviderHealthUnknown}, sources[0])
+	assert.Equal(t, "origin", sources[1].Provider)
+
+	owncast, bunny := origin, "https://vz-123.b-cdn.net/abc/playlist.m3u8"
+	providers := []models.StreamProvider{
+		{Provider: "owncast", StreamType: "hls", ProviderURL: &owncast, HealthStatus: models.ProviderHealthDegraded},
+		{Provider: "bunny_stream", StreamType: "hls", ProviderURL: &bunny, HealthStatus: models.ProviderHealthHealthy},
+		{Provider: "youtube", StreamType: "youtube", ProviderVideoID: video, HealthStatus: models.ProviderHealthUnknown},
+	}
+	sources = playbackSources(stream, providers)
+	require.Len(t, sources, 3)
+	// Degraded sources move to the end, the others keep their priority
+	assert.Equal(t, "bunny_stream", sources[0].Provider)
+	assert.Equal(t, bunny, sources[0].URL)
+	assert.Equal(t, playbackSource{Provider: "youtube", StreamType: "youtube", SourceID: video, Health: models.ProviderHealthUnknown}, sources[1])
+	assert.Equal(t, "owncast", sources[2].Provider)
+}
+
+func TestDefaultPlaybackURL(t *testing.T) {
+	cdn, origin := "https://cdn.example.com/hls/race-1/index.m3u8", "http://origin.example.com/hls/race-1/index.m3u8"
+	stream := &models.Stream{StreamType: "hls", CDNURL: &cdn, OriginURL: &origin}
+	providers := func(cdnHealth, originHealth string) []models.StreamProvider {
+		return []models.StreamProvider{
+			{Provider: "bunny_cdn", StreamType: "hls", ProviderURL: &cdn, HealthStatus: cdnHealth},
+			{Provider: "owncast", StreamType: "hls", ProviderURL: &origin, HealthStatus: originHealth},
+		}
+	}
+
+	field, url := defaultPlaybackURL(stream, playbackSources(stream, nil))
+	assert.Equal(t, "cdn_url", field, "Without providers the CDN comes first")
+	assert.Equal(t, cdn, url)
+
+	field, url = defaultPlaybackURL(stream, playbackSources(stream, providers(models.ProviderHealthHealthy, models.ProviderHealthHealthy)))
+	assert.Equal(t, "cdn_url", field)
+	assert.Equal(t, cdn, url)
+
+	// A degraded CDN fails over to the origin
+	field, url = defaultPlaybackURL(stream, playbackSources(stream, providers(models.ProviderHealthDegraded, models.ProviderHealthHealthy)))
+	assert.Equal(t, "origin_url", field)
+	assert.Equal(t, origin, url)
+
+	// When every source is degraded the first one is still played
+	field, url = defaultPlaybackURL(stream, playbackSources(stream, providers(models.ProviderHealthDegraded, models.ProviderHealthDegraded)))
+	assert.Equal(t, "cdn_url", field)
+	assert.Equal(t, cdn, url)
+
+	// YouTube-only sources leave the stream's own URLs as the default
+	video := []models.StreamProvider{{Provider: "youtube", StreamType: "youtube", ProviderVideoID: "dQw4w9WgXcQ", HealthStatus: models.ProviderHealthUnknown}}
+	field, url = defaultPlaybackURL(stream, playbackSources(stream, video))
+	assert.Equal(t, "cdn_url", field)
+	assert.Equal(t, cdn, url)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// nginx-rtmp posts its callbacks as a form: name is the stream name the
+// encoder publishes to, which is the stream key, and clientid identifies the
+// RTMP connection. on_update and on_publish_done carry the published name
+// even after on_publish renamed the stream.
+type publishCallback struct {
+	streamKey  string
+	clientID   string
+	clientAddr string
+	app        string
+	call       string
+}
+
+func parsePublishCallback(c *fiber.Ctx) publishCallback {
+	return publishCallback{
+		streamKey:  c.FormValue("name"),
+		clientID:   c.FormValue("clientid"),
+		clientAddr: c.FormValue("addr"),
+		app:        c.FormValue("app"),
+		call:       c.FormValue("call"),
+	}
+}
+
+// authorizedPublishCallback checks the shared secret nginx-rtmp is configured
+// to send with its callbacks
+func (h *StreamHandler) authorizedPublishCallback(c *fiber.Ctx) bool {
+	if h.publishSecret == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Query("secret")), []byte(h.publishSecret)) == 1
+}
+
+// PublishStream authorizes an encoder publishing over RTMP (nginx-rtmp
+// on_publish). nginx drops the encoder unless it gets a 2xx or 3xx, so
+// unknown keys, streams that can't go live (a replay is ready) and lookup
+// failures are refused. An accepted publish starts a publish session and sets
+// the stream live, and is redirected to the stream's ID: nginx names the HLS
+// directory after the new name, so the key doesn't end up in playback URLs
+// and the directory stays the same when the key is rotated.
+// POST /auth/stream
+func (h *StreamHandler) PublishStream(c *fiber.Ctx) error {
+	if !h.authorizedPublishCallback(c) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	cb := parsePublishCallback(c)
+	if cb.clientID == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	stream, err := h.streamRepo.GetByStreamKey(cb.streamKey)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up stream key")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if stream == nil {
+		// Never log the key itself
+		logger.WithFields(map[string]interface{}{
+			"client_addr": cb.clientAddr,
+			"app":         cb.app,
+		}).Warn("Refused RTMP publish with unknown stream key")
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	session := &models.StreamPublishSession{ClientID: cb.clientID}
+	if cb.clientAddr != "" {
+		session.ClientAddr = &cb.clientAddr
+	}
+	if cb.app != "" {
+		session.App = &cb.app
+	}
+	change, err := h.streamRepo.StartPublishSession(stream, session)
+	if errors.Is(err, repository.ErrInvalidStreamTransition) {
+		logger.WithFields(map[string]interface{}{
+			"race_id": stream.RaceID,
+			"status":  stream.Status,
+		}).Warn("Refused RTMP publish to a stream that can't go live")
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+	if err != nil {
+		logger.WithError(err).WithField("race_id", stream.RaceID).Error("Failed to start publish session")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	h.statusListener.notify(change)
+
+	logger.WithFields(map[string]interface{}{
+		"race_id":     stream.RaceID,
+		"stream_id":   stream.ID,
+		"session_id":  session.ID,
+		"client_addr": cb.clientAddr,
+	}).Info("Stream publish started")
+
+	// A Location that isn't an rtmp:// URL renames the stream
+	c.Set(fiber.HeaderLocation, stream.ID)
+	return c.SendStatus(fiber.StatusFound)
+}
+
+// PublishStreamUpdate re-checks the key of an encoder that is publishing
+// (nginx-rtmp on_update, sent every notify_update_timeout). nginx drops the
+// encoder unless it gets a 2xx, so a key that was revoked, or rotated out
+// and past its overlap window, stops the broadcast within one interval.
+// Lookup failures are let through rather than cutting a live broadcast.
+// RTMP players are only allowed from this host, since they aren't checked
+// for playback tokens; others are dropped.
+// POST /auth/stream/update
+func (h *StreamHandler) PublishStreamUpdate(c *fiber.Ctx) error {
+	if !h.authorizedPublishCallback(c) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	cb := parsePublishCallback(c)
+	if cb.call != "update_publish" {
+		if ip := net.ParseIP(cb.clientAddr); ip != nil && ip.IsLoopback() {
+			return c.SendStatus(fiber.StatusOK)
+		}
+		logger.WithFields(map[string]interface{}{
+			"client_addr": cb.clientAddr,
+			"app":         cb.app,
+		}).Warn("Dropping RTMP player from outside the origin host")
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	stream, err := h.streamRepo.GetByStreamKey(cb.streamKey)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up stream key")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	if stream == nil {
+		logger.WithFields(map[string]interface{}{
+			"client_addr": cb.clientAddr,
+			"app":         cb.app,
+		}).Warn("Dropping RTMP publish whose stream key is no longer valid")
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// PublishStreamDone records an encoder disconnecting (nginx-rtmp
+// on_publish_done) and ends the stream once nothing publishes to it.
+// nginx ignores the response.
+// POST /auth/stream/done
+func (h *StreamHandler) PublishStreamDone(c *fiber.Ctx) error {
+	if !h.authorizedPublishCallback(c) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	cb := parsePublishCallback(c)
+	if cb.clientID == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	// The key may have been revoked or rotated out while it was publishing
+	stream, err := h.streamRepo.GetByAnyStreamKey(cb.streamKey)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up stream key")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if stream == nil {
+		// The publish was refused
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	session, change, err := h.streamRepo.EndPublishSession(stream, cb.clientID)
+	if err != nil {
+		logger.WithError(err).WithField("race_id", stream.RaceID).Error("Failed to end publish session")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	h.statusListener.notify(change)
+	if session != nil {
+		logger.WithFields(map[string]interface{}{
+			"race_id":    stream.RaceID,
+			"stream_id":  stream.ID,
+			"session_id": session.ID,
+			"status":     stream.Status,
+		}).Info("Stream publish ended")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHandler_PublishStreamRefusals(t *testing.T) {
+	logger.Init("test")
+	app := fiber.New()
+	// No lookup reaches the database in these cases
+	handler := NewStreamHandler(repository.NewStreamRepository(nil), "callback-secret")
+	app.Post("/auth/stream", handler.PublishStream)
+	app.Post("/auth/stream/update", handler.PublishStreamUpdate)
+	app.Post("/auth/stream/done", handler.PublishStreamDone)
+
+	valid := url.Values{"call": {"publish"}, "app": {"live"}, "name": {""}, "clientid": {"7"}, "addr": {"10.0.0.5"}}
+	noClient := url.Values{"call": {"publish"}, "app": {"live"}, "name": {"key"}}
+	updatePublish := url.Values{"call": {"update_publish"}, "app": {"live"}, "name": {""}, "clientid": {"7"}}
+	updatePlay := url.Values{"call": {"update_play"}, "app": {"live"}, "name": {"key"}, "clientid": {"8"}, "addr": {"203.0.113.9"}}
+	updateLocalPlay := url.Values{"call": {"update_play"}, "app": {"live"}, "name": {"key"}, "clientid": {"9"}, "addr": {"127.0.0.1"}}
+
+	tests := []struct {
+		name   string
+		target string
+		form   url.Values
+		status int
+	}{
+		{"missing secret", "/auth/stream", valid, fiber.StatusForbidden},
+		{"wrong secret", "/auth/stream?secret=nope", valid, fiber.StatusForbidden},
+		{"missing client id", "/auth/stream?secret=callback-secret", noClient, fiber.StatusBadRequest},
+		{"empty stream key", "/auth/stream?secret=callback-secret", valid, fiber.StatusForbidden},
+		{"update for revoked key", "/auth/stream/update?secret=callback-secret", updatePublish, fiber.StatusForbidden},
+		{"update for remote player", "/auth/stream/update?secret=callback-secret", updatePlay, fiber.StatusForbidden},
+		{"update for local player", "/auth/stream/update?secret=callback-secret", updateLocalPlay, fiber.StatusOK},
+		{"done with wrong secret", "/auth/stream/done?secret=nope", valid, fiber.StatusForbidden},
+		{"done for unknown key", "/auth/stream/done?secret=callback-secret", valid, fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/middleware"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxStreamSources = 10
+
+// StreamSourceRequest is one playback source of a stream
+type StreamSourceRequest struct {
+	Provider   string `json:"provider"`    // e.g. owncast, bunny_stream, youtube
+	StreamType string `json:"stream_type"` // hls (default) or youtube
+	URL        string `json:"url"`         // HLS master playlist
+	VideoID    string `json:"video_id"`    // YouTube video ID, or the provider's ID for analytics
+}
+
+// ReplaceStreamSourcesRequest lists a stream's playback sources, preferred
+// first
+type ReplaceStreamSourcesRequest struct {
+	Sources []StreamSourceRequest `json:"sources"`
+}
+
+// validate checks and sanitizes the sources. It sends an error response and
+// returns false when they are invalid.
+func (req *ReplaceStreamSourcesRequest) validate(c *fiber.Ctx) bool {
+	fail := func(msg string) bool {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+		return false
+	}
+
+	if len(req.Sources) > maxStreamSources {
+		return fail("A stream can have at most 10 sources")
+	}
+
+	seen := make(map[string]bool, len(req.Sources))
+	for i := range req.Sources {
+		source := &req.Sources[i]
+		source.Provider = strings.ToLower(middleware.SanitizeString(source.Provider, 50))
+		source.URL = middleware.SanitizeString(source.URL, 500)
+		source.VideoID = middleware.SanitizeString(source.VideoID, 255)
+
+		if source.Provider == "" {
+			return fail("Every source needs a provider")
+		}
+		if seen[source.Provider] {
+			return fail("Each provider can only be listed once")
+		}
+		seen[source.Provider] = true
+
+		switch source.StreamType {
+		case "", "hls":
+			source.StreamType = "hls"
+			if !strings.HasPrefix(source.URL, "http://") && !strings.HasPrefix(source.URL, "https://") {
+				return fail("HLS sources need an http(s) playlist URL")
+			}
+		case "youtube":
+			if source.VideoID == "" {
+				return fail("YouTube sources need a video_id")
+			}
+		default:
+			return fail("Invalid stream type. Must be one of: hls, youtube")
+		}
+	}
+	return true
+}
+
+// ListStreamSources lists the playback sources of a race's stream in priority
+// order, with their health (admin only)
+// GET /admin/races/:id/stream/sources
+// GET /admin/races/:id/streams/:streamId/sources
+func (h *AdminHandler) ListStreamSources(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	sources, err := h.providerRepo.ListSources(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to list stream sources")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list stream sources",
+		})
+	}
+
+	return c.JSON(fiber.Map{"sources": sources})
+}
+
+// ReplaceStreamSources sets the playback sources of a race's stream, in
+// order of preference. Viewers get the first healthy one and fall back to the
+// next when it fails; an empty list plays the stream's own URLs (admin only).
+// PUT /admin/races/:id/stream/sources
+// PUT /admin/races/:id/streams/:streamId/sources
+func (h *AdminHandler) ReplaceStreamSources(c *fiber.Ctx) error {
+	raceID, ok := requireParam(c, "id", "Race ID is required")
+	if !ok {
+		return nil
+	}
+	if !middleware.ValidateUUID(raceID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid race ID format",
+		})
+	}
+
+	var req ReplaceStreamSourcesRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+	if !req.validate(c) {
+		return nil
+	}
+
+	stream, ok := h.loadFeedParam(c, raceID)
+	if !ok {
+		return nil
+	}
+
+	sources := make([]*models.StreamProvider, 0, len(req.Sources))
+	for _, source := range req.Sources {
+		sp := &models.StreamProvider{
+			Provider:        source.Provider,
+			StreamType:      source.StreamType,
+			ProviderVideoID: source.VideoID,
+		}
+		if source.URL != "" {
+			url := source.URL
+			sp.ProviderURL = &url
+		}
+		sources = append(sources, sp)
+	}
+
+	if err := h.providerRepo.ReplaceSources(stream.ID, sources); err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to replace stream sources")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save stream sources",
+		})
+	}
+
+	return c.JSON(fiber.Map{"sources": sources})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_ReplaceStreamSourcesValidation(t *testing.T) {
+	app := fiber.New()
+	// Requests are refused before the stream is loaded
+	handler := NewAdminHandler(nil, nil, nil, nil, nil, 0, 0)
+	app.Put("/admin/races/:id/stream/sources", handler.ReplaceStreamSources)
+
+	path := "/admin/races/8a4b3c2d-1e0f-4a5b-8c7d-6e5f4a3b2c1d/stream/sources"
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"invalid race id", "/admin/races/not-a-uuid/stream/sources", `{"sources": []}`},
+		{"missing provider", path, `{"sources": [{"url": "https://cdn.example.com/hls/a/index.m3u8"}]}`},
+		{"duplicate provider", path, `{"sources": [{"provider": "bunny", "url": "https://a.example.com/x.m3u8"}, {"provider": "Bunny", "url": "https://b.example.com/x.m3u8"}]}`},
+		{"hls without url", path, `{"sources": [{"provider": "owncast"}]}`},
+		{"hls with other scheme", path, `{"sources": [{"provider": "owncast", "url": "rtmp://origin/live"}]}`},
+		{"youtube without video", path, `{"sources": [{"provider": "youtube", "stream_type": "youtube"}]}`},
+		{"invalid stream type", path, `{"sources": [{"provider": "dash", "stream_type": "dash", "url": "https://a.example.com/x.mpd"}]}`},
+		{"too many", path, `{"sources": [` + strings.Repeat(`{"provider": "p", "url": "https://a.example.com/x.m3u8"},`, 10) + `{"provider": "q", "url": "https://a.example.com/x.m3u8"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// vodPlaylistName is the replay playlist's file name, next to the stream's
+// live playlist on the origin like the DVR playlist
+const vodPlaylistName = "vod.m3u8"
+
+const (
+	defaultReplayPageSize = 20
+	maxReplayPageSize     = 100
+)
+
+// replayURL returns the URL of the stream's replay playlist on the origin, or
+// "" when the stream has no replay
+func (h *RaceHandler) replayURL(stream *models.Stream) string {
+	if stream.Status != models.StreamStatusVODReady {
+		return ""
+	}
+	return originPlaylistURL(stream, vodPlaylistName)
+}
+
+// VODPlaylist serves the replay playlist of a vod_ready stream for nginx,
+// which proxies /hls/t/<token>/<stream>/vod.m3u8 here. The playlist lists all
+// of the stream's recorded segments and is ended, so players can seek through
+// the whole race.
+// GET /playback/vod
+func (h *RaceHandler) VODPlaylist(c *fiber.Ctx) error {
+	stream, _, ok := h.tokenStream(c, vodPlaylistName)
+	if !ok {
+		return nil
+	}
+	if stream.Status != models.StreamStatusVODReady {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	segments, err := h.segmentRepo.ListAll(stream.ID)
+	if err != nil {
+		logger.WithError(err).WithField("stream_id", stream.ID).Error("Failed to load replay segments")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if len(segments) == 0 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	playlist := dvrPlaylist(segments, 0, true)
+	playlist.PlaylistType = "VOD"
+	c.Set(fiber.HeaderContentType, "application/vnd.apple.mpegurl")
+	// The URL carries the viewer's token, and the playlist won't change
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return c.Send(playlist.Encode())
+}
+
+// GetRaceReplay returns the replay of a feed, the primary one unless
+// stream_id is given, with its duration, thumbnail offset and a replay URL
+// for the viewer. Viewers need the same access as to the live feed.
+// GET /races/:id/replay?stream_id=
+func (h *RaceHandler) GetRaceReplay(c *fiber.Ctx) error {
+	stream, access, ok := h.watchableFeed(c)
+	if !ok {
+		return nil
+	}
+	if h.replayURL(stream) == "" {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Replay not found for this race"})
+	}
+
+	response := fiber.Map{
+		"stream_id": stream.ID,
+		"name":      stream.Name,
+		"status":    stream.Status,
+	}
+	if !h.addPlaybackURL(c, response, stream, access.userID) {
+		return nil
+	}
+	if response["replay_url"] == nil {
+		return c.Status(fiber.StatusNotFound).JSON(APIError{Error: "Replay not found for this race"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetReplays lists the replay catalog, latest recording first, optionally of
+// one race category. Playback URLs come from GetRaceReplay, which checks the
+// viewer's access.
+// GET /replays?category=&limit=&offset=
+func (h *RaceHandler) GetReplays(c *fiber.Ctx) error {
+	limit, ok := replayQueryInt(c, "limit", defaultReplayPageSize, 1, maxReplayPageSize)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid limit parameter"})
+	}
+	offset, ok := replayQueryInt(c, "offset", 0, 0, -1)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(APIError{Error: "Invalid offset parameter"})
+	}
+
+	replays, err := h.vodRepo.ListReplays(c.Query("category"), limit, offset)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list replays")
+		return c.Status(fiber.StatusInternalServerError).JSON(APIError{Error: "Failed to fetch replays"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"replays": replays,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
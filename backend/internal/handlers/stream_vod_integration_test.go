@@ -0,0 +1,40 @@
+// +build integration
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_VODPlaylist_Integration(t *testing.T) {
+	app, handler, db := setupRaceTestApp(t)
+	app.Get("/playback/vod", handler.VODPlaylist)
+
+	raceID := testutil.CreateTestRace(t, db, "VOD Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	feed := createTestFeed(t, db, raceID, "main", models.StreamAccessRace, true)
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	recordTestSegments(t, db, feed.ID, start, 8, 3)
+
+	status, _ := getPlaylist(t, app, handler, "/playback/vod", feed, "vod.m3u8")
+	assert.Equal(t, fiber.StatusNotFound, status, "No replay while the stream is live")
+
+	_, err := db.Exec(`UPDATE streams SET status = $2 WHERE id = $1`, feed.ID, models.StreamStatusVODReady)
+	require.NoError(t, err)
+
+	status, playlist := getPlaylist(t, app, handler, "/playback/vod", feed, "vod.m3u8")
+	require.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n"+
+		"#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-MEDIA-SEQUENCE:0\n"+
+		testPlaylistSegments(start, 0, 7, 3)+
+		"#EXT-X-ENDLIST\n", playlist,
+		"The replay lists the whole recording")
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceHandler_ReplayURL(t *testing.T) {
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, nil, time.Hour)
+	origin, cdn := "http://origin.example.com/hls/race-1/index.m3u8?v=2", "https://cdn.example.com/live/index.m3u8"
+
+	assert.Equal(t, "http://origin.example.com/hls/race-1/vod.m3u8",
+		handler.replayURL(&models.Stream{Status: models.StreamStatusVODReady, OriginURL: &origin}))
+	assert.Empty(t, handler.replayURL(&models.Stream{Status: models.StreamStatusEnded, OriginURL: &origin}), "only once vod_ready")
+	assert.Empty(t, handler.replayURL(&models.Stream{Status: models.StreamStatusVODReady, OriginURL: &cdn}), "only from the HLS origin")
+	assert.Empty(t, handler.replayURL(&models.Stream{Status: models.StreamStatusVODReady}))
+}
+
+func TestRaceHandler_GetReplaysRefusals(t *testing.T) {
+	app := fiber.New()
+	handler := NewRaceHandler(nil, nil, nil, nil, nil, nil, nil, nil, time.Hour)
+	app.Get("/replays", handler.GetReplays)
+
+	for _, query := range []string{"limit=0", "limit=ten", "offset=-1"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/replays?"+query, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+	}
+}
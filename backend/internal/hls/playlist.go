@@ -0,0 +1,150 @@
+// Package hls reads and writes HLS media playlists
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotPlaylist is returned for input that doesn't start with #EXTM3U
+	ErrNotPlaylist = errors.New("not an HLS playlist")
+	// ErrMasterPlaylist is returned for master playlists, which list
+	// variants rather than segments
+	ErrMasterPlaylist = errors.New("master playlist, not a media playlist")
+)
+
+// Segment is a media segment of a playlist
+type Segment struct {
+	Sequence int64
+	URI      string
+	Duration float64
+	// ProgramDateTime is the wall clock time of the segment's first frame,
+	// zero when the playlist doesn't give one
+	ProgramDateTime time.Time
+	// Discontinuity is set when the segment follows a change of encoding or
+	// timestamps, such as a reconnected encoder
+	Discontinuity bool
+}
+
+// MediaPlaylist is a list of media segments
+type MediaPlaylist struct {
+	TargetDuration        int
+	MediaSequence         int64
+	DiscontinuitySequence int64
+	Segments              []Segment
+	// PlaylistType is VOD for playlists that won't change, EVENT for ones
+	// only appended to, or empty (EXT-X-PLAYLIST-TYPE)
+	PlaylistType string
+	// Ended is set when no segments will be added (EXT-X-ENDLIST)
+	Ended bool
+}
+
+// Parse reads a media playlist. Segments get their sequence number from
+// EXT-X-MEDIA-SEQUENCE.
+func Parse(r io.Reader) (*MediaPlaylist, error) {
+	scanner := bufio.NewScanner(r)
+	first := true
+	playlist := &MediaPlaylist{}
+	var next Segment
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			line = strings.TrimPrefix(line, "\xef\xbb\xbf")
+			if line != "#EXTM3U" {
+				return nil, ErrNotPlaylist
+			}
+			first = false
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			next.URI = line
+			next.Sequence = playlist.MediaSequence + int64(len(playlist.Segments))
+			playlist.Segments = append(playlist.Segments, next)
+			next = Segment{}
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+		var err error
+		switch tag {
+		case "#EXT-X-STREAM-INF", "#EXT-X-I-FRAME-STREAM-INF":
+			return nil, ErrMasterPlaylist
+		case "#EXT-X-TARGETDURATION":
+			playlist.TargetDuration, err = strconv.Atoi(value)
+		case "#EXT-X-MEDIA-SEQUENCE":
+			playlist.MediaSequence, err = strconv.ParseInt(value, 10, 64)
+		case "#EXT-X-DISCONTINUITY-SEQUENCE":
+			playlist.DiscontinuitySequence, err = strconv.ParseInt(value, 10, 64)
+		case "#EXTINF":
+			duration, _, _ := strings.Cut(value, ",")
+			next.Duration, err = strconv.ParseFloat(duration, 64)
+		case "#EXT-X-PROGRAM-DATE-TIME":
+			next.ProgramDateTime, err = time.Parse(time.RFC3339Nano, value)
+		case "#EXT-X-DISCONTINUITY":
+			next.Discontinuity = true
+		case "#EXT-X-PLAYLIST-TYPE":
+			playlist.PlaylistType = value
+		case "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", tag, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if first {
+		return nil, ErrNotPlaylist
+	}
+
+	return playlist, nil
+}
+
+// Encode writes the playlist, with the program date time of every segment
+// that has one. Its target duration is raised to fit the longest segment.
+func (p *MediaPlaylist) Encode() []byte {
+	target := p.TargetDuration
+	for _, segment := range p.Segments {
+		if d := int(math.Ceil(segment.Duration)); d > target {
+			target = d
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", target)
+	if p.PlaylistType != "" {
+		fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", p.PlaylistType)
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.MediaSequence)
+	if p.DiscontinuitySequence > 0 {
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", p.DiscontinuitySequence)
+	}
+	for _, segment := range p.Segments {
+		if segment.Discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if !segment.ProgramDateTime.IsZero() {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", segment.Duration, segment.URI)
+	}
+	if p.Ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,97 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	playlist, err := Parse(strings.NewReader(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-MEDIA-SEQUENCE:41
+#EXT-X-TARGETDURATION:6
+
+#EXTINF:6.000,
+41.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2026-07-05T12:00:00.500Z
+#EXTINF:5.5,
+42.ts
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, playlist.TargetDuration)
+	assert.Equal(t, int64(41), playlist.MediaSequence)
+	assert.False(t, playlist.Ended)
+	require.Len(t, playlist.Segments, 2)
+	assert.Equal(t, Segment{Sequence: 41, URI: "41.ts", Duration: 6}, playlist.Segments[0])
+	assert.Equal(t, Segment{
+		Sequence:        42,
+		URI:             "42.ts",
+		Duration:        5.5,
+		ProgramDateTime: time.Date(2026, 7, 5, 12, 0, 0, 500e6, time.UTC),
+		Discontinuity:   true,
+	}, playlist.Segments[1])
+}
+
+func TestParseRefusals(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		wantErr  error
+	}{
+		{"empty", "", ErrNotPlaylist},
+		{"not a playlist", "<html></html>", ErrNotPlaylist},
+		{"master playlist", "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow/index.m3u8\n", ErrMasterPlaylist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.playlist))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := Parse(strings.NewReader("#EXTM3U\n#EXTINF:six,\n1.ts\n"))
+	assert.Error(t, err)
+}
+
+func TestMediaPlaylistEncode(t *testing.T) {
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	playlist := &MediaPlaylist{
+		TargetDuration:        6,
+		MediaSequence:         7,
+		DiscontinuitySequence: 1,
+		Segments: []Segment{
+			{Sequence: 7, URI: "7.ts", Duration: 6, ProgramDateTime: start},
+			{Sequence: 8, URI: "8.ts", Duration: 6.4, ProgramDateTime: start.Add(6 * time.Second), Discontinuity: true},
+		},
+		PlaylistType: "VOD",
+		Ended:        true,
+	}
+
+	assert.Equal(t, `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:7
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-MEDIA-SEQUENCE:7
+#EXT-X-DISCONTINUITY-SEQUENCE:1
+#EXT-X-PROGRAM-DATE-TIME:2026-07-05T12:00:00.000Z
+#EXTINF:6.000,
+7.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2026-07-05T12:00:06.000Z
+#EXTINF:6.400,
+8.ts
+#EXT-X-ENDLIST
+`, string(playlist.Encode()))
+
+	// Encoded playlists parse back to the same segments
+	parsed, err := Parse(strings.NewReader(string(playlist.Encode())))
+	require.NoError(t, err)
+	assert.Equal(t, playlist.Segments, parsed.Segments)
+	assert.Equal(t, "VOD", parsed.PlaylistType)
+}
@@ -4,15 +4,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cyclingstream/backend/internal/chat"
 	"github.com/cyclingstream/backend/internal/logger"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// ChatPartyAuthorizer checks party membership for chat connections. It is
+// implemented by repository.ChatPartyRepository.
+type ChatPartyAuthorizer interface {
+	IsPartyMember(raceID, code, userID string) (bool, error)
+}
+
 // ChatAuthMiddleware extracts JWT token from WebSocket connection for optional authentication
-// Similar to OptionalUserAuthMiddleware but works with WebSocket upgrade
-func ChatAuthMiddleware(jwtSecret string) fiber.Handler {
+// Similar to OptionalUserAuthMiddleware but works with WebSocket upgrade.
+//
+// Clients may ask to join extra rooms with ?rooms=race:<id>,party:<code>. The
+// race room must match the race in the path; party rooms require a signed-in
+// member. Authorized party rooms are stored in the "chat_rooms" local as hub
+// room IDs.
+func ChatAuthMiddleware(jwtSecret string, parties ChatPartyAuthorizer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Check if this is a WebSocket upgrade request
 		isWebSocketUpgrade := websocket.IsWebSocketUpgrade(c)
@@ -45,6 +58,9 @@ func ChatAuthMiddleware(jwtSecret string) fiber.Handler {
 
 		if tokenString == "" {
 			logger.Info("ChatAuthMiddleware: No token found, continuing as anonymous")
+			if err := authorizeChatRooms(c, parties, ""); err != nil {
+				return err
+			}
 			return c.Next()
 		}
 
@@ -88,6 +104,77 @@ func ChatAuthMiddleware(jwtSecret string) fiber.Handler {
 		c.Locals("user_id", claims.UserID)
 		c.Locals("is_admin", claims.IsAdmin)
 
+		if err := authorizeChatRooms(c, parties, claims.UserID); err != nil {
+			return err
+		}
+
 		return c.Next()
 	}
 }
+
+// authorizeChatRooms checks the rooms requested in the query string and
+// stores the party rooms the user may join
+func authorizeChatRooms(c *fiber.Ctx, parties ChatPartyAuthorizer, userID string) error {
+	requested := c.Query("rooms")
+	if requested == "" {
+		return nil
+	}
+
+	raceID := c.Params("id")
+	var rooms []string
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(requested, ",") {
+		roomType, id, ok := chat.ParseRoomName(name)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid chat room")
+		}
+
+		switch roomType {
+		case chat.RoomTypeRace:
+			// Every connection joins its race room; naming another race is an error
+			if id != raceID {
+				return fiber.NewError(fiber.StatusBadRequest, "Chat room does not belong to this race")
+			}
+
+		case chat.RoomTypeParty:
+			id = strings.ToLower(id)
+			if userID == "" {
+				return fiber.ErrUnauthorized
+			}
+			roomID := chat.PartyRoomID(id)
+			if seen[roomID] {
+				continue
+			}
+			if len(rooms) == chat.MaxPartyRoomsPerClient {
+				return fiber.NewError(fiber.StatusBadRequest, "Too many party rooms")
+			}
+			if parties == nil {
+				return fiber.ErrForbidden
+			}
+			if _, err := uuid.Parse(raceID); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid race ID")
+			}
+
+			member, err := parties.IsPartyMember(raceID, id, userID)
+			if err != nil {
+				logger.WithError(err).Error("ChatAuthMiddleware: Failed to check party membership")
+				return fiber.ErrInternalServerError
+			}
+			if !member {
+				logger.Info("ChatAuthMiddleware: Not a member of requested party", map[string]interface{}{
+					"user_id": userID,
+					"race_id": raceID,
+				})
+				return fiber.ErrForbidden
+			}
+
+			seen[roomID] = true
+			// Copy the code out of Fiber's request buffer before storing it
+			rooms = append(rooms, string([]byte(roomID)))
+		}
+	}
+
+	c.Locals("chat_rooms", rooms)
+	return nil
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,7 +18,7 @@ func TestChatAuthMiddleware(t *testing.T) {
 	secret := "test-secret"
 	app := fiber.New()
 
-	app.Get("/ws", ChatAuthMiddleware(secret), func(c *fiber.Ctx) error {
+	app.Get("/ws", ChatAuthMiddleware(secret, nil), func(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusOK)
 	})
 
@@ -70,3 +71,74 @@ func TestChatAuthMiddleware(t *testing.T) {
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 	})
 }
+
+type fakePartyAuthorizer struct {
+	members map[string]bool // raceID/code/userID
+}
+
+func (f *fakePartyAuthorizer) IsPartyMember(raceID, code, userID string) (bool, error) {
+	return f.members[raceID+"/"+code+"/"+userID], nil
+}
+
+func TestChatAuthMiddleware_Rooms(t *testing.T) {
+	logger.Init("test")
+	secret := "test-secret"
+	raceID := "8f6c1c2e-4a3b-4d5e-9f00-112233445566"
+	parties := &fakePartyAuthorizer{members: map[string]bool{
+		raceID + "/abc123/user-1": true,
+	}}
+
+	app := fiber.New()
+	app.Get("/races/:id/chat/ws", ChatAuthMiddleware(secret, parties), func(c *fiber.Ctx) error {
+		rooms, _ := c.Locals("chat_rooms").([]string)
+		return c.JSON(rooms)
+	})
+
+	token := func(userID string) string {
+		signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			UserID: userID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			},
+		}).SignedString([]byte(secret))
+		return signed
+	}
+
+	makeReq := func(tokenStr, rooms string) *http.Response {
+		req := httptest.NewRequest("GET", "/races/"+raceID+"/chat/ws?rooms="+rooms, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		if tokenStr != "" {
+			req.Header.Set("Authorization", "Bearer "+tokenStr)
+		}
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	t.Run("members can join their party alongside the race room", func(t *testing.T) {
+		resp := makeReq(token("user-1"), "race:"+raceID+",party:abc123")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `["party:abc123"]`, string(body))
+	})
+
+	t.Run("rejects parties the user is not in", func(t *testing.T) {
+		resp := makeReq(token("user-2"), "party:abc123")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("anonymous users cannot join parties", func(t *testing.T) {
+		resp := makeReq("", "party:abc123")
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("rejects another race's room", func(t *testing.T) {
+		resp := makeReq(token("user-1"), "race:00000000-0000-0000-0000-000000000000")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("rejects unknown room types", func(t *testing.T) {
+		resp := makeReq(token("user-1"), "lobby:main")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
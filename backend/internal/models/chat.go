@@ -3,13 +3,286 @@ package models
 import "time"
 
 type ChatMessage struct {
-	ID           string    `json:"id" db:"id"`
-	RaceID       string    `json:"race_id" db:"race_id"`
-	UserID       *string   `json:"user_id,omitempty" db:"user_id"`
-	Username     string    `json:"username" db:"username"`
-	Message      string    `json:"message" db:"message"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	Role         string    `json:"role,omitempty" db:"user_role"`
-	Badges       []string  `json:"badges,omitempty" db:"badges"`
-	SpecialEmote bool      `json:"special_emote,omitempty" db:"special_emote"`
+	ID           string          `json:"id" db:"id"`
+	RaceID       string          `json:"race_id" db:"race_id"`
+	UserID       *string         `json:"user_id,omitempty" db:"user_id"`
+	Username     string          `json:"username" db:"username"`
+	Message      string          `json:"message" db:"message"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	Role         string          `json:"role,omitempty" db:"user_role"`
+	Badges       []string        `json:"badges,omitempty" db:"badges"`
+	SpecialEmote bool            `json:"special_emote,omitempty" db:"special_emote"`
+	Announcement bool            `json:"announcement,omitempty" db:"is_announcement"` // posted by the race team, highlighted
+	ReplyToID    *string         `json:"reply_to_id,omitempty" db:"reply_to_id"`
+	Emotes       []ChatEmoteSpan `json:"emotes,omitempty" db:"emotes"` // Stored as JSONB
+
+	ReplyTo  *ChatMessageReference `json:"reply_to,omitempty" db:"-"` // the message being replied to
+	Mentions []string              `json:"mentions,omitempty" db:"-"` // mentioned user IDs
+}
+
+// ChatPin is the message pinned to the top of a race's chat
+type ChatPin struct {
+	RaceID   string       `json:"race_id" db:"race_id"`
+	Message  *ChatMessage `json:"message" db:"-"`
+	PinnedBy *string      `json:"pinned_by,omitempty" db:"pinned_by"`
+	PinnedAt time.Time    `json:"pinned_at" db:"pinned_at"`
+}
+
+// ChatMessageReference is a short preview of a message, shown above replies
+type ChatMessageReference struct {
+	ID       string  `json:"id"`
+	UserID   *string `json:"user_id,omitempty"`
+	Username string  `json:"username"`
+	Message  string  `json:"message"` // empty when deleted
+	Deleted  bool    `json:"deleted,omitempty"`
+}
+
+// ChatHistoryCursor marks where a page of chat history ends. Pages continue
+// from just before MessageID when set, otherwise from just before Before.
+type ChatHistoryCursor struct {
+	MessageID string
+	Before    time.Time
+}
+
+// Chat restriction kinds
+const (
+	ChatRestrictionTimeout = "timeout"
+	ChatRestrictionBan     = "ban"
+)
+
+// ChatRestriction is a timeout or ban that prevents a user from sending chat messages.
+// A nil RaceID applies the restriction to every race.
+type ChatRestriction struct {
+	ID          string     `json:"id" db:"id"`
+	RaceID      *string    `json:"race_id,omitempty" db:"race_id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Kind        string     `json:"kind" db:"kind"` // timeout, ban
+	Reason      *string    `json:"reason,omitempty" db:"reason"`
+	ModeratorID *string    `json:"moderator_id,omitempty" db:"moderator_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ChatRoomSettings holds moderator-controlled settings for a race's chat room
+type ChatRoomSettings struct {
+	RaceID          string    `json:"race_id" db:"race_id"`
+	SlowModeSeconds int       `json:"slow_mode_seconds" db:"slow_mode_seconds"`
+	UpdatedBy       *string   `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Chat poll types
+const (
+	ChatPollTypeSingle = "single" // one option per voter
+	ChatPollTypeMulti  = "multi"  // up to MaxChoices options per voter
+	ChatPollTypeRanked = "ranked" // voters order options by preference
+)
+
+// ChatPollOption is a single answer in a chat poll. For ranked polls Votes
+// counts first preferences and Score holds the Borda count.
+type ChatPollOption struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Votes int    `json:"votes"`
+	Score int    `json:"score,omitempty"`
+}
+
+// ChatPollVoteCount is a running tally of a poll: how many ballots list an
+// option at a position, 0 being first. Positions are preferences in ranked
+// polls.
+type ChatPollVoteCount struct {
+	OptionID string `json:"option_id" db:"option_id"`
+	Rank     int    `json:"rank" db:"rank"`
+	Ballots  int    `json:"ballots" db:"ballots"`
+}
+
+// ChatPoll is a poll run in a race chat. TotalVotes counts voters, not selections.
+type ChatPoll struct {
+	ID         string           `json:"id" db:"id"`
+	RaceID     string           `json:"race_id" db:"race_id"`
+	Question   string           `json:"question" db:"question"`
+	Type       string           `json:"type" db:"poll_type"`
+	MaxChoices int              `json:"max_choices,omitempty" db:"max_choices"`
+	Options    []ChatPollOption `json:"options" db:"options"` // Stored as JSONB
+	TotalVotes int              `json:"total_votes" db:"total_votes"`
+	CreatedBy  *string          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+	ClosesAt   *time.Time       `json:"closes_at,omitempty" db:"closes_at"`
+	ClosedAt   *time.Time       `json:"closed_at,omitempty" db:"closed_at"`
+	Closed     bool             `json:"closed" db:"-"`
+}
+
+// ChatEmote is an emote that can be used in chat as :code:. A nil RaceID
+// makes it available in every race. Emotes without an ImageURL are built-in
+// and drawn by the frontend.
+type ChatEmote struct {
+	ID             string    `json:"id" db:"id"`
+	Code           string    `json:"code" db:"code"`
+	ImageURL       *string   `json:"image_url,omitempty" db:"image_url"`
+	RaceID         *string   `json:"race_id,omitempty" db:"race_id"`
+	MinLevel       int       `json:"min_level" db:"min_level"`
+	RequiresTicket bool      `json:"requires_ticket" db:"requires_ticket"`
+	CreatedBy      *string   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ChatEmoteSpan marks an emote in a message. Start and End are offsets in
+// UTF-16 code units, matching JavaScript string indexes; End is exclusive.
+type ChatEmoteSpan struct {
+	Code     string  `json:"code"`
+	Start    int     `json:"start"`
+	End      int     `json:"end"`
+	ImageURL *string `json:"image_url,omitempty"`
+}
+
+// ChatParty is an invite-only room layered on a race chat. Users join with
+// the invite code.
+type ChatParty struct {
+	ID          string    `json:"id" db:"id"`
+	RaceID      string    `json:"race_id" db:"race_id"`
+	InviteCode  string    `json:"invite_code" db:"invite_code"`
+	Name        string    `json:"name" db:"name"`
+	OwnerID     string    `json:"owner_id" db:"owner_id"`
+	MemberCount int       `json:"member_count" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChatPrivateMessage is a party message (PartyID set) or a whisper
+// (RecipientID set). Private messages are stored apart from public chat.
+type ChatPrivateMessage struct {
+	ID          string    `json:"id" db:"id"`
+	RaceID      string    `json:"race_id" db:"race_id"`
+	PartyID     *string   `json:"party_id,omitempty" db:"party_id"`
+	SenderID    string    `json:"sender_id" db:"sender_id"`
+	RecipientID *string   `json:"recipient_id,omitempty" db:"recipient_id"`
+	Username    string    `json:"username" db:"username"`
+	Message     string    `json:"message" db:"message"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Chat filter actions, from least to most severe. Allow disables a rule.
+const (
+	ChatFilterAllow  = "allow"
+	ChatFilterMask   = "mask"
+	ChatFilterFlag   = "flag"
+	ChatFilterReject = "reject"
+)
+
+// ChatFilterConfig configures a race's chat content filter. Each rule has an
+// action taken when it matches.
+type ChatFilterConfig struct {
+	BannedWords ChatFilterBannedWords `json:"banned_words"`
+	Links       ChatFilterLinks       `json:"links"`
+	Caps        ChatFilterCaps        `json:"caps"`
+	EmojiSpam   ChatFilterEmojiSpam   `json:"emoji_spam"`
+	Repeats     ChatFilterRepeats     `json:"repeats"`
+}
+
+// ChatFilterBannedWords matches words from a list, seeing through leetspeak
+// and stretched letters
+type ChatFilterBannedWords struct {
+	Action string   `json:"action"`
+	Words  []string `json:"words"`
+}
+
+// ChatFilterLinks matches links. Hosts on Deny are always rejected, hosts on
+// Allow (and their subdomains) pass, and other links get Action.
+type ChatFilterLinks struct {
+	Action string   `json:"action"`
+	Allow  []string `json:"allow"`
+	Deny   []string `json:"deny"`
+}
+
+// ChatFilterCaps matches messages with at least MinLetters letters where more
+// than MaxRatio of them are capitals
+type ChatFilterCaps struct {
+	Action     string  `json:"action"`
+	MinLetters int     `json:"min_letters"`
+	MaxRatio   float64 `json:"max_ratio"`
+}
+
+// ChatFilterEmojiSpam matches messages with more than MaxEmojis emoji
+type ChatFilterEmojiSpam struct {
+	Action    string `json:"action"`
+	MaxEmojis int    `json:"max_emojis"`
+}
+
+// ChatFilterRepeats matches runs of the same character longer than MaxRun
+type ChatFilterRepeats struct {
+	Action string `json:"action"`
+	MaxRun int    `json:"max_run"`
+}
+
+// ChatFilterMatch records a filter rule that matched a message
+type ChatFilterMatch struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Moderation queue statuses
+const (
+	ChatQueuePending  = "pending"
+	ChatQueueApproved = "approved"
+	ChatQueueRemoved  = "removed"
+)
+
+// ChatModerationQueueItem is a message flagged by the content filter for
+// review. Message holds the text as sent, before any masking.
+type ChatModerationQueueItem struct {
+	ID         string            `json:"id" db:"id"`
+	RaceID     string            `json:"race_id" db:"race_id"`
+	MessageID  *string           `json:"message_id,omitempty" db:"message_id"`
+	UserID     *string           `json:"user_id,omitempty" db:"user_id"`
+	Username   string            `json:"username" db:"username"`
+	Message    string            `json:"message" db:"message"`
+	Matches    []ChatFilterMatch `json:"matches" db:"matches"`
+	Status     string            `json:"status" db:"status"`
+	ReviewedBy *string           `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt *time.Time        `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// ChatHypeMinute is the number of chat reactions a race got in one minute.
+// OffsetSeconds is the minute's offset from the start of the stream, when known.
+type ChatHypeMinute struct {
+	Minute        time.Time      `json:"minute" db:"minute"`
+	Total         int            `json:"total" db:"total"`
+	Emotes        map[string]int `json:"emotes" db:"emotes"`
+	OffsetSeconds *int           `json:"offset_seconds,omitempty" db:"-"`
+}
+
+// ChatTranscriptEntry is one message in an exported or archived chat
+// transcript. Deleted messages are included along with who deleted them.
+type ChatTranscriptEntry struct {
+	ID           string     `json:"id"`
+	RaceID       string     `json:"race_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UserID       *string    `json:"user_id,omitempty"`
+	Username     string     `json:"username"`
+	Role         string     `json:"role,omitempty"`
+	Badges       []string   `json:"badges,omitempty"`
+	Message      string     `json:"message"`
+	Announcement bool       `json:"announcement,omitempty"`
+	ReplyToID    *string    `json:"reply_to_id,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy    *string    `json:"deleted_by,omitempty"`
+}
+
+// ChatRetentionPurge records one run of the chat retention job: the messages
+// created before Cutoff were written to ArchivePath and then deleted.
+// RaceCounts holds how many messages each race lost.
+type ChatRetentionPurge struct {
+	ID              string         `json:"id" db:"id"`
+	Cutoff          time.Time      `json:"cutoff" db:"cutoff"`
+	ArchivePath     string         `json:"archive_path" db:"archive_path"`
+	ArchiveBytes    int64          `json:"archive_bytes" db:"archive_bytes"`
+	MessageCount    int            `json:"message_count" db:"message_count"`
+	DeletedCount    int            `json:"deleted_count" db:"deleted_count"`
+	RaceCounts      map[string]int `json:"race_counts" db:"race_counts"`
+	OldestMessageAt *time.Time     `json:"oldest_message_at,omitempty" db:"oldest_message_at"`
+	NewestMessageAt *time.Time     `json:"newest_message_at,omitempty" db:"newest_message_at"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
 }
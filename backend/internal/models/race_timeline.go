@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Kinds of race timeline marker
+const (
+	MarkerTypeStart     = "start"
+	MarkerTypeAttack    = "attack"
+	MarkerTypeBreakaway = "breakaway"
+	MarkerTypeCrash     = "crash"
+	MarkerTypeKOM       = "kom"      // King of the Mountains climb or summit
+	MarkerTypeSprint    = "sprint"   // intermediate sprint
+	MarkerTypeKmToGo    = "km_to_go" // distance to the finish, e.g. "last 10 km"
+	MarkerTypeFinish    = "finish"
+	MarkerTypeOther     = "other"
+)
+
+// IsValidMarkerType reports whether markerType is a known marker type
+func IsValidMarkerType(markerType string) bool {
+	switch markerType {
+	case MarkerTypeStart, MarkerTypeAttack, MarkerTypeBreakaway, MarkerTypeCrash, MarkerTypeKOM,
+		MarkerTypeSprint, MarkerTypeKmToGo, MarkerTypeFinish, MarkerTypeOther:
+		return true
+	}
+	return false
+}
+
+// RaceMarker is a chapter marker on a feed's recording. Offset is seconds
+// into the feed's recorded segments.
+type RaceMarker struct {
+	ID         string    `json:"id" db:"id"`
+	RaceID     string    `json:"race_id" db:"race_id"`
+	StreamID   string    `json:"stream_id" db:"stream_id"`
+	Offset     float64   `json:"offset_seconds" db:"offset_seconds"`
+	MarkerType string    `json:"marker_type" db:"marker_type"`
+	Title      string    `json:"title" db:"title"`
+	KmToGo     *float64  `json:"km_to_go,omitempty" db:"km_to_go"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RaceClip is a shareable part of a feed's recording, from Start to End
+// seconds into its recorded segments
+type RaceClip struct {
+	ID        string    `json:"id" db:"id"`
+	RaceID    string    `json:"race_id" db:"race_id"`
+	StreamID  string    `json:"stream_id" db:"stream_id"`
+	MarkerID  *string   `json:"marker_id,omitempty" db:"marker_id"`
+	CreatedBy *string   `json:"created_by,omitempty" db:"created_by"`
+	Title     string    `json:"title" db:"title"`
+	Start     float64   `json:"start_offset_seconds" db:"start_offset_seconds"`
+	End       float64   `json:"end_offset_seconds" db:"end_offset_seconds"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ActivityBucket counts what viewers did in one bucket of a race: chat
+// messages sent and playback started
+type ActivityBucket struct {
+	Start          time.Time `json:"start"`
+	ChatMessages   int       `json:"chat_messages"`
+	PlaybackStarts int       `json:"playback_starts"`
+}
+
+// MarkerSuggestion is a moment of a feed's recording where viewer activity
+// spiked, offered to admins as a marker
+type MarkerSuggestion struct {
+	Offset float64 `json:"offset_seconds"`
+	// At is the wall clock time of the spike's bucket
+	At             time.Time `json:"at"`
+	ChatMessages   int       `json:"chat_messages"`
+	PlaybackStarts int       `json:"playback_starts"`
+	// Score is how far the bucket's activity stood out, in standard
+	// deviations above the race's mean
+	Score float64 `json:"score"`
+}
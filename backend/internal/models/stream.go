@@ -2,17 +2,95 @@ package models
 
 import "time"
 
+// Stream statuses
+const (
+	StreamStatusScheduled = "scheduled"
+	StreamStatusPreShow   = "pre_show"
+	StreamStatusLive      = "live"
+	StreamStatusEnded     = "ended"
+	StreamStatusVODReady  = "vod_ready"
+	StreamStatusFailed    = "failed"
+)
+
+// Who may watch a stream (feed) of a race
+const (
+	StreamAccessRace   = "race"   // the race's rules: login and payment as set on the race
+	StreamAccessPublic = "public" // anyone, e.g. a free finish-line cam on a paid race
+	StreamAccessLogin  = "login"  // signed-in viewers
+	StreamAccessPaid   = "paid"   // signed-in viewers entitled to the race (all of them on a free race)
+)
+
+// IsValidStreamAccess reports whether access is a known stream access rule
+func IsValidStreamAccess(access string) bool {
+	switch access {
+	case StreamAccessRace, StreamAccessPublic, StreamAccessLogin, StreamAccessPaid:
+		return true
+	}
+	return false
+}
+
+// streamTransitions lists the statuses each status can move to. A stream
+// that ended can go live again when the encoder reconnects; a failed stream
+// can be retried.
+var streamTransitions = map[string][]string{
+	StreamStatusScheduled: {StreamStatusPreShow, StreamStatusLive, StreamStatusFailed},
+	StreamStatusPreShow:   {StreamStatusScheduled, StreamStatusLive, StreamStatusFailed},
+	StreamStatusLive:      {StreamStatusEnded, StreamStatusFailed},
+	StreamStatusEnded:     {StreamStatusLive, StreamStatusVODReady, StreamStatusFailed},
+	StreamStatusVODReady:  {},
+	StreamStatusFailed:    {StreamStatusScheduled, StreamStatusPreShow, StreamStatusLive},
+}
+
+// IsValidStreamStatus reports whether status is a known stream status
+func IsValidStreamStatus(status string) bool {
+	_, ok := streamTransitions[status]
+	return ok
+}
+
+// CanTransitionStream reports whether a stream can move from one status to
+// another
+func CanTransitionStream(from, to string) bool {
+	for _, next := range streamTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream is a feed of a race. A race can have several (main feed, helicopter,
+// onboard cams); its primary feed stands for the race in listings and chat.
 type Stream struct {
-	ID         string    `json:"id" db:"id"`
-	RaceID     string    `json:"race_id" db:"race_id"`
-	Status     string    `json:"status" db:"status"`           // live, offline, upcoming
-	StreamType string    `json:"stream_type" db:"stream_type"` // hls, youtube
-	SourceID   *string   `json:"source_id,omitempty" db:"source_id"`
-	OriginURL  *string   `json:"origin_url,omitempty" db:"origin_url"`
-	CDNURL     *string   `json:"cdn_url,omitempty" db:"cdn_url"`
-	StreamKey  *string   `json:"stream_key,omitempty" db:"stream_key"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID         string  `json:"id" db:"id"`
+	RaceID     string  `json:"race_id" db:"race_id"`
+	Name       string  `json:"name" db:"name"`
+	IsPrimary  bool    `json:"is_primary" db:"is_primary"`
+	Access     string  `json:"access" db:"access"`           // race, public, login, paid
+	Status     string  `json:"status" db:"status"`           // scheduled, pre_show, live, ended, vod_ready, failed
+	StreamType string  `json:"stream_type" db:"stream_type"` // hls, youtube
+	SourceID   *string `json:"source_id,omitempty" db:"source_id"`
+	OriginURL  *string `json:"origin_url,omitempty" db:"origin_url"`
+	CDNURL     *string `json:"cdn_url,omitempty" db:"cdn_url"`
+	// How far back viewers can rewind during a live race, in seconds; nil
+	// uses the configured default and 0 disables DVR
+	DVRWindowSeconds *int       `json:"dvr_window_seconds,omitempty" db:"dvr_window_seconds"`
+	StartedAt        *time.Time `json:"started_at,omitempty" db:"started_at"` // first time the stream went live
+	EndedAt          *time.Time `json:"ended_at,omitempty" db:"ended_at"`     // when the stream last left live
+	PreviousStatus   *string    `json:"previous_status,omitempty" db:"previous_status"`
+	StatusChangedAt  *time.Time `json:"status_changed_at,omitempty" db:"status_changed_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// StreamStatusChange is a transition of a stream's status
+type StreamStatusChange struct {
+	StreamID  string    `json:"stream_id"`
+	RaceID    string    `json:"race_id"`
+	Name      string    `json:"name"`       // feed name
+	IsPrimary bool      `json:"is_primary"` // the race's status changed too
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changed_at"`
 }
 
 type StreamResponse struct {
@@ -22,3 +100,51 @@ type StreamResponse struct {
 	OriginURL  *string `json:"origin_url,omitempty"`
 	CDNURL     *string `json:"cdn_url,omitempty"`
 }
+
+// StreamPublishSession is one RTMP publish to a stream, from the encoder
+// connecting until it disconnects
+type StreamPublishSession struct {
+	ID         string     `json:"id" db:"id"`
+	StreamID   string     `json:"stream_id" db:"stream_id"`
+	ClientID   string     `json:"client_id" db:"client_id"` // nginx-rtmp connection ID
+	ClientAddr *string    `json:"client_addr,omitempty" db:"client_addr"`
+	App        *string    `json:"app,omitempty" db:"app"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	EndReason  *string    `json:"end_reason,omitempty" db:"end_reason"` // done, superseded
+}
+
+// StreamKey is an RTMP publish key of a stream. Only its hash is stored, so
+// the key itself is shown once, when it is generated.
+type StreamKey struct {
+	ID        string     `json:"id" db:"id"`
+	StreamID  string     `json:"stream_id" db:"stream_id"`
+	Hint      string     `json:"hint" db:"key_hint"` // last characters of the key
+	Status    string     `json:"status"`             // active, expiring, expired, revoked
+	CreatedBy *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"` // end of the overlap window after a rotation
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy *string    `json:"revoked_by,omitempty" db:"revoked_by"`
+}
+
+// StreamKeyEvent is an entry of the stream key audit trail
+type StreamKeyEvent struct {
+	ID           string     `json:"id" db:"id"`
+	StreamID     string     `json:"stream_id" db:"stream_id"`
+	KeyID        *string    `json:"key_id,omitempty" db:"key_id"`
+	Action       string     `json:"action" db:"action"` // created, rotated, revoked
+	Actor        *string    `json:"actor,omitempty" db:"actor"`
+	OverlapUntil *time.Time `json:"overlap_until,omitempty" db:"overlap_until"` // rotations: when the replaced keys stop working
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// StreamSegment is a media segment of a live HLS stream, recorded for DVR
+type StreamSegment struct {
+	StreamID        string    `json:"stream_id" db:"stream_id"`
+	Sequence        int64     `json:"sequence" db:"sequence"`
+	URI             string    `json:"uri" db:"uri"`
+	Duration        float64   `json:"duration_seconds" db:"duration_seconds"`
+	ProgramDateTime time.Time `json:"program_date_time" db:"program_date_time"`
+	Discontinuity   bool      `json:"discontinuity" db:"discontinuity"`
+}
@@ -2,6 +2,13 @@ package models
 
 import "time"
 
+// Health of a stream provider as seen by the health prober
+const (
+	ProviderHealthUnknown  = "unknown" // not checked yet, or not checkable (YouTube)
+	ProviderHealthHealthy  = "healthy"
+	ProviderHealthDegraded = "degraded"
+)
+
 type StreamProvider struct {
 	ID              string                 `json:"id" db:"id"`
 	StreamID        string                 `json:"stream_id" db:"stream_id"`
@@ -9,6 +16,20 @@ type StreamProvider struct {
 	ProviderVideoID string                 `json:"provider_video_id" db:"provider_video_id"`
 	ProviderURL     *string                `json:"provider_url,omitempty" db:"provider_url"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
-	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time              `json:"updated_at" db:"updated_at"`
+	// Priority orders the stream's playback sources, lowest first. Providers
+	// without one aren't played from.
+	Priority            *int       `json:"priority,omitempty" db:"priority"`
+	StreamType          string     `json:"stream_type" db:"stream_type"`
+	HealthStatus        string     `json:"health_status" db:"health_status"`
+	ConsecutiveFailures int        `json:"consecutive_failures" db:"consecutive_failures"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	LastError           *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// StreamProviderProbe is a playback source due a health check
+type StreamProviderProbe struct {
+	StreamProvider
+	RaceID string
 }
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionStream(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{StreamStatusScheduled, StreamStatusPreShow, true},
+		{StreamStatusScheduled, StreamStatusLive, true},
+		{StreamStatusPreShow, StreamStatusLive, true},
+		{StreamStatusLive, StreamStatusEnded, true},
+		{StreamStatusEnded, StreamStatusLive, true},
+		{StreamStatusEnded, StreamStatusVODReady, true},
+		{StreamStatusFailed, StreamStatusScheduled, true},
+		{StreamStatusLive, StreamStatusScheduled, false},
+		{StreamStatusScheduled, StreamStatusEnded, false},
+		{StreamStatusVODReady, StreamStatusLive, false},
+		{StreamStatusVODReady, StreamStatusFailed, false},
+		{StreamStatusLive, StreamStatusLive, false},
+		{StreamStatusLive, "offline", false},
+		{"offline", StreamStatusLive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, CanTransitionStream(tt.from, tt.to))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// VOD asset statuses
+const (
+	VODAssetProcessing = "processing"
+	VODAssetReady      = "ready"
+	VODAssetFailed     = "failed"
+)
+
+// Where a VOD asset's segments came from
+const (
+	VODSourceSegments  = "segments"  // recorded from the live playlist
+	VODSourceDirectory = "directory" // read from a directory of segment files
+)
+
+// VODAsset is the replay of a stream, made of the HLS segments recorded
+// while it was live
+type VODAsset struct {
+	ID           string  `json:"id" db:"id"`
+	StreamID     string  `json:"stream_id" db:"stream_id"`
+	RaceID       string  `json:"race_id" db:"race_id"`
+	Status       string  `json:"status" db:"status"` // processing, ready, failed
+	Source       string  `json:"source" db:"source"`
+	Duration     float64 `json:"duration_seconds" db:"duration_seconds"`
+	SegmentCount int     `json:"segment_count" db:"segment_count"`
+	// Offset into the replay of the frame used as its thumbnail
+	ThumbnailOffset *float64   `json:"thumbnail_offset_seconds,omitempty" db:"thumbnail_offset_seconds"`
+	RecordedFrom    *time.Time `json:"recorded_from,omitempty" db:"recorded_from"`
+	RecordedTo      *time.Time `json:"recorded_to,omitempty" db:"recorded_to"`
+	Error           *string    `json:"error,omitempty" db:"error"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Replay is a ready VOD asset in the replay catalog, with its race and feed
+type Replay struct {
+	VODAsset
+	RaceName   string  `json:"race_name"`
+	StreamName string  `json:"stream_name"`
+	IsPrimary  bool    `json:"is_primary"`
+	Category   *string `json:"category,omitempty"`
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrChatEmoteCodeTaken is returned when an emote code is already used in the same scope
+var ErrChatEmoteCodeTaken = errors.New("emote code already in use")
+
+type ChatEmoteRepository struct {
+	db *sql.DB
+}
+
+func NewChatEmoteRepository(db *sql.DB) *ChatEmoteRepository {
+	return &ChatEmoteRepository{db: db}
+}
+
+const chatEmoteColumns = `id, code, image_url, race_id, min_level, requires_ticket, created_by, created_at, updated_at`
+
+func scanChatEmote(scanner interface{ Scan(...interface{}) error }) (*models.ChatEmote, error) {
+	var e models.ChatEmote
+	var imageURL, raceID, createdBy sql.NullString
+
+	if err := scanner.Scan(
+		&e.ID,
+		&e.Code,
+		&imageURL,
+		&raceID,
+		&e.MinLevel,
+		&e.RequiresTicket,
+		&createdBy,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if imageURL.Valid {
+		e.ImageURL = &imageURL.String
+	}
+	if raceID.Valid {
+		e.RaceID = &raceID.String
+	}
+	if createdBy.Valid {
+		e.CreatedBy = &createdBy.String
+	}
+
+	return &e, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// ListAll returns every emote, global emotes first
+func (r *ChatEmoteRepository) ListAll() ([]*models.ChatEmote, error) {
+	query := `SELECT ` + chatEmoteColumns + ` FROM chat_emotes ORDER BY race_id NULLS FIRST, code`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat emotes: %w", err)
+	}
+	defer rows.Close()
+
+	var emotes []*models.ChatEmote
+	for rows.Next() {
+		emote, err := scanChatEmote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat emote: %w", err)
+		}
+		emotes = append(emotes, emote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat emotes: %w", err)
+	}
+
+	return emotes, nil
+}
+
+func (r *ChatEmoteRepository) GetByID(id string) (*models.ChatEmote, error) {
+	query := `SELECT ` + chatEmoteColumns + ` FROM chat_emotes WHERE id = $1`
+
+	emote, err := scanChatEmote(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat emote: %w", err)
+	}
+
+	return emote, nil
+}
+
+func (r *ChatEmoteRepository) Create(emote *models.ChatEmote) error {
+	query := `
+		INSERT INTO chat_emotes (code, image_url, race_id, min_level, requires_ticket, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		emote.Code,
+		emote.ImageURL,
+		emote.RaceID,
+		emote.MinLevel,
+		emote.RequiresTicket,
+		emote.CreatedBy,
+	).Scan(&emote.ID, &emote.CreatedAt, &emote.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrChatEmoteCodeTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create chat emote: %w", err)
+	}
+
+	return nil
+}
+
+// Update saves an emote's code, image and gating. Returns false if the emote doesn't exist.
+func (r *ChatEmoteRepository) Update(emote *models.ChatEmote) (bool, error) {
+	query := `
+		UPDATE chat_emotes
+		SET code = $2, image_url = $3, race_id = $4, min_level = $5, requires_ticket = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		emote.ID,
+		emote.Code,
+		emote.ImageURL,
+		emote.RaceID,
+		emote.MinLevel,
+		emote.RequiresTicket,
+	).Scan(&emote.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if isUniqueViolation(err) {
+		return false, ErrChatEmoteCodeTaken
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update chat emote: %w", err)
+	}
+
+	return true, nil
+}
+
+// Delete removes an emote. Returns false if it didn't exist.
+func (r *ChatEmoteRepository) Delete(id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM chat_emotes WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat emote: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat emote: %w", err)
+	}
+
+	return affected > 0, nil
+}
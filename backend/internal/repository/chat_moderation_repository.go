@@ -0,0 +1,429 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+type ChatModerationRepository struct {
+	db *sql.DB
+}
+
+func NewChatModerationRepository(db *sql.DB) *ChatModerationRepository {
+	return &ChatModerationRepository{db: db}
+}
+
+const chatRestrictionColumns = `id, race_id, user_id, kind, reason, moderator_id, expires_at, revoked_at, created_at`
+
+func scanChatRestriction(scanner interface{ Scan(...interface{}) error }) (*models.ChatRestriction, error) {
+	var r models.ChatRestriction
+	var raceID, reason, moderatorID sql.NullString
+	var expiresAt, revokedAt sql.NullTime
+
+	if err := scanner.Scan(
+		&r.ID,
+		&raceID,
+		&r.UserID,
+		&r.Kind,
+		&reason,
+		&moderatorID,
+		&expiresAt,
+		&revokedAt,
+		&r.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if raceID.Valid {
+		r.RaceID = &raceID.String
+	}
+	if reason.Valid {
+		r.Reason = &reason.String
+	}
+	if moderatorID.Valid {
+		r.ModeratorID = &moderatorID.String
+	}
+	if expiresAt.Valid {
+		r.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		r.RevokedAt = &revokedAt.Time
+	}
+
+	return &r, nil
+}
+
+// CreateRestriction stores a new timeout or ban
+func (r *ChatModerationRepository) CreateRestriction(restriction *models.ChatRestriction) error {
+	query := `
+		INSERT INTO chat_user_restrictions (race_id, user_id, kind, reason, moderator_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		restriction.RaceID,
+		restriction.UserID,
+		restriction.Kind,
+		restriction.Reason,
+		restriction.ModeratorID,
+		restriction.ExpiresAt,
+	).Scan(&restriction.ID, &restriction.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create chat restriction: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveRestriction returns the longest-lasting unexpired, unrevoked
+// restriction for a user in a race (including global restrictions), or nil.
+func (r *ChatModerationRepository) GetActiveRestriction(raceID, userID string) (*models.ChatRestriction, error) {
+	query := `
+		SELECT ` + chatRestrictionColumns + `
+		FROM chat_user_restrictions
+		WHERE user_id = $1
+			AND (race_id = $2 OR race_id IS NULL)
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY expires_at DESC NULLS FIRST
+		LIMIT 1
+	`
+
+	restriction, err := scanChatRestriction(r.db.QueryRow(query, userID, raceID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active chat restriction: %w", err)
+	}
+
+	return restriction, nil
+}
+
+// ListActiveByRace returns active restrictions affecting a race, including global ones
+func (r *ChatModerationRepository) ListActiveByRace(raceID string) ([]*models.ChatRestriction, error) {
+	query := `
+		SELECT ` + chatRestrictionColumns + `
+		FROM chat_user_restrictions
+		WHERE (race_id = $1 OR race_id IS NULL)
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, raceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat restrictions: %w", err)
+	}
+	defer rows.Close()
+
+	restrictions := []*models.ChatRestriction{}
+	for rows.Next() {
+		restriction, err := scanChatRestriction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat restriction: %w", err)
+		}
+		restrictions = append(restrictions, restriction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat restrictions: %w", err)
+	}
+
+	return restrictions, nil
+}
+
+// RevokeActive lifts a user's active restrictions in a race. Global
+// restrictions stay in place; they are lifted with RevokeGlobal. Returns the
+// number of restrictions revoked.
+func (r *ChatModerationRepository) RevokeActive(raceID, userID, moderatorID string) (int64, error) {
+	query := `
+		UPDATE chat_user_restrictions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = $3
+		WHERE user_id = $1
+			AND race_id = $2
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`
+
+	return r.revoke(query, userID, raceID, moderatorID)
+}
+
+// RevokeGlobal lifts a user's active global restrictions, leaving those of
+// single races in place. Returns the number of restrictions revoked.
+func (r *ChatModerationRepository) RevokeGlobal(userID, moderatorID string) (int64, error) {
+	query := `
+		UPDATE chat_user_restrictions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = $2
+		WHERE user_id = $1
+			AND race_id IS NULL
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`
+
+	return r.revoke(query, userID, moderatorID)
+}
+
+func (r *ChatModerationRepository) revoke(query string, args ...interface{}) (int64, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke chat restrictions: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// GetRoomSettings returns the chat settings for a race, with defaults when unset
+func (r *ChatModerationRepository) GetRoomSettings(raceID string) (*models.ChatRoomSettings, error) {
+	query := `
+		SELECT race_id, slow_mode_seconds, updated_by, updated_at
+		FROM chat_room_settings
+		WHERE race_id = $1
+	`
+
+	var settings models.ChatRoomSettings
+	var updatedBy sql.NullString
+	err := r.db.QueryRow(query, raceID).Scan(
+		&settings.RaceID,
+		&settings.SlowModeSeconds,
+		&updatedBy,
+		&settings.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return &models.ChatRoomSettings{RaceID: raceID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat room settings: %w", err)
+	}
+
+	if updatedBy.Valid {
+		settings.UpdatedBy = &updatedBy.String
+	}
+
+	return &settings, nil
+}
+
+// SetSlowMode stores the slow mode interval for a race (0 disables slow mode)
+func (r *ChatModerationRepository) SetSlowMode(raceID string, seconds int, moderatorID string) (*models.ChatRoomSettings, error) {
+	query := `
+		INSERT INTO chat_room_settings (race_id, slow_mode_seconds, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (race_id) DO UPDATE
+		SET slow_mode_seconds = EXCLUDED.slow_mode_seconds,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`
+
+	settings := &models.ChatRoomSettings{
+		RaceID:          raceID,
+		SlowModeSeconds: seconds,
+		UpdatedBy:       &moderatorID,
+	}
+	if err := r.db.QueryRow(query, raceID, seconds, moderatorID).Scan(&settings.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to set slow mode: %w", err)
+	}
+
+	return settings, nil
+}
+
+// GetFilterConfig returns a race's chat filter config, or nil when the race
+// uses the defaults
+func (r *ChatModerationRepository) GetFilterConfig(raceID string) (*models.ChatFilterConfig, error) {
+	var raw []byte
+	err := r.db.QueryRow(`SELECT filter_config FROM chat_room_settings WHERE race_id = $1`, raceID).Scan(&raw)
+	if err == sql.ErrNoRows || (err == nil && raw == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat filter config: %w", err)
+	}
+
+	var cfg models.ChatFilterConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode chat filter config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// SetFilterConfig stores a race's chat filter config. A nil config resets
+// the race to the defaults.
+func (r *ChatModerationRepository) SetFilterConfig(raceID string, cfg *models.ChatFilterConfig, moderatorID string) error {
+	var raw interface{}
+	if cfg != nil {
+		encoded, err := json.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to encode chat filter config: %w", err)
+		}
+		raw = string(encoded)
+	}
+
+	query := `
+		INSERT INTO chat_room_settings (race_id, filter_config, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (race_id) DO UPDATE
+		SET filter_config = EXCLUDED.filter_config,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := r.db.Exec(query, raceID, raw, moderatorID); err != nil {
+		return fmt.Errorf("failed to set chat filter config: %w", err)
+	}
+	return nil
+}
+
+const chatQueueColumns = `id, race_id, message_id, user_id, username, message, matches, status, reviewed_by, reviewed_at, created_at`
+
+func scanChatQueueItem(scanner interface{ Scan(...interface{}) error }) (*models.ChatModerationQueueItem, error) {
+	var item models.ChatModerationQueueItem
+	var messageID, userID, reviewedBy sql.NullString
+	var reviewedAt sql.NullTime
+	var matches []byte
+
+	if err := scanner.Scan(
+		&item.ID,
+		&item.RaceID,
+		&messageID,
+		&userID,
+		&item.Username,
+		&item.Message,
+		&matches,
+		&item.Status,
+		&reviewedBy,
+		&reviewedAt,
+		&item.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if messageID.Valid {
+		item.MessageID = &messageID.String
+	}
+	if userID.Valid {
+		item.UserID = &userID.String
+	}
+	if reviewedBy.Valid {
+		item.ReviewedBy = &reviewedBy.String
+	}
+	if reviewedAt.Valid {
+		item.ReviewedAt = &reviewedAt.Time
+	}
+	item.Matches = []models.ChatFilterMatch{}
+	if len(matches) > 0 {
+		if err := json.Unmarshal(matches, &item.Matches); err != nil {
+			return nil, fmt.Errorf("failed to decode filter matches: %w", err)
+		}
+	}
+
+	return &item, nil
+}
+
+// CreateQueueItem adds a flagged message to the moderation queue
+func (r *ChatModerationRepository) CreateQueueItem(item *models.ChatModerationQueueItem) error {
+	matches, err := json.Marshal(item.Matches)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter matches: %w", err)
+	}
+
+	query := `
+		INSERT INTO chat_moderation_queue (race_id, message_id, user_id, username, message, matches)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`
+
+	err = r.db.QueryRow(
+		query,
+		item.RaceID,
+		item.MessageID,
+		item.UserID,
+		item.Username,
+		item.Message,
+		string(matches),
+	).Scan(&item.ID, &item.Status, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create moderation queue item: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueueItem returns a moderation queue item by ID, or nil
+func (r *ChatModerationRepository) GetQueueItem(id string) (*models.ChatModerationQueueItem, error) {
+	query := `SELECT ` + chatQueueColumns + ` FROM chat_moderation_queue WHERE id = $1`
+
+	item, err := scanChatQueueItem(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation queue item: %w", err)
+	}
+
+	return item, nil
+}
+
+// ListQueue returns moderation queue items with a status, newest first. An
+// empty raceID lists every race.
+func (r *ChatModerationRepository) ListQueue(raceID, status string, limit int) ([]*models.ChatModerationQueueItem, error) {
+	query := `
+		SELECT ` + chatQueueColumns + `
+		FROM chat_moderation_queue
+		WHERE status = $1 AND ($2 = '' OR race_id::text = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, status, raceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list moderation queue: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.ChatModerationQueueItem{}
+	for rows.Next() {
+		item, err := scanChatQueueItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation queue item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating moderation queue: %w", err)
+	}
+
+	return items, nil
+}
+
+// ReviewQueueItem records a reviewer's decision on a pending item. Returns
+// false if the item doesn't exist or was already reviewed.
+func (r *ChatModerationRepository) ReviewQueueItem(id, status, reviewerID string) (bool, error) {
+	query := `
+		UPDATE chat_moderation_queue
+		SET status = $2, reviewed_by = $3, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(query, id, status, reviewerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to review moderation queue item: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,55 @@
+// +build integration
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChatModerationRepository_Revoke_Integration tests that race and global bans are lifted separately
+func TestChatModerationRepository_Revoke_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db := testutil.GetTestDB(t)
+	defer db.Close()
+
+	repo := NewChatModerationRepository(db)
+	raceID := testutil.CreateTestRace(t, db, "Moderation Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	userID := testutil.CreateTestUser(t, db, "banned@example.com", "hashedpassword", "Banned User")
+	moderatorID := testutil.CreateTestUser(t, db, "moderator@example.com", "hashedpassword", "Moderator")
+	defer testutil.CleanupUsers(t, db, []string{userID, moderatorID})
+
+	require.NoError(t, repo.CreateRestriction(&models.ChatRestriction{RaceID: &raceID, UserID: userID, Kind: models.ChatRestrictionBan, ModeratorID: &moderatorID}))
+	global := &models.ChatRestriction{UserID: userID, Kind: models.ChatRestrictionBan, ModeratorID: &moderatorID}
+	require.NoError(t, repo.CreateRestriction(global))
+
+	revoked, err := repo.RevokeActive(raceID, userID, moderatorID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+
+	active, err := repo.GetActiveRestriction(raceID, userID)
+	require.NoError(t, err)
+	require.NotNil(t, active, "Lifting the race ban leaves the global ban")
+	assert.Equal(t, global.ID, active.ID)
+
+	revoked, err = repo.RevokeActive(raceID, userID, moderatorID)
+	require.NoError(t, err)
+	assert.Zero(t, revoked)
+
+	revoked, err = repo.RevokeGlobal(userID, moderatorID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+
+	active, err = repo.GetActiveRestriction(raceID, userID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// ErrChatPartyCodeTaken is returned when a generated invite code collides with an existing party
+var ErrChatPartyCodeTaken = errors.New("party invite code already in use")
+
+// ChatPartyRepository stores watch parties, their members and private
+// messages (party messages and whispers)
+type ChatPartyRepository struct {
+	db *sql.DB
+}
+
+func NewChatPartyRepository(db *sql.DB) *ChatPartyRepository {
+	return &ChatPartyRepository{db: db}
+}
+
+const chatPartyColumns = `p.id, p.race_id, p.invite_code, p.name, p.owner_id, p.created_at,
+	(SELECT COUNT(*) FROM chat_party_members m WHERE m.party_id = p.id)`
+
+func scanChatParty(scanner interface{ Scan(...interface{}) error }) (*models.ChatParty, error) {
+	var p models.ChatParty
+	if err := scanner.Scan(
+		&p.ID,
+		&p.RaceID,
+		&p.InviteCode,
+		&p.Name,
+		&p.OwnerID,
+		&p.CreatedAt,
+		&p.MemberCount,
+	); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateParty stores a party and adds its owner as the first member
+func (r *ChatPartyRepository) CreateParty(party *models.ChatParty) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO chat_parties (race_id, invite_code, name, owner_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, party.RaceID, party.InviteCode, party.Name, party.OwnerID).Scan(&party.ID, &party.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrChatPartyCodeTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create chat party: %w", err)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO chat_party_members (party_id, user_id) VALUES ($1, $2)`, party.ID, party.OwnerID); err != nil {
+		return fmt.Errorf("failed to add chat party owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chat party: %w", err)
+	}
+
+	party.MemberCount = 1
+	return nil
+}
+
+// GetPartyByCode returns a race's party by invite code, or nil
+func (r *ChatPartyRepository) GetPartyByCode(raceID, code string) (*models.ChatParty, error) {
+	query := `SELECT ` + chatPartyColumns + ` FROM chat_parties p WHERE p.race_id = $1 AND p.invite_code = $2`
+
+	party, err := scanChatParty(r.db.QueryRow(query, raceID, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat party: %w", err)
+	}
+
+	return party, nil
+}
+
+// ListPartiesForUser returns the parties a user belongs to in a race
+func (r *ChatPartyRepository) ListPartiesForUser(raceID, userID string) ([]*models.ChatParty, error) {
+	query := `
+		SELECT ` + chatPartyColumns + `
+		FROM chat_parties p
+		JOIN chat_party_members me ON me.party_id = p.id AND me.user_id = $2
+		WHERE p.race_id = $1
+		ORDER BY p.created_at
+	`
+
+	rows, err := r.db.Query(query, raceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat parties: %w", err)
+	}
+	defer rows.Close()
+
+	var parties []*models.ChatParty
+	for rows.Next() {
+		party, err := scanChatParty(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat party: %w", err)
+		}
+		parties = append(parties, party)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat parties: %w", err)
+	}
+
+	return parties, nil
+}
+
+// AddMember adds a user to a party. Returns false if they were already a member.
+func (r *ChatPartyRepository) AddMember(partyID, userID string) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO chat_party_members (party_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (party_id, user_id) DO NOTHING
+	`, partyID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add chat party member: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to add chat party member: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// RemoveMember removes a user from a party. Returns false if they weren't a member.
+func (r *ChatPartyRepository) RemoveMember(partyID, userID string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM chat_party_members WHERE party_id = $1 AND user_id = $2`, partyID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove chat party member: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove chat party member: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// IsPartyMember reports whether a user belongs to the party with the given
+// invite code in a race
+func (r *ChatPartyRepository) IsPartyMember(raceID, code, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM chat_parties p
+			JOIN chat_party_members m ON m.party_id = p.id
+			WHERE p.race_id = $1 AND p.invite_code = $2 AND m.user_id = $3
+		)
+	`
+
+	var member bool
+	if err := r.db.QueryRow(query, raceID, code, userID).Scan(&member); err != nil {
+		return false, fmt.Errorf("failed to check chat party membership: %w", err)
+	}
+
+	return member, nil
+}
+
+// CreateMessage stores a party message or whisper
+func (r *ChatPartyRepository) CreateMessage(msg *models.ChatPrivateMessage) error {
+	query := `
+		INSERT INTO chat_private_messages (race_id, party_id, sender_id, recipient_id, username, message)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		msg.RaceID,
+		msg.PartyID,
+		msg.SenderID,
+		msg.RecipientID,
+		msg.Username,
+		msg.Message,
+	).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create private chat message: %w", err)
+	}
+
+	return nil
+}
+
+const chatPrivateMessageColumns = `id, race_id, party_id, sender_id, recipient_id, username, message, created_at`
+
+// scanPrivateMessagesNewestFirst reads newest-first rows and returns them
+// oldest first
+func scanPrivateMessagesNewestFirst(rows *sql.Rows) ([]*models.ChatPrivateMessage, error) {
+	var messages []*models.ChatPrivateMessage
+	for rows.Next() {
+		var msg models.ChatPrivateMessage
+		var partyID, recipientID sql.NullString
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.RaceID,
+			&partyID,
+			&msg.SenderID,
+			&recipientID,
+			&msg.Username,
+			&msg.Message,
+			&msg.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan private chat message: %w", err)
+		}
+		if partyID.Valid {
+			msg.PartyID = &partyID.String
+		}
+		if recipientID.Valid {
+			msg.RecipientID = &recipientID.String
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating private chat messages: %w", err)
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// ListPartyMessages returns a party's latest messages, oldest first
+func (r *ChatPartyRepository) ListPartyMessages(partyID string, limit int) ([]*models.ChatPrivateMessage, error) {
+	query := `
+		SELECT ` + chatPrivateMessageColumns + `
+		FROM chat_private_messages
+		WHERE party_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, partyID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list party messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPrivateMessagesNewestFirst(rows)
+}
+
+// ListWhispers returns the latest whispers between two users in a race, oldest first
+func (r *ChatPartyRepository) ListWhispers(raceID, userID, otherUserID string, limit int) ([]*models.ChatPrivateMessage, error) {
+	query := `
+		SELECT ` + chatPrivateMessageColumns + `
+		FROM chat_private_messages
+		WHERE race_id = $1
+			AND recipient_id IS NOT NULL
+			AND LEAST(sender_id, recipient_id) = LEAST($2::uuid, $3::uuid)
+			AND GREATEST(sender_id, recipient_id) = GREATEST($2::uuid, $3::uuid)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(query, raceID, userID, otherUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list whispers: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPrivateMessagesNewestFirst(rows)
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+type ChatPollRepository struct {
+	db *sql.DB
+}
+
+func NewChatPollRepository(db *sql.DB) *ChatPollRepository {
+	return &ChatPollRepository{db: db}
+}
+
+const chatPollColumns = `id, race_id, question, poll_type, max_choices, options, total_votes, created_by, created_at, closes_at, closed_at`
+
+func scanChatPoll(scanner interface{ Scan(...interface{}) error }) (*models.ChatPoll, error) {
+	var poll models.ChatPoll
+	var optionsJSON []byte
+	var createdBy sql.NullString
+	var closesAt, closedAt sql.NullTime
+
+	if err := scanner.Scan(
+		&poll.ID,
+		&poll.RaceID,
+		&poll.Question,
+		&poll.Type,
+		&poll.MaxChoices,
+		&optionsJSON,
+		&poll.TotalVotes,
+		&createdBy,
+		&poll.CreatedAt,
+		&closesAt,
+		&closedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(optionsJSON, &poll.Options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal poll options: %w", err)
+	}
+	if createdBy.Valid {
+		poll.CreatedBy = &createdBy.String
+	}
+	if closesAt.Valid {
+		poll.ClosesAt = &closesAt.Time
+	}
+	if closedAt.Valid {
+		poll.ClosedAt = &closedAt.Time
+		poll.Closed = true
+	}
+
+	return &poll, nil
+}
+
+// CreatePoll stores a new poll. The poll ID is generated by the caller.
+func (r *ChatPollRepository) CreatePoll(poll *models.ChatPoll) error {
+	optionsJSON, err := json.Marshal(poll.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal poll options: %w", err)
+	}
+
+	query := `
+		INSERT INTO chat_polls (id, race_id, question, poll_type, max_choices, options, created_by, created_at, closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err = r.db.Exec(
+		query,
+		poll.ID,
+		poll.RaceID,
+		poll.Question,
+		poll.Type,
+		poll.MaxChoices,
+		optionsJSON,
+		poll.CreatedBy,
+		poll.CreatedAt,
+		poll.ClosesAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create chat poll: %w", err)
+	}
+
+	return nil
+}
+
+// GetPoll returns a poll, or nil
+func (r *ChatPollRepository) GetPoll(pollID string) (*models.ChatPoll, error) {
+	query := `SELECT ` + chatPollColumns + ` FROM chat_polls WHERE id = $1`
+
+	poll, err := scanChatPoll(r.db.QueryRow(query, pollID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat poll: %w", err)
+	}
+	return poll, nil
+}
+
+// SaveBallot records or replaces a user's ballot for a poll and updates the
+// poll's vote counts and voters. It returns false when the poll is closed or
+// doesn't exist.
+func (r *ChatPollRepository) SaveBallot(pollID, userID string, optionIDs []string) (bool, error) {
+	optionsJSON, err := json.Marshal(optionIDs)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal ballot: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Ballots of the same poll are counted one at a time, and not once it's closed
+	var open bool
+	err = tx.QueryRow(`SELECT closed_at IS NULL FROM chat_polls WHERE id = $1 FOR UPDATE`, pollID).Scan(&open)
+	if err == sql.ErrNoRows || (err == nil && !open) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock chat poll: %w", err)
+	}
+
+	var previousJSON []byte
+	err = tx.QueryRow(`SELECT option_ids FROM chat_poll_votes WHERE poll_id = $1 AND user_id = $2`, pollID, userID).Scan(&previousJSON)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.Exec(`UPDATE chat_polls SET total_votes = total_votes + 1 WHERE id = $1`, pollID); err != nil {
+			return false, fmt.Errorf("failed to count poll voter: %w", err)
+		}
+	case err != nil:
+		return false, fmt.Errorf("failed to get poll ballot: %w", err)
+	default:
+		_, err := tx.Exec(`
+			UPDATE chat_poll_option_votes v
+			SET ballots = v.ballots - 1
+			FROM jsonb_array_elements_text($2::jsonb) WITH ORDINALITY AS b(option_id, position)
+			WHERE v.poll_id = $1 AND v.option_id = b.option_id AND v.rank = b.position - 1
+		`, pollID, previousJSON)
+		if err != nil {
+			return false, fmt.Errorf("failed to uncount previous poll ballot: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO chat_poll_votes (poll_id, user_id, option_ids)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (poll_id, user_id) DO UPDATE
+		SET option_ids = EXCLUDED.option_ids, updated_at = CURRENT_TIMESTAMP
+	`, pollID, userID, optionsJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to save poll ballot: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO chat_poll_option_votes (poll_id, option_id, rank, ballots)
+		SELECT $1::uuid, b.option_id, b.position - 1, 1
+		FROM jsonb_array_elements_text($2::jsonb) WITH ORDINALITY AS b(option_id, position)
+		ON CONFLICT (poll_id, option_id, rank) DO UPDATE
+		SET ballots = chat_poll_option_votes.ballots + 1
+	`, pollID, optionsJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to count poll ballot: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit poll ballot: %w", err)
+	}
+	return true, nil
+}
+
+// CountVotes returns a poll's running vote counts and number of voters
+func (r *ChatPollRepository) CountVotes(pollID string) ([]models.ChatPollVoteCount, int, error) {
+	var voters int
+	if err := r.db.QueryRow(`SELECT total_votes FROM chat_polls WHERE id = $1`, pollID).Scan(&voters); err != nil {
+		return nil, 0, fmt.Errorf("failed to get poll voters: %w", err)
+	}
+
+	rows, err := r.db.Query(`SELECT option_id, rank, ballots FROM chat_poll_option_votes WHERE poll_id = $1 AND ballots > 0`, pollID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list poll vote counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []models.ChatPollVoteCount{}
+	for rows.Next() {
+		var count models.ChatPollVoteCount
+		if err := rows.Scan(&count.OptionID, &count.Rank, &count.Ballots); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan poll vote count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating poll vote counts: %w", err)
+	}
+
+	return counts, voters, nil
+}
+
+// ClosePoll marks a poll closed and stores its final tallies. It returns false
+// when the poll was already closed (e.g. by another instance).
+func (r *ChatPollRepository) ClosePoll(poll *models.ChatPoll) (bool, error) {
+	optionsJSON, err := json.Marshal(poll.Options)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal poll options: %w", err)
+	}
+
+	query := `
+		UPDATE chat_polls
+		SET closed_at = $2, options = $3, total_votes = $4
+		WHERE id = $1 AND closed_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, poll.ID, poll.ClosedAt, optionsJSON, poll.TotalVotes)
+	if err != nil {
+		return false, fmt.Errorf("failed to close chat poll: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ListOpen returns the polls that have not been closed yet, of one race when
+// raceID is set, oldest first
+func (r *ChatPollRepository) ListOpen(raceID string) ([]*models.ChatPoll, error) {
+	query := `
+		SELECT ` + chatPollColumns + `
+		FROM chat_polls
+		WHERE closed_at IS NULL AND ($1 = '' OR race_id::text = $1)
+		ORDER BY created_at ASC
+	`
+
+	return r.queryPolls(query, raceID)
+}
+
+// ListByRace returns the most recent polls for a race, newest first
+func (r *ChatPollRepository) ListByRace(raceID string, limit int) ([]*models.ChatPoll, error) {
+	query := `
+		SELECT ` + chatPollColumns + `
+		FROM chat_polls
+		WHERE race_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	return r.queryPolls(query, raceID, limit)
+}
+
+func (r *ChatPollRepository) queryPolls(query string, args ...interface{}) ([]*models.ChatPoll, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat polls: %w", err)
+	}
+	defer rows.Close()
+
+	polls := []*models.ChatPoll{}
+	for rows.Next() {
+		poll, err := scanChatPoll(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat poll: %w", err)
+		}
+		polls = append(polls, poll)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat polls: %w", err)
+	}
+
+	return polls, nil
+}
@@ -0,0 +1,75 @@
+// +build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChatPollRepository_CountVotes_Integration tests the running poll tallies with real database
+func TestChatPollRepository_CountVotes_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db := testutil.GetTestDB(t)
+	defer db.Close()
+
+	repo := NewChatPollRepository(db)
+	raceID := testutil.CreateTestRace(t, db, "Poll Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	first := testutil.CreateTestUser(t, db, "poll-first@example.com", "hashedpassword", "First Voter")
+	second := testutil.CreateTestUser(t, db, "poll-second@example.com", "hashedpassword", "Second Voter")
+	defer testutil.CleanupUsers(t, db, []string{first, second})
+
+	poll := &models.ChatPoll{
+		ID:        uuid.NewString(),
+		RaceID:    raceID,
+		Question:  "Rank the favourites",
+		Type:      models.ChatPollTypeRanked,
+		Options:   []models.ChatPollOption{{ID: "a", Label: "A"}, {ID: "b", Label: "B"}},
+		CreatedAt: time.Now().UTC(),
+	}
+	require.NoError(t, repo.CreatePoll(poll))
+
+	saved, err := repo.SaveBallot(poll.ID, first, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.True(t, saved)
+	_, err = repo.SaveBallot(poll.ID, second, []string{"a"})
+	require.NoError(t, err)
+	// Replacing a ballot moves its counts and adds no voter
+	_, err = repo.SaveBallot(poll.ID, first, []string{"b", "a"})
+	require.NoError(t, err)
+
+	counts, voters, err := repo.CountVotes(poll.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, voters)
+	assert.ElementsMatch(t, []models.ChatPollVoteCount{
+		{OptionID: "a", Rank: 0, Ballots: 1},
+		{OptionID: "a", Rank: 1, Ballots: 1},
+		{OptionID: "b", Rank: 0, Ballots: 1},
+	}, counts)
+
+	closedAt := time.Now().UTC()
+	poll.ClosedAt = &closedAt
+	poll.TotalVotes = voters
+	closed, err := repo.ClosePoll(poll)
+	require.NoError(t, err)
+	require.True(t, closed)
+
+	saved, err = repo.SaveBallot(poll.ID, second, []string{"b"})
+	require.NoError(t, err)
+	assert.False(t, saved, "Closed polls take no ballots")
+	counts, voters, err = repo.CountVotes(poll.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, voters)
+	assert.Len(t, counts, 3)
+}
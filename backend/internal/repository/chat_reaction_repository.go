@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ChatReactionRepository stores the per-minute chat hype timeline
+type ChatReactionRepository struct {
+	db *sql.DB
+}
+
+func NewChatReactionRepository(db *sql.DB) *ChatReactionRepository {
+	return &ChatReactionRepository{db: db}
+}
+
+// AddHype adds reaction counts to a race's minute
+func (r *ChatReactionRepository) AddHype(raceID string, minute time.Time, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	emotes := make([]string, 0, len(counts))
+	values := make([]int64, 0, len(counts))
+	for emote, count := range counts {
+		emotes = append(emotes, emote)
+		values = append(values, int64(count))
+	}
+
+	query := `
+		INSERT INTO chat_hype_timeline (race_id, minute, emote, count)
+		SELECT $1, $2, t.emote, t.count
+		FROM unnest($3::text[], $4::int[]) AS t(emote, count)
+		ON CONFLICT (race_id, minute, emote) DO UPDATE
+		SET count = chat_hype_timeline.count + EXCLUDED.count
+	`
+
+	if _, err := r.db.Exec(query, raceID, minute, pq.Array(emotes), pq.Array(values)); err != nil {
+		return fmt.Errorf("failed to add chat hype: %w", err)
+	}
+	return nil
+}
+
+// GetHypeTimeline returns a race's reaction counts per minute, oldest first
+func (r *ChatReactionRepository) GetHypeTimeline(raceID string) ([]*models.ChatHypeMinute, error) {
+	query := `
+		SELECT minute, SUM(count), jsonb_object_agg(emote, count)
+		FROM chat_hype_timeline
+		WHERE race_id = $1
+		GROUP BY minute
+		ORDER BY minute
+	`
+
+	rows, err := r.db.Query(query, raceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat hype timeline: %w", err)
+	}
+	defer rows.Close()
+
+	timeline := []*models.ChatHypeMinute{}
+	for rows.Next() {
+		var m models.ChatHypeMinute
+		var emotes []byte
+		if err := rows.Scan(&m.Minute, &m.Total, &emotes); err != nil {
+			return nil, fmt.Errorf("failed to scan chat hype minute: %w", err)
+		}
+		if err := json.Unmarshal(emotes, &m.Emotes); err != nil {
+			return nil, fmt.Errorf("failed to decode chat hype emotes: %w", err)
+		}
+		timeline = append(timeline, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat hype timeline: %w", err)
+	}
+
+	return timeline, nil
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/cyclingstream/backend/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type ChatRepository struct {
@@ -20,8 +22,8 @@ func NewChatRepository(db *sql.DB) *ChatRepository {
 func (r *ChatRepository) Create(message *models.ChatMessage) error {
 	message.ID = uuid.New().String()
 	query := `
-		INSERT INTO chat_messages (id, race_id, user_id, username, message, user_role, badges, special_emote)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO chat_messages (id, race_id, user_id, username, message, user_role, badges, special_emote, reply_to_id, emotes, is_announcement)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING created_at
 	`
 
@@ -38,6 +40,15 @@ func (r *ChatRepository) Create(message *models.ChatMessage) error {
 		return fmt.Errorf("failed to marshal badges: %w", err)
 	}
 
+	var emotesJSON interface{}
+	if len(message.Emotes) > 0 {
+		data, err := json.Marshal(message.Emotes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal emotes: %w", err)
+		}
+		emotesJSON = data
+	}
+
 	err = r.db.QueryRow(
 		query,
 		message.ID,
@@ -48,6 +59,9 @@ func (r *ChatRepository) Create(message *models.ChatMessage) error {
 		message.Role,
 		badgesJSON,
 		message.SpecialEmote,
+		message.ReplyToID,
+		emotesJSON,
+		message.Announcement,
 	).Scan(&message.CreatedAt)
 
 	if err != nil {
@@ -58,21 +72,10 @@ func (r *ChatRepository) Create(message *models.ChatMessage) error {
 	return nil
 }
 
-func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*models.ChatMessage, error) {
-	query := `
-		SELECT id, race_id, user_id, username, message, user_role, COALESCE(badges::text, '[]'), special_emote, created_at
-		FROM chat_messages
-		WHERE race_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := r.db.Query(query, raceID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chat messages: %w", err)
-	}
-	defer rows.Close()
+const chatMessageColumns = `id, race_id, user_id, username, message, user_role, COALESCE(badges::text, '[]'), special_emote, reply_to_id, COALESCE(emotes::text, '[]'), is_announcement, created_at`
 
+// scanChatMessages reads chat message rows in the order returned
+func scanChatMessages(rows *sql.Rows) ([]*models.ChatMessage, error) {
 	var messages []*models.ChatMessage
 	for rows.Next() {
 		var msg models.ChatMessage
@@ -80,6 +83,8 @@ func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*model
 		var userRole sql.NullString
 		var badgesJSONStr string // Scan JSONB as text
 		var specialEmote bool
+		var replyToID sql.NullString
+		var emotesJSONStr string
 
 		err := rows.Scan(
 			&msg.ID,
@@ -90,6 +95,9 @@ func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*model
 			&userRole,
 			&badgesJSONStr,
 			&specialEmote,
+			&replyToID,
+			&emotesJSONStr,
+			&msg.Announcement,
 			&msg.CreatedAt,
 		)
 		if err != nil {
@@ -108,6 +116,10 @@ func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*model
 			msg.Role = userRole.String
 		}
 
+		if replyToID.Valid {
+			msg.ReplyToID = &replyToID.String
+		}
+
 		// Parse badges JSON
 		if badgesJSONStr != "" && badgesJSONStr != "null" {
 			if err := json.Unmarshal([]byte(badgesJSONStr), &msg.Badges); err != nil {
@@ -116,15 +128,32 @@ func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*model
 			}
 		}
 
+		if emotesJSONStr != "" && emotesJSONStr != "[]" && emotesJSONStr != "null" {
+			if err := json.Unmarshal([]byte(emotesJSONStr), &msg.Emotes); err != nil {
+				msg.Emotes = nil
+			}
+		}
+
 		msg.SpecialEmote = specialEmote
 
 		messages = append(messages, &msg)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating chat messages: %w", err)
 	}
 
+	return messages, nil
+}
+
+// scanChatMessagesNewestFirst reads newest-first rows and returns them in
+// chronological order (oldest first)
+func scanChatMessagesNewestFirst(rows *sql.Rows) ([]*models.ChatMessage, error) {
+	messages, err := scanChatMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
 	// Reverse to get chronological order (oldest first)
 	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
 		messages[i], messages[j] = messages[j], messages[i]
@@ -133,12 +162,30 @@ func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*model
 	return messages, nil
 }
 
-func (r *ChatRepository) GetRecentByRaceID(raceID string, limit int) ([]*models.ChatMessage, error) {
+func (r *ChatRepository) GetByRaceID(raceID string, limit, offset int) ([]*models.ChatMessage, error) {
 	query := `
-		SELECT id, race_id, user_id, username, message, user_role, COALESCE(badges::text, '[]'), special_emote, created_at
+		SELECT ` + chatMessageColumns + `
 		FROM chat_messages
-		WHERE race_id = $1
+		WHERE race_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(query, raceID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessagesNewestFirst(rows)
+}
+
+func (r *ChatRepository) GetRecentByRaceID(raceID string, limit int) ([]*models.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE race_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC
 		LIMIT $2
 	`
 
@@ -148,68 +195,380 @@ func (r *ChatRepository) GetRecentByRaceID(raceID string, limit int) ([]*models.
 	}
 	defer rows.Close()
 
-	var messages []*models.ChatMessage
+	return scanChatMessagesNewestFirst(rows)
+}
+
+// GetBeforeByRaceID returns up to limit messages older than the cursor, in
+// chronological order. Paging by (created_at, id) keeps pages stable while new
+// messages arrive. A nil cursor starts from the newest message.
+func (r *ChatRepository) GetBeforeByRaceID(raceID string, cursor *models.ChatHistoryCursor, limit int) ([]*models.ChatMessage, error) {
+	if cursor == nil {
+		return r.GetRecentByRaceID(raceID, limit)
+	}
+
+	var rows *sql.Rows
+	var err error
+	if cursor.MessageID != "" {
+		// The cursor message may have been deleted since; it still marks the position
+		query := `
+			SELECT ` + chatMessageColumns + `
+			FROM chat_messages
+			WHERE race_id = $1 AND deleted_at IS NULL
+				AND (created_at, id) < (SELECT created_at, id FROM chat_messages WHERE id = $2 AND race_id = $1)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $3
+		`
+		rows, err = r.db.Query(query, raceID, cursor.MessageID, limit)
+	} else {
+		query := `
+			SELECT ` + chatMessageColumns + `
+			FROM chat_messages
+			WHERE race_id = $1 AND deleted_at IS NULL AND created_at < $2
+			ORDER BY created_at DESC, id DESC
+			LIMIT $3
+		`
+		rows, err = r.db.Query(query, raceID, cursor.Before, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessagesNewestFirst(rows)
+}
+
+// GetBetweenByRaceID returns up to limit messages created in [from, to), oldest first
+func (r *ChatRepository) GetBetweenByRaceID(raceID string, from, to time.Time, limit int) ([]*models.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE race_id = $1 AND deleted_at IS NULL AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC, id ASC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(query, raceID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages in range: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// GetByID returns a visible (not deleted) message in a race, or nil
+func (r *ChatRepository) GetByID(raceID, messageID string) (*models.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE race_id = $1 AND id = $2 AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(query, raceID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat message: %w", err)
+	}
+	defer rows.Close()
+
+	messages, err := scanChatMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return messages[0], nil
+}
+
+// GetReplies returns up to limit replies to a message, oldest first
+func (r *ChatRepository) GetReplies(raceID, parentID string, limit int) ([]*models.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE race_id = $1 AND reply_to_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, raceID, parentID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat replies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// GetReferences returns reply previews for the given message IDs. Deleted
+// messages are included with their text removed.
+func (r *ChatRepository) GetReferences(messageIDs []string) (map[string]*models.ChatMessageReference, error) {
+	refs := make(map[string]*models.ChatMessageReference, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return refs, nil
+	}
+
+	query := `
+		SELECT id, user_id, username, message, deleted_at IS NOT NULL
+		FROM chat_messages
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.db.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat message references: %w", err)
+	}
+	defer rows.Close()
+
 	for rows.Next() {
-		var msg models.ChatMessage
+		var ref models.ChatMessageReference
 		var userID sql.NullString
-		var userRole sql.NullString
-		var badgesJSONStr string // Scan JSONB as text
-		var specialEmote bool
-
-		err := rows.Scan(
-			&msg.ID,
-			&msg.RaceID,
-			&userID,
-			&msg.Username,
-			&msg.Message,
-			&userRole,
-			&badgesJSONStr,
-			&specialEmote,
-			&msg.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		if err := rows.Scan(&ref.ID, &userID, &ref.Username, &ref.Message, &ref.Deleted); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message reference: %w", err)
+		}
+		if userID.Valid {
+			ref.UserID = &userID.String
+		}
+		if ref.Deleted {
+			ref.Message = ""
 		}
+		refs[ref.ID] = &ref
+	}
 
-		// Initialize defaults
-		msg.Badges = []string{}
-		msg.Role = ""
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat message references: %w", err)
+	}
 
-		if userID.Valid {
-			msg.UserID = &userID.String
+	return refs, nil
+}
+
+// CreateMentions records the users mentioned in a message
+func (r *ChatRepository) CreateMentions(raceID, messageID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO chat_mentions (message_id, race_id, mentioned_user_id)
+		SELECT $1, $2, unnest($3::uuid[])
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, messageID, raceID, pq.Array(userIDs)); err != nil {
+		return fmt.Errorf("failed to create chat mentions: %w", err)
+	}
+
+	return nil
+}
+
+// GetMentions returns the mentioned user IDs for each of the given messages
+func (r *ChatRepository) GetMentions(messageIDs []string) (map[string][]string, error) {
+	mentions := make(map[string][]string)
+	if len(messageIDs) == 0 {
+		return mentions, nil
+	}
+
+	query := `
+		SELECT message_id, mentioned_user_id
+		FROM chat_mentions
+		WHERE message_id = ANY($1)
+	`
+
+	rows, err := r.db.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID, userID string
+		if err := rows.Scan(&messageID, &userID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat mention: %w", err)
 		}
+		mentions[messageID] = append(mentions[messageID], userID)
+	}
 
-		if userRole.Valid {
-			msg.Role = userRole.String
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat mentions: %w", err)
+	}
+
+	return mentions, nil
+}
+
+// SoftDelete hides a message from history while keeping it for auditing.
+// Returns false when the message does not exist in the race or is already deleted.
+func (r *ChatRepository) SoftDelete(raceID, messageID, deletedBy string) (bool, error) {
+	query := `
+		UPDATE chat_messages
+		SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $3
+		WHERE id = $1 AND race_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, messageID, raceID, deletedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+const chatTranscriptColumns = `id, race_id, created_at, user_id, username, user_role, COALESCE(badges::text, '[]'), message, is_announcement, reply_to_id, deleted_at, deleted_by`
+
+// scanChatTranscriptEntry reads one row selected with chatTranscriptColumns
+func scanChatTranscriptEntry(rows *sql.Rows) (*models.ChatTranscriptEntry, error) {
+	var entry models.ChatTranscriptEntry
+	var userID, userRole, replyToID, deletedBy sql.NullString
+	var deletedAt sql.NullTime
+	var badgesJSONStr string
+
+	err := rows.Scan(
+		&entry.ID,
+		&entry.RaceID,
+		&entry.CreatedAt,
+		&userID,
+		&entry.Username,
+		&userRole,
+		&badgesJSONStr,
+		&entry.Message,
+		&entry.Announcement,
+		&replyToID,
+		&deletedAt,
+		&deletedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chat transcript entry: %w", err)
+	}
+
+	if userID.Valid {
+		entry.UserID = &userID.String
+	}
+	entry.Role = userRole.String
+	if replyToID.Valid {
+		entry.ReplyToID = &replyToID.String
+	}
+	if deletedAt.Valid {
+		entry.DeletedAt = &deletedAt.Time
+	}
+	if deletedBy.Valid {
+		entry.DeletedBy = &deletedBy.String
+	}
+	if badgesJSONStr != "" && badgesJSONStr != "[]" && badgesJSONStr != "null" {
+		if err := json.Unmarshal([]byte(badgesJSONStr), &entry.Badges); err != nil {
+			entry.Badges = nil
 		}
+	}
 
-		// Parse badges JSON
-		if badgesJSONStr != "" && badgesJSONStr != "null" {
-			if err := json.Unmarshal([]byte(badgesJSONStr), &msg.Badges); err != nil {
-				// If unmarshaling fails, use empty array
-				msg.Badges = []string{}
-			}
+	return &entry, nil
+}
+
+// ForEachTranscriptEntry calls fn for every message of a race, deleted ones
+// included, oldest first. Rows are read as fn consumes them, so a transcript
+// of any length is never held in memory; an error from fn stops the scan and
+// is returned.
+func (r *ChatRepository) ForEachTranscriptEntry(raceID string, fn func(*models.ChatTranscriptEntry) error) error {
+	query := `
+		SELECT ` + chatTranscriptColumns + `
+		FROM chat_messages
+		WHERE race_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(query, raceID)
+	if err != nil {
+		return fmt.Errorf("failed to query chat transcript: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanChatTranscriptEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
 		}
+	}
 
-		msg.SpecialEmote = specialEmote
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chat transcript: %w", err)
+	}
+	return nil
+}
 
-		messages = append(messages, &msg)
+// GetPin returns the message pinned in a race, or nil
+func (r *ChatRepository) GetPin(raceID string) (*models.ChatPin, error) {
+	pin := models.ChatPin{RaceID: raceID}
+	var messageID string
+	var pinnedBy sql.NullString
+	err := r.db.QueryRow(
+		`SELECT message_id, pinned_by, pinned_at FROM chat_pins WHERE race_id = $1`,
+		raceID,
+	).Scan(&messageID, &pinnedBy, &pin.PinnedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat pin: %w", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating chat messages: %w", err)
+	// Deleted messages are unpinned, but don't show one if that failed
+	pin.Message, err = r.GetByID(raceID, messageID)
+	if err != nil || pin.Message == nil {
+		return nil, err
 	}
+	if pinnedBy.Valid {
+		pin.PinnedBy = &pinnedBy.String
+	}
+	return &pin, nil
+}
 
-	// Reverse to get chronological order (oldest first)
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+// SetPin pins a message in its race, replacing any pinned message
+func (r *ChatRepository) SetPin(message *models.ChatMessage, pinnedBy string) (*models.ChatPin, error) {
+	query := `
+		INSERT INTO chat_pins (race_id, message_id, pinned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (race_id) DO UPDATE
+		SET message_id = EXCLUDED.message_id,
+			pinned_by = EXCLUDED.pinned_by,
+			pinned_at = CURRENT_TIMESTAMP
+		RETURNING pinned_at
+	`
+
+	pin := &models.ChatPin{
+		RaceID:   message.RaceID,
+		Message:  message,
+		PinnedBy: &pinnedBy,
+	}
+	if err := r.db.QueryRow(query, message.RaceID, message.ID, pinnedBy).Scan(&pin.PinnedAt); err != nil {
+		return nil, fmt.Errorf("failed to pin chat message: %w", err)
 	}
+	return pin, nil
+}
 
-	return messages, nil
+// DeletePin unpins a race's message. When messageID is set, the pin is only
+// removed if it is that message. Returns false when nothing was unpinned.
+func (r *ChatRepository) DeletePin(raceID string, messageID *string) (bool, error) {
+	result, err := r.db.Exec(
+		`DELETE FROM chat_pins WHERE race_id = $1 AND ($2::uuid IS NULL OR message_id = $2)`,
+		raceID, messageID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to unpin chat message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
 }
 
 func (r *ChatRepository) CountByRaceID(raceID string) (int, error) {
-	query := `SELECT COUNT(*) FROM chat_messages WHERE race_id = $1`
+	query := `SELECT COUNT(*) FROM chat_messages WHERE race_id = $1 AND deleted_at IS NULL`
 
 	var count int
 	err := r.db.QueryRow(query, raceID).Scan(&count)
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+const (
+	// Rows read or deleted per query by the retention job, so a large purge
+	// never holds long locks on chat_messages
+	chatRetentionBatchSize = 5000
+
+	// Advisory lock key held while a replica runs the retention job
+	chatRetentionLockKey = 7261455018
+)
+
+// ChatRetentionRepository archives and deletes old chat messages and records
+// each purge
+type ChatRetentionRepository struct {
+	db *sql.DB
+}
+
+func NewChatRetentionRepository(db *sql.DB) *ChatRetentionRepository {
+	return &ChatRetentionRepository{db: db}
+}
+
+// TryLock takes a cluster-wide lock so that only one replica purges at a
+// time. ok is false when another replica holds it; otherwise unlock must be
+// called when the purge is over.
+func (r *ChatRetentionRepository) TryLock(ctx context.Context) (unlock func(), ok bool, err error) {
+	// Advisory locks belong to a session, so hold on to one connection
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for chat retention lock: %w", err)
+	}
+
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, chatRetentionLockKey).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to take chat retention lock: %w", err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		// Closing the session would release the lock too, but the
+		// connection goes back to the pool
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, chatRetentionLockKey)
+		conn.Close()
+	}, true, nil
+}
+
+// ForEachMessageBefore calls fn for every message created before cutoff,
+// deleted ones included, oldest first. Messages are read in batches.
+func (r *ChatRetentionRepository) ForEachMessageBefore(cutoff time.Time, fn func(*models.ChatTranscriptEntry) error) error {
+	query := `
+		SELECT ` + chatTranscriptColumns + `
+		FROM chat_messages
+		WHERE created_at < $1
+		  AND (created_at, id) > ($2, $3)
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+
+	// Keyset pagination from before the oldest possible message
+	lastCreatedAt := time.Time{}
+	lastID := "00000000-0000-0000-0000-000000000000"
+	for {
+		rows, err := r.db.Query(query, cutoff, lastCreatedAt, lastID, chatRetentionBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to query chat messages for retention: %w", err)
+		}
+
+		count := 0
+		for rows.Next() {
+			entry, err := scanChatTranscriptEntry(rows)
+			if err == nil {
+				err = fn(entry)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			lastCreatedAt, lastID = entry.CreatedAt, entry.ID
+			count++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error iterating chat messages for retention: %w", err)
+		}
+
+		if count < chatRetentionBatchSize {
+			return nil
+		}
+	}
+}
+
+// DeleteMessagesBefore deletes every message created before cutoff, in
+// batches, and returns how many were deleted. Replies to them lose their
+// reply reference; their mentions are deleted with them.
+func (r *ChatRetentionRepository) DeleteMessagesBefore(cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM chat_messages
+		WHERE id IN (
+			SELECT id FROM chat_messages
+			WHERE created_at < $1
+			LIMIT $2
+		)
+	`
+
+	var total int64
+	for {
+		result, err := r.db.Exec(query, cutoff, chatRetentionBatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to delete chat messages: %w", err)
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		total += deleted
+
+		if deleted < chatRetentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// CreatePurge records a retention run
+func (r *ChatRetentionRepository) CreatePurge(purge *models.ChatRetentionPurge) error {
+	raceCounts, err := json.Marshal(purge.RaceCounts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purged race counts: %w", err)
+	}
+
+	query := `
+		INSERT INTO chat_retention_purges (cutoff, archive_path, archive_bytes, message_count, deleted_count, race_counts, oldest_message_at, newest_message_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	err = r.db.QueryRow(
+		query,
+		purge.Cutoff,
+		purge.ArchivePath,
+		purge.ArchiveBytes,
+		purge.MessageCount,
+		purge.DeletedCount,
+		raceCounts,
+		purge.OldestMessageAt,
+		purge.NewestMessageAt,
+	).Scan(&purge.ID, &purge.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record chat retention purge: %w", err)
+	}
+	return nil
+}
+
+// ListPurges returns the most recent retention runs, newest first
+func (r *ChatRetentionRepository) ListPurges(limit int) ([]*models.ChatRetentionPurge, error) {
+	query := `
+		SELECT id, cutoff, archive_path, archive_bytes, message_count, deleted_count, race_counts::text, oldest_message_at, newest_message_at, created_at
+		FROM chat_retention_purges
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat retention purges: %w", err)
+	}
+	defer rows.Close()
+
+	purges := []*models.ChatRetentionPurge{}
+	for rows.Next() {
+		var purge models.ChatRetentionPurge
+		var raceCounts string
+		var oldest, newest sql.NullTime
+		if err := rows.Scan(
+			&purge.ID,
+			&purge.Cutoff,
+			&purge.ArchivePath,
+			&purge.ArchiveBytes,
+			&purge.MessageCount,
+			&purge.DeletedCount,
+			&raceCounts,
+			&oldest,
+			&newest,
+			&purge.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chat retention purge: %w", err)
+		}
+		if err := json.Unmarshal([]byte(raceCounts), &purge.RaceCounts); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal purged race counts: %w", err)
+		}
+		if oldest.Valid {
+			purge.OldestMessageAt = &oldest.Time
+		}
+		if newest.Valid {
+			purge.NewestMessageAt = &newest.Time
+		}
+		purges = append(purges, &purge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat retention purges: %w", err)
+	}
+	return purges, nil
+}
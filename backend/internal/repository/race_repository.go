@@ -21,7 +21,7 @@ func (r *RaceRepository) GetAll() ([]models.Race, error) {
 		       r.is_free, r.price_cents, r.requires_login, r.stage_name, r.stage_type, r.elevation_meters, 
 		       r.estimated_finish_time, r.stage_length_km, r.created_at, r.updated_at, s.status AS stream_status
 		FROM races r
-		LEFT JOIN streams s ON r.id = s.race_id
+		LEFT JOIN streams s ON r.id = s.race_id AND s.is_primary
 		ORDER BY r.start_date DESC NULLS LAST, r.created_at DESC
 	`
 
@@ -249,6 +249,21 @@ func (r *RaceRepository) GetLiveRaces() ([]models.Race, error) {
 	return r.queryRaces(query)
 }
 
+// GetRacesWithReplays returns races with a ready replay, latest first
+func (r *RaceRepository) GetRacesWithReplays(limit int) ([]models.Race, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.start_date, r.end_date, r.location, r.category, 
+		       r.is_free, r.price_cents, r.requires_login, r.stage_name, r.stage_type, r.elevation_meters, 
+		       r.estimated_finish_time, r.stage_length_km, r.created_at, r.updated_at
+		FROM races r
+		WHERE EXISTS (SELECT 1 FROM vod_assets v WHERE v.race_id = r.id AND v.status = 'ready')
+		ORDER BY r.start_date DESC NULLS LAST
+		LIMIT $1
+	`
+
+	return r.queryRaces(query, limit)
+}
+
 // GetSimilarRaces returns races similar to the given race (same category, similar elevation/distance)
 func (r *RaceRepository) GetSimilarRaces(raceID string, limit int) ([]models.Race, error) {
 	// First get the reference race
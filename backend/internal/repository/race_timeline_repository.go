@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+type RaceTimelineRepository struct {
+	db *sql.DB
+}
+
+func NewRaceTimelineRepository(db *sql.DB) *RaceTimelineRepository {
+	return &RaceTimelineRepository{db: db}
+}
+
+const raceMarkerColumns = `id, race_id, stream_id, offset_seconds, marker_type, title, km_to_go, created_at, updated_at`
+
+func scanRaceMarker(scanner interface{ Scan(...interface{}) error }) (*models.RaceMarker, error) {
+	var marker models.RaceMarker
+	err := scanner.Scan(
+		&marker.ID,
+		&marker.RaceID,
+		&marker.StreamID,
+		&marker.Offset,
+		&marker.MarkerType,
+		&marker.Title,
+		&marker.KmToGo,
+		&marker.CreatedAt,
+		&marker.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+const raceClipColumns = `id, race_id, stream_id, marker_id, created_by, title, start_offset_seconds, end_offset_seconds, created_at`
+
+func scanRaceClip(scanner interface{ Scan(...interface{}) error }) (*models.RaceClip, error) {
+	var clip models.RaceClip
+	err := scanner.Scan(
+		&clip.ID,
+		&clip.RaceID,
+		&clip.StreamID,
+		&clip.MarkerID,
+		&clip.CreatedBy,
+		&clip.Title,
+		&clip.Start,
+		&clip.End,
+		&clip.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &clip, nil
+}
+
+// ListMarkers returns a race's markers in timeline order, of one feed when
+// streamID is set
+func (r *RaceTimelineRepository) ListMarkers(raceID, streamID string) ([]models.RaceMarker, error) {
+	query := `
+		SELECT ` + raceMarkerColumns + `
+		FROM race_markers
+		WHERE race_id = $1 AND ($2 = '' OR stream_id::text = $2)
+		ORDER BY offset_seconds, created_at
+	`
+
+	rows, err := r.db.Query(query, raceID, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list race markers: %w", err)
+	}
+	defer rows.Close()
+
+	markers := []models.RaceMarker{}
+	for rows.Next() {
+		marker, err := scanRaceMarker(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan race marker: %w", err)
+		}
+		markers = append(markers, *marker)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating race markers: %w", err)
+	}
+	return markers, nil
+}
+
+// GetMarker returns one of a race's markers, or nil
+func (r *RaceTimelineRepository) GetMarker(raceID, markerID string) (*models.RaceMarker, error) {
+	query := `SELECT ` + raceMarkerColumns + ` FROM race_markers WHERE race_id = $1 AND id = $2`
+
+	marker, err := scanRaceMarker(r.db.QueryRow(query, raceID, markerID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get race marker: %w", err)
+	}
+	return marker, nil
+}
+
+// CreateMarker adds a marker, setting its ID and timestamps
+func (r *RaceTimelineRepository) CreateMarker(marker *models.RaceMarker) error {
+	query := `
+		INSERT INTO race_markers (race_id, stream_id, offset_seconds, marker_type, title, km_to_go)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(query, marker.RaceID, marker.StreamID, marker.Offset, marker.MarkerType, marker.Title, marker.KmToGo).
+		Scan(&marker.ID, &marker.CreatedAt, &marker.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create race marker: %w", err)
+	}
+	return nil
+}
+
+// UpdateMarker saves a marker's feed, offset, type, title and distance. It
+// returns false when the race has no such marker.
+func (r *RaceTimelineRepository) UpdateMarker(marker *models.RaceMarker) (bool, error) {
+	query := `
+		UPDATE race_markers
+		SET stream_id = $3, offset_seconds = $4, marker_type = $5, title = $6, km_to_go = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE race_id = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(query, marker.RaceID, marker.ID, marker.StreamID, marker.Offset, marker.MarkerType, marker.Title, marker.KmToGo).
+		Scan(&marker.CreatedAt, &marker.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update race marker: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteMarker removes one of a race's markers. Clips made from it are kept.
+// It returns false when the race has no such marker.
+func (r *RaceTimelineRepository) DeleteMarker(raceID, markerID string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM race_markers WHERE race_id = $1 AND id = $2`, raceID, markerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete race marker: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// CreateClip adds a clip, setting its ID and creation time
+func (r *RaceTimelineRepository) CreateClip(clip *models.RaceClip) error {
+	query := `
+		INSERT INTO race_clips (race_id, stream_id, marker_id, created_by, title, start_offset_seconds, end_offset_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(query, clip.RaceID, clip.StreamID, clip.MarkerID, clip.CreatedBy, clip.Title, clip.Start, clip.End).
+		Scan(&clip.ID, &clip.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create race clip: %w", err)
+	}
+	return nil
+}
+
+// GetClip returns a clip, or nil
+func (r *RaceTimelineRepository) GetClip(id string) (*models.RaceClip, error) {
+	query := `SELECT ` + raceClipColumns + ` FROM race_clips WHERE id = $1`
+
+	clip, err := scanRaceClip(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get race clip: %w", err)
+	}
+	return clip, nil
+}
+
+// ActivityBuckets counts the race's chat messages and the feed's playback
+// starts in each bucket from from until to, oldest first. Every bucket is
+// returned, including quiet ones.
+func (r *RaceTimelineRepository) ActivityBuckets(raceID, streamID string, from, to time.Time, bucket time.Duration) ([]models.ActivityBucket, error) {
+	if !to.After(from) || bucket <= 0 {
+		return []models.ActivityBucket{}, nil
+	}
+
+	buckets := make([]models.ActivityBucket, int((to.Sub(from)+bucket-1)/bucket))
+	for i := range buckets {
+		buckets[i].Start = from.Add(time.Duration(i) * bucket)
+	}
+
+	chatQuery := `
+		SELECT FLOOR(EXTRACT(EPOCH FROM created_at - $2) / $4)::int, COUNT(*)
+		FROM chat_messages
+		WHERE race_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+		GROUP BY 1
+	`
+	err := r.countBuckets(chatQuery, buckets, func(b *models.ActivityBucket, n int) { b.ChatMessages = n },
+		raceID, from, to, bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chat activity: %w", err)
+	}
+
+	playbackQuery := `
+		SELECT FLOOR(EXTRACT(EPOCH FROM created_at - $2) / $4)::int, COUNT(*)
+		FROM playback_events
+		WHERE stream_id = $1 AND created_at >= $2 AND created_at < $3 AND event_type = 'play'
+		GROUP BY 1
+	`
+	err = r.countBuckets(playbackQuery, buckets, func(b *models.ActivityBucket, n int) { b.PlaybackStarts = n },
+		streamID, from, to, bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count playback activity: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// countBuckets runs a query returning bucket indexes and counts and sets each
+// count on its bucket
+func (r *RaceTimelineRepository) countBuckets(query string, buckets []models.ActivityBucket, set func(*models.ActivityBucket, int), args ...interface{}) error {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index, count int
+		if err := rows.Scan(&index, &count); err != nil {
+			return err
+		}
+		if index >= 0 && index < len(buckets) {
+			set(&buckets[index], count)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// streamKeyActive matches keys of stream_keys k that still authorize publishes
+const streamKeyActive = `k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > CURRENT_TIMESTAMP)`
+
+const streamKeyColumns = `k.id, k.stream_id, k.key_hint,
+	CASE
+		WHEN k.revoked_at IS NOT NULL THEN 'revoked'
+		WHEN k.expires_at IS NULL THEN 'active'
+		WHEN k.expires_at > CURRENT_TIMESTAMP THEN 'expiring'
+		ELSE 'expired'
+	END,
+	k.created_by, k.created_at, k.expires_at, k.revoked_at, k.revoked_by`
+
+func scanStreamKey(scanner interface{ Scan(...interface{}) error }) (*models.StreamKey, error) {
+	var k models.StreamKey
+	var createdBy, revokedBy sql.NullString
+	var expiresAt, revokedAt sql.NullTime
+
+	if err := scanner.Scan(
+		&k.ID,
+		&k.StreamID,
+		&k.Hint,
+		&k.Status,
+		&createdBy,
+		&k.CreatedAt,
+		&expiresAt,
+		&revokedAt,
+		&revokedBy,
+	); err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		k.CreatedBy = &createdBy.String
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	if revokedBy.Valid {
+		k.RevokedBy = &revokedBy.String
+	}
+
+	return &k, nil
+}
+
+// StreamKeyRepository stores the hashed RTMP publish keys of streams and the
+// audit trail of their changes
+type StreamKeyRepository struct {
+	db *sql.DB
+}
+
+func NewStreamKeyRepository(db *sql.DB) *StreamKeyRepository {
+	return &StreamKeyRepository{db: db}
+}
+
+// Issue stores the hash of a new key for a stream. Keys of the stream that
+// are still active expire after overlap, so an encoder using one can be moved
+// to the new key; the event is recorded as a rotation in that case and as a
+// creation otherwise.
+func (r *StreamKeyRepository) Issue(streamID, key, actor string, overlap time.Duration) (*models.StreamKey, *models.StreamKeyEvent, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Concurrent rotations of the same stream are applied one at a time
+	if _, err := tx.Exec(`SELECT id FROM streams WHERE id = $1 FOR UPDATE`, streamID); err != nil {
+		return nil, nil, fmt.Errorf("failed to lock stream: %w", err)
+	}
+
+	overlapUntil := time.Now().Add(overlap)
+	result, err := tx.Exec(`
+		UPDATE stream_keys k
+		SET expires_at = LEAST(COALESCE(k.expires_at, $2), $2)
+		WHERE k.stream_id = $1 AND `+streamKeyActive,
+		streamID, overlapUntil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to expire previous stream keys: %w", err)
+	}
+	replaced, err := result.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	var keyID string
+	err = tx.QueryRow(`
+		INSERT INTO stream_keys (stream_id, key_hash, key_hint, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, streamID, hashStreamKey(key), streamKeyHint(key), nullIfEmpty(actor)).Scan(&keyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create stream key: %w", err)
+	}
+
+	event := &models.StreamKeyEvent{StreamID: streamID, KeyID: &keyID, Action: "created"}
+	if replaced > 0 {
+		event.Action = "rotated"
+		event.OverlapUntil = &overlapUntil
+	}
+	if err := insertStreamKeyEvent(tx, event, actor); err != nil {
+		return nil, nil, err
+	}
+
+	streamKey, err := scanStreamKey(tx.QueryRow(`SELECT `+streamKeyColumns+` FROM stream_keys k WHERE k.id = $1`, keyID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stream key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit stream key: %w", err)
+	}
+	return streamKey, event, nil
+}
+
+// Revoke revokes a key of a stream straight away. It returns nil when the
+// stream has no such key or it is already revoked.
+func (r *StreamKeyRepository) Revoke(streamID, keyID, actor string) (*models.StreamKey, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	streamKey, err := scanStreamKey(tx.QueryRow(`
+		UPDATE stream_keys k
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = $3
+		WHERE k.stream_id = $1 AND k.id = $2 AND k.revoked_at IS NULL
+		RETURNING `+streamKeyColumns,
+		streamID, keyID, nullIfEmpty(actor)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke stream key: %w", err)
+	}
+
+	event := &models.StreamKeyEvent{StreamID: streamID, KeyID: &streamKey.ID, Action: "revoked"}
+	if err := insertStreamKeyEvent(tx, event, actor); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stream key revocation: %w", err)
+	}
+	return streamKey, nil
+}
+
+// ListByStream returns the keys of a stream, newest first
+func (r *StreamKeyRepository) ListByStream(streamID string) ([]*models.StreamKey, error) {
+	query := `SELECT ` + streamKeyColumns + ` FROM stream_keys k WHERE k.stream_id = $1 ORDER BY k.created_at DESC`
+
+	rows, err := r.db.Query(query, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.StreamKey{}
+	for rows.Next() {
+		k, err := scanStreamKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stream keys: %w", err)
+	}
+	return keys, nil
+}
+
+// ListEvents returns the most recent key changes of a stream, newest first
+func (r *StreamKeyRepository) ListEvents(streamID string, limit int) ([]*models.StreamKeyEvent, error) {
+	query := `
+		SELECT id, stream_id, key_id, action, actor, overlap_until, created_at
+		FROM stream_key_events
+		WHERE stream_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, streamID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream key events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.StreamKeyEvent{}
+	for rows.Next() {
+		var e models.StreamKeyEvent
+		var keyID, actor sql.NullString
+		var overlapUntil sql.NullTime
+		if err := rows.Scan(&e.ID, &e.StreamID, &keyID, &e.Action, &actor, &overlapUntil, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stream key event: %w", err)
+		}
+		if keyID.Valid {
+			e.KeyID = &keyID.String
+		}
+		if actor.Valid {
+			e.Actor = &actor.String
+		}
+		if overlapUntil.Valid {
+			e.OverlapUntil = &overlapUntil.Time
+		}
+		events = append(events, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stream key events: %w", err)
+	}
+	return events, nil
+}
+
+func insertStreamKeyEvent(tx *sql.Tx, event *models.StreamKeyEvent, actor string) error {
+	if actor != "" {
+		event.Actor = &actor
+	}
+	err := tx.QueryRow(`
+		INSERT INTO stream_key_events (stream_id, key_id, action, actor, overlap_until)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, event.StreamID, event.KeyID, event.Action, event.Actor, event.OverlapUntil).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stream key event: %w", err)
+	}
+	return nil
+}
+
+// streamKeyHint returns the last characters of a key, kept so admins can
+// tell keys apart
+func streamKeyHint(key string) string {
+	if len(key) <= 4 {
+		return ""
+	}
+	return key[len(key)-4:]
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
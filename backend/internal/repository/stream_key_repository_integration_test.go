@@ -0,0 +1,100 @@
+// +build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamKeyRepository_Integration tests issuing and rotating stream keys with real database
+func TestStreamKeyRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db := testutil.GetTestDB(t)
+	defer db.Close()
+
+	repo := NewStreamKeyRepository(db)
+	streamRepo := NewStreamRepository(db)
+	raceID := testutil.CreateTestRace(t, db, "Stream Key Test Race")
+	defer testutil.CleanupRaces(t, db, []string{raceID})
+
+	stream := &models.Stream{RaceID: raceID}
+	require.NoError(t, streamRepo.CreateFeed(stream))
+
+	const oldKey, newKey = "live_0123456789abcdef", "live_fedcba9876543210"
+
+	t.Run("Issue the first key", func(t *testing.T) {
+		key, event, err := repo.Issue(stream.ID, oldKey, "", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "cdef", key.Hint)
+		assert.Equal(t, "active", key.Status)
+		assert.Nil(t, key.ExpiresAt)
+		assert.Equal(t, "created", event.Action)
+		assert.Nil(t, event.OverlapUntil)
+
+		found, err := streamRepo.GetByStreamKey(oldKey)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, stream.ID, found.ID)
+	})
+
+	t.Run("Rotate with an overlap", func(t *testing.T) {
+		key, event, err := repo.Issue(stream.ID, newKey, "", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "active", key.Status)
+		assert.Equal(t, "rotated", event.Action)
+		require.NotNil(t, event.OverlapUntil)
+
+		// Both keys publish until the overlap ends
+		for _, k := range []string{oldKey, newKey} {
+			found, err := streamRepo.GetByStreamKey(k)
+			require.NoError(t, err)
+			assert.NotNil(t, found, k)
+		}
+
+		keys, err := repo.ListByStream(stream.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "active", keys[0].Status)
+		assert.Equal(t, "expiring", keys[1].Status)
+		require.NotNil(t, keys[1].ExpiresAt)
+		assert.WithinDuration(t, *event.OverlapUntil, *keys[1].ExpiresAt, time.Millisecond)
+	})
+
+	t.Run("Old key refused after the overlap", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			found, err := streamRepo.GetByStreamKey(oldKey)
+			return err == nil && found == nil
+		}, 5*time.Second, 100*time.Millisecond)
+
+		found, err := streamRepo.GetByStreamKey(newKey)
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+
+		// Still known, so publishes with it are dropped rather than ignored
+		found, err = streamRepo.GetByAnyStreamKey(oldKey)
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+
+		keys, err := repo.ListByStream(stream.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "expired", keys[1].Status)
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		events, err := repo.ListEvents(stream.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, "rotated", events[0].Action)
+		assert.Equal(t, "created", events[1].Action)
+	})
+}
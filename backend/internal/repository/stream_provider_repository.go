@@ -16,46 +16,87 @@ func NewStreamProviderRepository(db *sql.DB) *StreamProviderRepository {
 	return &StreamProviderRepository{db: db}
 }
 
-// GetPrimaryByStreamID returns the latest provider row for a stream.
-func (r *StreamProviderRepository) GetPrimaryByStreamID(streamID string) (*models.StreamProvider, error) {
-	query := `
-		SELECT id, stream_id, provider, provider_video_id, provider_url, metadata, created_at, updated_at
-		FROM stream_providers
-		WHERE stream_id = $1
-		ORDER BY updated_at DESC, created_at DESC
-		LIMIT 1
-	`
+const streamProviderColumns = `sp.id, sp.stream_id, sp.provider, sp.provider_video_id, sp.provider_url, sp.metadata,
+	sp.priority, sp.stream_type, sp.health_status, sp.consecutive_failures, sp.last_checked_at, sp.last_error,
+	sp.created_at, sp.updated_at`
 
+func scanStreamProvider(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.StreamProvider, error) {
 	var sp models.StreamProvider
 	var metadataRaw []byte
-	err := r.db.QueryRow(query, streamID).Scan(
+	dest := []interface{}{
 		&sp.ID,
 		&sp.StreamID,
 		&sp.Provider,
 		&sp.ProviderVideoID,
 		&sp.ProviderURL,
 		&metadataRaw,
+		&sp.Priority,
+		&sp.StreamType,
+		&sp.HealthStatus,
+		&sp.ConsecutiveFailures,
+		&sp.LastCheckedAt,
+		&sp.LastError,
 		&sp.CreatedAt,
 		&sp.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to load stream provider: %w", err)
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
 
 	if len(metadataRaw) > 0 {
 		if err := json.Unmarshal(metadataRaw, &sp.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal provider metadata: %w", err)
+			return nil, fmt.Errorf("unmarshal stream provider metadata: %w", err)
 		}
 	}
-
 	return &sp, nil
 }
 
+func (r *StreamProviderRepository) list(query string, args ...interface{}) ([]models.StreamProvider, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list stream providers: %w", err)
+	}
+	defer rows.Close()
+
+	providers := []models.StreamProvider{}
+	for rows.Next() {
+		sp, err := scanStreamProvider(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stream provider: %w", err)
+		}
+		providers = append(providers, *sp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate stream providers: %w", err)
+	}
+
+	return providers, nil
+}
+
+// GetPrimaryByStreamID returns the latest provider row for a stream.
+func (r *StreamProviderRepository) GetPrimaryByStreamID(streamID string) (*models.StreamProvider, error) {
+	query := `
+		SELECT ` + streamProviderColumns + `
+		FROM stream_providers sp
+		WHERE sp.stream_id = $1
+		ORDER BY sp.updated_at DESC, sp.created_at DESC
+		LIMIT 1
+	`
+
+	sp, err := scanStreamProvider(r.db.QueryRow(query, streamID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stream provider: %w", err)
+	}
+
+	return sp, nil
+}
+
 // Upsert creates or updates a provider row keyed by stream_id + provider.
+// Playback source settings and health are left alone.
 func (r *StreamProviderRepository) Upsert(sp *models.StreamProvider) error {
 	var metadataRaw []byte
 	var err error
@@ -94,45 +135,106 @@ func (r *StreamProviderRepository) Upsert(sp *models.StreamProvider) error {
 
 // ListByProvider returns providers filtered by provider name.
 func (r *StreamProviderRepository) ListByProvider(provider string) ([]models.StreamProvider, error) {
+	return r.list(`SELECT `+streamProviderColumns+` FROM stream_providers sp WHERE sp.provider = $1`, provider)
+}
+
+// ListSources returns a stream's playback sources in priority order
+func (r *StreamProviderRepository) ListSources(streamID string) ([]models.StreamProvider, error) {
 	query := `
-		SELECT id, stream_id, provider, provider_video_id, provider_url, metadata, created_at, updated_at
-		FROM stream_providers
-		WHERE provider = $1
+		SELECT ` + streamProviderColumns + `
+		FROM stream_providers sp
+		WHERE sp.stream_id = $1 AND sp.priority IS NOT NULL
+		ORDER BY sp.priority, sp.created_at
 	`
+	return r.list(query, streamID)
+}
 
-	rows, err := r.db.Query(query, provider)
+// ReplaceSources makes sources the stream's playback sources, in that order.
+// Providers left out stop being sources but are kept for their analytics. A
+// source whose URL changes starts over as unknown to the health prober.
+func (r *StreamProviderRepository) ReplaceSources(streamID string, sources []*models.StreamProvider) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("list stream providers: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE stream_providers SET priority = NULL, updated_at = CURRENT_TIMESTAMP WHERE stream_id = $1 AND priority IS NOT NULL`, streamID); err != nil {
+		return fmt.Errorf("failed to clear stream sources: %w", err)
+	}
+
+	query := `
+		INSERT INTO stream_providers AS sp (stream_id, provider, provider_video_id, provider_url, stream_type, priority)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (stream_id, provider) DO UPDATE
+		SET provider_video_id = CASE WHEN EXCLUDED.provider_video_id <> '' THEN EXCLUDED.provider_video_id ELSE sp.provider_video_id END,
+			provider_url = EXCLUDED.provider_url,
+			stream_type = EXCLUDED.stream_type,
+			priority = EXCLUDED.priority,
+			health_status = CASE WHEN sp.provider_url IS DISTINCT FROM EXCLUDED.provider_url THEN 'unknown' ELSE sp.health_status END,
+			consecutive_failures = CASE WHEN sp.provider_url IS DISTINCT FROM EXCLUDED.provider_url THEN 0 ELSE sp.consecutive_failures END,
+			last_error = CASE WHEN sp.provider_url IS DISTINCT FROM EXCLUDED.provider_url THEN NULL ELSE sp.last_error END,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + streamProviderColumns
+	for i, source := range sources {
+		saved, err := scanStreamProvider(tx.QueryRow(query,
+			streamID, source.Provider, source.ProviderVideoID, source.ProviderURL, source.StreamType, i))
+		if err != nil {
+			return fmt.Errorf("failed to save stream source: %w", err)
+		}
+		*source = *saved
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stream sources: %w", err)
+	}
+	return nil
+}
+
+// ListProbeTargets returns the HLS playback sources of streams that are in
+// pre-show or live, the ones the health prober checks
+func (r *StreamProviderRepository) ListProbeTargets() ([]models.StreamProviderProbe, error) {
+	query := `
+		SELECT ` + streamProviderColumns + `, s.race_id
+		FROM stream_providers sp
+		JOIN streams s ON s.id = sp.stream_id
+		WHERE sp.priority IS NOT NULL
+			AND sp.stream_type = 'hls'
+			AND sp.provider_url IS NOT NULL
+			AND s.status IN ('pre_show', 'live')
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("list stream probe targets: %w", err)
 	}
 	defer rows.Close()
 
-	var providers []models.StreamProvider
+	var targets []models.StreamProviderProbe
 	for rows.Next() {
-		var sp models.StreamProvider
-		var metadataRaw []byte
-		if err := rows.Scan(
-			&sp.ID,
-			&sp.StreamID,
-			&sp.Provider,
-			&sp.ProviderVideoID,
-			&sp.ProviderURL,
-			&metadataRaw,
-			&sp.CreatedAt,
-			&sp.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("scan stream provider: %w", err)
-		}
-		if len(metadataRaw) > 0 {
-			if err := json.Unmarshal(metadataRaw, &sp.Metadata); err != nil {
-				return nil, fmt.Errorf("unmarshal stream provider metadata: %w", err)
-			}
+		var raceID string
+		sp, err := scanStreamProvider(rows, &raceID)
+		if err != nil {
+			return nil, fmt.Errorf("scan stream probe target: %w", err)
 		}
-		providers = append(providers, sp)
+		targets = append(targets, models.StreamProviderProbe{StreamProvider: *sp, RaceID: raceID})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate stream providers: %w", err)
+		return nil, fmt.Errorf("iterate stream probe targets: %w", err)
 	}
 
-	return providers, nil
+	return targets, nil
+}
+
+// UpdateHealth records the outcome of a health check
+func (r *StreamProviderRepository) UpdateHealth(id, status string, failures int, lastError *string) error {
+	query := `
+		UPDATE stream_providers
+		SET health_status = $2, consecutive_failures = $3, last_error = $4, last_checked_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(query, id, status, failures, lastError); err != nil {
+		return fmt.Errorf("failed to update stream provider health: %w", err)
+	}
+	return nil
 }
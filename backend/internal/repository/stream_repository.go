@@ -1,42 +1,86 @@
 package repository
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cyclingstream/backend/internal/models"
 )
 
+var (
+	// ErrStreamNotFound is returned when a race has no stream, or no feed
+	// with the given ID
+	ErrStreamNotFound = errors.New("stream not found")
+	// ErrStreamNameTaken is returned when a race already has a feed with the
+	// name
+	ErrStreamNameTaken = errors.New("stream name already used for this race")
+	// ErrPrimaryStreamRequired is returned when the primary feed would be
+	// demoted without another one taking its place
+	ErrPrimaryStreamRequired = errors.New("a race's primary stream can only be replaced by making another stream primary")
+	// ErrInvalidStreamTransition is returned when a stream can't move from
+	// its status to the requested one
+	ErrInvalidStreamTransition = errors.New("invalid stream status transition")
+)
+
 type StreamRepository struct {
 	db *sql.DB
 }
 
-func NewStreamRepository(db *sql.DB) *StreamRepository {
-	return &StreamRepository{db: db}
-}
+// streamSessionUpdate records session times as the status ($2) changes:
+// started_at is set the first time the stream goes live and kept afterwards so
+// replays stay anchored to the original broadcast; ended_at is set when a live
+// stream goes to any other status and cleared if it goes live again.
+const streamSessionUpdate = `started_at = CASE WHEN $2 = 'live' THEN COALESCE(streams.started_at, CURRENT_TIMESTAMP) ELSE streams.started_at END,
+			ended_at = CASE WHEN $2 = 'live' THEN NULL WHEN streams.status = 'live' THEN CURRENT_TIMESTAMP ELSE streams.ended_at END`
 
-func (r *StreamRepository) GetByID(streamID string) (*models.Stream, error) {
-	query := `
-		SELECT id, race_id, status, stream_type, source_id, origin_url, cdn_url, stream_key, created_at, updated_at
-		FROM streams
-		WHERE id = $1
-		LIMIT 1
-	`
+const streamColumns = `s.id, s.race_id, s.name, s.is_primary, s.access, s.status, s.stream_type, s.source_id, s.origin_url, s.cdn_url, s.dvr_window_seconds, s.started_at, s.ended_at,
+	s.previous_status, s.status_changed_at, s.created_at, s.updated_at`
 
+func scanStream(scanner interface{ Scan(...interface{}) error }) (*models.Stream, error) {
 	var stream models.Stream
-	err := r.db.QueryRow(query, streamID).Scan(
+	if err := scanner.Scan(
 		&stream.ID,
 		&stream.RaceID,
+		&stream.Name,
+		&stream.IsPrimary,
+		&stream.Access,
 		&stream.Status,
 		&stream.StreamType,
 		&stream.SourceID,
 		&stream.OriginURL,
 		&stream.CDNURL,
-		&stream.StreamKey,
+		&stream.DVRWindowSeconds,
+		&stream.StartedAt,
+		&stream.EndedAt,
+		&stream.PreviousStatus,
+		&stream.StatusChangedAt,
 		&stream.CreatedAt,
 		&stream.UpdatedAt,
-	)
+	); err != nil {
+		return nil, err
+	}
+	return &stream, nil
+}
 
+// hashStreamKey returns the hex SHA-256 stored for a stream key. Keys are
+// long random strings, so a fast unsalted hash is enough to look them up.
+func hashStreamKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewStreamRepository(db *sql.DB) *StreamRepository {
+	return &StreamRepository{db: db}
+}
+
+func (r *StreamRepository) GetByID(streamID string) (*models.Stream, error) {
+	query := `SELECT ` + streamColumns + ` FROM streams s WHERE s.id = $1 LIMIT 1`
+
+	stream, err := scanStream(r.db.QueryRow(query, streamID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -44,31 +88,14 @@ func (r *StreamRepository) GetByID(streamID string) (*models.Stream, error) {
 		return nil, fmt.Errorf("failed to get stream by id: %w", err)
 	}
 
-	return &stream, nil
+	return stream, nil
 }
 
+// GetByRaceID returns the race's primary stream
 func (r *StreamRepository) GetByRaceID(raceID string) (*models.Stream, error) {
-	query := `
-		SELECT id, race_id, status, stream_type, source_id, origin_url, cdn_url, stream_key, created_at, updated_at
-		FROM streams
-		WHERE race_id = $1
-		LIMIT 1
-	`
-
-	var stream models.Stream
-	err := r.db.QueryRow(query, raceID).Scan(
-		&stream.ID,
-		&stream.RaceID,
-		&stream.Status,
-		&stream.StreamType,
-		&stream.SourceID,
-		&stream.OriginURL,
-		&stream.CDNURL,
-		&stream.StreamKey,
-		&stream.CreatedAt,
-		&stream.UpdatedAt,
-	)
+	query := `SELECT ` + streamColumns + ` FROM streams s WHERE s.race_id = $1 AND s.is_primary LIMIT 1`
 
+	stream, err := scanStream(r.db.QueryRow(query, raceID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -76,15 +103,90 @@ func (r *StreamRepository) GetByRaceID(raceID string) (*models.Stream, error) {
 		return nil, fmt.Errorf("failed to get stream: %w", err)
 	}
 
-	return &stream, nil
+	return stream, nil
 }
 
-func (r *StreamRepository) GetAll() ([]models.Stream, error) {
+// GetFeed returns a stream of the race by ID, or nil when the race has no
+// such stream
+func (r *StreamRepository) GetFeed(raceID, streamID string) (*models.Stream, error) {
+	query := `SELECT ` + streamColumns + ` FROM streams s WHERE s.race_id = $1 AND s.id = $2`
+
+	stream, err := scanStream(r.db.QueryRow(query, raceID, streamID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream feed: %w", err)
+	}
+
+	return stream, nil
+}
+
+// ListByRaceID returns the streams (feeds) of a race, primary first
+func (r *StreamRepository) ListByRaceID(raceID string) ([]*models.Stream, error) {
+	query := `SELECT ` + streamColumns + ` FROM streams s WHERE s.race_id = $1 ORDER BY s.is_primary DESC, s.created_at, s.name`
+
+	rows, err := r.db.Query(query, raceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list race streams: %w", err)
+	}
+	defer rows.Close()
+
+	streams := []*models.Stream{}
+	for rows.Next() {
+		stream, err := scanStream(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream: %w", err)
+		}
+		streams = append(streams, stream)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating race streams: %w", err)
+	}
+	return streams, nil
+}
+
+// GetByStreamKey returns the stream an RTMP publish key belongs to, or nil
+// when the key is unknown, revoked or past its rotation overlap window
+func (r *StreamRepository) GetByStreamKey(streamKey string) (*models.Stream, error) {
+	return r.getByStreamKey(streamKey, true)
+}
+
+// GetByAnyStreamKey is GetByStreamKey but also matches revoked and expired
+// keys, so that publishes started with them can still be wound up
+func (r *StreamRepository) GetByAnyStreamKey(streamKey string) (*models.Stream, error) {
+	return r.getByStreamKey(streamKey, false)
+}
+
+func (r *StreamRepository) getByStreamKey(streamKey string, activeOnly bool) (*models.Stream, error) {
+	if streamKey == "" {
+		return nil, nil
+	}
+
 	query := `
-		SELECT id, race_id, status, stream_type, source_id, origin_url, cdn_url, stream_key, created_at, updated_at
-		FROM streams
-		ORDER BY created_at DESC
+		SELECT ` + streamColumns + `
+		FROM stream_keys k
+		JOIN streams s ON s.id = k.stream_id
+		WHERE k.key_hash = $1
 	`
+	if activeOnly {
+		query += ` AND ` + streamKeyActive
+	}
+
+	stream, err := scanStream(r.db.QueryRow(query, hashStreamKey(streamKey)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream by key: %w", err)
+	}
+
+	return stream, nil
+}
+
+func (r *StreamRepository) GetAll() ([]models.Stream, error) {
+	query := `SELECT ` + streamColumns + ` FROM streams s ORDER BY s.created_at DESC`
 
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -94,22 +196,11 @@ func (r *StreamRepository) GetAll() ([]models.Stream, error) {
 
 	var streams []models.Stream
 	for rows.Next() {
-		var s models.Stream
-		if err := rows.Scan(
-			&s.ID,
-			&s.RaceID,
-			&s.Status,
-			&s.StreamType,
-			&s.SourceID,
-			&s.OriginURL,
-			&s.CDNURL,
-			&s.StreamKey,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		); err != nil {
+		s, err := scanStream(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan stream: %w", err)
 		}
-		streams = append(streams, s)
+		streams = append(streams, *s)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -119,18 +210,20 @@ func (r *StreamRepository) GetAll() ([]models.Stream, error) {
 	return streams, nil
 }
 
+// CreateOrUpdate creates a race's primary stream with stream.Status, or
+// updates its settings. The status of an existing stream is left alone: it
+// only changes through UpdateStatus, which enforces the status transitions.
 func (r *StreamRepository) CreateOrUpdate(stream *models.Stream) error {
 	query := `
-		INSERT INTO streams (race_id, status, stream_type, source_id, origin_url, cdn_url, stream_key)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (race_id) DO UPDATE
-		SET status = $2, stream_type = $3, source_id = $4, origin_url = $5, cdn_url = $6, stream_key = $7, updated_at = CURRENT_TIMESTAMP
-		RETURNING id, created_at, updated_at
+		INSERT INTO streams (race_id, name, is_primary, access, status, stream_type, source_id, origin_url, cdn_url, started_at, status_changed_at)
+		VALUES ($1, $7, TRUE, $8, $2, $3, $4, $5, $6, CASE WHEN $2 = 'live' THEN CURRENT_TIMESTAMP END, CURRENT_TIMESTAMP)
+		ON CONFLICT (race_id) WHERE is_primary DO UPDATE
+		SET stream_type = $3, source_id = $4, origin_url = $5, cdn_url = $6,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, name, is_primary, access, status, started_at, ended_at, previous_status, status_changed_at, created_at, updated_at
 	`
 
-	if stream.StreamType == "" {
-		stream.StreamType = "hls"
-	}
+	setStreamDefaults(stream)
 
 	err := r.db.QueryRow(
 		query,
@@ -140,9 +233,25 @@ func (r *StreamRepository) CreateOrUpdate(stream *models.Stream) error {
 		stream.SourceID,
 		stream.OriginURL,
 		stream.CDNURL,
-		stream.StreamKey,
-	).Scan(&stream.ID, &stream.CreatedAt, &stream.UpdatedAt)
+		stream.Name,
+		stream.Access,
+	).Scan(
+		&stream.ID,
+		&stream.Name,
+		&stream.IsPrimary,
+		&stream.Access,
+		&stream.Status,
+		&stream.StartedAt,
+		&stream.EndedAt,
+		&stream.PreviousStatus,
+		&stream.StatusChangedAt,
+		&stream.CreatedAt,
+		&stream.UpdatedAt,
+	)
 
+	if isUniqueViolation(err) {
+		return ErrStreamNameTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create or update stream: %w", err)
 	}
@@ -150,26 +259,355 @@ func (r *StreamRepository) CreateOrUpdate(stream *models.Stream) error {
 	return nil
 }
 
-func (r *StreamRepository) UpdateStatus(raceID string, status string) error {
+func setStreamDefaults(stream *models.Stream) {
+	if stream.Name == "" {
+		stream.Name = "Main"
+	}
+	if stream.Access == "" {
+		stream.Access = models.StreamAccessRace
+	}
+	if stream.StreamType == "" {
+		stream.StreamType = "hls"
+	}
+	if stream.Status == "" {
+		stream.Status = models.StreamStatusScheduled
+	}
+}
+
+// CreateFeed adds a stream to a race. When it is primary it takes over from
+// the race's current primary stream; the race's first stream is always
+// primary.
+func (r *StreamRepository) CreateFeed(stream *models.Stream) error {
+	setStreamDefaults(stream)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hasPrimary bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM streams WHERE race_id = $1 AND is_primary)`, stream.RaceID).Scan(&hasPrimary); err != nil {
+		return fmt.Errorf("failed to check primary stream: %w", err)
+	}
+	if !hasPrimary {
+		stream.IsPrimary = true
+	} else if stream.IsPrimary {
+		if err := demotePrimaryStream(tx, stream.RaceID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO streams AS s (race_id, name, is_primary, access, status, stream_type, source_id, origin_url, cdn_url, dvr_window_seconds, started_at, status_changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $5 = 'live' THEN CURRENT_TIMESTAMP END, CURRENT_TIMESTAMP)
+		RETURNING ` + streamColumns
+	created, err := scanStream(tx.QueryRow(query,
+		stream.RaceID, stream.Name, stream.IsPrimary, stream.Access, stream.Status,
+		stream.StreamType, stream.SourceID, stream.OriginURL, stream.CDNURL, stream.DVRWindowSeconds))
+	if isUniqueViolation(err) {
+		return ErrStreamNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create stream: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stream: %w", err)
+	}
+	*stream = *created
+	return nil
+}
+
+// UpdateFeed updates the name, access, source and DVR window of a race's stream, and
+// makes it primary when stream.IsPrimary is set. The status is left alone.
+func (r *StreamRepository) UpdateFeed(stream *models.Stream) error {
+	setStreamDefaults(stream)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasPrimary bool
+	err = tx.QueryRow(`SELECT is_primary FROM streams WHERE race_id = $1 AND id = $2 FOR UPDATE`, stream.RaceID, stream.ID).Scan(&wasPrimary)
+	if err == sql.ErrNoRows {
+		return ErrStreamNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock stream: %w", err)
+	}
+	if wasPrimary && !stream.IsPrimary {
+		return ErrPrimaryStreamRequired
+	}
+	if stream.IsPrimary && !wasPrimary {
+		if err := demotePrimaryStream(tx, stream.RaceID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE streams s
+		SET name = $3, is_primary = $4, access = $5, stream_type = $6, source_id = $7, origin_url = $8, cdn_url = $9,
+			dvr_window_seconds = $10, updated_at = CURRENT_TIMESTAMP
+		WHERE s.race_id = $1 AND s.id = $2
+		RETURNING ` + streamColumns
+	updated, err := scanStream(tx.QueryRow(query,
+		stream.RaceID, stream.ID, stream.Name, stream.IsPrimary, stream.Access,
+		stream.StreamType, stream.SourceID, stream.OriginURL, stream.CDNURL, stream.DVRWindowSeconds))
+	if isUniqueViolation(err) {
+		return ErrStreamNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update stream: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stream: %w", err)
+	}
+	*stream = *updated
+	return nil
+}
+
+func demotePrimaryStream(tx *sql.Tx, raceID string) error {
+	if _, err := tx.Exec(`UPDATE streams SET is_primary = FALSE, updated_at = CURRENT_TIMESTAMP WHERE race_id = $1 AND is_primary`, raceID); err != nil {
+		return fmt.Errorf("failed to demote primary stream: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus moves a race's primary stream to status. It returns the
+// change, or nil when the stream already has that status, and
+// ErrInvalidStreamTransition when the stream can't move there from its
+// current status.
+func (r *StreamRepository) UpdateStatus(raceID string, status string) (*models.StreamStatusChange, error) {
+	return r.updateStatus(status, `SELECT id FROM streams WHERE race_id = $1 AND is_primary`, raceID)
+}
+
+// UpdateFeedStatus is UpdateStatus for a given stream of the race
+func (r *StreamRepository) UpdateFeedStatus(raceID, streamID, status string) (*models.StreamStatusChange, error) {
+	return r.updateStatus(status, `SELECT id FROM streams WHERE race_id = $1 AND id = $2`, raceID, streamID)
+}
+
+// updateStatus moves the stream selected by query to status
+func (r *StreamRepository) updateStatus(status, query string, args ...interface{}) (*models.StreamStatusChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var streamID string
+	err = tx.QueryRow(query, args...).Scan(&streamID)
+	if err == sql.ErrNoRows {
+		return nil, ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	change, err := transitionStream(tx, streamID, status, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stream status: %w", err)
+	}
+	return change, nil
+}
+
+// transitionStream locks a stream and moves it to status, refreshing stream
+// when it is not nil. It returns nil when the stream already has that status.
+func transitionStream(tx *sql.Tx, streamID, status string, stream *models.Stream) (*models.StreamStatusChange, error) {
+	change := &models.StreamStatusChange{StreamID: streamID, To: status}
+	err := tx.QueryRow(`SELECT race_id, name, is_primary, status FROM streams WHERE id = $1 FOR UPDATE`, streamID).Scan(&change.RaceID, &change.Name, &change.IsPrimary, &change.From)
+	if err == sql.ErrNoRows {
+		return nil, ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stream: %w", err)
+	}
+
+	if change.From == status {
+		return nil, nil
+	}
+	if !models.CanTransitionStream(change.From, status) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidStreamTransition, change.From, status)
+	}
+
 	query := `
 		UPDATE streams
-		SET status = $2, updated_at = CURRENT_TIMESTAMP
-		WHERE race_id = $1
+		SET status = $2, ` + streamSessionUpdate + `,
+			previous_status = streams.status, status_changed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING started_at, ended_at, status_changed_at, updated_at
+	`
+	var startedAt, endedAt sql.NullTime
+	var updatedAt time.Time
+	if err := tx.QueryRow(query, streamID, status).Scan(&startedAt, &endedAt, &change.ChangedAt, &updatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update stream status: %w", err)
+	}
+
+	if stream != nil {
+		stream.Status = status
+		stream.PreviousStatus = &change.From
+		stream.StatusChangedAt = &change.ChangedAt
+		stream.StartedAt, stream.EndedAt = nil, nil
+		if startedAt.Valid {
+			stream.StartedAt = &startedAt.Time
+		}
+		if endedAt.Valid {
+			stream.EndedAt = &endedAt.Time
+		}
+		stream.UpdatedAt = updatedAt
+	}
+	return change, nil
+}
+
+// StartDuePreShows moves scheduled streams whose race starts within lead, and
+// has not finished, to pre-show and returns the changes
+func (r *StreamRepository) StartDuePreShows(lead time.Duration) ([]*models.StreamStatusChange, error) {
+	query := `
+		UPDATE streams s
+		SET status = $1, previous_status = s.status, status_changed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		FROM races r
+		WHERE r.id = s.race_id
+		  AND s.status = $2
+		  AND r.start_date IS NOT NULL
+		  AND r.start_date <= $3
+		  AND (r.end_date IS NULL OR r.end_date > CURRENT_TIMESTAMP)
+		RETURNING s.id, s.race_id, s.name, s.is_primary, s.status_changed_at
 	`
 
-	result, err := r.db.Exec(query, raceID, status)
+	rows, err := r.db.Query(query, models.StreamStatusPreShow, models.StreamStatusScheduled, time.Now().Add(lead))
 	if err != nil {
-		return fmt.Errorf("failed to update stream status: %w", err)
+		return nil, fmt.Errorf("failed to start pre-shows: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	changes := []*models.StreamStatusChange{}
+	for rows.Next() {
+		change := &models.StreamStatusChange{From: models.StreamStatusScheduled, To: models.StreamStatusPreShow}
+		if err := rows.Scan(&change.StreamID, &change.RaceID, &change.Name, &change.IsPrimary, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pre-show stream: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pre-show streams: %w", err)
+	}
+	return changes, nil
+}
+
+// StartPublishSession records an encoder starting to publish to a stream and
+// sets the stream live. Sessions of the stream still open, left behind when
+// nginx missed a disconnect, are ended as superseded. It returns the status
+// change, if any, and ErrInvalidStreamTransition when the stream can't go
+// live, e.g. once its replay is ready.
+func (r *StreamRepository) StartPublishSession(stream *models.Stream, session *models.StreamPublishSession) (*models.StreamStatusChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Also applies publish callbacks for the same stream one at a time
+	change, err := transitionStream(tx, stream.ID, models.StreamStatusLive, stream)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("stream not found")
+	if _, err := tx.Exec(`
+		UPDATE stream_publish_sessions
+		SET ended_at = CURRENT_TIMESTAMP, end_reason = 'superseded'
+		WHERE stream_id = $1 AND ended_at IS NULL
+	`, stream.ID); err != nil {
+		return nil, fmt.Errorf("failed to end previous publish sessions: %w", err)
 	}
 
-	return nil
+	session.StreamID = stream.ID
+	err = tx.QueryRow(`
+		INSERT INTO stream_publish_sessions (stream_id, client_id, client_addr, app)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, started_at
+	`, session.StreamID, session.ClientID, session.ClientAddr, session.App).Scan(&session.ID, &session.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publish session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit publish session: %w", err)
+	}
+	return change, nil
+}
+
+// EndPublishSession records the encoder with clientID no longer publishing to
+// a stream and, once no publish is left, ends the stream. It returns a nil
+// session when the client has no open session, e.g. when a newer publish
+// already superseded it; the stream is then left as it is.
+func (r *StreamRepository) EndPublishSession(stream *models.Stream, clientID string) (*models.StreamPublishSession, *models.StreamStatusChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM streams WHERE id = $1 FOR UPDATE`, stream.ID).Scan(&status); err != nil {
+		return nil, nil, fmt.Errorf("failed to lock stream: %w", err)
+	}
+
+	var session models.StreamPublishSession
+	var clientAddr, app, endReason sql.NullString
+	err = tx.QueryRow(`
+		UPDATE stream_publish_sessions
+		SET ended_at = CURRENT_TIMESTAMP, end_reason = 'done'
+		WHERE stream_id = $1 AND client_id = $2 AND ended_at IS NULL
+		RETURNING id, stream_id, client_id, client_addr, app, started_at, ended_at, end_reason
+	`, stream.ID, clientID).Scan(
+		&session.ID,
+		&session.StreamID,
+		&session.ClientID,
+		&clientAddr,
+		&app,
+		&session.StartedAt,
+		&session.EndedAt,
+		&endReason,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to end publish session: %w", err)
+	}
+	if clientAddr.Valid {
+		session.ClientAddr = &clientAddr.String
+	}
+	if app.Valid {
+		session.App = &app.String
+	}
+	if endReason.Valid {
+		session.EndReason = &endReason.String
+	}
+
+	var stillPublishing bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM stream_publish_sessions WHERE stream_id = $1 AND ended_at IS NULL)`, stream.ID).Scan(&stillPublishing); err != nil {
+		return nil, nil, fmt.Errorf("failed to check open publish sessions: %w", err)
+	}
+
+	// An admin may already have moved the stream on, e.g. to failed
+	var change *models.StreamStatusChange
+	if status == models.StreamStatusLive && !stillPublishing {
+		if change, err = transitionStream(tx, stream.ID, models.StreamStatusEnded, stream); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit publish session: %w", err)
+	}
+	return &session, change, nil
 }
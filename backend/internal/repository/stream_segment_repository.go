@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+type StreamSegmentRepository struct {
+	db *sql.DB
+}
+
+func NewStreamSegmentRepository(db *sql.DB) *StreamSegmentRepository {
+	return &StreamSegmentRepository{db: db}
+}
+
+const streamSegmentColumns = `stream_id, sequence, uri, duration_seconds, program_date_time, discontinuity`
+
+func scanStreamSegment(scanner interface{ Scan(...interface{}) error }) (*models.StreamSegment, error) {
+	var segment models.StreamSegment
+	err := scanner.Scan(
+		&segment.StreamID,
+		&segment.Sequence,
+		&segment.URI,
+		&segment.Duration,
+		&segment.ProgramDateTime,
+		&segment.Discontinuity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+func (r *StreamSegmentRepository) list(query string, args ...interface{}) ([]models.StreamSegment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream segments: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []models.StreamSegment{}
+	for rows.Next() {
+		segment, err := scanStreamSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream segment: %w", err)
+		}
+		segments = append(segments, *segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stream segments: %w", err)
+	}
+	return segments, nil
+}
+
+// ListRecordingTargets returns the live streams whose segments are recorded:
+// those played from the HLS origin
+func (r *StreamSegmentRepository) ListRecordingTargets() ([]*models.Stream, error) {
+	query := `SELECT ` + streamColumns + ` FROM streams s WHERE s.status = $1 AND s.origin_url LIKE '%/hls/%'`
+
+	rows, err := r.db.Query(query, models.StreamStatusLive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streams to record: %w", err)
+	}
+	defer rows.Close()
+
+	streams := []*models.Stream{}
+	for rows.Next() {
+		stream, err := scanStream(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream: %w", err)
+		}
+		streams = append(streams, stream)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating streams to record: %w", err)
+	}
+	return streams, nil
+}
+
+// LatestSegment returns the last recorded segment of a stream, or nil
+func (r *StreamSegmentRepository) LatestSegment(streamID string) (*models.StreamSegment, error) {
+	query := `SELECT ` + streamSegmentColumns + ` FROM stream_segments WHERE stream_id = $1 ORDER BY sequence DESC LIMIT 1`
+
+	segment, err := scanStreamSegment(r.db.QueryRow(query, streamID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest stream segment: %w", err)
+	}
+	return segment, nil
+}
+
+// InsertSegments records segments. Segments already recorded, e.g. by
+// another replica, are skipped.
+func (r *StreamSegmentRepository) InsertSegments(segments []models.StreamSegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO stream_segments (` + streamSegmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (stream_id, sequence) DO NOTHING
+	`
+	for _, segment := range segments {
+		_, err := tx.Exec(query, segment.StreamID, segment.Sequence, segment.URI, segment.Duration, segment.ProgramDateTime, segment.Discontinuity)
+		if err != nil {
+			return fmt.Errorf("failed to insert stream segment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stream segments: %w", err)
+	}
+	return nil
+}
+
+// ListAll returns all of a stream's recorded segments, in order
+func (r *StreamSegmentRepository) ListAll(streamID string) ([]models.StreamSegment, error) {
+	query := `SELECT ` + streamSegmentColumns + ` FROM stream_segments WHERE stream_id = $1 ORDER BY sequence`
+	return r.list(query, streamID)
+}
+
+// ListWindow returns a stream's segments from the last window before its
+// latest one, in order, and the number of discontinuities before the first
+// of them
+func (r *StreamSegmentRepository) ListWindow(streamID string, window time.Duration) ([]models.StreamSegment, int64, error) {
+	query := `
+		SELECT ` + streamSegmentColumns + `
+		FROM stream_segments
+		WHERE stream_id = $1
+			AND program_date_time >= (SELECT MAX(program_date_time) FROM stream_segments WHERE stream_id = $1) - make_interval(secs => $2)
+		ORDER BY sequence
+	`
+
+	segments, err := r.list(query, streamID, window.Seconds())
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(segments) == 0 {
+		return segments, 0, nil
+	}
+
+	var discontinuities int64
+	err = r.db.QueryRow(
+		`SELECT COUNT(*) FROM stream_segments WHERE stream_id = $1 AND discontinuity AND sequence < $2`,
+		streamID, segments[0].Sequence,
+	).Scan(&discontinuities)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count stream discontinuities: %w", err)
+	}
+
+	return segments, discontinuities, nil
+}
@@ -132,6 +132,56 @@ func (r *UserRepository) GetByIDs(ids []string) (map[string]*models.User, error)
 	return result, nil
 }
 
+// FindByMentionHandles resolves lowercase @handles to user IDs. A handle matches
+// a display name with whitespace removed or the local part of an email, so one
+// handle can match several users.
+func (r *UserRepository) FindByMentionHandles(handles []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(handles))
+	if len(handles) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT id,
+			lower(regexp_replace(COALESCE(name, ''), '\s+', '', 'g')),
+			lower(split_part(email, '@', 1))
+		FROM users
+		WHERE lower(regexp_replace(COALESCE(name, ''), '\s+', '', 'g')) = ANY($1)
+			OR lower(split_part(email, '@', 1)) = ANY($1)
+		LIMIT 100
+	`
+
+	rows, err := r.db.Query(query, pq.Array(handles))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mention handles: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(handles))
+	for _, h := range handles {
+		wanted[h] = true
+	}
+
+	for rows.Next() {
+		var id, nameHandle, emailHandle string
+		if err := rows.Scan(&id, &nameHandle, &emailHandle); err != nil {
+			return nil, fmt.Errorf("failed to scan mention handle: %w", err)
+		}
+		if wanted[nameHandle] {
+			result[nameHandle] = append(result[nameHandle], id)
+		}
+		if emailHandle != nameHandle && wanted[emailHandle] {
+			result[emailHandle] = append(result[emailHandle], id)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating mention handles: %w", err)
+	}
+
+	return result, nil
+}
+
 func (r *UserRepository) GetPublicByID(id string) (*models.PublicUser, error) {
 	query := `SELECT id, name, bio, points, xp_total, level, best_streak_weeks, created_at FROM users WHERE id = $1`
 
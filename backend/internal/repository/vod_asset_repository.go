@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+type VODAssetRepository struct {
+	db *sql.DB
+}
+
+func NewVODAssetRepository(db *sql.DB) *VODAssetRepository {
+	return &VODAssetRepository{db: db}
+}
+
+// An asset left processing this long, e.g. by a replica that stopped, is
+// taken over by the next recording manager
+const vodClaimTimeout = 10 * time.Minute
+
+const vodAssetColumns = `v.id, v.stream_id, v.race_id, v.status, v.source, v.duration_seconds, v.segment_count,
+	v.thumbnail_offset_seconds, v.recorded_from, v.recorded_to, v.error, v.created_at, v.updated_at`
+
+func scanVODAsset(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.VODAsset, error) {
+	var asset models.VODAsset
+	dest := []interface{}{
+		&asset.ID,
+		&asset.StreamID,
+		&asset.RaceID,
+		&asset.Status,
+		&asset.Source,
+		&asset.Duration,
+		&asset.SegmentCount,
+		&asset.ThumbnailOffset,
+		&asset.RecordedFrom,
+		&asset.RecordedTo,
+		&asset.Error,
+		&asset.CreatedAt,
+		&asset.UpdatedAt,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ListDue returns the streams that ended at least settle ago and have no VOD
+// asset yet, or one left processing
+func (r *VODAssetRepository) ListDue(settle time.Duration) ([]*models.Stream, error) {
+	query := `
+		SELECT ` + streamColumns + `
+		FROM streams s
+		LEFT JOIN vod_assets v ON v.stream_id = s.id
+		WHERE s.status = $1
+			AND s.ended_at <= $2
+			AND (v.id IS NULL OR (v.status = $3 AND v.updated_at < $4))
+		ORDER BY s.ended_at
+	`
+
+	now := time.Now()
+	rows, err := r.db.Query(query, models.StreamStatusEnded, now.Add(-settle), models.VODAssetProcessing, now.Add(-vodClaimTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streams due for VOD: %w", err)
+	}
+	defer rows.Close()
+
+	streams := []*models.Stream{}
+	for rows.Next() {
+		stream, err := scanStream(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream: %w", err)
+		}
+		streams = append(streams, stream)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating streams due for VOD: %w", err)
+	}
+	return streams, nil
+}
+
+// Claim starts registering the stream's VOD asset. It returns nil when
+// another recording manager is already at it or the asset was registered.
+func (r *VODAssetRepository) Claim(stream *models.Stream, source string) (*models.VODAsset, error) {
+	query := `
+		INSERT INTO vod_assets AS v (stream_id, race_id, source)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (stream_id) DO UPDATE
+		SET source = EXCLUDED.source, updated_at = CURRENT_TIMESTAMP
+		WHERE v.status = $4 AND v.updated_at < $5
+		RETURNING ` + vodAssetColumns
+
+	asset, err := scanVODAsset(r.db.QueryRow(query, stream.ID, stream.RaceID, source, models.VODAssetProcessing, time.Now().Add(-vodClaimTimeout)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim VOD asset: %w", err)
+	}
+	return asset, nil
+}
+
+// Fail records that the asset couldn't be registered. Failed assets aren't
+// retried until they are deleted.
+func (r *VODAssetRepository) Fail(id, reason string) error {
+	query := `UPDATE vod_assets SET status = $2, error = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := r.db.Exec(query, id, models.VODAssetFailed, reason); err != nil {
+		return fmt.Errorf("failed to record VOD asset failure: %w", err)
+	}
+	return nil
+}
+
+// Complete marks the asset ready with its duration, thumbnail and recording
+// times, and moves its stream to vod_ready. When the stream went live again
+// in the meantime the asset is dropped, to be registered once the stream
+// ends again, and ErrInvalidStreamTransition is returned.
+func (r *VODAssetRepository) Complete(asset *models.VODAsset) (*models.StreamStatusChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	change, err := transitionStream(tx, asset.StreamID, models.StreamStatusVODReady, nil)
+	if errors.Is(err, ErrInvalidStreamTransition) {
+		if _, dropErr := tx.Exec(`DELETE FROM vod_assets WHERE id = $1`, asset.ID); dropErr != nil {
+			return nil, fmt.Errorf("failed to drop VOD asset: %w", dropErr)
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			return nil, fmt.Errorf("failed to drop VOD asset: %w", commitErr)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE vod_assets v
+		SET status = $2, duration_seconds = $3, segment_count = $4, thumbnail_offset_seconds = $5,
+			recorded_from = $6, recorded_to = $7, error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE v.id = $1
+		RETURNING ` + vodAssetColumns
+	completed, err := scanVODAsset(tx.QueryRow(query,
+		asset.ID, models.VODAssetReady, asset.Duration, asset.SegmentCount, asset.ThumbnailOffset,
+		asset.RecordedFrom, asset.RecordedTo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete VOD asset: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit VOD asset: %w", err)
+	}
+	*asset = *completed
+	return change, nil
+}
+
+// GetReadyByStreamID returns the stream's replay, or nil when it has none
+// ready
+func (r *VODAssetRepository) GetReadyByStreamID(streamID string) (*models.VODAsset, error) {
+	query := `SELECT ` + vodAssetColumns + ` FROM vod_assets v WHERE v.stream_id = $1 AND v.status = $2`
+
+	asset, err := scanVODAsset(r.db.QueryRow(query, streamID, models.VODAssetReady))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load VOD asset: %w", err)
+	}
+	return asset, nil
+}
+
+// ListReplays returns ready replays, latest recording first, optionally of
+// one race category
+func (r *VODAssetRepository) ListReplays(category string, limit, offset int) ([]models.Replay, error) {
+	query := `
+		SELECT ` + vodAssetColumns + `, r.name, s.name, s.is_primary, r.category
+		FROM vod_assets v
+		JOIN streams s ON s.id = v.stream_id
+		JOIN races r ON r.id = v.race_id
+		WHERE v.status = $1 AND ($2 = '' OR r.category = $2)
+		ORDER BY v.recorded_from DESC NULLS LAST, s.is_primary DESC, s.name
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(query, models.VODAssetReady, category, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replays: %w", err)
+	}
+	defer rows.Close()
+
+	replays := []models.Replay{}
+	for rows.Next() {
+		var replay models.Replay
+		asset, err := scanVODAsset(rows, &replay.RaceName, &replay.StreamName, &replay.IsPrimary, &replay.Category)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan replay: %w", err)
+		}
+		replay.VODAsset = *asset
+		replays = append(replays, replay)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating replays: %w", err)
+	}
+	return replays, nil
+}
+
+// RaceIDsWithReplays returns which of the races have a ready replay
+func (r *VODAssetRepository) RaceIDsWithReplays(raceIDs []string) (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT race_id FROM vod_assets WHERE status = $1 AND race_id = ANY($2)`, models.VODAssetReady, pq.Array(raceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find races with replays: %w", err)
+	}
+	defer rows.Close()
+
+	withReplays := make(map[string]bool, len(raceIDs))
+	for rows.Next() {
+		var raceID string
+		if err := rows.Scan(&raceID); err != nil {
+			return nil, fmt.Errorf("failed to scan race ID: %w", err)
+		}
+		withReplays[raceID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating races with replays: %w", err)
+	}
+	return withReplays, nil
+}
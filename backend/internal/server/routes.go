@@ -2,11 +2,13 @@ package server
 
 import (
 	"log"
+	"time"
 
 	"github.com/cyclingstream/backend/internal/chat"
 	"github.com/cyclingstream/backend/internal/config"
 	"github.com/cyclingstream/backend/internal/database"
 	"github.com/cyclingstream/backend/internal/handlers"
+	"github.com/cyclingstream/backend/internal/logger"
 	"github.com/cyclingstream/backend/internal/middleware"
 	"github.com/cyclingstream/backend/internal/repository"
 	"github.com/cyclingstream/backend/internal/services"
@@ -43,7 +45,11 @@ func SetupRoutes(app *fiber.App, db *database.DB, cfg *config.Config, hub *chat.
 	// Initialize repositories
 	raceRepo := repository.NewRaceRepository(db.DB)
 	streamRepo := repository.NewStreamRepository(db.DB)
+	streamKeyRepo := repository.NewStreamKeyRepository(db.DB)
 	streamProviderRepo := repository.NewStreamProviderRepository(db.DB)
+	streamSegmentRepo := repository.NewStreamSegmentRepository(db.DB)
+	vodAssetRepo := repository.NewVODAssetRepository(db.DB)
+	raceTimelineRepo := repository.NewRaceTimelineRepository(db.DB)
 	userRepo := repository.NewUserRepository(db.DB)
 	paymentRepo := repository.NewPaymentRepository(db.DB)
 	entitlementRepo := repository.NewEntitlementRepository(db.DB)
@@ -55,11 +61,17 @@ func SetupRoutes(app *fiber.App, db *database.DB, cfg *config.Config, hub *chat.
 	streamStatsRepo := repository.NewStreamStatsRepository(db.DB)
 	bunnyStatsRepo := repository.NewBunnyStatsRepository(db.DB)
 	chatRepo := repository.NewChatRepository(db.DB)
+	chatModerationRepo := repository.NewChatModerationRepository(db.DB)
+	chatPollRepo := repository.NewChatPollRepository(db.DB)
+	chatEmoteRepo := repository.NewChatEmoteRepository(db.DB)
+	chatPartyRepo := repository.NewChatPartyRepository(db.DB)
+	chatReactionRepo := repository.NewChatReactionRepository(db.DB)
+	chatRetentionRepo := repository.NewChatRetentionRepository(db.DB)
 	achievementRepo := repository.NewAchievementRepository(db.DB)
 	userPrefsRepo := repository.NewUserPreferencesRepository(db.DB)
 	userFavRepo := repository.NewUserFavoriteRepository(db.DB)
 	watchHistoryRepo := repository.NewWatchHistoryRepository(db.DB)
-	recommendationService := services.NewRecommendationService(raceRepo, watchHistoryRepo, userFavRepo, streamRepo)
+	recommendationService := services.NewRecommendationService(raceRepo, watchHistoryRepo, userFavRepo, streamRepo, vodAssetRepo)
 	missionRepo := repository.NewMissionRepository(db.DB)
 	userMissionRepo := repository.NewUserMissionRepository(db.DB)
 	xpService := services.NewXPService(userRepo, &cfg.XP.Leveling)
@@ -84,11 +96,12 @@ func SetupRoutes(app *fiber.App, db *database.DB, cfg *config.Config, hub *chat.
 	}
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler(db)
-	raceHandler := handlers.NewRaceHandler(raceRepo, streamRepo, entitlementRepo)
-	streamHandler := handlers.NewStreamHandler(streamRepo)
+	healthHandler := handlers.NewHealthHandler(db, hub)
+	playbackSigner := services.NewPlaybackSigner(cfg.Stream.PlaybackTokenSecret, cfg.Stream.PlaybackTokenTTL, cfg.Stream.PlaybackOriginHosts)
+	raceHandler := handlers.NewRaceHandler(raceRepo, streamRepo, streamProviderRepo, streamSegmentRepo, vodAssetRepo, raceTimelineRepo, entitlementRepo, playbackSigner, cfg.Stream.DVRWindow)
+	streamHandler := handlers.NewStreamHandler(streamRepo, cfg.Stream.PublishCallbackSecret)
 	authHandler := handlers.NewAuthHandler(userRepo, cfg.JWTSecret)
-	adminHandler := handlers.NewAdminHandler(raceRepo, streamRepo, revenueRepo)
+	adminHandler := handlers.NewAdminHandler(raceRepo, streamRepo, revenueRepo, streamKeyRepo, streamProviderRepo, cfg.Stream.KeyRotationOverlap, cfg.Stream.DVRMaxWindow)
 	paymentHandler := handlers.NewPaymentHandler(
 		paymentRepo,
 		entitlementRepo,
@@ -111,8 +124,34 @@ func SetupRoutes(app *fiber.App, db *database.DB, cfg *config.Config, hub *chat.
 		bunnyEnabled,
 	)
 	costHandler := handlers.NewCostHandler(costRepo, raceRepo)
-	pollManager := chat.NewPollManager()
-	chatHandler := handlers.NewChatHandler(chatRepo, raceRepo, streamRepo, userRepo, entitlementRepo, hub, rateLimiter, missionTriggers, pollManager)
+	pollManager := chat.NewPollManager(chatPollRepo)
+	emoteRegistry := chat.NewEmoteRegistry(chatEmoteRepo)
+	if err := emoteRegistry.Refresh(); err != nil {
+		logger.WithError(err).Warn("Failed to load chat emotes, using built-in emotes")
+	}
+	emoteRegistry.Start()
+	reactions := chat.NewReactionAggregator(chatReactionRepo)
+	chatHandler := handlers.NewChatHandler(chatRepo, raceRepo, streamRepo, userRepo, entitlementRepo, hub, rateLimiter, missionTriggers, pollManager, chatModerationRepo, chatEmoteRepo, emoteRegistry, chatPartyRepo, chatReactionRepo, reactions, chatRetentionRepo)
+	pollManager.Start(chatHandler.BroadcastPollClosed)
+	hub.SetReactionSink(reactions.AddRemote)
+	reactions.Start(hub.PublishReactions, chatHandler.BroadcastReactionSummary)
+	streamHandler.SetStatusListener(chatHandler.BroadcastStreamStatus)
+	adminHandler.SetStatusListener(chatHandler.BroadcastStreamStatus)
+	services.NewStreamScheduler(streamRepo, cfg.Stream.PreShowLead, cfg.Stream.SchedulerInterval).Start(chatHandler.BroadcastStreamStatus)
+	services.NewStreamHealthProber(streamProviderRepo, playbackSigner, cfg.Stream.ProbeInterval, cfg.Stream.ProbeTimeout, cfg.Stream.ProbeFailures).Start()
+	services.NewSegmentRecorder(streamSegmentRepo, playbackSigner, cfg.Stream.DVRPollInterval).Start()
+	var recordingSource services.RecordingSource = services.NewRecordedSegmentSource(streamSegmentRepo)
+	if cfg.Stream.VODSegmentDir != "" {
+		recordingSource = services.NewDirectorySegmentSource(cfg.Stream.VODSegmentDir)
+	}
+	services.NewRecordingManager(vodAssetRepo, recordingSource, streamSegmentRepo, cfg.Stream.VODSettleDelay, cfg.Stream.VODInterval).Start(chatHandler.BroadcastStreamStatus)
+	if cfg.Chat != nil && cfg.Chat.Retention.Days > 0 {
+		chat.NewRetentionJob(chatRetentionRepo, chat.RetentionConfig{
+			MaxAge:     time.Duration(cfg.Chat.Retention.Days) * 24 * time.Hour,
+			Interval:   cfg.Chat.Retention.Interval,
+			ArchiveDir: cfg.Chat.Retention.ArchiveDir,
+		}).Start()
+	}
 	userHandler := handlers.NewUserHandler(userRepo, watchSessionRepo)
 	userPrefsHandler := handlers.NewUserPreferencesHandler(userPrefsRepo)
 	userFavHandler := handlers.NewUserFavoritesHandler(userFavRepo)
@@ -129,19 +168,20 @@ func SetupRoutes(app *fiber.App, db *database.DB, cfg *config.Config, hub *chat.
 	authMiddleware := middleware.AuthMiddleware(cfg.JWTSecret)
 	userAuthMiddleware := middleware.UserAuthMiddleware(cfg.JWTSecret)
 	optionalUserAuthMiddleware := middleware.OptionalUserAuthMiddleware(cfg.JWTSecret)
-	chatAuthMiddleware := middleware.ChatAuthMiddleware(cfg.JWTSecret)
+	chatAuthMiddleware := middleware.ChatAuthMiddleware(cfg.JWTSecret, chatPartyRepo)
 	csrfProtection := middleware.CSRFProtection(cfg.JWTSecret)
 
 	// Setup route groups
 	setupPublicRoutes(app, healthHandler, raceHandler, userHandler, missionsHandler)
-	setupAuthRoutes(app, authHandler)
+	setupAuthRoutes(app, authHandler, streamHandler)
+	setupPlaybackRoutes(app, raceHandler)
 	setupViewerRoutes(app, viewerHandler, optionalUserAuthMiddleware)
-	setupStreamRoutes(app, raceHandler, streamHandler, optionalUserAuthMiddleware)
+	setupStreamRoutes(app, raceHandler, streamHandler, optionalUserAuthMiddleware, userAuthMiddleware, csrfProtection)
 	setupChatRoutes(app, chatHandler, chatAuthMiddleware, authMiddleware, userAuthMiddleware)
 	setupUserRoutes(app, authHandler, paymentHandler, watchHandler, userPrefsHandler, userFavHandler, watchHistoryHandler, recommendationsHandler, missionsHandler, xpHandler, weeklyHandler, achievementsHandler, userAuthMiddleware, csrfProtection)
 	setupPredictionRoutes(app, predictionsHandler, optionalUserAuthMiddleware, userAuthMiddleware, csrfProtection)
 	setupWebhookRoutes(app, paymentHandler)
-	setupAdminRoutes(app, adminHandler, analyticsHandler, costHandler, authMiddleware, csrfProtection)
+	setupAdminRoutes(app, adminHandler, analyticsHandler, costHandler, chatHandler, raceHandler, authMiddleware, csrfProtection)
 	setupAnalyticsRoutes(app, analyticsIngestionHandler)
 }
 
@@ -150,6 +190,7 @@ func setupPublicRoutes(app *fiber.App, healthHandler *handlers.HealthHandler, ra
 	public := app.Group("", middleware.LenientRateLimiter())
 	public.Get("/health", healthHandler.GetHealth)
 	public.Get("/races", raceHandler.GetRaces)
+	public.Get("/replays", raceHandler.GetReplays)
 	public.Get("/leaderboard", userHandler.GetLeaderboard)
 	// Public user profile (no auth required) - uses /profiles to avoid conflict with authenticated /users group
 	public.Get("/profiles/:id", userHandler.GetPublicProfile)
@@ -161,11 +202,15 @@ func setupPublicRoutes(app *fiber.App, healthHandler *handlers.HealthHandler, ra
 	public.Get("/missions/active", missionsHandler.GetActiveMissions)
 }
 
-func setupAuthRoutes(app *fiber.App, authHandler *handlers.AuthHandler) {
+func setupAuthRoutes(app *fiber.App, authHandler *handlers.AuthHandler, streamHandler *handlers.StreamHandler) {
 	// Auth routes with strict rate limiting (prevent brute force)
 	auth := app.Group("/auth", middleware.StrictRateLimiter())
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
+	// nginx-rtmp on_publish / on_update / on_publish_done callbacks
+	auth.Post("/stream", streamHandler.PublishStream)
+	auth.Post("/stream/update", streamHandler.PublishStreamUpdate)
+	auth.Post("/stream/done", streamHandler.PublishStreamDone)
 }
 
 func setupViewerRoutes(app *fiber.App, viewerHandler *handlers.ViewerHandler, optionalAuth fiber.Handler) {
@@ -179,11 +224,29 @@ func setupViewerRoutes(app *fiber.App, viewerHandler *handlers.ViewerHandler, op
 	viewer.Get("/races/:id/viewers/unique", viewerHandler.GetUniqueViewers)
 }
 
-func setupStreamRoutes(app *fiber.App, raceHandler *handlers.RaceHandler, streamHandler *handlers.StreamHandler, optionalAuth fiber.Handler) {
+func setupStreamRoutes(app *fiber.App, raceHandler *handlers.RaceHandler, streamHandler *handlers.StreamHandler, optionalAuth fiber.Handler, userAuth fiber.Handler, csrf fiber.Handler) {
 	// Stream endpoint - optional auth (checks inside handler)
 	stream := app.Group("", middleware.LenientRateLimiter())
 	stream.Get("/races/:id/stream", optionalAuth, raceHandler.GetRaceStream)
 	stream.Get("/races/:id/stream/status", streamHandler.GetStreamStatus)
+	stream.Post("/races/:id/stream/token", optionalAuth, raceHandler.RefreshPlaybackToken)
+	stream.Get("/races/:id/replay", optionalAuth, raceHandler.GetRaceReplay)
+	// Timeline markers and clips of the races' recordings
+	stream.Get("/races/:id/markers", raceHandler.GetRaceMarkers)
+	stream.Get("/clips/:id", optionalAuth, raceHandler.GetClip)
+	stream.Post("/races/:id/clips", userAuth, csrf, raceHandler.CreateClip)
+}
+
+func setupPlaybackRoutes(app *fiber.App, raceHandler *handlers.RaceHandler) {
+	// nginx auth_request for every HLS playlist and segment. Kept out of
+	// /auth so its rate limiter does not throttle all viewers behind nginx
+	app.Get("/playback/authorize", raceHandler.AuthorizePlayback)
+	// DVR playlists, proxied by nginx from /hls/t/<token>/<stream>/dvr.m3u8
+	app.Get("/playback/dvr", raceHandler.DVRPlaylist)
+	// Replay playlists, proxied from /hls/t/<token>/<stream>/vod.m3u8
+	app.Get("/playback/vod", raceHandler.VODPlaylist)
+	// Clip playlists, proxied from /hls/t/<token>/<stream>/clip-<id>.m3u8
+	app.Get("/playback/clip", raceHandler.ClipPlaylist)
 }
 
 func setupChatRoutes(app *fiber.App, chatHandler *handlers.ChatHandler, chatAuth fiber.Handler, adminAuth fiber.Handler, userAuth fiber.Handler) {
@@ -192,9 +255,39 @@ func setupChatRoutes(app *fiber.App, chatHandler *handlers.ChatHandler, chatAuth
 	chatRoutes.Get("/races/:id/chat/ws", chatAuth, chatHandler.HandleWebSocket)
 	chatRoutes.Get("/races/:id/chat/history", chatHandler.GetChatHistory)
 	chatRoutes.Get("/races/:id/chat/stats", chatHandler.GetChatStats)
+	chatRoutes.Get("/races/:id/chat/presence", chatHandler.GetChatPresence)
+	chatRoutes.Get("/races/:id/chat/polls", chatHandler.GetPollHistory)
+	chatRoutes.Get("/races/:id/chat/replay", chatHandler.GetReplayChat)
+	chatRoutes.Get("/races/:id/chat/messages/:messageId/replies", chatHandler.GetMessageReplies)
+	chatRoutes.Get("/races/:id/chat/emotes", chatHandler.GetRaceEmotes)
+
+	// Watch parties and whispers
+	chatRoutes.Get("/races/:id/chat/parties", userAuth, chatHandler.ListChatParties)
+	chatRoutes.Post("/races/:id/chat/parties", userAuth, chatHandler.CreateChatParty)
+	chatRoutes.Post("/races/:id/chat/parties/:code/members", userAuth, chatHandler.JoinChatParty)
+	chatRoutes.Delete("/races/:id/chat/parties/:code/members/me", userAuth, chatHandler.LeaveChatParty)
+	chatRoutes.Get("/races/:id/chat/parties/:code/messages", userAuth, chatHandler.GetChatPartyMessages)
+	chatRoutes.Get("/races/:id/chat/whispers/:userId", userAuth, chatHandler.GetWhispers)
 	chatRoutes.Post("/races/:id/chat/polls", adminAuth, chatHandler.CreatePoll)
 	chatRoutes.Post("/races/:id/chat/polls/:pollId/close", adminAuth, chatHandler.ClosePoll)
 	chatRoutes.Post("/races/:id/chat/polls/:pollId/vote", userAuth, chatHandler.CastPollVote)
+
+	// Chat moderation (admin only)
+	chatRoutes.Get("/races/:id/chat/moderation", adminAuth, chatHandler.GetModerationState)
+	chatRoutes.Delete("/races/:id/chat/moderation/messages/:messageId", adminAuth, chatHandler.DeleteChatMessage)
+	chatRoutes.Post("/races/:id/chat/moderation/timeouts", adminAuth, chatHandler.TimeoutChatUser)
+	chatRoutes.Post("/races/:id/chat/moderation/bans", adminAuth, chatHandler.BanChatUser)
+	chatRoutes.Delete("/races/:id/chat/moderation/restrictions/:userId", adminAuth, chatHandler.LiftChatRestriction)
+	chatRoutes.Put("/races/:id/chat/moderation/slow-mode", adminAuth, chatHandler.SetChatSlowMode)
+	chatRoutes.Get("/races/:id/chat/moderation/filter", adminAuth, chatHandler.GetChatFilter)
+	chatRoutes.Put("/races/:id/chat/moderation/filter", adminAuth, chatHandler.SetChatFilter)
+	chatRoutes.Delete("/races/:id/chat/moderation/filter", adminAuth, chatHandler.ResetChatFilter)
+
+	// Pinned message and announcements
+	chatRoutes.Get("/races/:id/chat/pin", chatHandler.GetChatPin)
+	chatRoutes.Put("/races/:id/chat/pin", adminAuth, chatHandler.PinChatMessage)
+	chatRoutes.Delete("/races/:id/chat/pin", adminAuth, chatHandler.UnpinChatMessage)
+	chatRoutes.Post("/races/:id/chat/announcements", adminAuth, chatHandler.PostChatAnnouncement)
 }
 
 func setupUserRoutes(app *fiber.App, authHandler *handlers.AuthHandler, paymentHandler *handlers.PaymentHandler, watchHandler *handlers.WatchHandler, userPrefsHandler *handlers.UserPreferencesHandler, userFavHandler *handlers.UserFavoritesHandler, watchHistoryHandler *handlers.WatchHistoryHandler, recommendationsHandler *handlers.RecommendationsHandler, missionsHandler *handlers.MissionsHandler, xpHandler *handlers.XPHandler, weeklyHandler *handlers.WeeklyHandler, achievementsHandler *handlers.AchievementsHandler, userAuth fiber.Handler, csrf fiber.Handler) {
@@ -266,7 +359,7 @@ func setupPredictionRoutes(app *fiber.App, predictionsHandler *handlers.Predicti
 	userPredictions.Get("/me/predictions", predictionsHandler.GetUserPredictions)
 }
 
-func setupAdminRoutes(app *fiber.App, adminHandler *handlers.AdminHandler, analyticsHandler *handlers.AnalyticsHandler, costHandler *handlers.CostHandler, adminAuth fiber.Handler, csrf fiber.Handler) {
+func setupAdminRoutes(app *fiber.App, adminHandler *handlers.AdminHandler, analyticsHandler *handlers.AnalyticsHandler, costHandler *handlers.CostHandler, chatHandler *handlers.ChatHandler, raceHandler *handlers.RaceHandler, adminAuth fiber.Handler, csrf fiber.Handler) {
 	// Admin routes (protected) with standard rate limiting and CSRF protection
 	admin := app.Group("/admin", adminAuth, middleware.StandardRateLimiter(), csrf)
 
@@ -278,6 +371,29 @@ func setupAdminRoutes(app *fiber.App, adminHandler *handlers.AdminHandler, analy
 	// Streams
 	admin.Post("/races/:id/stream", adminHandler.UpdateStream)
 	admin.Put("/races/:id/stream/status", adminHandler.UpdateStreamStatus)
+	admin.Get("/races/:id/stream/keys", adminHandler.ListStreamKeys)
+	admin.Post("/races/:id/stream/keys", adminHandler.CreateStreamKey)
+	admin.Get("/races/:id/stream/keys/events", adminHandler.ListStreamKeyEvents)
+	admin.Delete("/races/:id/stream/keys/:keyId", adminHandler.RevokeStreamKey)
+	admin.Get("/races/:id/stream/sources", adminHandler.ListStreamSources)
+	admin.Put("/races/:id/stream/sources", adminHandler.ReplaceStreamSources)
+	// Feeds: the routes above act on the race's primary feed
+	admin.Get("/races/:id/streams", adminHandler.ListStreamFeeds)
+	admin.Post("/races/:id/streams", adminHandler.CreateStreamFeed)
+	admin.Put("/races/:id/streams/:streamId", adminHandler.UpdateStreamFeed)
+	admin.Put("/races/:id/streams/:streamId/status", adminHandler.UpdateStreamStatus)
+	admin.Get("/races/:id/streams/:streamId/keys", adminHandler.ListStreamKeys)
+	admin.Post("/races/:id/streams/:streamId/keys", adminHandler.CreateStreamKey)
+	admin.Get("/races/:id/streams/:streamId/keys/events", adminHandler.ListStreamKeyEvents)
+	admin.Delete("/races/:id/streams/:streamId/keys/:keyId", adminHandler.RevokeStreamKey)
+	admin.Get("/races/:id/streams/:streamId/sources", adminHandler.ListStreamSources)
+	admin.Put("/races/:id/streams/:streamId/sources", adminHandler.ReplaceStreamSources)
+
+	// Race timeline markers
+	admin.Get("/races/:id/markers/suggestions", raceHandler.SuggestRaceMarkers)
+	admin.Post("/races/:id/markers", raceHandler.CreateRaceMarker)
+	admin.Put("/races/:id/markers/:markerId", raceHandler.UpdateRaceMarker)
+	admin.Delete("/races/:id/markers/:markerId", raceHandler.DeleteRaceMarker)
 
 	// Revenue
 	admin.Get("/revenue", adminHandler.GetRevenue)
@@ -302,6 +418,29 @@ func setupAdminRoutes(app *fiber.App, adminHandler *handlers.AdminHandler, analy
 	admin.Put("/costs/:id", costHandler.UpdateCost)
 	admin.Delete("/costs/:id", costHandler.DeleteCost)
 	admin.Get("/costs/races/:race_id", costHandler.GetCostsByRace)
+
+	// Chat emotes
+	admin.Get("/chat/emotes", chatHandler.ListChatEmotes)
+	admin.Post("/chat/emotes", chatHandler.CreateChatEmote)
+	admin.Put("/chat/emotes/:emoteId", chatHandler.UpdateChatEmote)
+	admin.Delete("/chat/emotes/:emoteId", chatHandler.DeleteChatEmote)
+
+	// Chat moderation queue (messages flagged by the content filter)
+	admin.Get("/chat/moderation-queue", chatHandler.ListModerationQueue)
+	admin.Post("/chat/moderation-queue/:itemId/review", chatHandler.ReviewModerationQueueItem)
+
+	// Global chat bans, which race moderation leaves in place
+	admin.Delete("/chat/restrictions/:userId", chatHandler.LiftGlobalChatRestriction)
+
+	// Chat send queue counters per room
+	admin.Get("/chat/delivery-stats", chatHandler.GetChatDeliveryStats)
+
+	// Chat hype timeline (reactions per minute)
+	admin.Get("/races/:id/chat/hype", chatHandler.GetChatHype)
+
+	// Chat transcripts and retention
+	admin.Get("/races/:id/chat/export", chatHandler.ExportChatTranscript)
+	admin.Get("/chat/retention/purges", chatHandler.ListChatRetentionPurges)
 }
 
 func setupAnalyticsRoutes(app *fiber.App, analyticsHandler *handlers.AnalyticsIngestionHandler) {
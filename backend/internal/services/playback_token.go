@@ -0,0 +1,179 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HLSPathPrefix is where the origin serves HLS. Playback URLs below it get a
+// token as their first path segment after PlaybackTokenPrefix, which keeps it
+// on the segment and variant requests players resolve relative to the
+// playlist.
+const (
+	HLSPathPrefix       = "/hls/"
+	PlaybackTokenPrefix = HLSPathPrefix + "t/"
+)
+
+var (
+	// ErrPlaybackTokenInvalid is returned for malformed or forged tokens, and
+	// for tokens used outside the stream they were issued for
+	ErrPlaybackTokenInvalid = errors.New("invalid playback token")
+	// ErrPlaybackTokenExpired is returned for tokens past their expiry
+	ErrPlaybackTokenExpired = errors.New("playback token expired")
+	// ErrPlaybackScopeTooBroad is returned for origin URLs directly in
+	// HLSPathPrefix, whose token would grant every stream
+	ErrPlaybackScopeTooBroad = errors.New("playback URL must be in a stream directory below " + HLSPathPrefix)
+)
+
+// PlaybackClaims is what a playback token grants: the HLS files under Scope,
+// to one user (empty for anonymous viewers of free races), until ExpiresAt
+type PlaybackClaims struct {
+	RaceID    string
+	UserID    string
+	Scope     string
+	ExpiresAt time.Time
+}
+
+// PlaybackSigner issues and checks HMAC-signed playback tokens
+type PlaybackSigner struct {
+	secret      []byte
+	ttl         time.Duration
+	originHosts map[string]bool
+	now         func() time.Time
+}
+
+// NewPlaybackSigner creates a signer whose tokens last ttl. Only URLs on
+// originHosts, the hosts of the origin checking the tokens, are signed; a
+// host without a port matches it on any port.
+func NewPlaybackSigner(secret string, ttl time.Duration, originHosts []string) *PlaybackSigner {
+	hosts := make(map[string]bool, len(originHosts))
+	for _, host := range originHosts {
+		hosts[strings.ToLower(host)] = true
+	}
+	return &PlaybackSigner{secret: []byte(secret), ttl: ttl, originHosts: hosts, now: time.Now}
+}
+
+// SignURL returns rawURL with a token for the race and user. URLs that are
+// not served from HLSPathPrefix of an origin host (external CDNs, Owncast,
+// YouTube) are returned as is, with a zero expiry. Origin URLs directly in
+// HLSPathPrefix are refused with ErrPlaybackScopeTooBroad.
+func (s *PlaybackSigner) SignURL(rawURL, raceID, userID string) (string, time.Time, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse playback URL: %w", err)
+	}
+	if !s.isOrigin(u) || !strings.HasPrefix(u.Path, HLSPathPrefix) || strings.HasPrefix(u.Path, PlaybackTokenPrefix) {
+		return rawURL, time.Time{}, nil
+	}
+
+	claims := PlaybackClaims{
+		RaceID:    raceID,
+		UserID:    userID,
+		Scope:     path.Dir(u.Path) + "/",
+		ExpiresAt: s.now().Add(s.ttl).Truncate(time.Second),
+	}
+	if !validScope(claims.Scope) {
+		return "", time.Time{}, ErrPlaybackScopeTooBroad
+	}
+	u.Path = PlaybackTokenPrefix + s.Sign(claims) + "/" + strings.TrimPrefix(u.Path, HLSPathPrefix)
+	return u.String(), claims.ExpiresAt, nil
+}
+
+// Sign returns the token for claims
+func (s *PlaybackSigner) Sign(claims PlaybackClaims) string {
+	payload := strings.Join([]string{
+		claims.RaceID,
+		claims.UserID,
+		strconv.FormatInt(claims.ExpiresAt.Unix(), 10),
+		claims.Scope,
+	}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.signature(payload)
+}
+
+// Verify checks a token's signature and expiry and returns its claims
+func (s *PlaybackSigner) Verify(token string) (*PlaybackClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrPlaybackTokenInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrPlaybackTokenInvalid
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(signature), []byte(s.signature(payload))) {
+		return nil, ErrPlaybackTokenInvalid
+	}
+
+	fields := strings.SplitN(payload, "|", 4)
+	if len(fields) != 4 {
+		return nil, ErrPlaybackTokenInvalid
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, ErrPlaybackTokenInvalid
+	}
+
+	claims := &PlaybackClaims{
+		RaceID:    fields[0],
+		UserID:    fields[1],
+		Scope:     fields[3],
+		ExpiresAt: time.Unix(expires, 0),
+	}
+	if !s.now().Before(claims.ExpiresAt) {
+		return claims, ErrPlaybackTokenExpired
+	}
+	return claims, nil
+}
+
+// Authorize checks a request for a tokenized HLS path, as in
+// /hls/t/<token>/<stream>/index.m3u8, and returns the token's claims
+func (s *PlaybackSigner) Authorize(requestPath string) (*PlaybackClaims, error) {
+	rest, ok := strings.CutPrefix(requestPath, PlaybackTokenPrefix)
+	if !ok {
+		return nil, ErrPlaybackTokenInvalid
+	}
+	token, file, ok := strings.Cut(rest, "/")
+	if !ok {
+		return nil, ErrPlaybackTokenInvalid
+	}
+
+	claims, err := s.Verify(token)
+	if err != nil {
+		return claims, err
+	}
+	if !validScope(claims.Scope) {
+		return claims, ErrPlaybackTokenInvalid
+	}
+
+	// Clean resolves dot segments so a token can't reach outside its scope
+	if !strings.HasPrefix(path.Clean(HLSPathPrefix+file), claims.Scope) {
+		return claims, ErrPlaybackTokenInvalid
+	}
+	return claims, nil
+}
+
+// isOrigin reports whether u is on one of the origin hosts
+func (s *PlaybackSigner) isOrigin(u *url.URL) bool {
+	return s.originHosts[strings.ToLower(u.Host)] || s.originHosts[strings.ToLower(u.Hostname())]
+}
+
+// validScope reports whether scope is a stream directory below HLSPathPrefix
+// rather than HLSPathPrefix itself
+func validScope(scope string) bool {
+	return strings.HasPrefix(scope, HLSPathPrefix) && scope != HLSPathPrefix
+}
+
+func (s *PlaybackSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
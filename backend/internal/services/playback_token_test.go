@@ -0,0 +1,104 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlaybackSigner(now time.Time) *PlaybackSigner {
+	s := NewPlaybackSigner("playback-secret", 10*time.Minute, []string{"origin.example.com"})
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestPlaybackSigner_SignURL(t *testing.T) {
+	now := time.Date(2024, 7, 14, 13, 0, 0, 0, time.UTC)
+	s := newTestPlaybackSigner(now)
+
+	signed, expiresAt, err := s.SignURL("https://origin.example.com/hls/race-1/index.m3u8", "r1", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(10*time.Minute), expiresAt)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "origin.example.com", u.Host)
+	assert.True(t, strings.HasPrefix(u.Path, PlaybackTokenPrefix))
+	assert.True(t, strings.HasSuffix(u.Path, "/race-1/index.m3u8"))
+
+	claims, err := s.Authorize(u.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "r1", claims.RaceID)
+	assert.Equal(t, "u1", claims.UserID)
+	assert.Equal(t, "/hls/race-1/", claims.Scope)
+
+	// Segments resolved relative to the playlist carry the token
+	segment := strings.TrimSuffix(u.Path, "index.m3u8") + "720p/seg-42.ts"
+	_, err = s.Authorize(segment)
+	assert.NoError(t, err)
+
+	// Signing again doesn't nest tokens
+	again, _, err := s.SignURL(signed, "r1", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, signed, again)
+
+	// Other URLs are left alone, including /hls/ on hosts other than the origin
+	for _, external := range []string{
+		"https://vz-123.b-cdn.net/abc/playlist.m3u8",
+		"http://owncast.example.com:8080/hls/stream.m3u8",
+		"https://origin.example.com.evil.net/hls/race-1/index.m3u8",
+	} {
+		unsigned, expiresAt, err := s.SignURL(external, "r1", "u1")
+		require.NoError(t, err)
+		assert.Equal(t, external, unsigned)
+		assert.True(t, expiresAt.IsZero())
+	}
+
+	// A host without a port matches it on any port
+	signed, _, err = s.SignURL("http://origin.example.com:8080/hls/race-1/index.m3u8", "r1", "u1")
+	require.NoError(t, err)
+	assert.Contains(t, signed, PlaybackTokenPrefix)
+
+	// A token for a playlist directly in /hls/ would grant every stream
+	_, _, err = s.SignURL("http://origin.example.com/hls/stream.m3u8", "r1", "u1")
+	assert.ErrorIs(t, err, ErrPlaybackScopeTooBroad)
+}
+
+func TestPlaybackSigner_AuthorizeRefusals(t *testing.T) {
+	now := time.Date(2024, 7, 14, 13, 0, 0, 0, time.UTC)
+	s := newTestPlaybackSigner(now)
+
+	token := s.Sign(PlaybackClaims{RaceID: "r1", UserID: "u1", Scope: "/hls/race-1/", ExpiresAt: now.Add(time.Minute)})
+	expired := s.Sign(PlaybackClaims{RaceID: "r1", UserID: "u1", Scope: "/hls/race-1/", ExpiresAt: now})
+	forged := NewPlaybackSigner("other-secret", time.Minute, nil).Sign(PlaybackClaims{RaceID: "r1", Scope: "/hls/race-1/", ExpiresAt: now.Add(time.Minute)})
+	everyStream := s.Sign(PlaybackClaims{RaceID: "r1", UserID: "u1", Scope: HLSPathPrefix, ExpiresAt: now.Add(time.Minute)})
+	encoded, signature, _ := strings.Cut(token, ".")
+	tampered := encoded[:len(encoded)-2] + "AA." + signature
+
+	tests := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{"no token", "/hls/race-1/index.m3u8", ErrPlaybackTokenInvalid},
+		{"token only", PlaybackTokenPrefix + token, ErrPlaybackTokenInvalid},
+		{"garbage token", PlaybackTokenPrefix + "garbage/race-1/index.m3u8", ErrPlaybackTokenInvalid},
+		{"forged token", PlaybackTokenPrefix + forged + "/race-1/index.m3u8", ErrPlaybackTokenInvalid},
+		{"tampered token", PlaybackTokenPrefix + tampered + "/race-1/index.m3u8", ErrPlaybackTokenInvalid},
+		{"other stream", PlaybackTokenPrefix + token + "/race-2/index.m3u8", ErrPlaybackTokenInvalid},
+		{"escaping scope", PlaybackTokenPrefix + token + "/race-1/../race-2/index.m3u8", ErrPlaybackTokenInvalid},
+		{"scope of every stream", PlaybackTokenPrefix + everyStream + "/race-1/index.m3u8", ErrPlaybackTokenInvalid},
+		{"expired", PlaybackTokenPrefix + expired + "/race-1/index.m3u8", ErrPlaybackTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Authorize(tt.path)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
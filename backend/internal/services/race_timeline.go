@@ -0,0 +1,144 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+const (
+	// Buckets whose activity is this many standard deviations above the
+	// mean are spikes
+	spikeMinScore = 2.0
+	// and need at least this many chat messages and playback starts, so a
+	// quiet race's every message isn't one
+	spikeMinActivity = 5
+	// Suggestions are at least this far apart, and from existing markers
+	suggestionSpacing = 2 * time.Minute
+	// Viewers react to what they saw a stream delay and a moment earlier,
+	// so suggestions start this long before their spike
+	suggestionLead = 30 * time.Second
+)
+
+// RecordingOffset returns how far into the recorded segments the wall clock
+// time at was recorded. Times in a gap between segments map to the start of
+// the next one. It returns false for times outside the recording.
+func RecordingOffset(segments []models.StreamSegment, at time.Time) (float64, bool) {
+	var offset float64
+	for _, segment := range segments {
+		if at.Before(segment.ProgramDateTime) {
+			return offset, offset > 0
+		}
+		if at.Before(segment.ProgramDateTime.Add(seconds(segment.Duration))) {
+			return offset + at.Sub(segment.ProgramDateTime).Seconds(), true
+		}
+		offset += segment.Duration
+	}
+	return 0, false
+}
+
+// ClipSegments returns the segments playing from start to end seconds into
+// the recording. Segments can't be cut, so the clip begins at the start of
+// the segment start is in.
+func ClipSegments(segments []models.StreamSegment, start, end float64) []models.StreamSegment {
+	var clip []models.StreamSegment
+	var offset float64
+	for _, segment := range segments {
+		if offset >= end {
+			break
+		}
+		if offset+segment.Duration > start {
+			clip = append(clip, segment)
+		}
+		offset += segment.Duration
+	}
+	return clip
+}
+
+// SuggestMarkers returns the spikes in viewer activity during the recording
+// as marker suggestions, most outstanding first. Each bucket's chat messages
+// and playback starts are scored by how far they stand out from the race's
+// mean; spikes near a marker in existing, or near a better spike, are left
+// out.
+func SuggestMarkers(segments []models.StreamSegment, buckets []models.ActivityBucket, existing []models.RaceMarker, limit int) []models.MarkerSuggestion {
+	chat := make([]float64, len(buckets))
+	plays := make([]float64, len(buckets))
+	for i, b := range buckets {
+		chat[i], plays[i] = float64(b.ChatMessages), float64(b.PlaybackStarts)
+	}
+	chatScore, playScore := standardScorer(chat), standardScorer(plays)
+
+	candidates := make([]models.MarkerSuggestion, 0)
+	for i, b := range buckets {
+		if b.ChatMessages+b.PlaybackStarts < spikeMinActivity {
+			continue
+		}
+		score := chatScore(chat[i]) + playScore(plays[i])
+		if score < spikeMinScore {
+			continue
+		}
+		offset, ok := RecordingOffset(segments, b.Start)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, models.MarkerSuggestion{
+			Offset:         math.Max(offset-suggestionLead.Seconds(), 0),
+			At:             b.Start,
+			ChatMessages:   b.ChatMessages,
+			PlaybackStarts: b.PlaybackStarts,
+			Score:          math.Round(score*100) / 100,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	taken := make([]float64, 0, len(existing)+limit)
+	for _, marker := range existing {
+		taken = append(taken, marker.Offset)
+	}
+	suggestions := make([]models.MarkerSuggestion, 0, limit)
+	for _, candidate := range candidates {
+		if len(suggestions) >= limit {
+			break
+		}
+		if nearAny(candidate.Offset, taken, suggestionSpacing.Seconds()) {
+			continue
+		}
+		suggestions = append(suggestions, candidate)
+		taken = append(taken, candidate.Offset)
+	}
+	return suggestions
+}
+
+// standardScorer returns how many standard deviations a value is above the
+// mean of values, or 0 when they don't vary
+func standardScorer(values []float64) func(float64) float64 {
+	if len(values) == 0 {
+		return func(float64) float64 { return 0 }
+	}
+	var sum, squares float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(values)))
+	return func(v float64) float64 {
+		if stddev == 0 {
+			return 0
+		}
+		return (v - mean) / stddev
+	}
+}
+
+func nearAny(offset float64, offsets []float64, distance float64) bool {
+	for _, o := range offsets {
+		if math.Abs(offset-o) < distance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingOffset(t *testing.T) {
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	segments := recordedSegments(start, 10)
+	// An encoder reconnect a minute after the first ten segments
+	resumed := start.Add(2 * time.Minute)
+	segments = append(segments, models.StreamSegment{Sequence: 10, Duration: 6, ProgramDateTime: resumed, Discontinuity: true})
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		want   float64
+		wantOK bool
+	}{
+		{"first segment", start, 0, true},
+		{"within a segment", start.Add(15 * time.Second), 15, true},
+		{"gap maps to the next segment", start.Add(90 * time.Second), 60, true},
+		{"after the gap", resumed.Add(2 * time.Second), 62, true},
+		{"before the recording", start.Add(-time.Second), 0, false},
+		{"after the recording", resumed.Add(6 * time.Second), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, ok := RecordingOffset(segments, tt.at)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.InDelta(t, tt.want, offset, 1e-9)
+			}
+		})
+	}
+}
+
+func TestClipSegments(t *testing.T) {
+	segments := recordedSegments(time.Now(), 10)
+
+	clip := ClipSegments(segments, 14, 30)
+	require.Len(t, clip, 3, "the segment the start is in is included whole")
+	assert.Equal(t, int64(2), clip[0].Sequence)
+	assert.Equal(t, int64(4), clip[2].Sequence)
+
+	assert.Len(t, ClipSegments(segments, 0, 1000), 10)
+	assert.Empty(t, ClipSegments(segments, 60, 90), "past the end of the recording")
+}
+
+func TestSuggestMarkers(t *testing.T) {
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	// An hour recorded, in minute buckets
+	segments := recordedSegments(start, 600)
+	buckets := make([]models.ActivityBucket, 60)
+	for i := range buckets {
+		buckets[i] = models.ActivityBucket{Start: start.Add(time.Duration(i) * time.Minute), ChatMessages: 4, PlaybackStarts: 1}
+	}
+	buckets[10].ChatMessages = 60 // crash
+	buckets[11].ChatMessages = 40 // still talking about it
+	buckets[30].PlaybackStarts = 25
+	buckets[30].ChatMessages = 20 // attack, viewers tuning in
+	buckets[50].ChatMessages = 50 // already marked
+
+	existing := []models.RaceMarker{{Offset: 50*60 - 15}}
+	suggestions := SuggestMarkers(segments, buckets, existing, 5)
+
+	require.Len(t, suggestions, 2)
+	offsets := []float64{suggestions[0].Offset, suggestions[1].Offset}
+	assert.ElementsMatch(t, []float64{10*60 - 30, 30*60 - 30}, offsets, "suggestions lead their spike")
+	assert.GreaterOrEqual(t, suggestions[0].Score, suggestions[1].Score)
+	for _, s := range suggestions {
+		assert.GreaterOrEqual(t, s.Score, spikeMinScore)
+	}
+
+	assert.Len(t, SuggestMarkers(segments, buckets, existing, 1), 1)
+	assert.Empty(t, SuggestMarkers(nil, buckets, nil, 5), "spikes outside the recording")
+}
+
+func TestSuggestMarkers_QuietRace(t *testing.T) {
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	buckets := make([]models.ActivityBucket, 30)
+	for i := range buckets {
+		buckets[i] = models.ActivityBucket{Start: start.Add(time.Duration(i) * time.Minute)}
+	}
+	buckets[5].ChatMessages = 3
+
+	assert.Empty(t, SuggestMarkers(recordedSegments(start, 300), buckets, nil, 5))
+}
@@ -10,6 +10,7 @@ type RecommendationService struct {
 	watchHistoryRepo   *repository.WatchHistoryRepository
 	userFavRepo        *repository.UserFavoriteRepository
 	streamRepo         *repository.StreamRepository
+	vodRepo            *repository.VODAssetRepository
 }
 
 func NewRecommendationService(
@@ -17,12 +18,14 @@ func NewRecommendationService(
 	watchHistoryRepo *repository.WatchHistoryRepository,
 	userFavRepo *repository.UserFavoriteRepository,
 	streamRepo *repository.StreamRepository,
+	vodRepo *repository.VODAssetRepository,
 ) *RecommendationService {
 	return &RecommendationService{
 		raceRepo:         raceRepo,
 		watchHistoryRepo: watchHistoryRepo,
 		userFavRepo:      userFavRepo,
 		streamRepo:       streamRepo,
+		vodRepo:          vodRepo,
 	}
 }
 
@@ -124,7 +127,8 @@ func (s *RecommendationService) GetUpcomingRacesForUser(userID string, limit int
 	return result, nil
 }
 
-// GetRecommendedReplays returns recommended replays based on watch history
+// GetRecommendedReplays returns races with a replay, those similar to the
+// user's watch history first
 func (s *RecommendationService) GetRecommendedReplays(userID string, limit int) ([]models.Race, error) {
 	// Get watch history
 	history, err := s.watchHistoryRepo.GetByUserID(userID, 10, 0)
@@ -133,8 +137,8 @@ func (s *RecommendationService) GetRecommendedReplays(userID string, limit int)
 	}
 
 	if len(history) == 0 {
-		// No history, return popular races (for now, just upcoming)
-		return s.raceRepo.GetUpcomingRaces(limit)
+		// No history, return the latest replays
+		return s.raceRepo.GetRacesWithReplays(limit)
 	}
 
 	// Get categories from watch history
@@ -185,6 +189,36 @@ func (s *RecommendationService) GetRecommendedReplays(userID string, limit int)
 		}
 	}
 
+	// Only races that can be replayed
+	ids := make([]string, 0, len(recommended))
+	for _, race := range recommended {
+		ids = append(ids, race.ID)
+	}
+	withReplays, err := s.vodRepo.RaceIDsWithReplays(ids)
+	if err != nil {
+		return nil, err
+	}
+	replayable := recommended[:0]
+	for _, race := range recommended {
+		if withReplays[race.ID] {
+			replayable = append(replayable, race)
+		}
+	}
+	recommended = replayable
+
+	// Fill up with the latest replays
+	if len(recommended) < limit {
+		latest, err := s.raceRepo.GetRacesWithReplays(limit)
+		if err == nil {
+			for _, race := range latest {
+				if !seen[race.ID] {
+					seen[race.ID] = true
+					recommended = append(recommended, race)
+				}
+			}
+		}
+	}
+
 	if len(recommended) > limit {
 		recommended = recommended[:limit]
 	}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/hls"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+)
+
+// VODStore keeps the VOD assets of ended streams
+type VODStore interface {
+	ListDue(settle time.Duration) ([]*models.Stream, error)
+	Claim(stream *models.Stream, source string) (*models.VODAsset, error)
+	Fail(id, reason string) error
+	Complete(asset *models.VODAsset) (*models.StreamStatusChange, error)
+}
+
+// SegmentCatalog holds the segments replay playlists are built from
+type SegmentCatalog interface {
+	ListAll(streamID string) ([]models.StreamSegment, error)
+	InsertSegments(segments []models.StreamSegment) error
+}
+
+// RecordingSource finds the segments recorded of a stream
+type RecordingSource interface {
+	// Name is stored as the VOD asset's source
+	Name() string
+	Segments(stream *models.Stream) ([]models.StreamSegment, error)
+}
+
+var errNoSegments = errors.New("no recorded segments")
+
+// The thumbnail is taken a tenth of the way in, past the pre-race
+// countdown, and at most this far in
+const maxThumbnailOffset = 10 * time.Minute
+
+// RecordingManager turns ended streams into replays. Once a stream has been
+// ended for the settle delay, so an encoder that reconnects keeps the stream
+// live instead, it registers the stream's recorded segments as a VOD asset
+// and moves the stream to vod_ready.
+type RecordingManager struct {
+	store    VODStore
+	source   RecordingSource
+	catalog  SegmentCatalog
+	settle   time.Duration
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRecordingManager creates a manager checking for ended streams every
+// interval. Segments found by source are added to catalog, which replay
+// playlists are built from.
+func NewRecordingManager(store VODStore, source RecordingSource, catalog SegmentCatalog, settle, interval time.Duration) *RecordingManager {
+	return &RecordingManager{
+		store:    store,
+		source:   source,
+		catalog:  catalog,
+		settle:   settle,
+		interval: interval,
+	}
+}
+
+// Start checks now and then every interval, calling onChange for each stream
+// moved to vod_ready
+func (m *RecordingManager) Start(onChange func(*models.StreamStatusChange)) {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.tick(onChange)
+
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the manager
+func (m *RecordingManager) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.done
+		m.stop = nil
+	}
+}
+
+func (m *RecordingManager) tick(onChange func(*models.StreamStatusChange)) {
+	streams, err := m.store.ListDue(m.settle)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list streams due for VOD")
+		return
+	}
+
+	for _, stream := range streams {
+		entry := logger.WithFields(map[string]interface{}{
+			"race_id":   stream.RaceID,
+			"stream_id": stream.ID,
+		})
+
+		asset, change, err := m.Register(stream)
+		switch {
+		case errors.Is(err, repository.ErrInvalidStreamTransition):
+			entry.Info("Stream went live again before its replay was ready")
+		case err != nil:
+			entry.WithError(err).Warn("Failed to register stream replay")
+		case asset != nil:
+			entry.WithFields(map[string]interface{}{
+				"duration_seconds": asset.Duration,
+				"segments":         asset.SegmentCount,
+			}).Info("Stream replay ready")
+		}
+		if change != nil {
+			onChange(change)
+		}
+	}
+}
+
+// Register registers the stream's recorded segments as its VOD asset and
+// moves it to vod_ready. It returns a nil asset when another manager is
+// already registering it. Streams without segments get a failed asset.
+func (m *RecordingManager) Register(stream *models.Stream) (*models.VODAsset, *models.StreamStatusChange, error) {
+	asset, err := m.store.Claim(stream, m.source.Name())
+	if err != nil || asset == nil {
+		return nil, nil, err
+	}
+
+	segments, err := m.source.Segments(stream)
+	if err == nil && len(segments) == 0 {
+		err = errNoSegments
+	}
+	// Segments recorded from the live playlist are already in the catalog
+	if err == nil && m.source.Name() != models.VODSourceSegments {
+		err = m.catalog.InsertSegments(segments)
+	}
+	if err != nil {
+		if failErr := m.store.Fail(asset.ID, err.Error()); failErr != nil {
+			logger.WithError(failErr).WithField("stream_id", stream.ID).Error("Failed to record VOD failure")
+		}
+		return nil, nil, err
+	}
+
+	summarizeVOD(asset, segments)
+	change, err := m.store.Complete(asset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return asset, change, nil
+}
+
+// summarizeVOD sets the asset's duration, segment count, thumbnail offset and
+// recording times from its segments
+func summarizeVOD(asset *models.VODAsset, segments []models.StreamSegment) {
+	var duration float64
+	for _, segment := range segments {
+		duration += segment.Duration
+	}
+	asset.Duration = duration
+	asset.SegmentCount = len(segments)
+
+	thumbnail := math.Min(duration/10, maxThumbnailOffset.Seconds())
+	asset.ThumbnailOffset = &thumbnail
+
+	from := segments[0].ProgramDateTime
+	last := segments[len(segments)-1]
+	to := last.ProgramDateTime.Add(seconds(last.Duration))
+	asset.RecordedFrom, asset.RecordedTo = &from, &to
+}
+
+// RecordedSegmentSource reads the segments the SegmentRecorder recorded
+// while the stream was live
+type RecordedSegmentSource struct {
+	catalog SegmentCatalog
+}
+
+// NewRecordedSegmentSource creates a source reading catalog
+func NewRecordedSegmentSource(catalog SegmentCatalog) *RecordedSegmentSource {
+	return &RecordedSegmentSource{catalog: catalog}
+}
+
+func (s *RecordedSegmentSource) Name() string {
+	return models.VODSourceSegments
+}
+
+func (s *RecordedSegmentSource) Segments(stream *models.Stream) ([]models.StreamSegment, error) {
+	return s.catalog.ListAll(stream.ID)
+}
+
+// Fragment length nginx-rtmp is configured with (hls_fragment), for segments
+// no playlist gives a duration for
+const defaultSegmentDuration = 6.0
+
+// DirectorySegmentSource reads segments from a directory laid out like the
+// origin's hls_path, for origins the recorder can't poll and for testing: the
+// stream with origin playlist /hls/<name>/index.m3u8 has its files in
+// <root>/<name>/. Files named by their sequence number (<n>.ts, as nginx-rtmp
+// writes them) are its segments. Their durations and program date times come
+// from the media playlists in the directory that still list them. Otherwise a
+// segment lasts the playlists' target duration and starts where the one before
+// it ended, or, after a gap, its duration before its file was last written.
+type DirectorySegmentSource struct {
+	root string
+}
+
+// NewDirectorySegmentSource creates a source reading below root
+func NewDirectorySegmentSource(root string) *DirectorySegmentSource {
+	return &DirectorySegmentSource{root: root}
+}
+
+func (s *DirectorySegmentSource) Name() string {
+	return models.VODSourceDirectory
+}
+
+func (s *DirectorySegmentSource) Segments(stream *models.Stream) ([]models.StreamSegment, error) {
+	dir, err := s.streamDir(stream)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment directory: %w", err)
+	}
+
+	listed, target := readPlaylists(dir, entries)
+
+	type file struct {
+		name     string
+		sequence int64
+		modTime  time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		name := entry.Name()
+		base, ok := strings.CutSuffix(name, ".ts")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		sequence, err := strconv.ParseInt(base, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read segment file: %w", err)
+		}
+		files = append(files, file{name: name, sequence: sequence, modTime: info.ModTime().UTC()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].sequence < files[j].sequence })
+
+	segments := make([]models.StreamSegment, 0, len(files))
+	for i, f := range files {
+		segment := models.StreamSegment{
+			StreamID: stream.ID,
+			Sequence: f.sequence,
+			URI:      f.name,
+			Duration: target,
+		}
+		if l, ok := listed[f.name]; ok {
+			segment.Duration = l.Duration
+			segment.ProgramDateTime = l.ProgramDateTime
+			segment.Discontinuity = l.Discontinuity
+		}
+		contiguous := i > 0 && f.sequence == files[i-1].sequence+1
+		if i > 0 && !contiguous {
+			segment.Discontinuity = true
+		}
+		if segment.ProgramDateTime.IsZero() {
+			if contiguous && !segment.Discontinuity {
+				previous := segments[i-1]
+				segment.ProgramDateTime = previous.ProgramDateTime.Add(seconds(previous.Duration))
+			} else {
+				segment.ProgramDateTime = f.modTime.Add(-seconds(segment.Duration))
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// streamDir returns the directory of the stream's origin playlist below root
+func (s *DirectorySegmentSource) streamDir(stream *models.Stream) (string, error) {
+	if stream.OriginURL == nil {
+		return "", errors.New("stream has no origin URL")
+	}
+	u, err := url.Parse(*stream.OriginURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse origin URL: %w", err)
+	}
+	rel, ok := strings.CutPrefix(path.Clean(u.Path), HLSPathPrefix)
+	if !ok {
+		return "", errors.New("origin URL is not below " + HLSPathPrefix)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(path.Dir(rel))), nil
+}
+
+// readPlaylists returns the segments listed by the media playlists among
+// entries, by URI, and their longest target duration
+func readPlaylists(dir string, entries []os.DirEntry) (map[string]hls.Segment, float64) {
+	listed := map[string]hls.Segment{}
+	target := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".m3u8") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		playlist, err := hls.Parse(f)
+		f.Close()
+		if err != nil {
+			// Master playlists list no segments
+			continue
+		}
+		for _, segment := range playlist.Segments {
+			listed[segment.URI] = segment
+		}
+		if playlist.TargetDuration > target {
+			target = playlist.TargetDuration
+		}
+	}
+
+	if target == 0 {
+		return listed, defaultSegmentDuration
+	}
+	return listed, float64(target)
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/cyclingstream/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSegmentFile(t *testing.T, dir, name string, modTime time.Time) {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, []byte("ts"), 0o644))
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+}
+
+func TestDirectorySegmentSource(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "race-1")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	written := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"3.ts", "4.ts", "5.ts", "9.ts"} {
+		writeSegmentFile(t, dir, name, written.Add(time.Duration(i)*4*time.Second))
+	}
+	writeSegmentFile(t, dir, "poster.jpg", written)
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:4\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2026-07-05T11:00:00.000Z\n#EXTINF:3.5,\n4.ts\n#EXTINF:4.0,\n5.ts\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(playlist), 0o644))
+
+	origin := "https://cdn.example.com/hls/race-1/index.m3u8"
+	segments, err := NewDirectorySegmentSource(root).Segments(&models.Stream{ID: "s1", OriginURL: &origin})
+	require.NoError(t, err)
+	require.Len(t, segments, 4)
+
+	listed := time.Date(2026, 7, 5, 11, 0, 0, 0, time.UTC)
+	assert.Equal(t, int64(3), segments[0].Sequence)
+	assert.Equal(t, 4.0, segments[0].Duration, "unlisted segments last the target duration")
+	assert.Equal(t, written.Add(-4*time.Second), segments[0].ProgramDateTime)
+	assert.Equal(t, 3.5, segments[1].Duration)
+	assert.Equal(t, listed, segments[1].ProgramDateTime)
+	assert.Equal(t, listed.Add(3500*time.Millisecond), segments[2].ProgramDateTime)
+	assert.False(t, segments[2].Discontinuity)
+	assert.Equal(t, "9.ts", segments[3].URI)
+	assert.True(t, segments[3].Discontinuity, "missing segments are a discontinuity")
+	for _, segment := range segments {
+		assert.Equal(t, "s1", segment.StreamID)
+	}
+}
+
+func TestDirectorySegmentSource_Refusals(t *testing.T) {
+	source := NewDirectorySegmentSource(t.TempDir())
+
+	for name, origin := range map[string]string{
+		"outside the origin": "https://cdn.example.com/live/race-1/index.m3u8",
+		"traversal":          "https://cdn.example.com/hls/../etc/index.m3u8",
+		"missing directory":  "https://cdn.example.com/hls/race-1/index.m3u8",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := source.Segments(&models.Stream{ID: "s1", OriginURL: &origin})
+			assert.Error(t, err)
+		})
+	}
+}
+
+type fakeVODStore struct {
+	due       []*models.Stream
+	claimed   bool
+	failed    string
+	completed *models.VODAsset
+	err       error
+}
+
+func (f *fakeVODStore) ListDue(settle time.Duration) ([]*models.Stream, error) {
+	return f.due, nil
+}
+
+func (f *fakeVODStore) Claim(stream *models.Stream, source string) (*models.VODAsset, error) {
+	if f.claimed {
+		return nil, nil
+	}
+	f.claimed = true
+	return &models.VODAsset{ID: "v1", StreamID: stream.ID, RaceID: stream.RaceID, Status: models.VODAssetProcessing, Source: source}, nil
+}
+
+func (f *fakeVODStore) Fail(id, reason string) error {
+	f.failed = reason
+	return nil
+}
+
+func (f *fakeVODStore) Complete(asset *models.VODAsset) (*models.StreamStatusChange, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.completed = asset
+	return &models.StreamStatusChange{StreamID: asset.StreamID, From: models.StreamStatusEnded, To: models.StreamStatusVODReady}, nil
+}
+
+type fakeRecordingSource struct {
+	name     string
+	segments []models.StreamSegment
+}
+
+func (f *fakeRecordingSource) Name() string { return f.name }
+
+func (f *fakeRecordingSource) Segments(stream *models.Stream) ([]models.StreamSegment, error) {
+	return f.segments, nil
+}
+
+func recordedSegments(start time.Time, count int) []models.StreamSegment {
+	segments := make([]models.StreamSegment, count)
+	for i := range segments {
+		segments[i] = models.StreamSegment{
+			StreamID:        "s1",
+			Sequence:        int64(i),
+			Duration:        6,
+			ProgramDateTime: start.Add(time.Duration(i) * 6 * time.Second),
+		}
+	}
+	return segments
+}
+
+func TestRecordingManager_Register(t *testing.T) {
+	logger.Init("test")
+	start := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	stream := &models.Stream{ID: "s1", RaceID: "r1"}
+
+	store := &fakeVODStore{}
+	catalog := &fakeSegmentStore{}
+	source := &fakeRecordingSource{name: models.VODSourceDirectory, segments: recordedSegments(start, 20)}
+	manager := NewRecordingManager(store, source, catalog, time.Minute, time.Minute)
+
+	asset, change, err := manager.Register(stream)
+	require.NoError(t, err)
+	require.NotNil(t, change)
+	assert.Equal(t, models.StreamStatusVODReady, change.To)
+	assert.Len(t, catalog.inserted, 20, "directory segments are added to the catalog")
+
+	assert.Same(t, store.completed, asset)
+	assert.Equal(t, 120.0, asset.Duration)
+	assert.Equal(t, 20, asset.SegmentCount)
+	assert.Equal(t, 12.0, *asset.ThumbnailOffset)
+	assert.Equal(t, start, *asset.RecordedFrom)
+	assert.Equal(t, start.Add(2*time.Minute), *asset.RecordedTo)
+
+	asset, change, err = manager.Register(stream)
+	assert.NoError(t, err)
+	assert.Nil(t, asset, "claimed assets are skipped")
+	assert.Nil(t, change)
+}
+
+func TestRecordingManager_RegisterRecordedSegments(t *testing.T) {
+	logger.Init("test")
+	long := recordedSegments(time.Now(), 2000)
+	catalog := &fakeSegmentStore{}
+	store := &fakeVODStore{}
+	manager := NewRecordingManager(store, &fakeRecordingSource{name: models.VODSourceSegments, segments: long}, catalog, time.Minute, time.Minute)
+
+	asset, _, err := manager.Register(&models.Stream{ID: "s1", RaceID: "r1"})
+	require.NoError(t, err)
+	assert.Empty(t, catalog.inserted, "recorded segments are already in the catalog")
+	assert.Equal(t, maxThumbnailOffset.Seconds(), *asset.ThumbnailOffset)
+}
+
+func TestRecordingManager_RegisterFailures(t *testing.T) {
+	logger.Init("test")
+	stream := &models.Stream{ID: "s1", RaceID: "r1"}
+
+	store := &fakeVODStore{}
+	manager := NewRecordingManager(store, &fakeRecordingSource{name: models.VODSourceSegments}, &fakeSegmentStore{}, time.Minute, time.Minute)
+	_, _, err := manager.Register(stream)
+	assert.ErrorIs(t, err, errNoSegments)
+	assert.Equal(t, errNoSegments.Error(), store.failed)
+
+	store = &fakeVODStore{err: repository.ErrInvalidStreamTransition}
+	source := &fakeRecordingSource{name: models.VODSourceSegments, segments: recordedSegments(time.Now(), 3)}
+	manager = NewRecordingManager(store, source, &fakeSegmentStore{}, time.Minute, time.Minute)
+	_, change, err := manager.Register(stream)
+	assert.True(t, errors.Is(err, repository.ErrInvalidStreamTransition))
+	assert.Nil(t, change)
+	assert.Empty(t, store.failed, "streams live again aren't failed")
+}
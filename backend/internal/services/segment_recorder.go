@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/hls"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// SegmentStore lists the streams to record and keeps their segments
+type SegmentStore interface {
+	ListRecordingTargets() ([]*models.Stream, error)
+	LatestSegment(streamID string) (*models.StreamSegment, error)
+	InsertSegments(segments []models.StreamSegment) error
+}
+
+// Origin playlists only hold the last few segments
+const recorderMaxBytes = 1 << 20
+
+// SegmentRecorder polls the origin playlist of each live stream and records
+// its new segments with their program date time, which the DVR playlist is
+// built from. The origin keeps the segment files (hls_cleanup off), so the
+// recorded URIs stay playable after they leave its live playlist.
+type SegmentRecorder struct {
+	store    SegmentStore
+	signer   *PlaybackSigner
+	client   *http.Client
+	interval time.Duration
+	now      func() time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSegmentRecorder creates a recorder polling every interval. Origin URLs
+// are signed with signer, as nginx requires a playback token.
+func NewSegmentRecorder(store SegmentStore, signer *PlaybackSigner, interval time.Duration) *SegmentRecorder {
+	return &SegmentRecorder{
+		store:    store,
+		signer:   signer,
+		client:   &http.Client{Timeout: interval},
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start polls now and then every interval
+func (r *SegmentRecorder) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.tick()
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the recorder
+func (r *SegmentRecorder) Stop() {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+}
+
+func (r *SegmentRecorder) tick() {
+	streams, err := r.store.ListRecordingTargets()
+	if err != nil {
+		logger.WithError(err).Error("Failed to list streams to record")
+		return
+	}
+
+	for _, stream := range streams {
+		if err := r.record(stream); err != nil {
+			logger.WithError(err).WithField("stream_id", stream.ID).Warn("Failed to record stream segments")
+		}
+	}
+}
+
+// record adds the segments of the stream's origin playlist that aren't
+// recorded yet
+func (r *SegmentRecorder) record(stream *models.Stream) error {
+	playlist, err := r.fetch(stream)
+	if err != nil {
+		return err
+	}
+
+	latest, err := r.store.LatestSegment(stream.ID)
+	if err != nil {
+		return err
+	}
+
+	return r.store.InsertSegments(stampSegments(stream.ID, latest, playlist.Segments, r.now()))
+}
+
+func (r *SegmentRecorder) fetch(stream *models.Stream) (*hls.MediaPlaylist, error) {
+	url, _, err := r.signer.SignURL(*stream.OriginURL, stream.RaceID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("playlist returned status %d", resp.StatusCode)
+	}
+	return hls.Parse(io.LimitReader(resp.Body, recorderMaxBytes))
+}
+
+// stampSegments returns the segments of a live playlist that come after
+// latest, the last recorded one, with their program date time. Times given
+// by the playlist are kept. Otherwise the segments continue from latest when
+// it is still in the playlist or directly precedes it, and are placed so the
+// playlist ends at now when the stream restarted or nothing was recorded yet.
+// A gap in the sequence after latest is marked as a discontinuity.
+func stampSegments(streamID string, latest *models.StreamSegment, segments []hls.Segment, now time.Time) []models.StreamSegment {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var start time.Time
+	var elapsed time.Duration
+	for _, segment := range segments {
+		if latest != nil && segment.Sequence == latest.Sequence {
+			start = latest.ProgramDateTime.Add(-elapsed)
+		}
+		elapsed += seconds(segment.Duration)
+	}
+	first := segments[0]
+	switch {
+	case !first.ProgramDateTime.IsZero():
+		start = first.ProgramDateTime
+	case !start.IsZero():
+	case latest != nil && first.Sequence == latest.Sequence+1 && !first.Discontinuity:
+		start = latest.ProgramDateTime.Add(seconds(latest.Duration))
+	default:
+		start = now.Add(-elapsed)
+	}
+
+	var stamped []models.StreamSegment
+	at := start
+	for _, segment := range segments {
+		if !segment.ProgramDateTime.IsZero() {
+			at = segment.ProgramDateTime
+		}
+		if latest == nil || segment.Sequence > latest.Sequence {
+			stamped = append(stamped, models.StreamSegment{
+				StreamID:        streamID,
+				Sequence:        segment.Sequence,
+				URI:             segment.URI,
+				Duration:        segment.Duration,
+				ProgramDateTime: at,
+				Discontinuity:   segment.Discontinuity,
+			})
+		}
+		at = at.Add(seconds(segment.Duration))
+	}
+
+	if len(stamped) > 0 && latest != nil && stamped[0].Sequence != latest.Sequence+1 {
+		stamped[0].Discontinuity = true
+	}
+	return stamped
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/hls"
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func liveSegments(first int64, count int) []hls.Segment {
+	segments := make([]hls.Segment, count)
+	for i := range segments {
+		seq := first + int64(i)
+		segments[i] = hls.Segment{Sequence: seq, URI: fmt.Sprintf("%d.ts", seq), Duration: 6}
+	}
+	return segments
+}
+
+func TestStampSegments(t *testing.T) {
+	now := time.Date(2026, 7, 5, 12, 0, 0, 0, time.UTC)
+	recorded := now.Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		latest    *models.StreamSegment
+		segments  []hls.Segment
+		wantFirst int64
+		wantCount int
+		wantStart time.Time
+		wantGap   bool
+	}{
+		{
+			name:      "first poll ends at now",
+			segments:  liveSegments(10, 5),
+			wantFirst: 10,
+			wantCount: 5,
+			wantStart: now.Add(-30 * time.Second),
+		},
+		{
+			name:      "continues from the latest segment in the playlist",
+			latest:    &models.StreamSegment{Sequence: 12, Duration: 6, ProgramDateTime: recorded},
+			segments:  liveSegments(10, 5),
+			wantFirst: 13,
+			wantCount: 2,
+			wantStart: recorded.Add(6 * time.Second),
+		},
+		{
+			name:      "continues from the segment before the playlist",
+			latest:    &models.StreamSegment{Sequence: 9, Duration: 4, ProgramDateTime: recorded},
+			segments:  liveSegments(10, 5),
+			wantFirst: 10,
+			wantCount: 5,
+			wantStart: recorded.Add(4 * time.Second),
+		},
+		{
+			name:      "a gap is a discontinuity ending at now",
+			latest:    &models.StreamSegment{Sequence: 3, Duration: 6, ProgramDateTime: recorded},
+			segments:  liveSegments(10, 5),
+			wantFirst: 10,
+			wantCount: 5,
+			wantStart: now.Add(-30 * time.Second),
+			wantGap:   true,
+		},
+		{
+			name:     "nothing new",
+			latest:   &models.StreamSegment{Sequence: 14, Duration: 6, ProgramDateTime: recorded},
+			segments: liveSegments(10, 5),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stamped := stampSegments("s1", tt.latest, tt.segments, now)
+			require.Len(t, stamped, tt.wantCount)
+			if tt.wantCount == 0 {
+				return
+			}
+			assert.Equal(t, tt.wantFirst, stamped[0].Sequence)
+			assert.Equal(t, tt.wantStart, stamped[0].ProgramDateTime)
+			assert.Equal(t, tt.wantGap, stamped[0].Discontinuity)
+			for i := 1; i < len(stamped); i++ {
+				assert.Equal(t, stamped[i-1].ProgramDateTime.Add(6*time.Second), stamped[i].ProgramDateTime)
+				assert.Equal(t, "s1", stamped[i].StreamID)
+			}
+		})
+	}
+}
+
+func TestStampSegments_KeepsPlaylistTimes(t *testing.T) {
+	pdt := time.Date(2026, 7, 5, 11, 0, 0, 0, time.UTC)
+	segments := liveSegments(1, 2)
+	segments[0].ProgramDateTime = pdt
+
+	stamped := stampSegments("s1", nil, segments, pdt.Add(time.Hour))
+	require.Len(t, stamped, 2)
+	assert.Equal(t, pdt, stamped[0].ProgramDateTime)
+	assert.Equal(t, pdt.Add(6*time.Second), stamped[1].ProgramDateTime)
+}
+
+type fakeSegmentStore struct {
+	streams  []*models.Stream
+	latest   *models.StreamSegment
+	inserted []models.StreamSegment
+}
+
+func (f *fakeSegmentStore) ListRecordingTargets() ([]*models.Stream, error) {
+	return f.streams, nil
+}
+
+func (f *fakeSegmentStore) LatestSegment(streamID string) (*models.StreamSegment, error) {
+	return f.latest, nil
+}
+
+func (f *fakeSegmentStore) InsertSegments(segments []models.StreamSegment) error {
+	f.inserted = append(f.inserted, segments...)
+	return nil
+}
+
+func (f *fakeSegmentStore) ListAll(streamID string) ([]models.StreamSegment, error) {
+	return f.inserted, nil
+}
+
+func TestSegmentRecorder_Tick(t *testing.T) {
+	logger.Init("test")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, PlaybackTokenPrefix) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:20\n#EXTINF:6.0,\n20.ts\n#EXTINF:6.0,\n21.ts\n"))
+	}))
+	defer server.Close()
+
+	origin := server.URL + "/hls/race-1/index.m3u8"
+	store := &fakeSegmentStore{
+		streams: []*models.Stream{{ID: "s1", RaceID: "r1", OriginURL: &origin}},
+		latest:  &models.StreamSegment{StreamID: "s1", Sequence: 20, URI: "20.ts", Duration: 6},
+	}
+	recorder := NewSegmentRecorder(store, NewPlaybackSigner("playback-secret", time.Minute, []string{"127.0.0.1"}), time.Second)
+	recorder.tick()
+
+	require.Len(t, store.inserted, 1, "segments already recorded are skipped")
+	assert.Equal(t, int64(21), store.inserted[0].Sequence)
+	assert.Equal(t, "21.ts", store.inserted[0].URI)
+}
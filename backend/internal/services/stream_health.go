@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// ProviderHealthStore lists the playback sources to check and records the
+// results
+type ProviderHealthStore interface {
+	ListProbeTargets() ([]models.StreamProviderProbe, error)
+	UpdateHealth(id, status string, failures int, lastError *string) error
+}
+
+// Concurrent playlist fetches per check
+const probeConcurrency = 8
+
+// Only the start of a playlist is read
+const probeMaxBytes = 64 << 10
+
+var errNotPlaylist = errors.New("response is not an HLS playlist")
+
+// StreamHealthProber fetches the HLS master playlist of each playback source
+// of pre-show and live streams. A source is marked degraded after
+// failureThreshold failed checks in a row and healthy after a good one, so
+// viewers are sent to the best working source.
+type StreamHealthProber struct {
+	store            ProviderHealthStore
+	signer           *PlaybackSigner
+	client           *http.Client
+	interval         time.Duration
+	failureThreshold int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStreamHealthProber creates a prober checking every interval. Origin
+// URLs are signed with signer, as nginx requires a playback token.
+func NewStreamHealthProber(store ProviderHealthStore, signer *PlaybackSigner, interval, timeout time.Duration, failureThreshold int) *StreamHealthProber {
+	return &StreamHealthProber{
+		store:            store,
+		signer:           signer,
+		client:           &http.Client{Timeout: timeout},
+		interval:         interval,
+		failureThreshold: failureThreshold,
+	}
+}
+
+// Start checks now and then every interval
+func (p *StreamHealthProber) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.tick()
+
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the prober
+func (p *StreamHealthProber) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+}
+
+func (p *StreamHealthProber) tick() {
+	targets, err := p.store.ListProbeTargets()
+	if err != nil {
+		logger.WithError(err).Error("Failed to list stream sources to probe")
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target *models.StreamProviderProbe) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.check(target)
+		}(&targets[i])
+	}
+	wg.Wait()
+}
+
+func (p *StreamHealthProber) check(target *models.StreamProviderProbe) {
+	probeErr := p.probe(target)
+	status, failures := nextProviderHealth(target.HealthStatus, target.ConsecutiveFailures, probeErr, p.failureThreshold)
+
+	var lastError *string
+	if probeErr != nil {
+		msg := probeErr.Error()
+		lastError = &msg
+	}
+	if err := p.store.UpdateHealth(target.ID, status, failures, lastError); err != nil {
+		logger.WithError(err).WithField("provider_id", target.ID).Error("Failed to record stream source health")
+		return
+	}
+
+	if status != target.HealthStatus {
+		entry := logger.WithFields(map[string]interface{}{
+			"race_id":   target.RaceID,
+			"stream_id": target.StreamID,
+			"provider":  target.Provider,
+			"health":    status,
+		})
+		if status == models.ProviderHealthDegraded {
+			entry.WithError(probeErr).Warn("Stream source degraded")
+		} else {
+			entry.Info("Stream source health changed")
+		}
+	}
+}
+
+// probe fetches the source's playlist
+func (p *StreamHealthProber) probe(target *models.StreamProviderProbe) error {
+	url, _, err := p.signer.SignURL(*target.ProviderURL, target.RaceID, "")
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("playlist returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, probeMaxBytes))
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))), []byte("#EXTM3U")) {
+		return errNotPlaylist
+	}
+	return nil
+}
+
+// nextProviderHealth returns a source's health and failure count after a
+// check that failed with probeErr, or succeeded when it is nil
+func nextProviderHealth(status string, failures int, probeErr error, threshold int) (string, int) {
+	if probeErr == nil {
+		return models.ProviderHealthHealthy, 0
+	}
+
+	failures++
+	if failures >= threshold {
+		return models.ProviderHealthDegraded, failures
+	}
+	return status, failures
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type healthUpdate struct {
+	status    string
+	failures  int
+	lastError *string
+}
+
+type fakeProviderHealthStore struct {
+	mu      sync.Mutex
+	targets []models.StreamProviderProbe
+	updates map[string]healthUpdate
+}
+
+func (f *fakeProviderHealthStore) ListProbeTargets() ([]models.StreamProviderProbe, error) {
+	return f.targets, nil
+}
+
+func (f *fakeProviderHealthStore) UpdateHealth(id, status string, failures int, lastError *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates[id] = healthUpdate{status, failures, lastError}
+	return nil
+}
+
+func probeTarget(id, url, health string, failures int) models.StreamProviderProbe {
+	return models.StreamProviderProbe{
+		StreamProvider: models.StreamProvider{ID: id, StreamID: "s1", Provider: id, ProviderURL: &url, HealthStatus: health, ConsecutiveFailures: failures},
+		RaceID:         "r1",
+	}
+}
+
+func TestStreamHealthProber_Tick(t *testing.T) {
+	logger.Init("test")
+	var tokenized atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, PlaybackTokenPrefix):
+			tokenized.Store(true)
+			_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n720p/index.m3u8\n"))
+		case r.URL.Path == "/ok.m3u8":
+			_, _ = w.Write([]byte("\xef\xbb\xbf#EXTM3U\n"))
+		case r.URL.Path == "/html.m3u8":
+			_, _ = w.Write([]byte("<html>maintenance</html>"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	store := &fakeProviderHealthStore{
+		targets: []models.StreamProviderProbe{
+			probeTarget("origin", server.URL+"/hls/race-1/index.m3u8", models.ProviderHealthUnknown, 0),
+			probeTarget("recovered", server.URL+"/ok.m3u8", models.ProviderHealthDegraded, 4),
+			probeTarget("first-failure", server.URL+"/down.m3u8", models.ProviderHealthHealthy, 0),
+			probeTarget("second-failure", server.URL+"/down.m3u8", models.ProviderHealthHealthy, 1),
+			probeTarget("not-playlist", server.URL+"/html.m3u8", models.ProviderHealthUnknown, 1),
+		},
+		updates: map[string]healthUpdate{},
+	}
+	prober := NewStreamHealthProber(store, NewPlaybackSigner("playback-secret", time.Minute, []string{"127.0.0.1"}), time.Hour, time.Second, 2)
+	prober.tick()
+
+	assert.True(t, tokenized.Load(), "origin URLs are probed with a playback token")
+	require.Len(t, store.updates, 5)
+	assert.Equal(t, healthUpdate{models.ProviderHealthHealthy, 0, nil}, store.updates["origin"])
+	assert.Equal(t, healthUpdate{models.ProviderHealthHealthy, 0, nil}, store.updates["recovered"])
+
+	first := store.updates["first-failure"]
+	assert.Equal(t, models.ProviderHealthHealthy, first.status)
+	assert.Equal(t, 1, first.failures)
+	require.NotNil(t, first.lastError)
+	assert.Contains(t, *first.lastError, "503")
+
+	assert.Equal(t, models.ProviderHealthDegraded, store.updates["second-failure"].status)
+	assert.Equal(t, models.ProviderHealthDegraded, store.updates["not-playlist"].status)
+}
+
+func TestNextProviderHealth(t *testing.T) {
+	failed := errors.New("timeout")
+	tests := []struct {
+		name         string
+		status       string
+		failures     int
+		err          error
+		wantStatus   string
+		wantFailures int
+	}{
+		{"success", models.ProviderHealthDegraded, 3, nil, models.ProviderHealthHealthy, 0},
+		{"first failure keeps status", models.ProviderHealthHealthy, 0, failed, models.ProviderHealthHealthy, 1},
+		{"threshold reached", models.ProviderHealthHealthy, 2, failed, models.ProviderHealthDegraded, 3},
+		{"still degraded", models.ProviderHealthDegraded, 5, failed, models.ProviderHealthDegraded, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, failures := nextProviderHealth(tt.status, tt.failures, tt.err, 3)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantFailures, failures)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+)
+
+// PreShowStore moves scheduled streams to pre-show
+type PreShowStore interface {
+	StartDuePreShows(lead time.Duration) ([]*models.StreamStatusChange, error)
+}
+
+// StreamScheduler moves scheduled streams to pre-show shortly before their
+// race starts. Going live and ending are driven by the ingest callbacks.
+type StreamScheduler struct {
+	store    PreShowStore
+	lead     time.Duration
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStreamScheduler creates a scheduler that opens the pre-show lead before
+// each race's start date, checking every interval
+func NewStreamScheduler(store PreShowStore, lead, interval time.Duration) *StreamScheduler {
+	return &StreamScheduler{store: store, lead: lead, interval: interval}
+}
+
+// Start checks now and then every interval, calling onChange for each
+// stream moved to pre-show
+func (s *StreamScheduler) Start(onChange func(*models.StreamStatusChange)) {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.tick(onChange)
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler
+func (s *StreamScheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+}
+
+func (s *StreamScheduler) tick(onChange func(*models.StreamStatusChange)) {
+	changes, err := s.store.StartDuePreShows(s.lead)
+	if err != nil {
+		logger.WithError(err).Error("Failed to start stream pre-shows")
+		return
+	}
+
+	for _, change := range changes {
+		logger.WithFields(map[string]interface{}{
+			"race_id":   change.RaceID,
+			"stream_id": change.StreamID,
+		}).Info("Stream pre-show started")
+		onChange(change)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyclingstream/backend/internal/logger"
+	"github.com/cyclingstream/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePreShowStore struct {
+	mu      sync.Mutex
+	leads   []time.Duration
+	changes [][]*models.StreamStatusChange
+	err     error
+}
+
+func (f *fakePreShowStore) StartDuePreShows(lead time.Duration) ([]*models.StreamStatusChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leads = append(f.leads, lead)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.changes) == 0 {
+		return nil, nil
+	}
+	next := f.changes[0]
+	f.changes = f.changes[1:]
+	return next, nil
+}
+
+func TestStreamScheduler_ReportsPreShows(t *testing.T) {
+	logger.Init("test")
+	change := &models.StreamStatusChange{StreamID: "s1", RaceID: "r1", From: models.StreamStatusScheduled, To: models.StreamStatusPreShow}
+	store := &fakePreShowStore{changes: [][]*models.StreamStatusChange{{change}}}
+
+	scheduler := NewStreamScheduler(store, 15*time.Minute, time.Hour)
+	got := make(chan *models.StreamStatusChange, 1)
+	scheduler.Start(func(c *models.StreamStatusChange) { got <- c })
+	defer scheduler.Stop()
+
+	select {
+	case c := <-got:
+		assert.Equal(t, change, c)
+	case <-time.After(time.Second):
+		t.Fatal("pre-show change was not reported")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.NotEmpty(t, store.leads)
+	assert.Equal(t, 15*time.Minute, store.leads[0])
+}
+
+func TestStreamScheduler_StoreError(t *testing.T) {
+	logger.Init("test")
+	store := &fakePreShowStore{err: errors.New("database unavailable")}
+
+	scheduler := NewStreamScheduler(store, time.Minute, time.Hour)
+	scheduler.tick(func(*models.StreamStatusChange) {
+		t.Fatal("no change should be reported when the store fails")
+	})
+}
@@ -128,12 +128,12 @@ func CreateTestUser(t *testing.T, db *sql.DB, email, password, name string) stri
 	return userID
 }
 
-// CreateTestStream creates a test stream for a race
+// CreateTestStream creates a test stream for a race, as its primary feed
 func CreateTestStream(t *testing.T, db *sql.DB, raceID, status string) {
 	query := `
-		INSERT INTO streams (id, race_id, status, origin_url, cdn_url, created_at, updated_at)
-		VALUES (gen_random_uuid(), $1, $2, 'http://test.com/stream.m3u8', 'http://cdn.test.com/stream.m3u8', NOW(), NOW())
-		ON CONFLICT (race_id) DO UPDATE SET status = $2, updated_at = NOW()
+		INSERT INTO streams (id, race_id, is_primary, status, origin_url, cdn_url, created_at, updated_at)
+		VALUES (gen_random_uuid(), $1, TRUE, $2, 'http://test.com/stream.m3u8', 'http://cdn.test.com/stream.m3u8', NOW(), NOW())
+		ON CONFLICT (race_id) WHERE is_primary DO UPDATE SET status = $2, updated_at = NOW()
 	`
 
 	_, err := db.Exec(query, raceID, status)
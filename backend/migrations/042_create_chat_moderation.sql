@@ -0,0 +1,39 @@
+-- Chat moderation: message deletion, user timeouts/bans and per-race slow mode
+
+-- Deleted messages are kept for auditing but hidden from history
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255);
+
+-- Timeouts and bans. A NULL race_id applies the restriction to every race.
+-- moderator_id/revoked_by are plain text because admin tokens are not tied to user rows.
+CREATE TABLE IF NOT EXISTS chat_user_restrictions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID REFERENCES races(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('timeout', 'ban')),
+    reason TEXT,
+    moderator_id VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE, -- NULL for permanent bans
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_user_restrictions_user_active
+    ON chat_user_restrictions(user_id, race_id)
+    WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_chat_user_restrictions_race_active
+    ON chat_user_restrictions(race_id)
+    WHERE revoked_at IS NULL;
+
+-- Per-race chat settings
+CREATE TABLE IF NOT EXISTS chat_room_settings (
+    race_id UUID PRIMARY KEY REFERENCES races(id) ON DELETE CASCADE,
+    slow_mode_seconds INTEGER NOT NULL DEFAULT 0 CHECK (slow_mode_seconds >= 0),
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE chat_user_restrictions IS 'Chat timeouts and bans enforced when users send messages';
+COMMENT ON TABLE chat_room_settings IS 'Per-race chat settings such as slow mode';
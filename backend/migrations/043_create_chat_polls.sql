@@ -0,0 +1,42 @@
+-- Persist chat polls and ballots so they survive restarts and keep history
+
+CREATE TABLE IF NOT EXISTS chat_polls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    question VARCHAR(255) NOT NULL,
+    poll_type VARCHAR(20) NOT NULL DEFAULT 'single' CHECK (poll_type IN ('single', 'multi', 'ranked')),
+    max_choices INTEGER NOT NULL DEFAULT 0 CHECK (max_choices >= 0),
+    options JSONB NOT NULL, -- Array of {id, label, votes, score}; tallies are final once closed
+    total_votes INTEGER NOT NULL DEFAULT 0, -- Voters so far, kept up to date by each first ballot
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    closes_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_polls_race_created ON chat_polls(race_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_polls_open ON chat_polls(closes_at) WHERE closed_at IS NULL;
+
+-- One ballot per user per poll; option_ids is ordered by preference for ranked polls
+CREATE TABLE IF NOT EXISTS chat_poll_votes (
+    poll_id UUID NOT NULL REFERENCES chat_polls(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    option_ids JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (poll_id, user_id)
+);
+
+-- Running tallies: how many ballots list each option at each position (the
+-- preference in ranked polls). Ballots update them as they are cast, so
+-- votes don't re-read every ballot.
+CREATE TABLE IF NOT EXISTS chat_poll_option_votes (
+    poll_id UUID NOT NULL REFERENCES chat_polls(id) ON DELETE CASCADE,
+    option_id TEXT NOT NULL,
+    rank INTEGER NOT NULL CHECK (rank >= 0),
+    ballots INTEGER NOT NULL DEFAULT 0 CHECK (ballots >= 0),
+    PRIMARY KEY (poll_id, option_id, rank)
+);
+
+COMMENT ON TABLE chat_polls IS 'Chat polls per race; options hold final tallies after closing';
+COMMENT ON COLUMN chat_poll_votes.option_ids IS 'Selected option IDs, ordered by preference for ranked polls';
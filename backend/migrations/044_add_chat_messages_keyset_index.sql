@@ -0,0 +1,4 @@
+-- Supports keyset pagination of chat history by (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_chat_messages_race_created_id
+    ON chat_messages(race_id, created_at DESC, id DESC)
+    WHERE deleted_at IS NULL;
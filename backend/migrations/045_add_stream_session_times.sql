@@ -0,0 +1,11 @@
+-- Track when a stream first went live and when it last ended, so chat replay
+-- can be aligned with the recording
+
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS started_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS ended_at TIMESTAMP WITH TIME ZONE;
+
+-- Best guess for streams that are live right now: the last status change
+UPDATE streams
+SET started_at = updated_at
+WHERE status = 'live' AND started_at IS NULL;
@@ -0,0 +1,25 @@
+-- Replies and @mentions in race chat
+
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS reply_to_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_reply_to
+    ON chat_messages(reply_to_id, created_at)
+    WHERE reply_to_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS chat_mentions (
+    message_id UUID NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    mentioned_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, mentioned_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_mentions_user ON chat_mentions(mentioned_user_id, created_at DESC);
+
+-- @handles are display names without whitespace, or the email local part,
+-- matched case-insensitively
+CREATE INDEX IF NOT EXISTS idx_users_mention_name
+    ON users (lower(regexp_replace(COALESCE(name, ''), '\s+', '', 'g')));
+CREATE INDEX IF NOT EXISTS idx_users_mention_email
+    ON users (lower(split_part(email, '@', 1)));
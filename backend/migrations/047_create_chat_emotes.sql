@@ -0,0 +1,37 @@
+-- Custom chat emotes managed by admins. A NULL race_id makes the emote
+-- available in every race; a race emote with the same code takes precedence.
+-- Built-in emotes have no image_url and are drawn by the frontend.
+
+CREATE TABLE IF NOT EXISTS chat_emotes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(32) NOT NULL CHECK (code ~ '^[a-z0-9_]{2,32}$'),
+    image_url TEXT,
+    race_id UUID REFERENCES races(id) ON DELETE CASCADE,
+    min_level INTEGER NOT NULL DEFAULT 0 CHECK (min_level >= 0),
+    requires_ticket BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_emotes_global_code
+    ON chat_emotes(code)
+    WHERE race_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_emotes_race_code
+    ON chat_emotes(race_id, code)
+    WHERE race_id IS NOT NULL;
+
+-- The emotes that used to be hard-coded in the chat service
+INSERT INTO chat_emotes (code, created_by)
+SELECT t.code, 'system'
+FROM unnest(ARRAY['bike', 'fire', 'zap', 'bolt', 'clap', 'crown', 'rocket', 'heart', 'star', 'podium']) AS t(code)
+WHERE NOT EXISTS (
+    SELECT 1 FROM chat_emotes e WHERE e.code = t.code AND e.race_id IS NULL
+);
+
+-- Emote spans parsed when a message is sent, so history renders the emotes
+-- the sender was allowed to use at the time
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS emotes JSONB;
+
+COMMENT ON TABLE chat_emotes IS 'Chat emotes, optionally scoped to a race and gated by level or ticket';
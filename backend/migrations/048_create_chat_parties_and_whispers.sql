@@ -0,0 +1,46 @@
+-- Private chat: invite-only watch party rooms layered on a race chat, and
+-- one-to-one whispers. Private messages are kept apart from chat_messages so
+-- they never show up in public history, replay or exports.
+
+CREATE TABLE IF NOT EXISTS chat_parties (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    invite_code VARCHAR(16) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_parties_race ON chat_parties(race_id);
+
+CREATE TABLE IF NOT EXISTS chat_party_members (
+    party_id UUID NOT NULL REFERENCES chat_parties(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (party_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_party_members_user ON chat_party_members(user_id);
+
+-- A private message goes either to a party or to a single recipient
+CREATE TABLE IF NOT EXISTS chat_private_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    party_id UUID REFERENCES chat_parties(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((party_id IS NULL) <> (recipient_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_private_messages_party
+    ON chat_private_messages(party_id, created_at DESC)
+    WHERE party_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_chat_private_messages_whisper
+    ON chat_private_messages(race_id, LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id), created_at DESC)
+    WHERE recipient_id IS NOT NULL;
+
+COMMENT ON TABLE chat_parties IS 'Invite-only watch party rooms within a race chat';
+COMMENT ON TABLE chat_private_messages IS 'Party messages and whispers, stored apart from public chat';
@@ -0,0 +1,28 @@
+-- Chat content filter: per-race rule configuration and a queue of flagged
+-- messages for moderators to review
+
+-- NULL uses the built-in defaults
+ALTER TABLE chat_room_settings
+    ADD COLUMN IF NOT EXISTS filter_config JSONB;
+
+-- reviewed_by is plain text because admin tokens are not tied to user rows
+CREATE TABLE IF NOT EXISTS chat_moderation_queue (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    username VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    matches JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'removed')),
+    reviewed_by VARCHAR(255),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_moderation_queue_status
+    ON chat_moderation_queue(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_moderation_queue_race
+    ON chat_moderation_queue(race_id, created_at DESC);
+
+COMMENT ON TABLE chat_moderation_queue IS 'Chat messages flagged by the content filter, awaiting moderator review';
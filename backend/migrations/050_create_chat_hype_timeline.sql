@@ -0,0 +1,12 @@
+-- Chat hype timeline: reactions per emote for each minute of a race.
+-- Reactions themselves are not stored; instances add their per-minute counts.
+
+CREATE TABLE IF NOT EXISTS chat_hype_timeline (
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    minute TIMESTAMP WITH TIME ZONE NOT NULL,
+    emote VARCHAR(32) NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0 CHECK (count >= 0),
+    PRIMARY KEY (race_id, minute, emote)
+);
+
+COMMENT ON TABLE chat_hype_timeline IS 'Chat reaction counts per race, minute and emote, used to find highlight moments';
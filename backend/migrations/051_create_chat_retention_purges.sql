@@ -0,0 +1,21 @@
+-- Chat retention: each run archives messages older than the retention period
+-- to a compressed file on disk before deleting them, and records it here
+
+CREATE TABLE IF NOT EXISTS chat_retention_purges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cutoff TIMESTAMP WITH TIME ZONE NOT NULL,
+    archive_path TEXT NOT NULL,
+    archive_bytes BIGINT NOT NULL DEFAULT 0,
+    message_count INTEGER NOT NULL DEFAULT 0 CHECK (message_count >= 0),
+    deleted_count INTEGER NOT NULL DEFAULT 0 CHECK (deleted_count >= 0),
+    -- race ID -> messages purged; races may since have been deleted
+    race_counts JSONB NOT NULL DEFAULT '{}',
+    oldest_message_at TIMESTAMP WITH TIME ZONE,
+    newest_message_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_retention_purges_created
+    ON chat_retention_purges(created_at DESC);
+
+COMMENT ON TABLE chat_retention_purges IS 'Chat messages archived and deleted by the retention job';
@@ -0,0 +1,18 @@
+-- Chat pins and announcements: admins can pin one message to the top of a
+-- race's chat and post announcements, which are highlighted and skip rate limits
+
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS is_announcement BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One pin per race. Purging the message removes the pin; pinned_by is plain
+-- text because admin tokens are not tied to user rows
+CREATE TABLE IF NOT EXISTS chat_pins (
+    race_id UUID PRIMARY KEY REFERENCES races(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+    pinned_by VARCHAR(255),
+    pinned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_pins_message ON chat_pins(message_id);
+
+COMMENT ON TABLE chat_pins IS 'Message pinned to the top of each race chat';
@@ -0,0 +1,35 @@
+-- RTMP publish sessions: nginx-rtmp calls the backend when an encoder starts
+-- and stops publishing, and each publish is recorded here
+
+CREATE TABLE IF NOT EXISTS stream_publish_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    -- nginx-rtmp connection ID; only unique while nginx is running
+    client_id VARCHAR(64) NOT NULL,
+    client_addr VARCHAR(255),
+    app VARCHAR(255),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    -- done when the encoder stopped, superseded when a new publish replaced it
+    end_reason VARCHAR(32)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_publish_sessions_stream ON stream_publish_sessions(stream_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_stream_publish_sessions_open ON stream_publish_sessions(stream_id) WHERE ended_at IS NULL;
+
+-- Publishes are authorized by looking the stream up by key. A key shared by
+-- several streams can't tell them apart, so it is cleared on all of them
+-- and their keys have to be generated again.
+UPDATE streams
+SET stream_key = NULL
+WHERE stream_key IN (
+    SELECT stream_key
+    FROM streams
+    WHERE stream_key IS NOT NULL AND stream_key <> ''
+    GROUP BY stream_key
+    HAVING COUNT(*) > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streams_stream_key ON streams(stream_key) WHERE stream_key IS NOT NULL AND stream_key <> '';
+
+COMMENT ON TABLE stream_publish_sessions IS 'Each time an encoder published to a stream over RTMP';
@@ -0,0 +1,50 @@
+-- Stream keys: the backend generates RTMP publish keys and stores only their
+-- SHA-256 hash. A rotated key stays valid until the end of its overlap window
+-- so the encoder can be switched over; revoked keys are refused at once.
+
+CREATE TABLE IF NOT EXISTS stream_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    key_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the key
+    key_hint VARCHAR(8) NOT NULL,      -- last characters, to tell keys apart
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE, -- set when the key is rotated out
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_keys_stream ON stream_keys(stream_id, created_at DESC);
+
+-- Audit trail of key changes. Actors are plain text because admin tokens are
+-- not tied to user rows
+CREATE TABLE IF NOT EXISTS stream_key_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    key_id UUID REFERENCES stream_keys(id) ON DELETE SET NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('created', 'rotated', 'revoked')),
+    actor VARCHAR(255),
+    -- for rotations, when the replaced keys stop being accepted
+    overlap_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_key_events_stream ON stream_key_events(stream_id, created_at DESC);
+
+-- Move the plaintext keys over, then drop them
+INSERT INTO stream_keys (stream_id, key_hash, key_hint, created_by)
+SELECT id, encode(sha256(convert_to(stream_key, 'UTF8')), 'hex'), CASE WHEN length(stream_key) > 4 THEN right(stream_key, 4) ELSE '' END, 'migration'
+FROM streams
+WHERE stream_key IS NOT NULL AND stream_key <> ''
+ON CONFLICT (key_hash) DO NOTHING;
+
+INSERT INTO stream_key_events (stream_id, key_id, action, actor)
+SELECT stream_id, id, 'created', 'migration'
+FROM stream_keys
+WHERE created_by = 'migration';
+
+DROP INDEX IF EXISTS idx_streams_stream_key;
+ALTER TABLE streams DROP COLUMN IF EXISTS stream_key;
+
+COMMENT ON TABLE stream_keys IS 'Hashed RTMP publish keys of each stream';
+COMMENT ON TABLE stream_key_events IS 'Who created, rotated and revoked stream keys';
@@ -0,0 +1,31 @@
+-- Stream status state machine
+-- Old: live, offline, upcoming
+-- New: scheduled -> pre_show -> live -> ended -> vod_ready, plus failed.
+-- Transitions are enforced by the backend (StreamRepository).
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_status_check;
+
+UPDATE streams
+SET status = 'scheduled', updated_at = CURRENT_TIMESTAMP
+WHERE status = 'upcoming';
+
+-- Offline streams that have been on air are over; the others never started
+UPDATE streams
+SET status = CASE WHEN started_at IS NOT NULL THEN 'ended' ELSE 'scheduled' END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE status = 'offline';
+
+ALTER TABLE streams
+ADD CONSTRAINT streams_status_check CHECK (status IN ('scheduled', 'pre_show', 'live', 'ended', 'vod_ready', 'failed'));
+
+ALTER TABLE streams
+ALTER COLUMN status SET DEFAULT 'scheduled';
+
+-- Last transition, for viewers polling the status
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS previous_status VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE streams SET status_changed_at = updated_at WHERE status_changed_at IS NULL;
+
+COMMENT ON COLUMN streams.status IS 'scheduled, pre_show, live, ended, vod_ready, failed';
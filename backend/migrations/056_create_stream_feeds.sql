@@ -0,0 +1,19 @@
+-- Multiple feeds per race: main feed, helicopter, onboard and finish-line
+-- cams. Each feed is a row of streams with its own status, keys and playback
+-- analytics; the primary feed is the race's stream for listings and chat.
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_race_id_unique;
+
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS name VARCHAR(100) NOT NULL DEFAULT 'Main',
+    ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Who may watch the feed: race (the race's rules), public (anyone),
+    -- login (signed-in viewers) or paid (viewers entitled to the race)
+    ADD COLUMN IF NOT EXISTS access VARCHAR(16) NOT NULL DEFAULT 'race'
+        CHECK (access IN ('race', 'public', 'login', 'paid'));
+
+-- Existing streams were the only stream of their race
+UPDATE streams SET is_primary = TRUE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streams_race_primary ON streams(race_id) WHERE is_primary;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streams_race_name ON streams(race_id, LOWER(name));
@@ -0,0 +1,17 @@
+-- Ordered playback sources per stream, e.g. Owncast origin, then Bunny CDN,
+-- then YouTube. Provider rows with a priority are playback sources, lowest
+-- first; rows without one only link the stream to a provider's analytics.
+-- The health prober fetches each HLS source's master playlist and marks it
+-- degraded after repeated failures.
+
+ALTER TABLE stream_providers
+    ADD COLUMN IF NOT EXISTS priority INTEGER,
+    ADD COLUMN IF NOT EXISTS stream_type VARCHAR(16) NOT NULL DEFAULT 'hls'
+        CHECK (stream_type IN ('hls', 'youtube')),
+    ADD COLUMN IF NOT EXISTS health_status VARCHAR(16) NOT NULL DEFAULT 'unknown'
+        CHECK (health_status IN ('unknown', 'healthy', 'degraded')),
+    ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_stream_providers_sources ON stream_providers(stream_id, priority) WHERE priority IS NOT NULL;
@@ -0,0 +1,23 @@
+-- DVR: segments of live HLS streams recorded by the backend, which serves
+-- them back as a sliding-window playlist with EXT-X-PROGRAM-DATE-TIME tags so
+-- viewers can rewind during a race. Segment files stay on the HLS origin.
+
+-- How far back viewers can rewind; NULL uses STREAM_DVR_WINDOW, 0 disables DVR
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS dvr_window_seconds INTEGER CHECK (dvr_window_seconds >= 0);
+
+CREATE TABLE IF NOT EXISTS stream_segments (
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    -- Media sequence number on the origin
+    sequence BIGINT NOT NULL,
+    -- As listed by the origin playlist, usually relative to it
+    uri TEXT NOT NULL,
+    duration_seconds DOUBLE PRECISION NOT NULL,
+    program_date_time TIMESTAMPTZ NOT NULL,
+    -- Starts a new encoder timeline, e.g. after the encoder reconnected
+    discontinuity BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stream_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_segments_program_date_time ON stream_segments(stream_id, program_date_time);
@@ -0,0 +1,29 @@
+-- Replays: once a live stream has ended, the recording manager registers its
+-- recorded HLS segments as a VOD asset and moves the stream to vod_ready. The
+-- replay playlist is built from stream_segments, so the segment files stay
+-- where the origin wrote them.
+
+CREATE TABLE IF NOT EXISTS vod_assets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id UUID NOT NULL UNIQUE REFERENCES streams(id) ON DELETE CASCADE,
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    -- processing while a recording manager registers it; failed assets are
+    -- not retried until they are deleted
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'ready', 'failed')),
+    -- Where the segments came from: segments (recorded from the live
+    -- playlist) or directory (read from VOD_SEGMENT_DIR)
+    source VARCHAR(20) NOT NULL,
+    duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    segment_count INTEGER NOT NULL DEFAULT 0,
+    -- Offset into the replay of the frame used as its thumbnail
+    thumbnail_offset_seconds DOUBLE PRECISION,
+    -- Wall clock time of the first and the end of the last segment
+    recorded_from TIMESTAMPTZ,
+    recorded_to TIMESTAMPTZ,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vod_assets_race ON vod_assets(race_id);
+CREATE INDEX IF NOT EXISTS idx_vod_assets_ready ON vod_assets(recorded_from DESC) WHERE status = 'ready';
@@ -0,0 +1,35 @@
+-- Race timeline: chapter markers on a feed's recording ("KOM sprint",
+-- "last 10 km", "crash at 45 km") and viewer clips of it. Offsets are seconds
+-- into the feed's recorded segments, as played back from the replay.
+
+CREATE TABLE IF NOT EXISTS race_markers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    offset_seconds DOUBLE PRECISION NOT NULL CHECK (offset_seconds >= 0),
+    marker_type VARCHAR(20) NOT NULL CHECK (marker_type IN ('start', 'attack', 'breakaway', 'crash', 'kom', 'sprint', 'km_to_go', 'finish', 'other')),
+    title VARCHAR(100) NOT NULL,
+    km_to_go NUMERIC(6, 2) CHECK (km_to_go >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_race_markers_race ON race_markers(race_id, offset_seconds);
+CREATE INDEX IF NOT EXISTS idx_race_markers_stream ON race_markers(stream_id);
+
+-- A clip plays the segments covering its offsets, so it is shared as a link
+-- to the clip rather than to a playlist with a viewer's token
+CREATE TABLE IF NOT EXISTS race_clips (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    race_id UUID NOT NULL REFERENCES races(id) ON DELETE CASCADE,
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    marker_id UUID REFERENCES race_markers(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(100) NOT NULL,
+    start_offset_seconds DOUBLE PRECISION NOT NULL CHECK (start_offset_seconds >= 0),
+    end_offset_seconds DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_offset_seconds > start_offset_seconds)
+);
+
+CREATE INDEX IF NOT EXISTS idx_race_clips_race ON race_clips(race_id, created_at DESC);
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cyclingstream/backend/internal/models"
 	_ "github.com/lib/pq"
 	"github.com/joho/godotenv"
 )
@@ -76,7 +77,7 @@ func main() {
 		fmt.Printf("  %s: %d\n", status, count)
 
 		// Check for invalid statuses
-		if status != "live" && status != "offline" && status != "upcoming" {
+		if !models.IsValidStreamStatus(status) {
 			invalidStatuses = append(invalidStatuses, status)
 		}
 	}
@@ -110,10 +111,10 @@ func main() {
 	// Check for invalid statuses
 	if len(invalidStatuses) > 0 {
 		fmt.Printf("\n❌ Invalid statuses found: %v\n", invalidStatuses)
-		fmt.Println("   Expected only: live, offline, upcoming")
+		fmt.Println("   Expected only: scheduled, pre_show, live, ended, vod_ready, failed")
 		os.Exit(1)
 	} else {
-		fmt.Println("\n✅ All statuses are valid (live, offline, upcoming)")
+		fmt.Println("\n✅ All statuses are valid (scheduled, pre_show, live, ended, vod_ready, failed)")
 	}
 
 	// Verify default value
@@ -129,10 +130,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	if defaultValue == "'upcoming'::character varying" || defaultValue == "'upcoming'" {
-		fmt.Println("✅ Default status is set to 'upcoming'")
+	if defaultValue == "'scheduled'::character varying" || defaultValue == "'scheduled'" {
+		fmt.Println("✅ Default status is set to 'scheduled'")
 	} else {
-		fmt.Printf("⚠️  Default status is: %s (expected 'upcoming')\n", defaultValue)
+		fmt.Printf("⚠️  Default status is: %s (expected 'scheduled')\n", defaultValue)
 	}
 
 	fmt.Println("\n✅ Migration verification complete!")
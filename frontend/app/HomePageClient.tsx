@@ -37,7 +37,7 @@ export function HomePageClient() {
             r.status === 'fulfilled'
           )
           .map(r => r.value)
-          .filter(item => item.stream?.status === 'live' || item.stream?.status === 'planned');
+          .filter(item => item.stream?.status === 'live' || item.stream?.status === 'pre_show');
         
         setLiveRacesData(liveData);
       } catch (err) {
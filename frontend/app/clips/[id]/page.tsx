@@ -0,0 +1,58 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { useParams } from 'next/navigation';
+
+import ErrorMessage from '@/components/ErrorMessage';
+import { Navigation } from '@/components/layout/Navigation';
+import Footer from '@/components/layout/Footer';
+import DynamicVideoPlayer from '@/components/video/DynamicVideoPlayer';
+import { ClipResponse, getClip } from '@/lib/api';
+import { APIErrorHandler } from '@/lib/error-handler';
+
+// Shared clip links open here. The clip is fetched in the browser so the
+// viewer's login unlocks clips of login-only and paid feeds.
+export default function ClipPage() {
+  const params = useParams();
+  const id = params.id as string;
+  const [clip, setClip] = useState<ClipResponse | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    getClip(id)
+      .then(setClip)
+      .catch((err) => setError(APIErrorHandler.getErrorMessage(err)));
+  }, [id]);
+
+  return (
+    <div className="min-h-screen bg-background flex flex-col">
+      <Navigation variant="full" />
+      <main className="flex-1 max-w-5xl mx-auto px-6 lg:px-8 pt-8 pb-6 sm:pb-8 w-full">
+        {error ? (
+          <ErrorMessage message={error} />
+        ) : !clip ? (
+          <div className="aspect-video bg-black rounded-lg" />
+        ) : (
+          <>
+            <DynamicVideoPlayer
+              streamUrl={clip.clip_url}
+              status="vod_ready"
+              streamType="hls"
+              streamId={clip.clip.stream_id}
+              raceId={clip.clip.race_id}
+            />
+            <h1 className="text-2xl sm:text-3xl font-bold text-foreground mt-6">{clip.clip.title}</h1>
+            <p className="text-muted-foreground mt-1">
+              {clip.race_name} · {clip.stream_name}
+            </p>
+            <Link href={`/races/${clip.clip.race_id}/watch`} className="mt-4 inline-block text-primary hover:underline">
+              Watch the full race →
+            </Link>
+          </>
+        )}
+      </main>
+      <Footer />
+    </div>
+  );
+}
@@ -74,7 +74,7 @@ export default function Chat({ onCollapse }: ChatProps) {
     setHistoryError(null);
 
     try {
-      const response = await getChatHistory(raceId, CHAT_HISTORY_LIMIT, 0);
+      const response = await getChatHistory(raceId, CHAT_HISTORY_LIMIT);
       // Messages are already in chronological order from API
       setMessages(response.messages);
       setHistoryLoaded(true);
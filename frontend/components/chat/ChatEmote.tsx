@@ -4,11 +4,25 @@ import { cn } from '@/lib/utils';
 
 interface ChatEmoteProps {
   text: string;
+  imageUrl?: string;
   special?: boolean;
   disabled?: boolean;
 }
 
-export default function ChatEmote({ text, special, disabled }: ChatEmoteProps) {
+export default function ChatEmote({ text, imageUrl, special, disabled }: ChatEmoteProps) {
+  if (imageUrl) {
+    return (
+      // eslint-disable-next-line @next/next/no-img-element
+      <img
+        src={imageUrl}
+        alt={text}
+        title={text}
+        className={cn('chat-emote inline-block h-7 w-auto align-middle', special && 'h-10')}
+        loading="lazy"
+      />
+    );
+  }
+
   return (
     <span
       className={cn(
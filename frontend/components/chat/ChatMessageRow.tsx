@@ -2,7 +2,7 @@
 
 import Link from 'next/link';
 import { useMemo } from 'react';
-import { ChatMessage } from '@/lib/api';
+import { ChatEmoteSpan, ChatMessage } from '@/lib/api';
 import { getUserColor, renderUserBadges } from '@/lib/chat-utils';
 import { cn } from '@/lib/utils';
 import ChatEmote from './ChatEmote';
@@ -29,6 +29,7 @@ const KNOWN_EMOTES = new Set([
 type Segment = {
   type: 'text' | 'emote';
   value: string;
+  imageUrl?: string;
 };
 
 export default function ChatMessageRow({ message, animate, pulseClass, index }: ChatMessageRowProps) {
@@ -36,7 +37,10 @@ export default function ChatMessageRow({ message, animate, pulseClass, index }:
   const badges = useMemo(() => renderUserBadges(message.badges), [message.badges]);
   const primaryBadge = badges?.[0];
 
-  const segments = useMemo(() => parseSegments(message.message), [message.message]);
+  const segments = useMemo(
+    () => parseSegments(message.message, message.emotes),
+    [message.message, message.emotes]
+  );
   const isAltRow = typeof index === 'number' && index % 2 === 1;
 
   const usernameNode = message.user_id ? (
@@ -98,6 +102,7 @@ export default function ChatMessageRow({ message, animate, pulseClass, index }:
               <ChatEmote
                 key={`${message.id}-emote-${idx}`}
                 text={segment.value}
+                imageUrl={segment.imageUrl}
                 special={message.special_emote}
                 disabled={!animate}
               />
@@ -113,11 +118,37 @@ export default function ChatMessageRow({ message, animate, pulseClass, index }:
   );
 }
 
-function parseSegments(message: string): Segment[] {
+function parseSegments(message: string, emotes?: ChatEmoteSpan[]): Segment[] {
   if (!message) {
     return [];
   }
 
+  // Emote spans from the server take precedence; older messages fall back to the built-in set
+  if (emotes && emotes.length > 0) {
+    const segments: Segment[] = [];
+    let cursor = 0;
+    [...emotes]
+      .sort((a, b) => a.start - b.start)
+      .forEach((span) => {
+        if (span.start < cursor || span.end > message.length) {
+          return;
+        }
+        if (span.start > cursor) {
+          segments.push({ type: 'text', value: message.slice(cursor, span.start) });
+        }
+        segments.push({
+          type: 'emote',
+          value: message.slice(span.start, span.end),
+          imageUrl: span.image_url,
+        });
+        cursor = span.end;
+      });
+    if (cursor < message.length) {
+      segments.push({ type: 'text', value: message.slice(cursor) });
+    }
+    return segments;
+  }
+
   const tokens = message.split(/(\s+)/);
   const segments: Segment[] = [];
 
@@ -1,8 +1,10 @@
 'use client';
 
-import { useState, useCallback } from 'react';
-import { StreamResponse } from '@/lib/api';
+import { useState, useCallback, useEffect } from 'react';
+import { StreamResponse, PlaybackSource, PlaybackTokenResponse, refreshPlaybackToken } from '@/lib/api';
+import { playbackRefreshDelay } from '@/lib/playbackToken';
 import DynamicVideoPlayer from '@/components/video/DynamicVideoPlayer';
+import { Button } from '@/components/ui/button';
 import { StreamFetcher } from './StreamFetcher';
 
 interface StreamProviderProps {
@@ -11,17 +13,76 @@ interface StreamProviderProps {
   initialStream: StreamResponse | null;
 }
 
+// Applies a renewed token to the feed it was issued for
+function applyRenewedToken(stream: StreamResponse, renewed: PlaybackTokenResponse): StreamResponse {
+  const updated = stream.stream_id === renewed.stream_id ? { ...stream, ...renewed } : { ...stream };
+  updated.feeds = stream.feeds?.map(feed => (feed.stream_id === renewed.stream_id ? { ...feed, ...renewed } : feed));
+  return updated;
+}
+
 /**
  * Client component that manages stream state and fetches stream when authenticated
  */
 export function StreamProvider({ raceId, requiresLogin, initialStream }: StreamProviderProps) {
   const [stream, setStream] = useState<StreamResponse | null>(initialStream);
+  const [selectedFeedId, setSelectedFeedId] = useState<string | undefined>(undefined);
+  // Sources of the current feed that failed to play
+  const [failed, setFailed] = useState<{ feedId?: string; providers: string[] }>({ providers: [] });
 
   const handleStreamUpdate = useCallback((newStream: StreamResponse | null) => {
     setStream(newStream);
   }, []);
 
-  const streamUrl = stream?.cdn_url || stream?.origin_url;
+  // The selected feed while it can be watched, otherwise the default one
+  const feeds = stream?.feeds ?? [];
+  const feed = feeds.find(f => f.stream_id === selectedFeedId && !f.locked) ?? stream;
+
+  // Renew the playback token before it expires so long races keep playing
+  const tokenExpiresAt = feed?.token_expires_at;
+  const feedId = feed?.stream_id;
+  useEffect(() => {
+    if (!tokenExpiresAt) {
+      return;
+    }
+
+    const timer = setTimeout(async () => {
+      try {
+        const renewed = await refreshPlaybackToken(raceId, feedId);
+        setStream(prev => (prev ? applyRenewedToken(prev, renewed) : prev));
+      } catch (error) {
+        // Access was lost or the API is down; playback stops when the token expires
+        console.error('Failed to renew playback token:', error);
+      }
+    }, playbackRefreshDelay(tokenExpiresAt));
+
+    return () => clearTimeout(timer);
+  }, [raceId, feedId, tokenExpiresAt]);
+
+  // Play the best source that hasn't failed, falling back down the list. A
+  // live feed's DVR playlist comes first so viewers can rewind, and an ended
+  // feed's replay so they can watch the whole race.
+  let firstSource: PlaybackSource | null = null;
+  if (feed?.status === 'live' && feed.dvr_url) {
+    firstSource = { provider: 'dvr', stream_type: 'hls', url: feed.dvr_url, health: 'unknown' };
+  } else if (feed?.status === 'vod_ready' && feed.replay_url) {
+    firstSource = { provider: 'replay', stream_type: 'hls', url: feed.replay_url, health: 'unknown' };
+  }
+  const sources = firstSource ? [firstSource, ...(feed?.sources ?? [])] : feed?.sources ?? [];
+  const failedProviders = failed.feedId === feedId ? failed.providers : [];
+  const source = sources.find(s => !failedProviders.includes(s.provider)) ?? sources[sources.length - 1];
+  const sourceProvider = source?.provider;
+
+  const handlePlaybackError = useCallback(() => {
+    if (!sourceProvider) {
+      return;
+    }
+    setFailed(prev => {
+      const providers = prev.feedId === feedId ? prev.providers : [];
+      return providers.includes(sourceProvider) ? prev : { feedId, providers: [...providers, sourceProvider] };
+    });
+  }, [feedId, sourceProvider]);
+
+  const streamUrl = source ? source.url : feed?.cdn_url || feed?.origin_url;
 
   return (
     <>
@@ -32,15 +93,37 @@ export function StreamProvider({ raceId, requiresLogin, initialStream }: StreamP
         onStreamUpdate={handleStreamUpdate}
       />
       <DynamicVideoPlayer
+        // A new source starts with a fresh player
+        key={`${feedId}:${sourceProvider}`}
         streamUrl={streamUrl}
-        status={stream?.status || 'offline'}
-        streamType={stream?.stream_type}
-        sourceId={stream?.source_id}
-        streamId={stream?.stream_id}
-        provider={stream?.provider}
+        status={feed?.status || 'offline'}
+        streamType={source ? source.stream_type : feed?.stream_type}
+        sourceId={source ? source.source_id : feed?.source_id}
+        streamId={feed?.stream_id}
+        provider={sourceProvider ?? feed?.provider}
         requiresLogin={requiresLogin}
         raceId={raceId}
+        onPlaybackError={handlePlaybackError}
+        dvr={sourceProvider === 'dvr'}
       />
+      {feeds.length > 1 && (
+        <div className="flex flex-wrap gap-2 mt-3" role="group" aria-label="Camera feeds">
+          {feeds.map(f => (
+            <Button
+              key={f.stream_id}
+              size="sm"
+              variant={f.stream_id === feed?.stream_id ? 'default' : 'outline'}
+              disabled={f.locked}
+              title={f.locked ? (f.requires_payment ? 'Requires a race pass' : 'Sign in to watch') : undefined}
+              aria-pressed={f.stream_id === feed?.stream_id}
+              onClick={() => setSelectedFeedId(f.stream_id)}
+            >
+              {f.name}
+              {f.status === 'live' && <span className="ml-2 h-2 w-2 rounded-full bg-red-500" aria-label="live" />}
+            </Button>
+          ))}
+        </div>
+      )}
     </>
   );
 }
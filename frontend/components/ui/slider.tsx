@@ -6,12 +6,14 @@ import { cn } from "@/lib/utils"
 interface SliderProps {
   value: number[]
   onValueChange: (value: number[]) => void
+  min?: number
   max?: number
   step?: number
   className?: string
+  "aria-label"?: string
 }
 
-export function Slider({ value, onValueChange, max = 100, step = 1, className }: SliderProps) {
+export function Slider({ value, onValueChange, min = 0, max = 100, step = 1, className, "aria-label": ariaLabel }: SliderProps) {
   const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     onValueChange([parseFloat(e.target.value)])
   }
@@ -20,11 +22,12 @@ export function Slider({ value, onValueChange, max = 100, step = 1, className }:
     <div className={cn("relative flex w-full touch-none select-none items-center", className)}>
       <input
         type="range"
-        min={0}
+        min={min}
         max={max}
         step={step}
         value={value[0]}
         onChange={handleChange}
+        aria-label={ariaLabel}
         className="h-1.5 w-full cursor-pointer appearance-none rounded-full bg-secondary accent-primary"
       />
     </div>
@@ -16,9 +16,11 @@ interface DynamicVideoPlayerProps {
   provider?: string;
   requiresLogin?: boolean;
   raceId?: string;
+  onPlaybackError?: () => void;
+  dvr?: boolean;
 }
 
-export default function DynamicVideoPlayer({ streamUrl, status, streamType, sourceId, streamId, provider, requiresLogin, raceId }: DynamicVideoPlayerProps) {
+export default function DynamicVideoPlayer({ streamUrl, status, streamType, sourceId, streamId, provider, requiresLogin, raceId, onPlaybackError, dvr }: DynamicVideoPlayerProps) {
   return (
     <VideoPlayer
       streamUrl={streamUrl}
@@ -29,6 +31,8 @@ export default function DynamicVideoPlayer({ streamUrl, status, streamType, sour
       provider={provider}
       requiresLogin={requiresLogin}
       raceId={raceId}
+      onPlaybackError={onPlaybackError}
+      dvr={dvr}
     />
   );
 }
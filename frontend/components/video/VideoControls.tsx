@@ -20,6 +20,9 @@ interface VideoControlsProps {
   currentTime: number;
   duration: number | null;
   isLive: boolean;
+  dvr: boolean;
+  seekableStart: number | null;
+  liveEdge: number | null;
   qualityLevels: QualityLevel[];
   currentQuality: number;
   showSettingsMenu: boolean;
@@ -29,6 +32,7 @@ interface VideoControlsProps {
   onPlaybackSpeedChange: (speed: number) => void;
   onQualityChange: (level: number) => void;
   onSeek: (time: number) => void;
+  onGoLive: () => void;
   onToggleFullscreen: () => Promise<void>;
   onToggleSettingsMenu: () => void;
   onCloseMenus: () => void;
@@ -44,6 +48,9 @@ export const VideoControls = memo(function VideoControls({
   currentTime,
   duration,
   isLive,
+  dvr,
+  seekableStart,
+  liveEdge,
   qualityLevels,
   currentQuality,
   showSettingsMenu,
@@ -53,6 +60,7 @@ export const VideoControls = memo(function VideoControls({
   onPlaybackSpeedChange,
   onQualityChange,
   onSeek,
+  onGoLive,
   onToggleFullscreen,
   onToggleSettingsMenu,
   onCloseMenus,
@@ -76,6 +84,12 @@ export const VideoControls = memo(function VideoControls({
     return padded;
   };
 
+  // A live DVR stream can be rewound from seekableStart up to the live edge
+  const canRewind = isLive && dvr && seekableStart !== null && liveEdge !== null && liveEdge > seekableStart;
+  const behindLive = canRewind ? Math.max(0, liveEdge - currentTime) : 0;
+  // Within a few segments of the edge counts as live
+  const atLiveEdge = behindLive < 15;
+
   const timeLabel = isLive
     ? formatClock(Math.max(currentTime, watchTime))
     : `${formatClock(currentTime)} / ${formatClock(duration ?? 0)}`;
@@ -89,7 +103,33 @@ export const VideoControls = memo(function VideoControls({
       <div className="px-2 sm:px-3 pt-1.5 pb-2 flex flex-col gap-2 sm:gap-2.5">
         <div className="flex items-center gap-3">
           <div className="flex-1">
-            {isLive || !duration ? (
+            {canRewind ? (
+              <div className="flex items-center gap-3">
+                <Slider
+                  value={[Math.min(Math.max(currentTime, seekableStart), liveEdge)]}
+                  min={seekableStart}
+                  max={liveEdge}
+                  step={1}
+                  onValueChange={(val) => onSeek(val[0] || 0)}
+                  className="flex-1 cursor-pointer"
+                  aria-label="Rewind live stream"
+                />
+                <div className="flex items-center gap-2 text-[11px] sm:text-xs text-white/90 font-mono">
+                  {!atLiveEdge && <span>-{formatClock(behindLive)}</span>}
+                  <button
+                    type="button"
+                    onClick={onGoLive}
+                    disabled={atLiveEdge}
+                    className={`px-2.5 py-1 rounded-full text-xs sm:text-sm font-semibold leading-tight ${
+                      atLiveEdge ? 'bg-primary text-white' : 'bg-white/20 text-white hover:bg-white/30'
+                    }`}
+                    aria-label={atLiveEdge ? 'Live' : 'Go to live'}
+                  >
+                    LIVE
+                  </button>
+                </div>
+              </div>
+            ) : isLive || !duration ? (
               <div className="flex items-center gap-2">
                 <div className="flex-1 h-1.5 bg-white/15 rounded-full overflow-hidden">
                   <div className="h-full bg-primary w-full animate-pulse" />
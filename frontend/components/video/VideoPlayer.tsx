@@ -17,9 +17,11 @@ interface VideoPlayerProps {
   provider?: string;
   requiresLogin?: boolean; // Kept for backward compatibility but not used
   raceId?: string;
+  onPlaybackError?: () => void; // Playback failed for good, e.g. to switch sources
+  dvr?: boolean; // Playing a live DVR playlist, which can be rewound
 }
 
-export default function VideoPlayer({ streamUrl, status, streamType, sourceId, streamId }: VideoPlayerProps) {
+export default function VideoPlayer({ streamUrl, status, streamType, sourceId, streamId, onPlaybackError, dvr }: VideoPlayerProps) {
   const [showControls, setShowControls] = useState(false);
   const [showSettingsMenu, setShowSettingsMenu] = useState(false);
   const controlsTimeoutRef = useRef<ReturnType<typeof setTimeout> | null>(null);
@@ -40,6 +42,8 @@ export default function VideoPlayer({ streamUrl, status, streamType, sourceId, s
   watchTime,
   currentTime,
   duration,
+  seekableStart,
+  liveEdge,
   togglePlay,
   toggleMute,
   handleVolumeChange,
@@ -47,6 +51,7 @@ export default function VideoPlayer({ streamUrl, status, streamType, sourceId, s
   handleQualityChange,
   handleSeek,
   toggleFullscreen,
+  goLive,
 } = useVideoPlayer(streamUrl, status);
 
 const { trackPlay, trackPause, trackHeartbeat, trackEnded, trackError, trackBufferStart, trackBufferEnd } =
@@ -129,6 +134,12 @@ const { trackPlay, trackPause, trackHeartbeat, trackEnded, trackError, trackBuff
     };
   }, [isYouTube, streamId, trackHeartbeat, trackPlay]);
 
+  useEffect(() => {
+    if (error) {
+      onPlaybackError?.();
+    }
+  }, [error, onPlaybackError]);
+
   // Send errors once per occurrence
   useEffect(() => {
     if (!streamId || !error) return;
@@ -297,6 +308,9 @@ const { trackPlay, trackPause, trackHeartbeat, trackEnded, trackError, trackBuff
         currentTime={currentTime}
         duration={duration}
         isLive={status === 'live'}
+        dvr={dvr ?? false}
+        seekableStart={seekableStart}
+        liveEdge={liveEdge}
         qualityLevels={qualityLevels}
         currentQuality={currentQuality}
         showSettingsMenu={showSettingsMenu}
@@ -306,6 +320,7 @@ const { trackPlay, trackPause, trackHeartbeat, trackEnded, trackError, trackBuff
         onPlaybackSpeedChange={handlePlaybackSpeedChangeWithClose}
         onQualityChange={handleQualityChangeWithClose}
         onSeek={handleSeek}
+        onGoLive={goLive}
         onToggleFullscreen={toggleFullscreen}
         onToggleSettingsMenu={() => setShowSettingsMenu(!showSettingsMenu)}
         onCloseMenus={handleCloseMenus}
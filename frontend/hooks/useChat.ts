@@ -264,6 +264,10 @@ export function useChat(raceId: string, enabled: boolean): UseChatReturn {
                         role: msg.data.role,
                         badges: msg.data.badges,
                         special_emote: msg.data.special_emote,
+                        emotes: msg.data.emotes,
+                        reply_to_id: msg.data.reply_to_id,
+                        reply_to: msg.data.reply_to,
+                        mentions: msg.data.mentions,
                       };
                       setMessages((prev) => {
                         if (prev.some(m => m.id === chatMsg.id)) return prev;
@@ -271,6 +275,16 @@ export function useChat(raceId: string, enabled: boolean): UseChatReturn {
                       });
                     }
                     break;
+                  case 'history':
+                    if (Array.isArray(msg.data?.messages)) {
+                      const history: ChatMessage[] = msg.data.messages;
+                      setMessages((prev) => {
+                        const seen = new Set(prev.map((m) => m.id));
+                        const merged = [...history.filter((m) => !seen.has(m.id)), ...prev];
+                        return merged.sort((a, b) => a.created_at.localeCompare(b.created_at));
+                      });
+                    }
+                    break;
                   case 'error':
                     logger.warn('Chat server error:', msg.data?.message);
                     if (msg.data?.message) {
@@ -5,6 +5,7 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
 import Hls from 'hls.js';
 import { createContextLogger } from '@/lib/logger';
+import { getPlaybackToken, stripPlaybackToken, withPlaybackToken } from '@/lib/playbackToken';
 
 const logger = createContextLogger('useVideoPlayer');
 
@@ -31,6 +32,9 @@ export interface UseVideoPlayerReturn {
   watchTime: number;
   currentTime: number;
   duration: number | null;
+  // Rewindable range of a live stream, for DVR
+  seekableStart: number | null;
+  liveEdge: number | null;
   setIsPlaying: (playing: boolean) => void;
   setIsMuted: (muted: boolean) => void;
   setVolume: (volume: number[]) => void;
@@ -43,6 +47,7 @@ export interface UseVideoPlayerReturn {
   handleQualityChange: (level: number) => void;
   handleSeek: (time: number) => void;
   toggleFullscreen: () => Promise<void>;
+  goLive: () => void;
 }
 
 export function useVideoPlayer(streamUrl?: string, status: string = 'offline'): UseVideoPlayerReturn {
@@ -63,7 +68,25 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
   const [watchTime, setWatchTime] = useState(0);
   const [currentTime, setCurrentTime] = useState(0);
   const [duration, setDuration] = useState<number | null>(null);
+  const [seekableStart, setSeekableStart] = useState<number | null>(null);
+  const [liveEdge, setLiveEdge] = useState<number | null>(null);
   const networkErrorCountRef = useRef(0);
+  // Renewed playback tokens replace the one in the URL without reloading the player
+  const streamUrlRef = useRef(streamUrl);
+  const sourceUrl = streamUrl ? stripPlaybackToken(streamUrl) : undefined;
+
+  useEffect(() => {
+    streamUrlRef.current = streamUrl;
+
+    // Native HLS can't swap the token per request, so reload with the new one
+    const video = videoRef.current;
+    if (!streamUrl || hlsRef.current || !video || !video.src || video.src === streamUrl) return;
+    video.src = streamUrl;
+    video.play().catch((err) => {
+      logger.error('Error playing video:', err);
+      setIsPlaying(false);
+    });
+  }, [streamUrl]);
 
   // Watch time counter
   useEffect(() => {
@@ -159,12 +182,28 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
     }
   }, []);
 
+  const goLive = useCallback(() => {
+    const video = videoRef.current;
+    if (!video) return;
+    const edge = hlsRef.current?.liveSyncPosition
+      ?? (video.seekable.length > 0 ? video.seekable.end(video.seekable.length - 1) : null);
+    if (edge !== null && Number.isFinite(edge)) {
+      video.currentTime = edge;
+      setCurrentTime(edge);
+    }
+    if (video.paused) {
+      video.play().catch((err) => logger.error('Error playing video:', err));
+    }
+  }, []);
+
   // HLS initialization and management
   // eslint-disable-next-line react-hooks/exhaustive-deps
   useEffect(() => {
     if (!videoRef.current) return;
     setCurrentTime(0);
     setDuration(null);
+    setSeekableStart(null);
+    setLiveEdge(null);
 
     // Cleanup previous HLS instance
     if (hlsRef.current) {
@@ -195,6 +234,11 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
       if (Number.isFinite(dur) && dur !== Infinity) {
         setDuration(dur);
       }
+      const seekable = videoRef.current.seekable;
+      if (seekable.length > 0) {
+        setSeekableStart(seekable.start(0));
+        setLiveEdge(hlsRef.current?.liveSyncPosition ?? seekable.end(seekable.length - 1));
+      }
     };
 
     videoRef.current.addEventListener('waiting', handleWaiting);
@@ -211,6 +255,9 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
           enableWorker: true,
           lowLatencyMode: status === 'live',
           backBufferLength: 90,
+          xhrSetup: (xhr, url) => {
+            xhr.open('GET', withPlaybackToken(url, getPlaybackToken(streamUrlRef.current ?? '')), true);
+          },
         });
 
         hls.loadSource(streamUrl);
@@ -301,7 +348,7 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
       }
       networkErrorCountRef.current = 0;
     };
-  }, [streamUrl, status]); // Event handlers are stable, no need in deps
+  }, [sourceUrl, status]); // Event handlers are stable, no need in deps
 
   return {
     videoRef,
@@ -319,6 +366,8 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
     watchTime,
     currentTime,
     duration,
+    seekableStart,
+    liveEdge,
     setIsPlaying,
     setIsMuted,
     setVolume,
@@ -331,5 +380,6 @@ export function useVideoPlayer(streamUrl?: string, status: string = 'offline'):
     handleQualityChange,
     handleSeek,
     toggleFullscreen,
+    goLive,
   };
 }
@@ -24,14 +24,66 @@ export interface Race {
 
 export interface StreamResponse {
   stream_id?: string;
+  name?: string;
+  is_primary?: boolean;
+  access?: string;
   status: string;
   stream_type?: string;
   source_id?: string;
   origin_url?: string;
   cdn_url?: string;
   provider?: string;
+  token_expires_at?: string;
+  // Where the feed can be played from, best first; switch to the next one
+  // when playback fails
+  sources?: PlaybackSource[];
+  // DVR playlist of a live feed, rewindable by dvr_window_seconds
+  dvr_url?: string;
+  dvr_window_seconds?: number;
+  // Replay playlist of a vod_ready feed
+  replay_url?: string;
+  replay?: Replay;
+  locked?: boolean;
+  requires_login?: boolean;
+  requires_payment?: boolean;
+  // Every feed of the race, primary first; the fields above are the first
+  // one the viewer may watch
+  feeds?: StreamFeed[];
+}
+
+export interface PlaybackSource {
+  provider: string;
+  stream_type: string;
+  url?: string;
+  source_id?: string;
+  health: 'unknown' | 'healthy' | 'degraded';
 }
 
+export interface Replay {
+  id: string;
+  stream_id: string;
+  race_id: string;
+  status: string;
+  duration_seconds: number;
+  segment_count: number;
+  // Point to take the replay's thumbnail from
+  thumbnail_offset_seconds?: number;
+  recorded_from?: string;
+  recorded_to?: string;
+}
+
+// A replay in the catalog, with its race and feed
+export interface ReplayListing extends Replay {
+  race_name: string;
+  stream_name: string;
+  is_primary: boolean;
+  category?: string;
+}
+
+export type StreamFeed = Omit<StreamResponse, 'feeds'> & { stream_id: string; name: string };
+
+export type PlaybackTokenResponse = Pick<StreamResponse, 'stream_id' | 'origin_url' | 'cdn_url' | 'sources' | 'dvr_url' | 'dvr_window_seconds' | 'replay_url' | 'replay' | 'token_expires_at'>;
+
 /**
  * Fetches data from the API with standardized error handling
  */
@@ -74,6 +126,92 @@ export async function getRaceStream(id: string, suppressAuthErrorLog = false): P
   }
 }
 
+export async function refreshPlaybackToken(id: string, streamId?: string): Promise<PlaybackTokenResponse> {
+  const token = typeof window !== 'undefined' ? localStorage.getItem('auth_token') : null;
+  const query = streamId ? `?stream_id=${encodeURIComponent(streamId)}` : '';
+  return fetchAPI<PlaybackTokenResponse>(`/races/${id}/stream/token${query}`, {
+    method: 'POST',
+    headers: token ? { 'Authorization': `Bearer ${token}` } : undefined,
+  });
+}
+
+export interface ReplayPage {
+  replays: ReplayListing[];
+  limit: number;
+  offset: number;
+}
+
+export async function getReplays(category?: string, limit = 20, offset = 0): Promise<ReplayPage> {
+  const params = new URLSearchParams({ limit: String(limit), offset: String(offset) });
+  if (category) {
+    params.set('category', category);
+  }
+  return fetchAPI<ReplayPage>(`/replays?${params}`);
+}
+
+export type RaceMarkerType = 'start' | 'attack' | 'breakaway' | 'crash' | 'kom' | 'sprint' | 'km_to_go' | 'finish' | 'other';
+
+// A chapter of a feed's replay, offset_seconds into its recording
+export interface RaceMarker {
+  id: string;
+  race_id: string;
+  stream_id: string;
+  offset_seconds: number;
+  marker_type: RaceMarkerType;
+  title: string;
+  km_to_go?: number;
+}
+
+export interface RaceClip {
+  id: string;
+  race_id: string;
+  stream_id: string;
+  marker_id?: string;
+  title: string;
+  start_offset_seconds: number;
+  end_offset_seconds: number;
+  created_at: string;
+}
+
+export interface ClipResponse {
+  clip: RaceClip;
+  race_name: string;
+  stream_name: string;
+  clip_url: string;
+  token_expires_at?: string;
+}
+
+export interface CreateClipRequest {
+  marker_id?: string;
+  title?: string;
+  start_offset_seconds?: number;
+  end_offset_seconds?: number;
+}
+
+export async function getRaceMarkers(raceId: string, streamId?: string): Promise<RaceMarker[]> {
+  const query = streamId ? `?stream_id=${encodeURIComponent(streamId)}` : '';
+  const response = await fetchAPI<{ markers: RaceMarker[] }>(`/races/${raceId}/markers${query}`);
+  return response.markers;
+}
+
+export async function createClip(raceId: string, payload: CreateClipRequest, streamId?: string): Promise<RaceClip> {
+  const query = streamId ? `?stream_id=${encodeURIComponent(streamId)}` : '';
+  return fetchAuthenticatedAPI<RaceClip>(`/races/${raceId}/clips${query}`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify(payload),
+  });
+}
+
+export async function getClip(id: string): Promise<ClipResponse> {
+  const token = typeof window !== 'undefined' ? localStorage.getItem('auth_token') : null;
+  return fetchAPI<ClipResponse>(`/clips/${id}`, {
+    headers: token ? { 'Authorization': `Bearer ${token}` } : undefined,
+  });
+}
+
 export interface ChatMessage {
   id: string;
   race_id: string;
@@ -84,12 +222,33 @@ export interface ChatMessage {
   role?: 'viewer' | 'mod' | 'vip' | 'subscriber';
   badges?: string[];
   special_emote?: boolean;
+  emotes?: ChatEmoteSpan[];
+  reply_to_id?: string;
+  reply_to?: ChatMessageReference;
+  mentions?: string[];
+}
+
+// Offsets are string indexes into the message; end is exclusive
+export interface ChatEmoteSpan {
+  code: string;
+  start: number;
+  end: number;
+  image_url?: string;
+}
+
+export interface ChatMessageReference {
+  id: string;
+  user_id?: string;
+  username: string;
+  message: string;
+  deleted?: boolean;
 }
 
 export interface ChatHistoryResponse {
   messages: ChatMessage[];
   limit: number;
-  offset: number;
+  has_more: boolean;
+  next_before: string | null;
 }
 
 export interface ChatPollOption {
@@ -146,8 +305,9 @@ export interface ChatStatsResponse {
   concurrent_connections: number;
 }
 
-export async function getChatHistory(raceId: string, limit = 50, offset = 0): Promise<ChatHistoryResponse> {
-  return fetchAPI<ChatHistoryResponse>(`/races/${raceId}/chat/history?limit=${limit}&offset=${offset}`);
+export async function getChatHistory(raceId: string, limit = 50, before?: string): Promise<ChatHistoryResponse> {
+  const cursor = before ? `&before=${encodeURIComponent(before)}` : '';
+  return fetchAPI<ChatHistoryResponse>(`/races/${raceId}/chat/history?limit=${limit}${cursor}`);
 }
 
 export async function getChatStats(raceId: string): Promise<ChatStatsResponse> {
@@ -0,0 +1,26 @@
+// Playback URLs served from the HLS origin carry a token as a path segment:
+// /hls/t/<token>/<stream>/index.m3u8. Segments are resolved relative to the
+// playlist, so they carry it too.
+const PLAYBACK_TOKEN_SEGMENT = /\/hls\/t\/[^/]+\//;
+
+export function getPlaybackToken(url: string): string | null {
+  const match = url.match(PLAYBACK_TOKEN_SEGMENT);
+  return match ? match[0].slice('/hls/t/'.length, -1) : null;
+}
+
+export function stripPlaybackToken(url: string): string {
+  return url.replace(PLAYBACK_TOKEN_SEGMENT, '/hls/');
+}
+
+export function withPlaybackToken(url: string, token: string | null): string {
+  if (!token) {
+    return url;
+  }
+  return url.replace(PLAYBACK_TOKEN_SEGMENT, `/hls/t/${token}/`);
+}
+
+// Renew a minute before expiry, or halfway through short-lived tokens
+export function playbackRefreshDelay(expiresAt: string, now = Date.now()): number {
+  const remaining = new Date(expiresAt).getTime() - now;
+  return Math.max(Math.min(remaining - 60_000, remaining / 2), 0);
+}
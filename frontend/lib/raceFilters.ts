@@ -1,11 +1,15 @@
 import { Race } from './api';
 
 export function isRaceReplay(race: Race): boolean {
-  // Offline races are replays
-  return race.stream_status === 'offline';
+  // Ended races are replays
+  return race.stream_status === 'ended' || race.stream_status === 'vod_ready';
 }
 
 export function isRaceUpcomingOrLive(race: Race): boolean {
   // Live or upcoming races show in main races page
-  return race.stream_status === 'live' || race.stream_status === 'upcoming';
+  return (
+    race.stream_status === 'live' ||
+    race.stream_status === 'pre_show' ||
+    race.stream_status === 'scheduled'
+  );
 }